@@ -0,0 +1,202 @@
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	Minute = time.Minute
+	Hour   = time.Hour
+)
+
+// Bucket is the pre-aggregated count of the events of a key, over a time window.
+type Bucket struct {
+	Key string `json:"key"`
+	// Start is the beginning of the window, truncated to the resolution.
+	Start      time.Time     `json:"start"`
+	Resolution time.Duration `json:"resolution"`
+	Count      int64         `json:"count"`
+}
+
+// Flusher persists aggregated buckets. Flushing the same bucket twice must add the counts, as buckets are flushed
+// as soon as possible, before their window is over.
+type Flusher interface {
+	Flush(ctx context.Context, buckets []Bucket) error
+}
+
+type Config struct {
+	// Resolution is the size of the aggregation windows, such as Minute or Hour. Defaults to Minute.
+	Resolution time.Duration
+	// FlushInterval is the delay between two flushes. Defaults to 10 seconds.
+	FlushInterval time.Duration
+	// MaxBuckets is the maximum number of buckets kept in memory. When a flush fails, the buckets are kept for the
+	// next attempt, and new events are dropped once the limit is reached. Defaults to 100000.
+	MaxBuckets int
+	// ShutdownTimeout bounds the final flush, once the context of Run is canceled. Defaults to 10 seconds.
+	ShutdownTimeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Resolution <= 0 {
+		c.Resolution = Minute
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 10 * time.Second
+	}
+	if c.MaxBuckets <= 0 {
+		c.MaxBuckets = 100000
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = 10 * time.Second
+	}
+
+	return c
+}
+
+type bucketKey struct {
+	key   string
+	start time.Time
+}
+
+// Aggregator counts events in memory, and periodically flushes the counts per key and per window.
+//
+//	aggregator := aggregate.NewAggregator(aggregate.NewPostgresFlusher(db), logger, aggregate.Config{})
+//	go aggregator.Run(ctx)
+//
+//	aggregator.Increment("profile_views:" + profileID)
+//
+// Counts are kept in memory between two flushes: events recorded right before a crash are lost, but a regular
+// shutdown (cancellation of the context of Run) flushes the remaining counts.
+type Aggregator struct {
+	flusher Flusher
+	logger  monitor.Logger
+	config  Config
+
+	mu      sync.Mutex
+	buckets map[bucketKey]int64
+	dropped int64
+}
+
+func NewAggregator(flusher Flusher, logger monitor.Logger, config Config) *Aggregator {
+	return &Aggregator{
+		flusher: flusher,
+		logger:  logger,
+		config:  config.withDefaults(),
+		buckets: make(map[bucketKey]int64),
+	}
+}
+
+// Increment adds one event to the current window of the key.
+func (a *Aggregator) Increment(key string) {
+	a.Add(key, 1)
+}
+
+// Add adds count events to the current window of the key.
+func (a *Aggregator) Add(key string, count int64) {
+	a.AddAt(key, count, time.Now())
+}
+
+// AddAt adds count events to the window of the key containing the given time.
+func (a *Aggregator) AddAt(key string, count int64, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.add(bucketKey{key: key, start: at.UTC().Truncate(a.config.Resolution)}, count)
+}
+
+// add increments a bucket. The caller must hold the lock.
+func (a *Aggregator) add(key bucketKey, count int64) {
+	if _, ok := a.buckets[key]; !ok && len(a.buckets) >= a.config.MaxBuckets {
+		a.dropped += count
+		return
+	}
+
+	a.buckets[key] += count
+}
+
+// Pending returns the number of buckets waiting to be flushed.
+func (a *Aggregator) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.buckets)
+}
+
+// Flush writes the pending buckets. On failure, the buckets are merged back with the events recorded in the
+// meantime, for the next flush.
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.buckets
+	dropped := a.dropped
+	a.buckets = make(map[bucketKey]int64, len(pending))
+	a.dropped = 0
+	a.mu.Unlock()
+
+	if dropped > 0 {
+		a.logger.Warn(fmt.Sprintf("[aggregate] %d events dropped, the buffer is full", dropped))
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	buckets := make([]Bucket, 0, len(pending))
+	for key, count := range pending {
+		buckets = append(buckets, Bucket{
+			Key:        key.key,
+			Start:      key.start,
+			Resolution: a.config.Resolution,
+			Count:      count,
+		})
+	}
+
+	// Stable ordering limits lock contention between instances flushing to the same rows.
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Key != buckets[j].Key {
+			return buckets[i].Key < buckets[j].Key
+		}
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+
+	if err := a.flusher.Flush(ctx, buckets); err != nil {
+		a.mu.Lock()
+		for key, count := range pending {
+			a.add(key, count)
+		}
+		a.mu.Unlock()
+
+		return fmt.Errorf("flush %d buckets: %w", len(buckets), err)
+	}
+
+	return nil
+}
+
+// Run flushes the buckets periodically, until the context is canceled. The remaining buckets are then flushed one
+// last time, within the shutdown timeout.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCTX, cancel := ctxutil.DetachWithTimeout(ctx, a.config.ShutdownTimeout)
+			defer cancel()
+
+			if err := a.Flush(flushCTX); err != nil {
+				a.logger.Error(err, "[aggregate] final flush failed, counts are lost")
+			}
+			return
+		case <-ticker.C:
+			if err := a.Flush(ctx); err != nil && ctx.Err() == nil {
+				a.logger.Error(err, "[aggregate] flush failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package aggregate
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// CounterRow stores the count of a key over a window.
+type CounterRow struct {
+	bun.BaseModel `bun:"table:aggregate_counters,alias:counter"`
+
+	Key string `bun:"key,pk"`
+	// Resolution is the size of the window, in seconds.
+	Resolution int64     `bun:"resolution,pk"`
+	BucketAt   time.Time `bun:"bucket_at,pk"`
+	Count      int64     `bun:"count,notnull"`
+	UpdatedAt  time.Time `bun:"updated_at,notnull"`
+}
+
+// CreateTable creates the counters table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*CounterRow)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+type postgresFlusher struct {
+	db bun.IDB
+}
+
+func (f *postgresFlusher) Flush(ctx context.Context, buckets []Bucket) error {
+	now := time.Now()
+	rows := make([]*CounterRow, 0, len(buckets))
+
+	for _, bucket := range buckets {
+		rows = append(rows, &CounterRow{
+			Key:        bucket.Key,
+			Resolution: int64(bucket.Resolution / time.Second),
+			BucketAt:   bucket.Start,
+			Count:      bucket.Count,
+			UpdatedAt:  now,
+		})
+	}
+
+	_, err := f.db.NewInsert().
+		Model(&rows).
+		On("CONFLICT (key, resolution, bucket_at) DO UPDATE").
+		Set("count = counter.count + EXCLUDED.count").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+
+	return err
+}
+
+// NewPostgresFlusher creates a flusher that adds the counts to the counters table, with a single upsert per flush.
+func NewPostgresFlusher(db bun.IDB) Flusher {
+	return &postgresFlusher{db: db}
+}
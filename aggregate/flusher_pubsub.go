@@ -0,0 +1,53 @@
+package aggregate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+)
+
+// pubsubBatchSize is the maximum number of buckets per Pub/Sub message.
+const pubsubBatchSize = 500
+
+type pubsubFlusher struct {
+	service *pubsub.Service
+	topic   string
+}
+
+func (f *pubsubFlusher) Flush(ctx context.Context, buckets []Bucket) error {
+	messages := make([]*pubsub.PubsubMessage, 0, len(buckets)/pubsubBatchSize+1)
+
+	for start := 0; start < len(buckets); start += pubsubBatchSize {
+		end := min(start+pubsubBatchSize, len(buckets))
+
+		data, err := json.Marshal(buckets[start:end])
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, &pubsub.PubsubMessage{Data: base64.StdEncoding.EncodeToString(data)})
+	}
+
+	_, err := f.service.Projects.Topics.Publish(f.topic, &pubsub.PublishRequest{Messages: messages}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("publish buckets: %w", err)
+	}
+
+	return nil
+}
+
+// NewPubSubFlusher creates a flusher that publishes the buckets on a Pub/Sub topic, in the form
+// "projects/{project}/topics/{topic}". Each message holds a JSON array of up to 500 buckets.
+func NewPubSubFlusher(ctx context.Context, topic string, opts ...option.ClientOption) (Flusher, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create pubsub client: %w", err)
+	}
+
+	return &pubsubFlusher{service: service, topic: topic}, nil
+}
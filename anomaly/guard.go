@@ -0,0 +1,293 @@
+package anomaly
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/in-rich/lib-go/monitor"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Action is the response of the guard to a suspicious identity.
+type Action int
+
+const (
+	// ActionLog only reports suspicious identities, to tune thresholds before enforcing them.
+	ActionLog Action = iota
+	// ActionTarpit delays the requests of suspicious identities, which slows scrapers down without revealing the
+	// detection.
+	ActionTarpit
+	// ActionBlock rejects the requests of suspicious identities for the block duration.
+	ActionBlock
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionTarpit:
+		return "tarpit"
+	case ActionBlock:
+		return "block"
+	default:
+		return "log"
+	}
+}
+
+const (
+	SignalRateSpike        = "rate_spike"
+	SignalEnumeration      = "enumeration"
+	SignalImpossibleTravel = "impossible_travel"
+)
+
+// Locator returns the approximate coordinates of an IP address, usually from a GeoIP database.
+type Locator func(ip string) (latitude, longitude float64, ok bool)
+
+type Config struct {
+	// Identity identifies the caller, such as the authenticated user. Defaults to the client IP.
+	Identity func(c *gin.Context) string
+	// RateLimit is the number of requests per RateWindow above which an identity is suspicious. Defaults to 300.
+	RateLimit int
+	// RateWindow defaults to 1 minute.
+	RateWindow time.Duration
+	// SequentialID extracts the numeric identifier of the requested resource, if any, to detect enumeration. The
+	// detection is disabled when nil.
+	SequentialID func(c *gin.Context) (int64, bool)
+	// SequentialLimit is the number of consecutive requests for increasing identifiers, with a step of at most
+	// SequentialStep, above which an identity is suspicious. Defaults to 20.
+	SequentialLimit int
+	// SequentialStep defaults to 1.
+	SequentialStep int64
+	// Locator enables the detection of impossible travel between consecutive client IPs. Disabled when nil.
+	Locator Locator
+	// MaxSpeed is the maximum plausible travel speed between two requests, in km/h. Defaults to 1000.
+	MaxSpeed float64
+
+	Action Action
+	// TarpitDelay is the delay added to the requests of suspicious identities with ActionTarpit. Defaults to 5
+	// seconds.
+	TarpitDelay time.Duration
+	// BlockDuration is the duration of the sanctions of suspicious identities. Defaults to 15 minutes.
+	BlockDuration time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Identity == nil {
+		c.Identity = func(c *gin.Context) string { return c.ClientIP() }
+	}
+	if c.RateLimit <= 0 {
+		c.RateLimit = 300
+	}
+	if c.RateWindow <= 0 {
+		c.RateWindow = time.Minute
+	}
+	if c.SequentialLimit <= 0 {
+		c.SequentialLimit = 20
+	}
+	if c.SequentialStep <= 0 {
+		c.SequentialStep = 1
+	}
+	if c.MaxSpeed <= 0 {
+		c.MaxSpeed = 1000
+	}
+	if c.TarpitDelay <= 0 {
+		c.TarpitDelay = 5 * time.Second
+	}
+	if c.BlockDuration <= 0 {
+		c.BlockDuration = 15 * time.Minute
+	}
+
+	return c
+}
+
+type location struct {
+	ip        string
+	latitude  float64
+	longitude float64
+	at        time.Time
+}
+
+type identityState struct {
+	windowStart time.Time
+	count       int
+
+	lastID int64
+	streak int
+
+	lastLocation *location
+
+	sanctionedUntil time.Time
+	lastSeen        time.Time
+}
+
+// Guard tracks the request patterns of every identity, and sanctions the suspicious ones.
+//
+//	guard := anomaly.NewGuard(logger, anomaly.Config{
+//		Action: anomaly.ActionTarpit,
+//		SequentialID: func(c *gin.Context) (int64, bool) {
+//			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+//			return id, err == nil
+//		},
+//	})
+//	router.Use(guard.Middleware())
+//
+// State is kept in memory, per instance.
+type Guard struct {
+	logger monitor.Logger
+	config Config
+
+	mu         sync.Mutex
+	identities map[string]*identityState
+	lastEvict  time.Time
+}
+
+func NewGuard(logger monitor.Logger, config Config) *Guard {
+	return &Guard{
+		logger:     logger,
+		config:     config.withDefaults(),
+		identities: make(map[string]*identityState),
+		lastEvict:  time.Now(),
+	}
+}
+
+// Middleware applies the guard to the requests.
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := g.config.Identity(c)
+		if identity == "" {
+			c.Next()
+			return
+		}
+
+		sanctioned, signals := g.observe(c, identity)
+
+		for _, signal := range signals {
+			g.logger.Warn(fmt.Sprintf(
+				"[anomaly] signal=%s identity=%s ip=%s path=%s action=%s",
+				signal, identity, c.ClientIP(), c.FullPath(), g.config.Action,
+			))
+		}
+
+		if !sanctioned {
+			c.Next()
+			return
+		}
+
+		switch g.config.Action {
+		case ActionBlock:
+			c.Header("Retry-After", strconv.Itoa(int(g.config.BlockDuration.Seconds())))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		case ActionTarpit:
+			timer := time.NewTimer(g.config.TarpitDelay)
+			select {
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			case <-timer.C:
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// observe updates the state of the identity with the current request, and returns whether the identity is
+// sanctioned, and the signals raised by the request.
+func (g *Guard) observe(c *gin.Context, identity string) (bool, []string) {
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.evict(now)
+
+	state, ok := g.identities[identity]
+	if !ok {
+		state = &identityState{windowStart: now}
+		g.identities[identity] = state
+	}
+	state.lastSeen = now
+
+	var signals []string
+
+	if now.Sub(state.windowStart) > g.config.RateWindow {
+		state.windowStart = now
+		state.count = 0
+	}
+	state.count++
+	if state.count == g.config.RateLimit+1 {
+		signals = append(signals, SignalRateSpike)
+	}
+
+	if g.config.SequentialID != nil {
+		if id, ok := g.config.SequentialID(c); ok {
+			if delta := id - state.lastID; state.streak > 0 && delta > 0 && delta <= g.config.SequentialStep {
+				state.streak++
+			} else {
+				state.streak = 1
+			}
+			state.lastID = id
+
+			if state.streak == g.config.SequentialLimit+1 {
+				signals = append(signals, SignalEnumeration)
+			}
+		}
+	}
+
+	if g.config.Locator != nil {
+		ip := c.ClientIP()
+		if latitude, longitude, ok := g.config.Locator(ip); ok {
+			current := &location{ip: ip, latitude: latitude, longitude: longitude, at: now}
+
+			if previous := state.lastLocation; previous != nil && previous.ip != ip {
+				distance := haversine(previous.latitude, previous.longitude, latitude, longitude)
+				hours := math.Max(now.Sub(previous.at).Hours(), 1.0/3600)
+
+				if distance/hours > g.config.MaxSpeed {
+					signals = append(signals, SignalImpossibleTravel)
+				}
+			}
+
+			state.lastLocation = current
+		}
+	}
+
+	if len(signals) > 0 && g.config.Action != ActionLog {
+		state.sanctionedUntil = now.Add(g.config.BlockDuration)
+	}
+
+	return now.Before(state.sanctionedUntil), signals
+}
+
+// evict forgets the identities idle for longer than the tracked windows. The caller must hold the lock.
+func (g *Guard) evict(now time.Time) {
+	if now.Sub(g.lastEvict) < time.Minute {
+		return
+	}
+	g.lastEvict = now
+
+	idle := max(g.config.RateWindow, time.Hour)
+	for identity, state := range g.identities {
+		if now.Sub(state.lastSeen) > idle && now.After(state.sanctionedUntil) {
+			delete(g.identities, identity)
+		}
+	}
+}
+
+// haversine returns the distance between two coordinates, in kilometers.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
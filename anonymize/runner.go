@@ -0,0 +1,230 @@
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrProdEnv     = errors.New("anonymization must not run in the production environment")
+	ErrMissingSalt = errors.New("anonymization requires a salt")
+	ErrNoColumns   = errors.New("table has no column to anonymize")
+)
+
+// Table declares the columns of a table to anonymize, and how.
+type Table struct {
+	Name string
+	// Key is a unique column, used to paginate the rows. Defaults to "id".
+	Key string
+	// Columns maps the columns to their strategy. Columns not listed are kept as is.
+	Columns map[string]Strategy
+}
+
+type Config struct {
+	// Salt keys the digests of the values. Keep it secret, and different from production secrets: anyone knowing
+	// it can confirm a guessed value from its hash. Required.
+	Salt string
+	// BatchSize is the number of rows updated per transaction. Defaults to 1000.
+	BatchSize int
+}
+
+func (c Config) withDefaults() Config {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+
+	return c
+}
+
+// Runner anonymizes the tables of a database restored from a production dump.
+//
+//	runner := anonymize.NewRunner(db, logger, anonymize.Config{Salt: cfg.AnonymizeSalt})
+//	err := runner.Run(ctx, anonymize.Table{
+//		Name: "users",
+//		Columns: map[string]anonymize.Strategy{
+//			"email":      anonymize.FakeEmail(),
+//			"first_name": anonymize.FakeFirstName(),
+//			"last_name":  anonymize.FakeLastName(),
+//			"phone":      anonymize.Null(),
+//			"linkedin":   anonymize.Hash(16),
+//		},
+//	})
+//
+// Substitutions are deterministic: the same original value gets the same anonymized value in every table. The
+// runner refuses to run in the production environment.
+type Runner struct {
+	db     bun.IDB
+	logger monitor.Logger
+	config Config
+}
+
+func NewRunner(db bun.IDB, logger monitor.Logger, config Config) *Runner {
+	return &Runner{db: db, logger: logger, config: config.withDefaults()}
+}
+
+// Run anonymizes the tables, one after the other.
+func (r *Runner) Run(ctx context.Context, tables ...Table) error {
+	for _, table := range tables {
+		if err := r.RunTable(ctx, table); err != nil {
+			return fmt.Errorf("anonymize %s: %w", table.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RunTable anonymizes a table, by batches of rows ordered by key.
+func (r *Runner) RunTable(ctx context.Context, table Table) error {
+	if deploy.ENV == deploy.ProdENV {
+		return ErrProdEnv
+	}
+	if r.config.Salt == "" {
+		return ErrMissingSalt
+	}
+	if len(table.Columns) == 0 {
+		return ErrNoColumns
+	}
+	if table.Key == "" {
+		table.Key = "id"
+	}
+
+	columns := make([]string, 0, len(table.Columns))
+	for column := range table.Columns {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	var (
+		lastKey any
+		total   int
+	)
+
+	for {
+		count, key, err := r.batch(ctx, table, columns, lastKey)
+		if err != nil {
+			return err
+		}
+
+		total += count
+		if count < r.config.BatchSize {
+			break
+		}
+
+		lastKey = key
+		r.logger.Info(fmt.Sprintf("[anonymize] %s: %d rows anonymized", table.Name, total))
+	}
+
+	r.logger.Info(fmt.Sprintf("[anonymize] %s: done, %d rows anonymized", table.Name, total))
+	return nil
+}
+
+// batch anonymizes the rows following lastKey, and returns their number and the key of the last one.
+func (r *Runner) batch(ctx context.Context, table Table, columns []string, lastKey any) (int, any, error) {
+	selected := make([]string, len(columns))
+	for i := range columns {
+		selected[i] = "?::text"
+	}
+
+	args := []any{bun.Ident(table.Key)}
+	for _, column := range columns {
+		args = append(args, bun.Ident(column))
+	}
+	args = append(args, bun.Ident(table.Name))
+
+	query := "SELECT ?, " + strings.Join(selected, ", ") + " FROM ?"
+	if lastKey != nil {
+		query += " WHERE ? > ?"
+		args = append(args, bun.Ident(table.Key), lastKey)
+	}
+	query += " ORDER BY ? LIMIT ?"
+	args = append(args, bun.Ident(table.Key), r.config.BatchSize)
+
+	var (
+		count int
+		key   any
+	)
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		type row struct {
+			key    any
+			values []any
+		}
+
+		var pending []row
+		for rows.Next() {
+			values := make([]sql.NullString, len(columns))
+			dest := []any{&key}
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+
+			if err := rows.Scan(dest...); err != nil {
+				_ = rows.Close()
+				return err
+			}
+
+			anonymized := make([]any, len(columns))
+			for i, column := range columns {
+				anonymized[i] = table.Columns[column](r.value(values[i]))
+			}
+
+			// Drivers return some types, such as uuid, as bytes: pass them back as text, for Postgres to cast.
+			if raw, ok := key.([]byte); ok {
+				key = string(raw)
+			}
+
+			pending = append(pending, row{key: key, values: anonymized})
+		}
+
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		assignments := make([]string, len(columns))
+		for i := range columns {
+			assignments[i] = "? = ?"
+		}
+		update := "UPDATE ? SET " + strings.Join(assignments, ", ") + " WHERE ? = ?"
+
+		for _, pendingRow := range pending {
+			updateArgs := []any{bun.Ident(table.Name)}
+			for i, column := range columns {
+				updateArgs = append(updateArgs, bun.Ident(column), pendingRow.values[i])
+			}
+			updateArgs = append(updateArgs, bun.Ident(table.Key), pendingRow.key)
+
+			if _, err := tx.ExecContext(ctx, update, updateArgs...); err != nil {
+				return err
+			}
+		}
+
+		count = len(pending)
+		return nil
+	})
+
+	return count, key, err
+}
+
+func (r *Runner) value(text sql.NullString) Value {
+	mac := hmac.New(sha256.New, []byte(r.config.Salt))
+	mac.Write([]byte(text.String))
+
+	return Value{Text: text.String, Null: !text.Valid, Digest: mac.Sum(nil)}
+}
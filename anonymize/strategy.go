@@ -0,0 +1,140 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Value is the original value of a column, read as text.
+type Value struct {
+	Text string
+	Null bool
+	// Digest is an HMAC-SHA256 of the text, keyed with the salt of the runner. Equal values get equal digests in
+	// every table, so anonymized values can still be joined.
+	Digest []byte
+}
+
+// rand returns a generator seeded with the digest, so the substitution of a value is stable across runs.
+func (v Value) rand() *rand.Rand {
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(v.Digest[:8]), binary.BigEndian.Uint64(v.Digest[8:16])))
+}
+
+// Strategy returns the anonymized value of a column. Returning nil sets the column to NULL.
+type Strategy func(value Value) any
+
+// stable wraps a strategy so NULL values stay NULL.
+func stable(strategy Strategy) Strategy {
+	return func(value Value) any {
+		if value.Null {
+			return nil
+		}
+
+		return strategy(value)
+	}
+}
+
+// Null sets the column to NULL.
+func Null() Strategy {
+	return func(Value) any {
+		return nil
+	}
+}
+
+// Fixed sets the column to the same value on every row, for example an empty string on NOT NULL columns.
+func Fixed(value any) Strategy {
+	return func(Value) any {
+		return value
+	}
+}
+
+// Hash replaces the value with the hexadecimal digest, truncated to length characters when length is positive.
+func Hash(length int) Strategy {
+	return stable(func(value Value) any {
+		digest := hex.EncodeToString(value.Digest)
+		if length > 0 && length < len(digest) {
+			digest = digest[:length]
+		}
+
+		return digest
+	})
+}
+
+// HashUUID replaces the value with a UUID derived from the digest, for uuid columns holding external
+// identifiers.
+func HashUUID() Strategy {
+	return stable(func(value Value) any {
+		b := make([]byte, 16)
+		copy(b, value.Digest)
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	})
+}
+
+var (
+	firstNames = []string{
+		"Alice", "Bruno", "Camille", "David", "Emma", "Farid", "Gabrielle", "Hugo", "Ines", "Jules", "Karim", "Lea",
+		"Marc", "Nina", "Oscar", "Paula", "Quentin", "Rose", "Samuel", "Theo", "Ursula", "Victor", "Wendy", "Yanis",
+	}
+	lastNames = []string{
+		"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau", "Simon",
+		"Laurent", "Lefebvre", "Michel", "Garcia", "Fournier", "Lambert", "Girard", "Bonnet", "Mercier",
+	}
+	companySuffixes = []string{"Labs", "Group", "Partners", "Industries", "Consulting", "Systems", "Studio"}
+)
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.IntN(len(values))]
+}
+
+// FakeFirstName replaces the value with a first name.
+func FakeFirstName() Strategy {
+	return stable(func(value Value) any {
+		return pick(value.rand(), firstNames)
+	})
+}
+
+// FakeLastName replaces the value with a last name.
+func FakeLastName() Strategy {
+	return stable(func(value Value) any {
+		return pick(value.rand(), lastNames)
+	})
+}
+
+// FakeName replaces the value with a full name.
+func FakeName() Strategy {
+	return stable(func(value Value) any {
+		r := value.rand()
+		return pick(r, firstNames) + " " + pick(r, lastNames)
+	})
+}
+
+// FakeEmail replaces the value with an address of the example.com domain, which never receives emails. The
+// address embeds 8 bytes of the digest of the original value, so unique constraints hold: collisions only become
+// likely around billions of distinct values.
+func FakeEmail() Strategy {
+	return stable(func(value Value) any {
+		r := value.rand()
+		local := strings.ToLower(pick(r, firstNames) + "." + pick(r, lastNames))
+		return fmt.Sprintf("%s.%s@example.com", local, hex.EncodeToString(value.Digest[:8]))
+	})
+}
+
+// FakePhone replaces the value with a number of the +1 555 fictional range.
+func FakePhone() Strategy {
+	return stable(func(value Value) any {
+		return fmt.Sprintf("+1555%07d", value.rand().IntN(10_000_000))
+	})
+}
+
+// FakeCompany replaces the value with a company name.
+func FakeCompany() Strategy {
+	return stable(func(value Value) any {
+		r := value.rand()
+		return pick(r, lastNames) + " " + pick(r, companySuffixes)
+	})
+}
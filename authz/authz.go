@@ -0,0 +1,231 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/pattern"
+	"google.golang.org/api/idtoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"slices"
+	"strings"
+)
+
+// AnyCaller allows every authenticated caller to invoke a method.
+const AnyCaller = "*"
+
+// builtinExempt lists the methods every caller may invoke: health checks.
+var builtinExempt = pattern.NewSet("/grpc.health.v1.Health/*")
+
+// Violation is a call rejected by the matrix.
+type Violation struct {
+	Method string
+	// Caller is the service account of the caller, or empty when the call carries no identity.
+	Caller string
+	// Rule is the key of the matrix that matched the method, or empty when no key matched.
+	Rule string
+}
+
+func (v Violation) String() string {
+	caller := v.Caller
+	if caller == "" {
+		caller = "anonymous caller"
+	}
+
+	if v.Rule == "" {
+		return fmt.Sprintf("%s may not call %s: method is not listed", caller, v.Method)
+	}
+
+	return fmt.Sprintf("%s may not call %s: not allowed by %s", caller, v.Method, v.Rule)
+}
+
+// Config is the authorization matrix of a service, read with deploy.LoadConfig. It maps full method names to the
+// service accounts allowed to invoke them.
+//
+//	authz:
+//	  audience: https://notes-abc123.a.run.app
+//	  matrix:
+//	    /notes.v1.Notes/*:
+//	      - api-gateway@inrich-prod.iam.gserviceaccount.com
+//	      - sync-worker@inrich-prod.iam.gserviceaccount.com
+//	    /notes.v1.Notes/DeleteNote:
+//	      - api-gateway@inrich-prod.iam.gserviceaccount.com
+//	    /notes.v1.Notes/GetNote:
+//	      - "*"
+//
+// Keys ending with "*" match by prefix. The most specific key applies alone: the callers of broader keys are not
+// inherited. "*" allows every authenticated caller.
+type Config struct {
+	Matrix map[string][]string `yaml:"matrix" json:"matrix"`
+	// AllowUnlisted lets every caller invoke the methods matching no key. Defaults to false: unlisted methods are
+	// rejected.
+	AllowUnlisted bool `yaml:"allowUnlisted" json:"allowUnlisted"`
+	// DryRun logs the violations without rejecting the calls, to roll out a new matrix.
+	DryRun bool `yaml:"dryRun" json:"dryRun"`
+	// Enabled turns the enforcement on. Defaults to true in release environments only: local calls carry no ID
+	// token.
+	Enabled *bool `yaml:"enabled" json:"enabled"`
+	// Audience is the audience the ID tokens of the callers are issued for, usually the URL of the service. When set,
+	// and Caller is not, callers are identified by ServiceAccount, which verifies the tokens.
+	Audience string `yaml:"audience" json:"audience"`
+	// Caller returns the verified service account of the caller, or an empty string. Defaults to ServiceAccount
+	// with the Audience. When neither is set, no caller is identified, and every call matching a key is rejected.
+	Caller func(ctx context.Context) string `yaml:"-" json:"-"`
+	// OnViolation is called for every violation, in addition to the log, for example to count them.
+	OnViolation func(violation Violation) `yaml:"-" json:"-"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Enabled == nil {
+		enabled := deploy.IsReleaseEnv()
+		c.Enabled = &enabled
+	}
+	if c.Caller == nil && c.Audience != "" {
+		c.Caller = ServiceAccount(c.Audience)
+	}
+
+	return c
+}
+
+// googleIssuers are the issuers of the ID tokens of Google service accounts.
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
+// ServiceAccount returns a function identifying callers by the email of their Google ID token. The token is
+// verified: its signature, expiry, issuer and audience must be valid, and its email verified. Calls without a
+// valid token have no caller.
+//
+// Verification does not rely on the platform: on Cloud Run, GKE or on-premises installations alike, a forged
+// token is rejected.
+func ServiceAccount(audience string) func(ctx context.Context) string {
+	return func(ctx context.Context) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return ""
+		}
+
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return ""
+		}
+
+		payload, err := idtoken.Validate(ctx, token, audience)
+		if err != nil || !slices.Contains(googleIssuers, payload.Issuer) {
+			return ""
+		}
+
+		email, _ := payload.Claims["email"].(string)
+		verified, _ := payload.Claims["email_verified"].(bool)
+		if !verified {
+			return ""
+		}
+
+		return email
+	}
+}
+
+// Enforcer rejects the calls of service accounts the matrix does not allow, to limit what a compromised internal
+// service can reach.
+//
+//	enforcer := authz.NewEnforcer(logger, cfg.Authz)
+//	listener, server, health := deploy.StartGRPCServer(
+//		logger, 50051, depsCheck,
+//		deploy.WithUnaryInterceptors(enforcer.UnaryServerInterceptor()),
+//		deploy.WithStreamInterceptors(enforcer.StreamServerInterceptor()),
+//	)
+//
+// Rejected calls fail with PermissionDenied. Health checks are always allowed.
+type Enforcer struct {
+	logger monitor.Logger
+	config Config
+
+	matrix *pattern.Matcher[[]string]
+}
+
+func NewEnforcer(logger monitor.Logger, config Config) *Enforcer {
+	config = config.withDefaults()
+	if *config.Enabled && config.Caller == nil {
+		logger.Warn("[authz] no audience nor caller configured: every call matching the matrix is rejected")
+	}
+
+	return &Enforcer{logger: logger, config: config, matrix: pattern.NewMatcher(config.Matrix)}
+}
+
+// Allowed reports whether the caller may invoke the method, and returns the violation otherwise.
+func (e *Enforcer) Allowed(method, caller string) (Violation, bool) {
+	if builtinExempt.Contains(method) {
+		return Violation{}, true
+	}
+
+	callers, rule, ok := e.matrix.Match(method)
+	if !ok {
+		return Violation{Method: method, Caller: caller}, e.config.AllowUnlisted
+	}
+
+	if caller != "" && (slices.Contains(callers, caller) || slices.Contains(callers, AnyCaller)) {
+		return Violation{}, true
+	}
+
+	return Violation{Method: method, Caller: caller, Rule: rule}, false
+}
+
+// check returns the error of a rejected call, or nil. Violations are logged and reported even in dry run.
+func (e *Enforcer) check(ctx context.Context, method string) error {
+	if !*e.config.Enabled {
+		return nil
+	}
+
+	// Without a way to verify callers, fail closed: no caller is identified.
+	var caller string
+	if e.config.Caller != nil {
+		caller = e.config.Caller(ctx)
+	}
+
+	violation, ok := e.Allowed(method, caller)
+	if ok {
+		return nil
+	}
+
+	e.logger.
+		With("method", violation.Method).
+		With("caller", violation.Caller).
+		Warn(fmt.Sprintf("[authz] %s", violation))
+	if e.config.OnViolation != nil {
+		e.config.OnViolation(violation)
+	}
+
+	if e.config.DryRun {
+		return nil
+	}
+
+	return status.Error(codes.PermissionDenied, fmt.Sprintf("caller is not allowed to call %s", method))
+}
+
+// UnaryServerInterceptor rejects the unary calls not allowed by the matrix. It does nothing when the enforcer is
+// disabled.
+func (e *Enforcer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := e.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects the streams not allowed by the matrix. It does nothing when the enforcer is
+// disabled.
+func (e *Enforcer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := e.check(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
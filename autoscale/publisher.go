@@ -0,0 +1,218 @@
+package autoscale
+
+import (
+	"cloud.google.com/go/compute/metadata"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxSeriesPerRequest is the limit of the Cloud Monitoring API.
+const maxSeriesPerRequest = 200
+
+// Signal is a value the autoscaler follows, such as the depth of a queue or the lag of a sync. Signals are
+// published as gauges: return the current value, not a delta.
+type Signal struct {
+	// Name of the metric, appended to the prefix of the publisher. Use snake case, such as "outbox_backlog".
+	Name string
+	// Labels distinguish several series of the same signal, such as one per queue.
+	Labels map[string]string
+	Read   func(ctx context.Context) (float64, error)
+}
+
+type Config struct {
+	// ProjectID receives the metrics. Defaults to the project of the instance. Outside Google Cloud, and without
+	// project, values are logged instead of being published.
+	ProjectID string
+	// Prefix of the metric types. Defaults to "custom.googleapis.com/inrich/".
+	Prefix string
+	// Interval between two publications. Cloud Monitoring rejects points written more often than every 5 seconds
+	// for the same series. Defaults to 1 minute.
+	Interval time.Duration
+	// Options configure the monitoring client, for example to use explicit credentials.
+	Options []option.ClientOption
+}
+
+func (c Config) withDefaults() Config {
+	if c.Prefix == "" {
+		c.Prefix = "custom.googleapis.com/inrich/"
+	}
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+	}
+
+	return c
+}
+
+// Publisher periodically writes signals to Cloud Monitoring, so autoscalers can follow the actual backlog of a
+// service instead of its CPU usage.
+//
+//	publisher, err := autoscale.NewPublisher(ctx, logger, autoscale.Config{},
+//		autoscale.Signal{Name: "outbox_backlog", Read: outbox.Backlog},
+//		autoscale.Signal{Name: "sync_lag_seconds", Read: syncer.LagSeconds},
+//	)
+//	go publisher.Run(ctx)
+//
+// Series are written against a generic_task resource, with the environment as namespace, the Cloud Run service as
+// job, and the instance as task. Every instance publishes its own series: backlog signals read from a shared
+// store are therefore identical across instances, and must be aggregated with a mean or a max by the autoscaling
+// rule, such as an external metric of a GKE HorizontalPodAutoscaler.
+type Publisher struct {
+	logger  monitor.Logger
+	config  Config
+	signals []Signal
+
+	service  *monitoring.Service
+	resource *monitoring.MonitoredResource
+}
+
+func NewPublisher(ctx context.Context, logger monitor.Logger, config Config, signals ...Signal) (*Publisher, error) {
+	config = config.withDefaults()
+	publisher := &Publisher{logger: logger, config: config, signals: signals}
+
+	onGCE := metadata.OnGCE()
+	if config.ProjectID == "" && onGCE {
+		projectID, err := metadata.ProjectIDWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("read project ID: %w", err)
+		}
+
+		publisher.config.ProjectID = projectID
+	}
+
+	if publisher.config.ProjectID == "" {
+		logger.Warn("[autoscale] no Google Cloud project, signals will only be logged")
+		return publisher, nil
+	}
+
+	service, err := monitoring.NewService(ctx, config.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("create monitoring client: %w", err)
+	}
+
+	publisher.service = service
+	publisher.resource = detectResource(ctx, publisher.config.ProjectID, onGCE)
+
+	return publisher, nil
+}
+
+// detectResource describes the instance the signals are read from.
+func detectResource(ctx context.Context, projectID string, onGCE bool) *monitoring.MonitoredResource {
+	location := "global"
+	task, _ := os.Hostname()
+
+	if onGCE {
+		// In the form "projects/123456789/regions/europe-west1".
+		if region, err := metadata.GetWithContext(ctx, "instance/region"); err == nil {
+			location = region[strings.LastIndex(region, "/")+1:]
+		}
+		if id, err := metadata.InstanceIDWithContext(ctx); err == nil {
+			task = id
+		}
+	}
+
+	job := os.Getenv("K_SERVICE")
+	if job == "" {
+		job = "unknown"
+	}
+
+	return &monitoring.MonitoredResource{
+		Type: "generic_task",
+		Labels: map[string]string{
+			"project_id": projectID,
+			"location":   location,
+			"namespace":  deploy.ENV,
+			"job":        job,
+			"task_id":    task,
+		},
+	}
+}
+
+// PublishOnce reads every signal, and writes their values. Signals failing to be read are skipped, and their
+// errors are returned with the error of the publication, if any.
+func (p *Publisher) PublishOnce(ctx context.Context) error {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	var (
+		errs   []error
+		series []*monitoring.TimeSeries
+	)
+
+	for _, signal := range p.signals {
+		value, err := signal.Read(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("read signal %s: %w", signal.Name, err))
+			continue
+		}
+
+		if p.service == nil {
+			p.logger.Info(fmt.Sprintf("[autoscale] %s%s = %g", signal.Name, formatLabels(signal.Labels), value))
+			continue
+		}
+
+		series = append(series, &monitoring.TimeSeries{
+			Metric:     &monitoring.Metric{Type: p.config.Prefix + signal.Name, Labels: signal.Labels},
+			Resource:   p.resource,
+			MetricKind: "GAUGE",
+			ValueType:  "DOUBLE",
+			Points: []*monitoring.Point{{
+				Interval: &monitoring.TimeInterval{EndTime: now},
+				Value:    &monitoring.TypedValue{DoubleValue: &value},
+			}},
+		})
+	}
+
+	for start := 0; start < len(series); start += maxSeriesPerRequest {
+		batch := series[start:min(start+maxSeriesPerRequest, len(series))]
+
+		_, err := p.service.Projects.TimeSeries.
+			Create("projects/"+p.config.ProjectID, &monitoring.CreateTimeSeriesRequest{TimeSeries: batch}).
+			Context(ctx).
+			Do()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("write time series: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	slices.Sort(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Run publishes the signals at every interval, until the context is canceled.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PublishOnce(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error(err, "[autoscale] failed to publish signals")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
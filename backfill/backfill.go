@@ -0,0 +1,154 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var ErrMissingHandler = errors.New("backfill requires fetch, key and process functions")
+
+// FetchFunc returns the next items of the dataset, sorted by key, strictly after the given key. The key is empty
+// on the first call.
+type FetchFunc[T any] func(ctx context.Context, after string, limit int) ([]T, error)
+
+// KeyFunc returns the key of an item, used to resume the backfill.
+type KeyFunc[T any] func(item T) string
+
+type ProcessFunc[T any] func(ctx context.Context, item T) error
+
+type Job[T any] struct {
+	// Name identifies the checkpoint of the backfill.
+	Name    string
+	Fetch   FetchFunc[T]
+	Key     KeyFunc[T]
+	Process ProcessFunc[T]
+	// Total returns the number of remaining items, to compute the ETA. Optional.
+	Total func(ctx context.Context) (int64, error)
+
+	// BatchSize is the number of items fetched at once. Checkpoints are saved after each batch. Defaults to 100.
+	BatchSize int
+	// Rate is the maximum number of items processed per second. Unlimited if 0.
+	Rate float64
+	// ContinueOnError logs failing items and moves on, instead of stopping the backfill.
+	ContinueOnError bool
+	// ProgressInterval is the minimum delay between two progress reports. Defaults to 10 seconds.
+	ProgressInterval time.Duration
+}
+
+// Run processes the dataset of the job, from its last checkpoint. Completed backfills are not run again, unless
+// their checkpoint is reset with ResetCheckpoint.
+func Run[T any](ctx context.Context, db bun.IDB, logger monitor.Logger, job Job[T]) (*Checkpoint, error) {
+	if job.Fetch == nil || job.Key == nil || job.Process == nil {
+		return nil, ErrMissingHandler
+	}
+	if job.BatchSize <= 0 {
+		job.BatchSize = 100
+	}
+	if job.ProgressInterval <= 0 {
+		job.ProgressInterval = 10 * time.Second
+	}
+
+	checkpoint, err := LoadCheckpoint(ctx, db, job.Name)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{Name: job.Name, StartedAt: time.Now()}
+	} else if checkpoint.CompletedAt != nil {
+		logger.Info(fmt.Sprintf("[backfill] %s already completed on %s", job.Name, checkpoint.CompletedAt))
+		return checkpoint, nil
+	} else {
+		logger.Info(fmt.Sprintf("[backfill] resuming %s after key %q", job.Name, checkpoint.LastKey))
+	}
+
+	var remaining int64
+	if job.Total != nil {
+		if remaining, err = job.Total(ctx); err != nil {
+			return checkpoint, fmt.Errorf("count remaining items: %w", err)
+		}
+	}
+
+	progress := newProgress(logger, job.Name, remaining, job.ProgressInterval)
+	pacer := newPacer(job.Rate)
+
+	for {
+		items, err := job.Fetch(ctx, checkpoint.LastKey, job.BatchSize)
+		if err != nil {
+			return checkpoint, fmt.Errorf("fetch items after %q: %w", checkpoint.LastKey, err)
+		}
+
+		for _, item := range items {
+			if err := pacer.wait(ctx); err != nil {
+				return checkpoint, saveOnExit(db, checkpoint, err)
+			}
+
+			key := job.Key(item)
+			if err := job.Process(ctx, item); err != nil {
+				if !job.ContinueOnError || ctx.Err() != nil {
+					return checkpoint, saveOnExit(db, checkpoint, fmt.Errorf("process item %q: %w", key, err))
+				}
+
+				checkpoint.Failed++
+				logger.Error(err, fmt.Sprintf("[backfill] %s: failed to process item %q", job.Name, key))
+			} else {
+				checkpoint.Processed++
+			}
+
+			checkpoint.LastKey = key
+			progress.tick(checkpoint)
+		}
+
+		if len(items) < job.BatchSize {
+			now := time.Now()
+			checkpoint.CompletedAt = &now
+		}
+
+		if err := saveCheckpoint(ctx, db, checkpoint); err != nil {
+			return checkpoint, fmt.Errorf("save checkpoint: %w", err)
+		}
+
+		if checkpoint.CompletedAt != nil {
+			progress.done(checkpoint)
+			return checkpoint, nil
+		}
+	}
+}
+
+// saveOnExit persists the progress made within the current batch, even if the context was canceled.
+func saveOnExit(db bun.IDB, checkpoint *Checkpoint, cause error) error {
+	if err := saveCheckpoint(context.Background(), db, checkpoint); err != nil {
+		return errors.Join(cause, fmt.Errorf("save checkpoint: %w", err))
+	}
+
+	return cause
+}
+
+// FetchModel creates a FetchFunc that pages over a bun model, using keyset pagination on the given column.
+//
+//	backfill.FetchModel[*entities.Note](db, "id", func(q *bun.SelectQuery) *bun.SelectQuery {
+//		return q.Where("content_v2 IS NULL")
+//	})
+func FetchModel[T any](db bun.IDB, column string, filter func(q *bun.SelectQuery) *bun.SelectQuery) FetchFunc[T] {
+	return func(ctx context.Context, after string, limit int) ([]T, error) {
+		items := make([]T, 0, limit)
+
+		q := db.NewSelect().Model(&items).OrderExpr("? ASC", bun.Ident(column)).Limit(limit)
+		if after != "" {
+			q = q.Where("? > ?", bun.Ident(column), after)
+		}
+		if filter != nil {
+			q = filter(q)
+		}
+
+		if err := q.Scan(ctx); err != nil {
+			return nil, err
+		}
+
+		return items, nil
+	}
+}
@@ -0,0 +1,65 @@
+package backfill
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Checkpoint stores the progress of a backfill, so an interrupted run resumes where it stopped.
+type Checkpoint struct {
+	bun.BaseModel `bun:"table:backfill_checkpoints,alias:checkpoint"`
+
+	Name string `bun:"name,pk"`
+	// LastKey is the key of the last processed item.
+	LastKey     string     `bun:"last_key,notnull"`
+	Processed   int64      `bun:"processed,notnull"`
+	Failed      int64      `bun:"failed,notnull"`
+	StartedAt   time.Time  `bun:"started_at,notnull"`
+	UpdatedAt   time.Time  `bun:"updated_at,notnull"`
+	CompletedAt *time.Time `bun:"completed_at"`
+}
+
+// CreateTable creates the checkpoint table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*Checkpoint)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+// LoadCheckpoint returns the checkpoint of a backfill, or nil if the backfill never ran.
+func LoadCheckpoint(ctx context.Context, db bun.IDB, name string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{Name: name}
+	if err := db.NewSelect().Model(checkpoint).WherePK().Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// ResetCheckpoint removes the checkpoint of a backfill, so the next run starts from the beginning.
+func ResetCheckpoint(ctx context.Context, db bun.IDB, name string) error {
+	_, err := db.NewDelete().Model((*Checkpoint)(nil)).Where("name = ?", name).Exec(ctx)
+	return err
+}
+
+func saveCheckpoint(ctx context.Context, db bun.IDB, checkpoint *Checkpoint) error {
+	checkpoint.UpdatedAt = time.Now()
+
+	_, err := db.NewInsert().
+		Model(checkpoint).
+		On("CONFLICT (name) DO UPDATE").
+		Set("last_key = EXCLUDED.last_key").
+		Set("processed = EXCLUDED.processed").
+		Set("failed = EXCLUDED.failed").
+		Set("updated_at = EXCLUDED.updated_at").
+		Set("completed_at = EXCLUDED.completed_at").
+		Exec(ctx)
+
+	return err
+}
@@ -0,0 +1,105 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"time"
+)
+
+type progress struct {
+	logger    monitor.Logger
+	name      string
+	remaining int64
+	interval  time.Duration
+
+	start      time.Time
+	lastReport time.Time
+	count      int64
+}
+
+func newProgress(logger monitor.Logger, name string, remaining int64, interval time.Duration) *progress {
+	now := time.Now()
+	return &progress{
+		logger:     logger,
+		name:       name,
+		remaining:  remaining,
+		interval:   interval,
+		start:      now,
+		lastReport: now,
+	}
+}
+
+func (p *progress) tick(checkpoint *Checkpoint) {
+	p.count++
+
+	now := time.Now()
+	if now.Sub(p.lastReport) < p.interval {
+		return
+	}
+	p.lastReport = now
+
+	elapsed := now.Sub(p.start)
+	rate := float64(p.count) / elapsed.Seconds()
+
+	message := fmt.Sprintf(
+		"[backfill] %s: %d processed, %d failed (%.1f items/s)",
+		p.name, checkpoint.Processed, checkpoint.Failed, rate,
+	)
+
+	if p.remaining > 0 && rate > 0 {
+		left := p.remaining - p.count
+		if left < 0 {
+			left = 0
+		}
+
+		eta := time.Duration(float64(left) / rate * float64(time.Second)).Round(time.Second)
+		message += fmt.Sprintf(", %.1f%% done, ETA %s", 100*float64(p.count)/float64(p.remaining), eta)
+	}
+
+	p.logger.Info(message)
+}
+
+func (p *progress) done(checkpoint *Checkpoint) {
+	p.logger.Info(fmt.Sprintf(
+		"[backfill] %s completed: %d processed, %d failed in %s",
+		p.name, checkpoint.Processed, checkpoint.Failed, time.Since(p.start).Round(time.Second),
+	))
+}
+
+// pacer spaces out items to respect a maximum rate.
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(rate float64) *pacer {
+	if rate <= 0 {
+		return &pacer{}
+	}
+
+	return &pacer{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+func (p *pacer) wait(ctx context.Context) error {
+	if p.interval == 0 {
+		return ctx.Err()
+	}
+
+	now := time.Now()
+	if p.next.After(now) {
+		timer := time.NewTimer(p.next.Sub(now))
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		now = p.next
+	}
+
+	p.next = now.Add(p.interval)
+	return nil
+}
@@ -0,0 +1,366 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedRule = errors.New("unsupported http rule")
+
+type GatewayConfig struct {
+	// Middlewares run before every route, such as authentication or rate limiting.
+	Middlewares []gin.HandlerFunc
+	// ForwardHeaders lists the request headers forwarded to the service as metadata. Defaults to the authorization
+	// header, and the request and trace identifiers.
+	ForwardHeaders []string
+	// Metadata adds metadata to every call, such as the identity resolved by an authentication middleware.
+	Metadata func(c *gin.Context) metadata.MD
+	// MarshalOptions encode the responses.
+	MarshalOptions protojson.MarshalOptions
+	// UnmarshalOptions decode the request bodies.
+	UnmarshalOptions protojson.UnmarshalOptions
+}
+
+func (c GatewayConfig) withDefaults() GatewayConfig {
+	if c.ForwardHeaders == nil {
+		c.ForwardHeaders = []string{"authorization", "x-request-id", "x-cloud-trace-context", "traceparent"}
+	}
+
+	return c
+}
+
+// route is a REST binding of a unary method.
+type route struct {
+	method     protoreflect.MethodDescriptor
+	fullMethod string
+	httpMethod string
+	path       string
+	// params maps the gin parameters of the path to the request fields they set.
+	params       map[string]string
+	body         string
+	responseBody string
+}
+
+// RegisterREST exposes the unary methods of a GRPC service annotated with google.api.http options as REST routes,
+// forwarding the calls to the connection.
+//
+//	service := notes_pb.File_notes_proto.Services().ByName("Notes")
+//	err := bridge.RegisterREST(router.Group("/api"), conn, service, bridge.GatewayConfig{
+//		Middlewares: []gin.HandlerFunc{auth.Middleware()},
+//	})
+//
+// Path variables, query parameters and bodies are bound to the request message the same way as grpc-gateway.
+// Path templates are limited to single-segment variables ("{id}" or "{id=*}") and a trailing "{path=**}":
+// templates using other patterns, or custom verbs, are rejected with ErrUnsupportedRule. Methods without
+// annotation are not exposed. Errors are converted with HTTPStatus.
+func RegisterREST(
+	router gin.IRouter, conn grpc.ClientConnInterface, service protoreflect.ServiceDescriptor, config GatewayConfig,
+) error {
+	config = config.withDefaults()
+
+	var routes []route
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+
+		rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			return fmt.Errorf("%w: %s is a streaming method, use SSE, NDJSON or Upload", ErrUnsupportedRule, method.FullName())
+		}
+
+		for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			r, err := newRoute(method, binding)
+			if err != nil {
+				return fmt.Errorf("%s: %w", method.FullName(), err)
+			}
+
+			routes = append(routes, r)
+		}
+	}
+
+	for _, r := range routes {
+		handlers := append(append([]gin.HandlerFunc{}, config.Middlewares...), r.handler(conn, config))
+		router.Handle(r.httpMethod, r.path, handlers...)
+	}
+
+	return nil
+}
+
+func newRoute(method protoreflect.MethodDescriptor, rule *annotations.HttpRule) (route, error) {
+	r := route{
+		method:       method,
+		fullMethod:   fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name()),
+		params:       make(map[string]string),
+		body:         rule.GetBody(),
+		responseBody: rule.GetResponseBody(),
+	}
+
+	var template string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		r.httpMethod, template = http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		r.httpMethod, template = http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		r.httpMethod, template = http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		r.httpMethod, template = http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		r.httpMethod, template = http.MethodPatch, pattern.Patch
+	default:
+		return r, fmt.Errorf("%w: custom method", ErrUnsupportedRule)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(template, "/"), "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, ":") && !strings.HasPrefix(segment, "{") {
+			return r, fmt.Errorf("%w: custom verb in %s", ErrUnsupportedRule, template)
+		}
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+
+		field, pattern, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"), "=")
+		param := fmt.Sprintf("p%d", len(r.params))
+
+		switch {
+		case pattern == "" || pattern == "*":
+			segments[i] = ":" + param
+		case pattern == "**" && i == len(segments)-1:
+			segments[i] = "*" + param
+		default:
+			return r, fmt.Errorf("%w: variable %s in %s", ErrUnsupportedRule, segment, template)
+		}
+
+		r.params[param] = field
+	}
+
+	r.path = "/" + strings.Join(segments, "/")
+	return r, nil
+}
+
+func (r route) newMessage(desc protoreflect.MessageDescriptor) protoreflect.Message {
+	if messageType, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName()); err == nil {
+		return messageType.New()
+	}
+
+	return dynamicpb.NewMessage(desc)
+}
+
+func (r route) handler(conn grpc.ClientConnInterface, config GatewayConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := r.newMessage(r.method.Input())
+		if err := r.bind(c, req, config); err != nil {
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+
+		md := metadata.MD{}
+		for _, header := range config.ForwardHeaders {
+			if values := c.Request.Header.Values(header); len(values) > 0 {
+				md.Append(header, values...)
+			}
+		}
+		if config.Metadata != nil {
+			md = metadata.Join(md, config.Metadata(c))
+		}
+
+		ctx := metadata.NewOutgoingContext(c.Request.Context(), md)
+
+		res := r.newMessage(r.method.Output())
+		if err := conn.Invoke(ctx, r.fullMethod, req.Interface(), res.Interface()); err != nil {
+			AbortWithGRPCError(c, err)
+			return
+		}
+
+		var out proto.Message = res.Interface()
+		if r.responseBody != "" {
+			field := res.Descriptor().Fields().ByName(protoreflect.Name(r.responseBody))
+			if field == nil || field.Message() == nil {
+				_ = c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("invalid response body %s", r.responseBody))
+				return
+			}
+
+			out = res.Get(field).Message().Interface()
+		}
+
+		data, err := config.MarshalOptions.Marshal(out)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json", data)
+	}
+}
+
+// bind fills the request message from the body, the path variables and the query parameters, in this order.
+func (r route) bind(c *gin.Context, req protoreflect.Message, config GatewayConfig) error {
+	if r.body != "" {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return fmt.Errorf("read request body: %w", err)
+		}
+
+		target := req
+		if r.body != "*" {
+			field := req.Descriptor().Fields().ByName(protoreflect.Name(r.body))
+			if field == nil || field.Message() == nil {
+				return fmt.Errorf("invalid body field %s", r.body)
+			}
+
+			target = req.Mutable(field).Message()
+		}
+
+		if len(data) > 0 {
+			if err := config.UnmarshalOptions.Unmarshal(data, target.Interface()); err != nil {
+				return fmt.Errorf("decode request body: %w", err)
+			}
+		}
+	}
+
+	for param, field := range r.params {
+		value := strings.TrimPrefix(c.Param(param), "/")
+		if err := setField(req, field, []string{value}); err != nil {
+			return fmt.Errorf("path variable %s: %w", field, err)
+		}
+	}
+
+	// Query parameters only bind the fields that are not covered by the body.
+	if r.body == "*" {
+		return nil
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		if r.body != "" && (key == r.body || strings.HasPrefix(key, r.body+".")) {
+			continue
+		}
+
+		if err := setField(req, key, values); err != nil {
+			return fmt.Errorf("query parameter %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// setField sets a scalar field of the message, or appends to a repeated one. The path is a dot-separated list of
+// field names, either in their proto or JSON form.
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil {
+			return fmt.Errorf("unknown field %s", name)
+		}
+
+		if i < len(names)-1 {
+			if field.Message() == nil || field.IsList() || field.IsMap() {
+				return fmt.Errorf("field %s is not a message", name)
+			}
+
+			msg = msg.Mutable(field).Message()
+			continue
+		}
+
+		if field.IsMap() || field.Message() != nil {
+			return fmt.Errorf("field %s is not a scalar", name)
+		}
+
+		if field.IsList() {
+			list := msg.Mutable(field).List()
+			for _, value := range values {
+				parsed, err := parseScalar(field, value)
+				if err != nil {
+					return err
+				}
+
+				list.Append(parsed)
+			}
+
+			return nil
+		}
+
+		if len(values) != 1 {
+			return fmt.Errorf("field %s is not repeated", name)
+		}
+
+		parsed, err := parseScalar(field, values[0])
+		if err != nil {
+			return err
+		}
+
+		msg.Set(field, parsed)
+	}
+
+	return nil
+}
+
+func parseScalar(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		parsed, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(parsed), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(parsed)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(parsed), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(parsed)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(parsed), err
+	case protoreflect.FloatKind:
+		parsed, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(parsed)), err
+	case protoreflect.DoubleKind:
+		parsed, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(parsed), err
+	case protoreflect.BytesKind:
+		parsed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			parsed, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(parsed), err
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByName(protoreflect.Name(value)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown value %s of enum %s", value, field.Enum().FullName())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(parsed)), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", field.Kind())
+	}
+}
@@ -0,0 +1,53 @@
+package bridge
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/in-rich/lib-go/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// HTTPStatus converts the GRPC status of an error to the closest HTTP status.
+func HTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// AbortWithGRPCError aborts the request with the HTTP status of the GRPC error. When the error tells the caller
+// when to retry (see ratelimit.Exhausted), the delay is sent in a Retry-After header, in seconds.
+func AbortWithGRPCError(c *gin.Context, err error) {
+	if retryAfter, ok := ratelimit.RetryAfter(err); ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	_ = c.AbortWithError(HTTPStatus(err), err)
+}
@@ -0,0 +1,231 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Receiver is the receiving side of a server-streaming GRPC call, as generated by the protoc compiler for Go.
+type Receiver[T any] interface {
+	Recv() (*T, error)
+}
+
+// Encoder serializes a message of the stream.
+type Encoder[T any] func(msg *T) ([]byte, error)
+
+// ProtoJSON encodes proto messages with their canonical JSON representation.
+func ProtoJSON[T any](msg *T) ([]byte, error) {
+	if message, ok := any(msg).(proto.Message); ok {
+		return protojson.Marshal(message)
+	}
+
+	return json.Marshal(msg)
+}
+
+type StreamConfig struct {
+	// Heartbeat is the interval of keep-alive comments sent on idle SSE streams, so proxies do not close them.
+	// Defaults to 15 seconds.
+	Heartbeat time.Duration
+}
+
+type received[T any] struct {
+	msg *T
+	err error
+}
+
+// receive forwards the messages of the stream to a channel. The channel is unbuffered: the next message is only
+// read from the GRPC stream once the previous one was written to the client, so a slow client slows the upstream
+// down instead of filling the memory of the gateway.
+func receive[T any](ctx context.Context, stream Receiver[T]) <-chan received[T] {
+	out := make(chan received[T])
+
+	go func() {
+		defer close(out)
+
+		for {
+			msg, err := stream.Recv()
+
+			select {
+			case out <- received[T]{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// SSE exposes a server-streaming GRPC call as a Server-Sent Events response. The stream must be opened with the
+// given context, which is canceled when the HTTP client disconnects.
+//
+//	router.GET("/notes/export", func(c *gin.Context) {
+//		bridge.SSE(c, func(ctx context.Context) (bridge.Receiver[notes_pb.Note], error) {
+//			return notesClient.ExportNotes(ctx, &notes_pb.ExportNotesRequest{})
+//		}, bridge.ProtoJSON[notes_pb.Note], bridge.StreamConfig{})
+//	})
+//
+// Each message is sent as a "message" event. The end of the stream is signaled by an "end" event, and GRPC
+// errors by an "error" event holding the status. Errors raised before the first message use a regular HTTP error
+// response instead.
+func SSE[T any](
+	c *gin.Context, open func(ctx context.Context) (Receiver[T], error), encode Encoder[T], config StreamConfig,
+) {
+	if config.Heartbeat <= 0 {
+		config.Heartbeat = 15 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := open(ctx)
+	if err != nil {
+		AbortWithGRPCError(c, err)
+		return
+	}
+
+	messages := receive(ctx, stream)
+
+	// Wait for the first message, to report early errors with a proper status code.
+	first, ok := <-messages
+	if !ok {
+		return
+	}
+	if first.err != nil && !errors.Is(first.err, io.EOF) {
+		AbortWithGRPCError(c, first.err)
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Disable buffering on nginx-like proxies.
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	heartbeat := time.NewTicker(config.Heartbeat)
+	defer heartbeat.Stop()
+
+	if !writeSSEMessage(c, first, encode) {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case next, ok := <-messages:
+			if !ok || !writeSSEMessage(c, next, encode) {
+				return
+			}
+		}
+	}
+}
+
+// writeSSEMessage writes a received item to the client, and returns false once the stream is over.
+func writeSSEMessage[T any](c *gin.Context, item received[T], encode Encoder[T]) bool {
+	var err error
+
+	switch {
+	case errors.Is(item.err, io.EOF):
+		_, _ = io.WriteString(c.Writer, "event: end\ndata: {}\n\n")
+		c.Writer.Flush()
+		return false
+	case item.err != nil:
+		payload, _ := json.Marshal(map[string]any{
+			"code":    status.Code(item.err).String(),
+			"message": status.Convert(item.err).Message(),
+		})
+		_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", payload)
+		c.Writer.Flush()
+		_ = c.Error(item.err)
+		return false
+	}
+
+	payload, err := encode(item.msg)
+	if err != nil {
+		_ = c.Error(err)
+		return false
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "event: message\ndata: %s\n\n", payload); err != nil {
+		return false
+	}
+
+	c.Writer.Flush()
+	return true
+}
+
+// NDJSON exposes a server-streaming GRPC call as a chunked response, with one JSON document per line. GRPC errors
+// raised after the first message are written as a last line of the form {"error": {"code": ..., "message": ...}}.
+func NDJSON[T any](c *gin.Context, open func(ctx context.Context) (Receiver[T], error), encode Encoder[T]) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stream, err := open(ctx)
+	if err != nil {
+		AbortWithGRPCError(c, err)
+		return
+	}
+
+	started := false
+	for item := range receive(ctx, stream) {
+		if errors.Is(item.err, io.EOF) {
+			if !started {
+				c.Status(http.StatusOK)
+			}
+			return
+		}
+
+		if item.err != nil {
+			if !started {
+				AbortWithGRPCError(c, item.err)
+				return
+			}
+
+			payload, _ := json.Marshal(map[string]any{"error": map[string]any{
+				"code":    status.Code(item.err).String(),
+				"message": status.Convert(item.err).Message(),
+			}})
+			_, _ = fmt.Fprintf(c.Writer, "%s\n", payload)
+			_ = c.Error(item.err)
+			return
+		}
+
+		payload, err := encode(item.msg)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		if !started {
+			c.Writer.Header().Set("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+
+		if _, err := fmt.Fprintf(c.Writer, "%s\n", payload); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
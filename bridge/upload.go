@@ -0,0 +1,81 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sender is the sending side of a client-streaming GRPC call, as generated by the protoc compiler for Go.
+type Sender[In any, Out any] interface {
+	Send(*In) error
+	CloseAndRecv() (*Out, error)
+}
+
+// Decoder parses a message of the stream.
+type Decoder[T any] func(data []byte) (*T, error)
+
+// ProtoJSONDecoder decodes proto messages from their canonical JSON representation.
+func ProtoJSONDecoder[T any](data []byte) (*T, error) {
+	msg := new(T)
+	if message, ok := any(msg).(proto.Message); ok {
+		return msg, protojson.Unmarshal(data, message)
+	}
+
+	return msg, json.Unmarshal(data, msg)
+}
+
+type UploadConfig struct {
+	// MaxLineSize is the maximum size of a single message in the request body. Defaults to 1MiB.
+	MaxLineSize int
+}
+
+// Upload forwards a NDJSON request body to a client-streaming GRPC call, one message per line, and returns the
+// response of the call. Lines are sent as they are read, so the request is never buffered as a whole. Empty lines
+// are ignored.
+func Upload[In any, Out any](
+	c *gin.Context, open func(ctx context.Context) (Sender[In, Out], error), decode Decoder[In], config UploadConfig,
+) (*Out, error) {
+	if config.MaxLineSize <= 0 {
+		config.MaxLineSize = 1 << 20
+	}
+
+	stream, err := open(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), config.MaxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		msg, err := decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode line %d: %w", line, err)
+		}
+
+		if err := stream.Send(msg); err != nil {
+			// The actual error is returned by CloseAndRecv.
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+
+	return stream.CloseAndRecv()
+}
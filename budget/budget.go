@@ -0,0 +1,103 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"time"
+)
+
+var ErrInvalidBudget = errors.New("invalid latency budget")
+
+// Budget splits the deadline of an inbound request between its downstream calls, so a single slow dependency
+// cannot consume the whole deadline.
+//
+//	b, err := budget.New(map[string]deploy.Percent{"teams": 40, "notes": 40}, 10*time.Second)
+//
+//	func (h *Handler) GetDashboard(ctx context.Context, in *pb.GetDashboardRequest) (*pb.Dashboard, error) {
+//		ctx = b.Start(ctx)
+//		teams, err := budget.Call(ctx, b, "teams", h.teams.ListTeams, &teams_pb.ListTeamsRequest{})
+//		...
+//	}
+//
+// Shares are relative to the time remaining when Start is called. A call whose name has no share receives the
+// unallocated part of the budget (20% in the example above).
+type Budget struct {
+	shares   map[string]float64
+	buffer   float64
+	fallback time.Duration
+}
+
+// New creates a budget from shares that must sum to at most 100%. The fallback is the total budget used when the
+// inbound request has no deadline.
+func New(shares map[string]deploy.Percent, fallback time.Duration) (*Budget, error) {
+	if fallback <= 0 {
+		return nil, fmt.Errorf("%w: fallback must be positive", ErrInvalidBudget)
+	}
+
+	budget := &Budget{shares: make(map[string]float64, len(shares)), fallback: fallback}
+
+	var total float64
+	for name, share := range shares {
+		total += share.Fraction()
+		budget.shares[name] = share.Fraction()
+	}
+
+	if total > 1 {
+		return nil, fmt.Errorf("%w: shares sum to %.0f%%", ErrInvalidBudget, total*100)
+	}
+
+	budget.buffer = 1 - total
+	return budget, nil
+}
+
+type totalKey struct{}
+
+// Start captures the time remaining before the deadline of the context, which is then split between the calls.
+// Without Start, shares are computed from the time remaining when each call starts.
+func (b *Budget) Start(ctx context.Context) context.Context {
+	return context.WithValue(ctx, totalKey{}, b.remaining(ctx))
+}
+
+func (b *Budget) remaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return b.fallback
+	}
+
+	return time.Until(deadline)
+}
+
+// Allocation returns the time allocated to the named call.
+func (b *Budget) Allocation(ctx context.Context, name string) time.Duration {
+	total, ok := ctx.Value(totalKey{}).(time.Duration)
+	if !ok {
+		total = b.remaining(ctx)
+	}
+
+	share, ok := b.shares[name]
+	if !ok {
+		share = b.buffer
+	}
+
+	return time.Duration(float64(total) * share)
+}
+
+// Context returns a context whose deadline is the allocation of the named call, from now. The deadline of the
+// parent context still applies if it is earlier.
+func (b *Budget) Context(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(
+		ctx, b.Allocation(ctx, name), fmt.Errorf("latency budget of %s exhausted", name),
+	)
+}
+
+// Call performs a call to a GRPC endpoint with deploy.CallGRPCEndpoint, within the allocation of the named call.
+func Call[In any, Out any](
+	ctx context.Context, budget *Budget, name string, callback deploy.GRPCCallback[In, Out], in *In,
+) (*Out, error) {
+	localCTX, cancel := budget.Context(ctx, name)
+	defer cancel()
+
+	return deploy.CallGRPCEndpoint(localCTX, callback, in)
+}
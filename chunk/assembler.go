@@ -0,0 +1,168 @@
+package chunk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// File is the destination of a reassembled payload, such as an *os.File. Reads are only used to resume a transfer.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Assembler writes the chunks of a payload to a file, and verifies their integrity.
+type Assembler struct {
+	dst    File
+	digest hash.Hash
+	offset int64
+	// MaxSize rejects payloads larger than the given number of bytes. Ignored when 0.
+	MaxSize int64
+	done    bool
+}
+
+// NewAssembler creates an assembler for a new transfer.
+func NewAssembler(dst File) *Assembler {
+	return &Assembler{dst: dst, digest: sha256.New()}
+}
+
+// ResumeAssembler creates an assembler for a transfer interrupted after size bytes were received. The received
+// bytes are read back from the file, to verify the digest of the whole payload at the end.
+func ResumeAssembler(dst File, size int64) (*Assembler, error) {
+	assembler := NewAssembler(dst)
+
+	if _, err := io.Copy(assembler.digest, io.NewSectionReader(dst, 0, size)); err != nil {
+		return nil, fmt.Errorf("read received payload: %w", err)
+	}
+
+	assembler.offset = size
+	return assembler, nil
+}
+
+// Offset returns the number of bytes received so far. Senders resume the transfer from this offset.
+func (a *Assembler) Offset() int64 {
+	return a.offset
+}
+
+// Done reports whether the final chunk was received and verified.
+func (a *Assembler) Done() bool {
+	return a.done
+}
+
+// Write verifies a chunk and writes its data. Chunks already received are ignored, so senders can safely resend
+// from an earlier offset. Chunks past the current offset return ErrOutOfOrder.
+func (a *Assembler) Write(chunk *Chunk) error {
+	if a.done {
+		return nil
+	}
+
+	if Checksum(chunk.Data) != chunk.Checksum {
+		return fmt.Errorf("%w at offset %d", ErrChecksum, chunk.Offset)
+	}
+
+	end := chunk.Offset + int64(len(chunk.Data))
+
+	switch {
+	case chunk.Offset > a.offset:
+		return fmt.Errorf("%w: expected offset %d, got %d", ErrOutOfOrder, a.offset, chunk.Offset)
+	case end < a.offset || (end == a.offset && !chunk.Final):
+		return nil
+	}
+
+	// Only write the part of the chunk that was not received yet.
+	data := chunk.Data[a.offset-chunk.Offset:]
+
+	if a.MaxSize > 0 && a.offset+int64(len(data)) > a.MaxSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, a.MaxSize)
+	}
+
+	if len(data) > 0 {
+		if _, err := a.dst.WriteAt(data, a.offset); err != nil {
+			return fmt.Errorf("write chunk at offset %d: %w", a.offset, err)
+		}
+
+		a.digest.Write(data)
+		a.offset = end
+	}
+
+	if chunk.Final {
+		if chunk.Size != a.offset {
+			return fmt.Errorf("%w: received %d bytes, expected %d", ErrDigest, a.offset, chunk.Size)
+		}
+
+		if !bytes.Equal(a.digest.Sum(nil), chunk.Digest) {
+			return ErrDigest
+		}
+
+		a.done = true
+	}
+
+	return nil
+}
+
+// Receive reads chunks until the final one, and writes them to the assembler. If the stream fails, the assembler
+// Offset can be used to resume the transfer.
+//
+//	err := chunk.Receive(ctx, assembler, func() (*chunk.Chunk, error) {
+//		msg, err := stream.Recv()
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &chunk.Chunk{Offset: msg.GetOffset(), Data: msg.GetData(), ...}, nil
+//	})
+func Receive(ctx context.Context, assembler *Assembler, recv func() (*Chunk, error)) error {
+	for !assembler.Done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		chunk, err := recv()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: received %d bytes", ErrIncomplete, assembler.Offset())
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := assembler.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Buffer is an in-memory File, for payloads small enough to be held in memory.
+type Buffer struct {
+	data []byte
+}
+
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+func (b *Buffer) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, b.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (b *Buffer) WriteAt(p []byte, off int64) (int, error) {
+	if end := off + int64(len(p)); end > int64(len(b.data)) {
+		b.data = append(b.data, make([]byte, end-int64(len(b.data)))...)
+	}
+
+	return copy(b.data[off:], p), nil
+}
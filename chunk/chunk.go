@@ -0,0 +1,121 @@
+package chunk
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	ErrChecksum   = errors.New("chunk checksum mismatch")
+	ErrDigest     = errors.New("payload digest mismatch")
+	ErrOutOfOrder = errors.New("chunk out of order")
+	ErrIncomplete = errors.New("stream ended before the final chunk")
+	ErrTooLarge   = errors.New("payload too large")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Chunk is a part of a payload sent over a streaming GRPC call. Services copy its fields to and from their own
+// proto messages:
+//
+//	message ExportChunk {
+//	  int64 offset = 1;
+//	  bytes data = 2;
+//	  fixed32 checksum = 3;
+//	  bool final = 4;
+//	  int64 size = 5;
+//	  bytes digest = 6;
+//	}
+type Chunk struct {
+	// Offset is the position of Data in the payload.
+	Offset int64
+	Data   []byte
+	// Checksum is the CRC-32C of Data.
+	Checksum uint32
+	// Final is set on the last chunk, which also carries the Size and the SHA-256 Digest of the whole payload. The
+	// final chunk may have no data.
+	Final  bool
+	Size   int64
+	Digest []byte
+}
+
+// Checksum returns the CRC-32C of data, as used in Chunk.Checksum.
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoli)
+}
+
+type Config struct {
+	// ChunkSize is the maximum size of the data of a single chunk. It must leave room for the other fields of the
+	// message under the GRPC maximum message size (4MiB by default). Defaults to 1MiB.
+	ChunkSize deploy.ByteSize
+}
+
+func (c Config) withDefaults() Config {
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = deploy.MiB
+	}
+
+	return c
+}
+
+// Send splits the payload into chunks, and passes them to send in order. Pass the Offset reported by the receiving
+// Assembler to resume an interrupted transfer: the payload before the offset is read to compute the digest, but is
+// not sent again.
+//
+//	err := chunk.Send(ctx, file, resumeAt, func(c *chunk.Chunk) error {
+//		return stream.Send(&pb.ExportChunk{Offset: c.Offset, Data: c.Data, Checksum: c.Checksum, ...})
+//	}, chunk.Config{})
+func Send(ctx context.Context, payload io.ReadSeeker, offset int64, send func(chunk *Chunk) error, config Config) error {
+	config = config.withDefaults()
+
+	if _, err := payload.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek payload: %w", err)
+	}
+
+	digest := sha256.New()
+	if offset > 0 {
+		if _, err := io.CopyN(digest, payload, offset); err != nil {
+			return fmt.Errorf("read payload before offset %d: %w", offset, err)
+		}
+	}
+
+	buffer := make([]byte, config.ChunkSize)
+	position := offset
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := io.ReadFull(payload, buffer)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("read payload: %w", err)
+		}
+
+		data := buffer[:n]
+		digest.Write(data)
+
+		chunk := &Chunk{Offset: position, Data: data, Checksum: Checksum(data)}
+		position += int64(n)
+
+		// A short read means the payload is exhausted.
+		if n < len(buffer) {
+			chunk.Final = true
+			chunk.Size = position
+			chunk.Digest = digest.Sum(nil)
+		}
+
+		if err := send(chunk); err != nil {
+			return err
+		}
+
+		if chunk.Final {
+			return nil
+		}
+	}
+}
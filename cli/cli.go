@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// Context is passed to every command.
+type Context[Cfg any] struct {
+	context.Context
+
+	Config *Cfg
+	Logger monitor.Logger
+	// Args are the positional arguments left after flag parsing.
+	Args []string
+}
+
+// Command is a subcommand exposed by a service binary, such as serve, migrate or reindex.
+type Command[Cfg any] struct {
+	Name  string
+	Usage string
+	// Flags declares the flags specific to the command.
+	Flags func(flags *flag.FlagSet)
+	Run   func(ctx *Context[Cfg]) error
+}
+
+// App describes a service binary.
+//
+//	func main() {
+//		cli.Run(cli.App[config.Config]{
+//			Name:   "notes-service",
+//			Config: []deploy.ConfigFile{deploy.GlobalConfig(globalCfg), deploy.DevConfig(devCfg)},
+//			Commands: []cli.Command[config.Config]{
+//				{Name: "serve", Usage: "start the GRPC server", Run: serve},
+//				{Name: "migrate", Usage: "apply pending migrations", Run: migrate},
+//			},
+//		})
+//	}
+type App[Cfg any] struct {
+	Name   string
+	Config []deploy.ConfigFile
+	// Logger creates the logger from the loaded configuration. Defaults to a GCP logger writing to stdout in
+	// release environments, and to a console logger otherwise.
+	Logger   func(cfg *Cfg) monitor.Logger
+	Commands []Command[Cfg]
+	// Default is the command run when the binary is called without arguments. No default command is run if empty.
+	Default string
+
+	// Output receives usage messages. Defaults to os.Stderr.
+	Output io.Writer
+}
+
+// Run executes the command selected by the process arguments, then exits the process with the resulting exit code.
+// A command that panics exits the process with monitor.ExitPanic, after writing a crash record.
+func Run[Cfg any](app App[Cfg]) {
+	defer monitor.RecoverCrash()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := app.Execute(ctx, os.Args[1:])
+	stop()
+
+	os.Exit(code)
+}
+
+// Execute runs the command selected by args, and returns the exit code of the process.
+func (app App[Cfg]) Execute(ctx context.Context, args []string) int {
+	output := app.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	commands := make(map[string]Command[Cfg], len(app.Commands))
+	for _, command := range app.Commands {
+		commands[command.Name] = command
+	}
+
+	name := app.Default
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "" || name == "help" || name == "-h" || name == "--help" {
+		app.usage(output)
+		return lo.Ternary(name == "", ExitUsage, ExitOK)
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		_, _ = fmt.Fprintf(output, "unknown command %q\n\n", name)
+		app.usage(output)
+		return ExitUsage
+	}
+
+	flags := flag.NewFlagSet(app.Name+" "+command.Name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	if command.Flags != nil {
+		command.Flags(flags)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+
+		return ExitUsage
+	}
+
+	cfg := deploy.LoadConfig[Cfg](app.Config...)
+	logger := app.logger(cfg)
+
+	err := command.Run(&Context[Cfg]{
+		Context: ctx,
+		Config:  cfg,
+		Logger:  logger,
+		Args:    flags.Args(),
+	})
+
+	code := exitCode(err)
+	switch {
+	case code == ExitUsage:
+		_, _ = fmt.Fprintf(output, "%s\n\n", err)
+		flags.Usage()
+	case err != nil && ctx.Err() != nil:
+		logger.Warn(fmt.Sprintf("command %s interrupted: %s", command.Name, err))
+		code = ExitInterrupted
+	case err != nil:
+		logger.Error(err, fmt.Sprintf("command %s failed", command.Name))
+	}
+
+	return code
+}
+
+func (app App[Cfg]) logger(cfg *Cfg) monitor.Logger {
+	if app.Logger != nil {
+		return app.Logger(cfg)
+	}
+
+	if deploy.IsReleaseEnv() {
+		return monitor.NewGCPLogger(zerolog.New(os.Stdout).With().Timestamp().Logger(), "")
+	}
+
+	return monitor.NewConsoleLogger()
+}
+
+func (app App[Cfg]) usage(output io.Writer) {
+	_, _ = fmt.Fprintf(output, "Usage: %s <command> [flags] [args]\n\nCommands:\n", app.Name)
+
+	commands := make([]Command[Cfg], len(app.Commands))
+	copy(commands, app.Commands)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+
+	for _, command := range commands {
+		_, _ = fmt.Fprintf(output, "  %-16s %s\n", command.Name, command.Usage)
+	}
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes returned by service binaries. Crashing binaries exit with monitor.ExitFatal or monitor.ExitPanic.
+const (
+	ExitOK = 0
+	// ExitFailure is returned when a command fails.
+	ExitFailure = 1
+	// ExitUsage is returned when the command line is invalid.
+	ExitUsage = 2
+	// ExitInterrupted is returned when the command is stopped by a signal before completion.
+	ExitInterrupted = 130
+)
+
+// ExitError lets a command choose the exit code of the process.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Exit wraps an error with a specific exit code.
+func Exit(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// UsageError reports an invalid command line.
+func UsageError(format string, args ...any) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return ExitFailure
+}
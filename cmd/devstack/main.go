@@ -0,0 +1,39 @@
+// Command devstack runs the services of a manifest locally, with prefixed output and automatic restarts.
+//
+//	go run github.com/in-rich/lib-go/cmd/devstack up -manifest devstack.yaml [services...]
+package main
+
+import (
+	"flag"
+	"github.com/in-rich/lib-go/cli"
+	"github.com/in-rich/lib-go/devstack"
+	"os"
+)
+
+type config struct{}
+
+func main() {
+	var manifestPath string
+
+	cli.Run(cli.App[config]{
+		Name:    "devstack",
+		Default: "up",
+		Commands: []cli.Command[config]{
+			{
+				Name:  "up",
+				Usage: "start the services of the manifest, or the given services and their dependencies",
+				Flags: func(flags *flag.FlagSet) {
+					flags.StringVar(&manifestPath, "manifest", "devstack.yaml", "path of the manifest")
+				},
+				Run: func(ctx *cli.Context[config]) error {
+					manifest, err := devstack.LoadManifest(manifestPath)
+					if err != nil {
+						return err
+					}
+
+					return devstack.NewStack(manifest, os.Stdout).Run(ctx, ctx.Args...)
+				},
+			},
+		},
+	})
+}
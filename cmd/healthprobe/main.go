@@ -0,0 +1,20 @@
+// Command healthprobe checks the GRPC health service of a server, for orchestrators running services without an
+// embedded health command.
+//
+//	healthprobe health -addr localhost:50051 [-service notes] [-tls auto|on|off] [-timeout 5s]
+package main
+
+import (
+	"github.com/in-rich/lib-go/cli"
+	"github.com/in-rich/lib-go/healthprobe"
+)
+
+type config struct{}
+
+func main() {
+	cli.Run(cli.App[config]{
+		Name:     "healthprobe",
+		Default:  "health",
+		Commands: []cli.Command[config]{healthprobe.Command[config]()},
+	})
+}
@@ -0,0 +1,280 @@
+// Command inrichcall calls a method of one of our GRPC services, with the TLS and identity logic of
+// deploy.OpenGRPCConn. Request and response messages are JSON, converted with the schema served by the GRPC
+// reflection service, registered by servers started with deploy.WithReflection.
+//
+//	ENV=staging inrichcall call -host notes-abc123.a.run.app -d '{"noteId": "..."}' notes.v1.Notes/GetNote
+//	inrichcall list -host localhost:50051 [service]
+//
+// Release environments use identity tokens: run it with service account credentials, such as
+// GOOGLE_APPLICATION_CREDENTIALS or an impersonated account.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/in-rich/lib-go/cli"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/healthprobe"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type config struct{}
+
+type headers []string
+
+func (h *headers) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headers) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("invalid header %q, expected name: value", value)
+	}
+
+	*h = append(*h, value)
+	return nil
+}
+
+func main() {
+	var (
+		host    string
+		mode    string
+		data    string
+		timeout time.Duration
+		extra   headers
+	)
+
+	connectionFlags := func(flags *flag.FlagSet) {
+		flags.StringVar(&host, "host", "localhost:50051", "address of the service")
+		flags.StringVar(&mode, "tls", healthprobe.TLSAuto, "TLS mode: auto, on, or off")
+		flags.DurationVar(&timeout, "timeout", 30*time.Second, "timeout of the call")
+	}
+
+	cli.Run(cli.App[config]{
+		Name: "inrichcall",
+		Commands: []cli.Command[config]{
+			{
+				Name:  "call",
+				Usage: "call a method with a JSON request: call [flags] package.Service/Method",
+				Flags: func(flags *flag.FlagSet) {
+					connectionFlags(flags)
+					flags.StringVar(&data, "d", "{}", "JSON request, @file to read it from a file, or - to read stdin")
+					flags.Var(&extra, "H", "metadata sent with the request, as name: value (repeatable)")
+				},
+				Run: func(ctx *cli.Context[config]) error {
+					if len(ctx.Args) != 1 {
+						return cli.UsageError("expected exactly one method, got %d", len(ctx.Args))
+					}
+
+					request, err := readRequest(data)
+					if err != nil {
+						return cli.UsageError("%s", err)
+					}
+
+					return call(ctx, host, mode, timeout, ctx.Args[0], request, extra)
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "list the services of the server, or the methods of a service: list [flags] [service]",
+				Flags: connectionFlags,
+				Run: func(ctx *cli.Context[config]) error {
+					return list(ctx, host, mode, timeout, ctx.Args)
+				},
+			},
+		},
+	})
+}
+
+func readRequest(data string) ([]byte, error) {
+	switch {
+	case data == "-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(data, "@"):
+		return os.ReadFile(strings.TrimPrefix(data, "@"))
+	default:
+		return []byte(data), nil
+	}
+}
+
+func list(ctx *cli.Context[config], host, mode string, timeout time.Duration, args []string) error {
+	conn, err := healthprobe.Dial(ctx.Logger, host, mode)
+	if err != nil {
+		return cli.UsageError("%s", err)
+	}
+	defer deploy.CloseGRPCConn(conn)
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	registry, err := newSchema(callCtx, conn)
+	if err != nil {
+		return err
+	}
+	defer registry.close()
+
+	if len(args) == 0 {
+		services, err := registry.services()
+		if err != nil {
+			return err
+		}
+
+		for _, service := range services {
+			fmt.Println(service)
+		}
+		return nil
+	}
+
+	service, err := registry.service(args[0])
+	if err != nil {
+		return err
+	}
+
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		fmt.Printf("%s/%s(%s) returns (%s%s)\n",
+			service.FullName(), method.Name(), method.Input().FullName(),
+			map[bool]string{true: "stream ", false: ""}[method.IsStreamingServer()], method.Output().FullName(),
+		)
+	}
+
+	return nil
+}
+
+func call(
+	ctx *cli.Context[config], host, mode string, timeout time.Duration, fullMethod string, request []byte, extra headers,
+) error {
+	serviceName, methodName, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return cli.UsageError("invalid method %q, expected package.Service/Method", fullMethod)
+	}
+
+	conn, err := healthprobe.Dial(ctx.Logger, host, mode)
+	if err != nil {
+		return cli.UsageError("%s", err)
+	}
+	defer deploy.CloseGRPCConn(conn)
+
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	registry, err := newSchema(callCtx, conn)
+	if err != nil {
+		return err
+	}
+	defer registry.close()
+
+	service, err := registry.service(serviceName)
+	if err != nil {
+		return err
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return fmt.Errorf("method %s not found in %s", methodName, serviceName)
+	}
+	if method.IsStreamingClient() {
+		return fmt.Errorf("client-streaming method %s is not supported", fullMethod)
+	}
+
+	types := dynamicpb.NewTypes(registry.files)
+
+	in := dynamicpb.NewMessage(method.Input())
+	if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal(request, in); err != nil {
+		return cli.UsageError("invalid request for %s: %s", method.Input().FullName(), err)
+	}
+
+	traceID, md := traceMetadata()
+	for _, header := range extra {
+		name, value, _ := strings.Cut(header, ":")
+		md.Append(strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value))
+	}
+	callCtx = metadata.NewOutgoingContext(callCtx, md)
+
+	_, _ = fmt.Fprintf(os.Stderr, "trace: %s\n", traceID)
+
+	printer := protojson.MarshalOptions{Multiline: true, Indent: "  ", Resolver: types}
+	path := "/" + serviceName + "/" + methodName
+	header := metadata.MD{}
+	start := time.Now()
+
+	defer func() {
+		if requestID := header.Get("x-request-id"); len(requestID) > 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "request: %s\n", requestID[0])
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "duration: %s\n", time.Since(start).Round(time.Millisecond))
+	}()
+
+	if !method.IsStreamingServer() {
+		out := dynamicpb.NewMessage(method.Output())
+		if err := conn.Invoke(callCtx, path, in, out, grpc.Header(&header)); err != nil {
+			return describe(err)
+		}
+
+		fmt.Println(printer.Format(out))
+		return nil
+	}
+
+	stream, err := conn.NewStream(callCtx, &grpc.StreamDesc{ServerStreams: true}, path, grpc.Header(&header))
+	if err != nil {
+		return describe(err)
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return describe(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return describe(err)
+	}
+
+	for {
+		out := dynamicpb.NewMessage(method.Output())
+		if err := stream.RecvMsg(out); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return describe(err)
+		}
+
+		fmt.Println(printer.Format(out))
+	}
+}
+
+// traceMetadata creates the trace headers of the call, so its logs and traces can be found.
+func traceMetadata() (string, metadata.MD) {
+	trace := make([]byte, 16)
+	span := make([]byte, 8)
+	_, _ = rand.Read(trace)
+	_, _ = rand.Read(span)
+
+	traceID := hex.EncodeToString(trace)
+	var spanID uint64
+	for _, b := range span {
+		spanID = spanID<<8 | uint64(b)
+	}
+
+	return traceID, metadata.Pairs(
+		"traceparent", "00-"+traceID+"-"+hex.EncodeToString(span)+"-01",
+		"x-cloud-trace-context", fmt.Sprintf("%s/%d;o=1", traceID, spanID),
+	)
+}
+
+func describe(err error) error {
+	if st, ok := status.FromError(err); ok {
+		return fmt.Errorf("%s: %s", st.Code(), st.Message())
+	}
+
+	return err
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// schema downloads the descriptors of a server through the GRPC reflection service.
+type schema struct {
+	stream reflectionpb.ServerReflection_ServerReflectionInfoClient
+	protos map[string]*descriptorpb.FileDescriptorProto
+	files  *protoregistry.Files
+}
+
+func newSchema(ctx context.Context, conn *grpc.ClientConn) (*schema, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("open reflection stream: %w", err)
+	}
+
+	return &schema{stream: stream, protos: make(map[string]*descriptorpb.FileDescriptorProto), files: new(protoregistry.Files)}, nil
+}
+
+func (s *schema) close() {
+	_ = s.stream.CloseSend()
+}
+
+func (s *schema) request(req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := s.stream.Send(req); err != nil {
+		return nil, err
+	}
+
+	res, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if errRes := res.GetErrorResponse(); errRes != nil {
+		return nil, fmt.Errorf("reflection error %d: %s", errRes.GetErrorCode(), errRes.GetErrorMessage())
+	}
+
+	return res, nil
+}
+
+// services lists the services exposed by the server.
+func (s *schema) services() ([]string, error) {
+	res, err := s.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(res.GetListServicesResponse().GetService()))
+	for _, service := range res.GetListServicesResponse().GetService() {
+		out = append(out, service.GetName())
+	}
+
+	return out, nil
+}
+
+func (s *schema) addFiles(res *reflectionpb.ServerReflectionResponse) error {
+	for _, raw := range res.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(raw, file); err != nil {
+			return fmt.Errorf("decode file descriptor: %w", err)
+		}
+
+		s.protos[file.GetName()] = file
+	}
+
+	return nil
+}
+
+// service returns the descriptor of a service, downloading the files it depends on.
+func (s *schema) service(name string) (protoreflect.ServiceDescriptor, error) {
+	res, err := s.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", name, err)
+	}
+
+	if err := s.addFiles(res); err != nil {
+		return nil, err
+	}
+
+	for _, file := range s.protos {
+		if err := s.register(file.GetName()); err != nil {
+			return nil, err
+		}
+	}
+
+	descriptor, err := s.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", name, err)
+	}
+
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+
+	return service, nil
+}
+
+// register builds a file descriptor after its dependencies.
+func (s *schema) register(name string) error {
+	if _, err := s.files.FindFileByPath(name); err == nil {
+		return nil
+	}
+
+	file, ok := s.protos[name]
+	if !ok {
+		// Well-known types are usually compiled in.
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(name); err == nil {
+			return nil
+		}
+
+		res, err := s.request(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		})
+		if err != nil {
+			return fmt.Errorf("download %s: %w", name, err)
+		}
+
+		if err := s.addFiles(res); err != nil {
+			return err
+		}
+
+		if file, ok = s.protos[name]; !ok {
+			return fmt.Errorf("download %s: file not returned by the server", name)
+		}
+	}
+
+	for _, dependency := range file.GetDependency() {
+		if err := s.register(dependency); err != nil {
+			return err
+		}
+	}
+
+	descriptor, err := protodesc.NewFile(file, resolver{s.files})
+	if err != nil {
+		return fmt.Errorf("build %s: %w", name, err)
+	}
+
+	return s.files.RegisterFile(descriptor)
+}
+
+// resolver looks up the downloaded files first, then the compiled-in ones.
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if descriptor, err := r.files.FindDescriptorByName(name); err == nil {
+		return descriptor, nil
+	}
+
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
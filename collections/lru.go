@@ -0,0 +1,189 @@
+package collections
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a cache, to export as metrics.
+type Stats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	Entries     int   `json:"entries"`
+	Size        int64 `json:"size"`
+}
+
+// HitRatio returns the fraction of lookups that found a value, between 0 and 1.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type LRUConfig[K comparable, V any] struct {
+	// MaxEntries is the maximum number of entries. Defaults to 10000.
+	MaxEntries int
+	// MaxSize is the maximum total size of the entries, as measured by Size. Ignored when 0.
+	MaxSize int64
+	// Size measures an entry, such as its length in bytes. Required with MaxSize.
+	Size func(value V) int64
+	// TTL is the default lifetime of the entries. Entries never expire when 0.
+	TTL time.Duration
+	// OnEvict is called when an entry is removed to make room, or because it expired. Optional. It is called with
+	// the lock of the cache held, so it must not use the cache.
+	OnEvict func(key K, value V)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	size      int64
+	expiresAt time.Time
+}
+
+// LRU is a bounded cache evicting the least recently used entries, with optional expiration.
+//
+//	tokens := collections.NewLRU(collections.LRUConfig[string, *Token]{MaxEntries: 50000, TTL: 5 * time.Minute})
+type LRU[K comparable, V any] struct {
+	config LRUConfig[K, V]
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[K]*list.Element
+	size    int64
+
+	hits, misses, evictions, expirations atomic.Int64
+}
+
+func NewLRU[K comparable, V any](config LRUConfig[K, V]) *LRU[K, V] {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 10000
+	}
+	if config.MaxSize > 0 && config.Size == nil {
+		panic("collections: Size is required with MaxSize")
+	}
+
+	return &LRU[K, V]{config: config, order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+// Get returns the value of a key, and marks it as recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[K, V])
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	c.hits.Add(1)
+	return entry.value, true
+}
+
+// Set stores a value with the default TTL.
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.config.TTL)
+}
+
+// SetWithTTL stores a value that expires after ttl. The value never expires when ttl is 0.
+func (c *LRU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry[K, V]{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	if c.config.Size != nil {
+		entry.size = c.config.Size(value)
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.size -= element.Value.(*lruEntry[K, V]).size
+		element.Value = entry
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(entry)
+	}
+	c.size += entry.size
+
+	for c.order.Len() > c.config.MaxEntries || (c.config.MaxSize > 0 && c.size > c.config.MaxSize && c.order.Len() > 1) {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Delete removes a key. OnEvict is not called.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := c.order.Remove(element).(*lruEntry[K, V])
+		delete(c.entries, key)
+		c.size -= entry.size
+	}
+}
+
+// Purge removes every entry. OnEvict is not called.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[K]*list.Element)
+	c.size = 0
+}
+
+// Len returns the number of entries, including expired entries not removed yet.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	entries, size := c.order.Len(), c.size
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Entries:     entries,
+		Size:        size,
+	}
+}
+
+// remove must be called with the lock held.
+func (c *LRU[K, V]) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry[K, V])
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+
+	if c.config.OnEvict != nil {
+		c.config.OnEvict(entry.key, entry.value)
+	}
+}
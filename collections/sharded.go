@@ -0,0 +1,148 @@
+package collections
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// HashString hashes string keys for a ShardedMap.
+func HashString(key string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	return hash.Sum64()
+}
+
+type shard[K comparable, V any] struct {
+	mu     sync.RWMutex
+	values map[K]V
+}
+
+// ShardedMap is a concurrent map split in shards with their own lock, so concurrent writers of different keys
+// rarely contend. Unlike sync.Map, it is typed, and its size is known.
+type ShardedMap[K comparable, V any] struct {
+	shards []*shard[K, V]
+	hash   func(key K) uint64
+}
+
+// NewShardedMap creates a map with the given number of shards (defaults to 32), using hash to assign keys to
+// shards.
+//
+//	accounts := collections.NewShardedMap[string, *AccountState](0, collections.HashString)
+func NewShardedMap[K comparable, V any](shards int, hash func(key K) uint64) *ShardedMap[K, V] {
+	if shards <= 0 {
+		shards = 32
+	}
+
+	out := &ShardedMap[K, V]{shards: make([]*shard[K, V], shards), hash: hash}
+	for i := range out.shards {
+		out.shards[i] = &shard[K, V]{values: make(map[K]V)}
+	}
+
+	return out
+}
+
+func (m *ShardedMap[K, V]) shard(key K) *shard[K, V] {
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+func (m *ShardedMap[K, V]) Load(key K) (V, bool) {
+	s := m.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (m *ShardedMap[K, V]) Store(key K, value V) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+// LoadOrStore returns the existing value of the key if any. Otherwise, it stores and returns the given value.
+// The loaded result is true if the value was loaded.
+func (m *ShardedMap[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.values[key]; ok {
+		return existing, true
+	}
+
+	s.values[key] = value
+	return value, false
+}
+
+// Compute replaces the value of a key with the result of fn, atomically. fn receives the current value, and
+// whether it exists. The key is deleted when fn returns false.
+func (m *ShardedMap[K, V]) Compute(key K, fn func(value V, ok bool) (V, bool)) (V, bool) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.values[key]
+	next, keep := fn(current, ok)
+	if !keep {
+		delete(s.values, key)
+		return next, false
+	}
+
+	s.values[key] = next
+	return next, true
+}
+
+func (m *ShardedMap[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+}
+
+// Len returns the number of keys in the map.
+func (m *ShardedMap[K, V]) Len() int {
+	total := 0
+	for _, s := range m.shards {
+		s.mu.RLock()
+		total += len(s.values)
+		s.mu.RUnlock()
+	}
+
+	return total
+}
+
+// Range calls fn for every key, until fn returns false. Each shard is locked while it is iterated, so fn must not
+// modify the map.
+func (m *ShardedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for key, value := range s.values {
+			if !fn(key, value) {
+				s.mu.RUnlock()
+				return
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// DeleteFunc removes the keys for which fn returns true, such as expired entries.
+func (m *ShardedMap[K, V]) DeleteFunc(fn func(key K, value V) bool) int {
+	deleted := 0
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for key, value := range s.values {
+			if fn(key, value) {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return deleted
+}
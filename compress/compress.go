@@ -0,0 +1,141 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"strings"
+)
+
+// Encoding is a compression algorithm, named after its HTTP Content-Encoding token.
+type Encoding string
+
+const (
+	Identity Encoding = "identity"
+	Gzip     Encoding = "gzip"
+	Zstd     Encoding = "zstd"
+	Snappy   Encoding = "snappy"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+// ParseEncoding parses a Content-Encoding value. An empty value is read as Identity.
+func ParseEncoding(value string) (Encoding, error) {
+	switch encoding := Encoding(strings.ToLower(strings.TrimSpace(value))); encoding {
+	case "":
+		return Identity, nil
+	case Identity, Gzip, Zstd, Snappy:
+		return encoding, nil
+	case "x-gzip":
+		return Gzip, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedEncoding, value)
+	}
+}
+
+func (e Encoding) String() string {
+	return string(e)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewWriter returns a writer compressing data to w. The writer must be closed to flush the compressed stream,
+// closing it does not close w.
+func NewWriter(w io.Writer, encoding Encoding) (io.WriteCloser, error) {
+	switch encoding {
+	case Identity, "":
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	case Snappy:
+		return snappy.NewBufferedWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, encoding)
+	}
+}
+
+// NewReader returns a reader decompressing data from r. Closing the reader does not close r.
+func NewReader(r io.Reader, encoding Encoding) (io.ReadCloser, error) {
+	switch encoding {
+	case Identity, "":
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	case Snappy:
+		return io.NopCloser(snappy.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, encoding)
+	}
+}
+
+// Compress compresses a payload in memory.
+func Compress(data []byte, encoding Encoding) ([]byte, error) {
+	if encoding == Identity || encoding == "" {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+
+	writer, err := NewWriter(&buf, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses a payload in memory. If maxSize is positive, decompression fails once the decompressed
+// payload exceeds maxSize bytes, to protect against decompression bombs.
+func Decompress(data []byte, encoding Encoding, maxSize int64) ([]byte, error) {
+	if encoding == Identity || encoding == "" {
+		return data, nil
+	}
+
+	reader, err := NewReader(bytes.NewReader(data), encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if maxSize <= 0 {
+		return io.ReadAll(reader)
+	}
+
+	out, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(out)) > maxSize {
+		return nil, fmt.Errorf("decompressed payload exceeds %d bytes", maxSize)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,63 @@
+package compress
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Negotiate selects the preferred encoding among the supported ones, from an Accept-Encoding header value.
+// Supported encodings are listed by order of preference of the server, which breaks ties between encodings the
+// client equally accepts. Identity is returned when no supported encoding is accepted.
+//
+//	encoding := compress.Negotiate(c.GetHeader("Accept-Encoding"), compress.Zstd, compress.Gzip)
+func Negotiate(acceptEncoding string, supported ...Encoding) Encoding {
+	weights := make(map[Encoding]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					weight = parsed
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcard = weight
+			continue
+		}
+
+		encoding, err := ParseEncoding(name)
+		if err != nil {
+			continue
+		}
+
+		weights[encoding] = weight
+	}
+
+	best := Identity
+	bestWeight := 0.0
+
+	for _, encoding := range supported {
+		weight, ok := weights[encoding]
+		if !ok {
+			weight = wildcard
+		}
+
+		if weight > bestWeight {
+			best = encoding
+			bestWeight = weight
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,121 @@
+package contractcheck
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/pattern"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"sync"
+)
+
+type Config struct {
+	// Enabled turns the checks on. Defaults to true in the staging environment only: checks are meant to catch
+	// breaking changes of upstream services before they reach production.
+	Enabled *bool
+	// OnViolation is called for every violation, in addition to the log, for example to count them.
+	OnViolation func(violation Violation)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Enabled == nil {
+		enabled := deploy.ENV == deploy.StagingEnv
+		c.Enabled = &enabled
+	}
+
+	return c
+}
+
+// Checker validates the responses of upstream services against the expectations of the caller.
+//
+//	checker := contractcheck.NewChecker(logger, contractcheck.Config{})
+//	checker.Expect("/notes.v1.Notes/ListNotes",
+//		contractcheck.Required("notes"),
+//		contractcheck.PageToken("page_size", "notes", "next_page_token"),
+//	)
+//	checker.Expect("/notes.v1.Notes/*", contractcheck.KnownEnums())
+//
+//	conn, err := grpc.NewClient(host, grpc.WithChainUnaryInterceptor(checker.UnaryClientInterceptor()))
+//
+// Violations are logged, and never fail the call.
+type Checker struct {
+	logger monitor.Logger
+	config Config
+
+	mu    sync.RWMutex
+	rules pattern.Matcher[[]Rule]
+}
+
+func NewChecker(logger monitor.Logger, config Config) *Checker {
+	return &Checker{
+		logger: logger,
+		config: config.withDefaults(),
+	}
+}
+
+// Expect registers rules for a full method name. Methods ending with "*" match by prefix. Every rule matching a
+// method applies.
+func (c *Checker) Expect(method string, rules ...Rule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, _ := c.rules.Pattern(method)
+	c.rules.Add(method, append(existing[:len(existing):len(existing)], rules...))
+}
+
+func (c *Checker) methodRules(method string) []Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var rules []Rule
+	for _, matched := range c.rules.MatchAll(method) {
+		rules = append(rules, matched...)
+	}
+
+	return rules
+}
+
+// Check returns the violations of a response.
+func (c *Checker) Check(method string, req, res proto.Message) []Violation {
+	var violations []Violation
+
+	for _, rule := range c.methodRules(method) {
+		for _, violation := range rule(req.ProtoReflect(), res.ProtoReflect()) {
+			violation.Method = method
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+// UnaryClientInterceptor checks the successful responses of the calls. It does nothing when the checker is
+// disabled.
+func (c *Checker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil || !*c.config.Enabled {
+			return err
+		}
+
+		reqMsg, okReq := req.(proto.Message)
+		resMsg, okRes := reply.(proto.Message)
+		if !okReq || !okRes {
+			return nil
+		}
+
+		for _, violation := range c.Check(method, reqMsg, resMsg) {
+			c.logger.Warn(fmt.Sprintf("[contractcheck] contract violation: %s", violation))
+			if c.config.OnViolation != nil {
+				c.config.OnViolation(violation)
+			}
+		}
+
+		return nil
+	}
+}
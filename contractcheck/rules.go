@@ -0,0 +1,157 @@
+package contractcheck
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"strings"
+)
+
+// Violation is a response breaking the expectations of the caller.
+type Violation struct {
+	// Method is the full name of the called method.
+	Method string
+	// Field is the path of the offending field in the response, if any.
+	Field  string
+	Reason string
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("%s: %s", v.Method, v.Reason)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", v.Method, v.Field, v.Reason)
+}
+
+// Rule checks the response of a call, given its request. The Method of the returned violations is set by the
+// Checker.
+type Rule func(req, res protoreflect.Message) []Violation
+
+// lookup returns the parent message and descriptor of the field at the dot-separated path. The parent is invalid
+// if an intermediate message is not set.
+func lookup(msg protoreflect.Message, path string) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			return msg, nil, fmt.Errorf("unknown field %s in %s", name, msg.Descriptor().FullName())
+		}
+
+		if i == len(names)-1 {
+			return msg, field, nil
+		}
+
+		if field.Message() == nil || field.IsList() || field.IsMap() {
+			return msg, nil, fmt.Errorf("field %s of %s is not a message", name, msg.Descriptor().FullName())
+		}
+
+		msg = msg.Get(field).Message()
+	}
+
+	return msg, nil, nil
+}
+
+// Required expects the fields at the given paths to be set in the response. Repeated and map fields must not be
+// empty, and scalar fields without explicit presence must not hold the zero value.
+func Required(paths ...string) Rule {
+	return func(_, res protoreflect.Message) []Violation {
+		var violations []Violation
+
+		for _, path := range paths {
+			parent, field, err := lookup(res, path)
+			if err != nil {
+				violations = append(violations, Violation{Field: path, Reason: err.Error()})
+				continue
+			}
+
+			if !parent.IsValid() || !parent.Has(field) {
+				violations = append(violations, Violation{Field: path, Reason: "required field is missing"})
+			}
+		}
+
+		return violations
+	}
+}
+
+// PageToken expects a next page token in every full page: when the response holds as many items as the page
+// size of the request, the token must not be empty, or the caller silently stops paginating. Pages are only
+// checked when the request sets a page size.
+func PageToken(pageSizeField, itemsField, tokenField string) Rule {
+	return func(req, res protoreflect.Message) []Violation {
+		_, sizeField, err := lookup(req, pageSizeField)
+		if err != nil {
+			return []Violation{{Field: pageSizeField, Reason: err.Error()}}
+		}
+		_, items, err := lookup(res, itemsField)
+		if err != nil || !items.IsList() {
+			return []Violation{{Field: itemsField, Reason: "not a repeated field"}}
+		}
+		_, token, err := lookup(res, tokenField)
+		if err != nil {
+			return []Violation{{Field: tokenField, Reason: err.Error()}}
+		}
+
+		pageSize := req.Get(sizeField).Int()
+		if pageSize <= 0 || int64(res.Get(items).List().Len()) < pageSize {
+			return nil
+		}
+
+		if res.Get(token).String() == "" {
+			return []Violation{{
+				Field:  tokenField,
+				Reason: fmt.Sprintf("full page of %d items without next page token", pageSize),
+			}}
+		}
+
+		return nil
+	}
+}
+
+// KnownEnums expects every enum value of the response, nested messages included, to be declared in the version
+// of the schema the caller was compiled with. Unknown values reveal an upstream schema change the caller does not
+// handle yet.
+func KnownEnums() Rule {
+	return func(_, res protoreflect.Message) []Violation {
+		var violations []Violation
+		walkEnums(res, "", &violations)
+		return violations
+	}
+}
+
+func walkEnums(msg protoreflect.Message, prefix string, violations *[]Violation) {
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		path := prefix + string(field.Name())
+
+		switch {
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				checkValue(field, list.Get(i), fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		case field.IsMap():
+			value.Map().Range(func(key protoreflect.MapKey, item protoreflect.Value) bool {
+				checkValue(field.MapValue(), item, fmt.Sprintf("%s[%s]", path, key), violations)
+				return true
+			})
+		default:
+			checkValue(field, value, path, violations)
+		}
+
+		return true
+	})
+}
+
+func checkValue(field protoreflect.FieldDescriptor, value protoreflect.Value, path string, violations *[]Violation) {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if field.Enum().Values().ByNumber(value.Enum()) == nil {
+			*violations = append(*violations, Violation{
+				Field:  path,
+				Reason: fmt.Sprintf("unknown value %d of enum %s", value.Enum(), field.Enum().FullName()),
+			})
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		walkEnums(value.Message(), path+".", violations)
+	}
+}
@@ -0,0 +1,100 @@
+package contracts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+// Sample is an event a producer publishes, as sent on the topic.
+type Sample struct {
+	// Name describes the sample in test results, such as "delivery with attachment".
+	Name     string
+	Schema   string
+	Encoding Encoding
+	Data     []byte
+}
+
+// JSONSample encodes a sample the way producers serialize it: proto messages with protojson, and other values
+// with encoding/json. Encoding failures panic, as samples are declared by tests.
+func JSONSample(schema, name string, value any) Sample {
+	var (
+		data []byte
+		err  error
+	)
+
+	if msg, ok := value.(proto.Message); ok {
+		data, err = protojson.Marshal(msg)
+	} else {
+		data, err = json.Marshal(value)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("encode sample %s: %s", name, err))
+	}
+
+	return Sample{Name: name, Schema: schema, Encoding: JSON, Data: data}
+}
+
+// Expectation is the way a consumer reads the events of a schema. Decode must parse the payload with the code of
+// the consumer, and return an error if a field the consumer relies on is missing or invalid.
+type Expectation struct {
+	// Consumer names the consuming service in test results.
+	Consumer string
+	Schema   string
+	Decode   func(data []byte) error
+}
+
+// Contract gathers the samples declared by producers, and the expectations declared by consumers. Producers and
+// consumers usually export their part from a shared package, so a single test covers every pair.
+type Contract struct {
+	Samples      []Sample
+	Expectations []Expectation
+}
+
+// Run validates every sample against its schema in the registry, then decodes it with every expectation of the
+// same schema. Expectations without sample fail too, since nothing guarantees the events they decode.
+//
+//	func TestNotificationContracts(t *testing.T) {
+//		contracts.Run(t, contracts.NewProtoRegistry(nil), contracts.Contract{
+//			Samples:      notifications.Samples,
+//			Expectations: append(mailer.Expectations, push.Expectations...),
+//		})
+//	}
+func Run(t *testing.T, registry Registry, contract Contract) {
+	t.Helper()
+
+	bySchema := make(map[string][]Sample)
+	for _, sample := range contract.Samples {
+		bySchema[sample.Schema] = append(bySchema[sample.Schema], sample)
+	}
+
+	for _, sample := range contract.Samples {
+		t.Run(fmt.Sprintf("producer/%s/%s", sample.Schema, sample.Name), func(t *testing.T) {
+			if err := registry.Validate(context.Background(), sample.Schema, sample.Encoding, sample.Data); err != nil {
+				t.Errorf("sample does not match the schema: %s", err)
+			}
+		})
+	}
+
+	for _, expectation := range contract.Expectations {
+		samples := bySchema[expectation.Schema]
+
+		if len(samples) == 0 {
+			t.Run(fmt.Sprintf("consumer/%s/%s", expectation.Consumer, expectation.Schema), func(t *testing.T) {
+				t.Errorf("no producer declares a sample of schema %s", expectation.Schema)
+			})
+			continue
+		}
+
+		for _, sample := range samples {
+			t.Run(fmt.Sprintf("consumer/%s/%s/%s", expectation.Consumer, sample.Schema, sample.Name), func(t *testing.T) {
+				if err := expectation.Decode(sample.Data); err != nil {
+					t.Errorf("consumer cannot decode the sample: %s", err)
+				}
+			})
+		}
+	}
+}
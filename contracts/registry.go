@@ -0,0 +1,102 @@
+package contracts
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var ErrUnknownSchema = errors.New("unknown schema")
+
+type Encoding string
+
+const (
+	JSON   Encoding = "JSON"
+	Binary Encoding = "BINARY"
+)
+
+// Registry validates payloads against the schemas of the topics.
+type Registry interface {
+	Validate(ctx context.Context, schema string, encoding Encoding, data []byte) error
+}
+
+type pubsubRegistry struct {
+	service *pubsub.Service
+	project string
+}
+
+func (r *pubsubRegistry) Validate(ctx context.Context, schema string, encoding Encoding, data []byte) error {
+	parent := "projects/" + r.project
+
+	_, err := r.service.Projects.Schemas.ValidateMessage(parent, &pubsub.ValidateMessageRequest{
+		Name:     fmt.Sprintf("%s/schemas/%s", parent, schema),
+		Encoding: string(encoding),
+		Message:  base64.StdEncoding.EncodeToString(data),
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("validate against schema %s: %w", schema, err)
+	}
+
+	return nil
+}
+
+// NewPubSubRegistry creates a registry validating payloads with the Pub/Sub schemas of the project, the same
+// schemas topics enforce on publication. Tests using it require credentials with the pubsub.schemas.validate
+// permission.
+func NewPubSubRegistry(ctx context.Context, project string, opts ...option.ClientOption) (Registry, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create pubsub client: %w", err)
+	}
+
+	return &pubsubRegistry{service: service, project: project}, nil
+}
+
+type protoRegistry struct {
+	files *protoregistry.Files
+}
+
+func (r *protoRegistry) Validate(_ context.Context, schema string, encoding Encoding, data []byte) error {
+	descriptor, err := r.files.FindDescriptorByName(protoreflect.FullName(schema))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnknownSchema, schema)
+	}
+
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return fmt.Errorf("%w: %s is not a message", ErrUnknownSchema, schema)
+	}
+
+	msg := dynamicpb.NewMessage(message)
+	switch encoding {
+	case Binary:
+		err = proto.Unmarshal(data, msg)
+	default:
+		// Unknown fields are rejected, as Pub/Sub does.
+		err = protojson.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("validate against schema %s: %w", schema, err)
+	}
+
+	return nil
+}
+
+// NewProtoRegistry creates a registry validating payloads against local protocol buffer definitions, for tests
+// running without credentials. Schemas are the full names of messages, such as "notifications.v1.Delivery". A nil
+// files uses the definitions linked in the test binary.
+func NewProtoRegistry(files *protoregistry.Files) Registry {
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+
+	return &protoRegistry{files: files}
+}
@@ -0,0 +1,100 @@
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// Detach returns a copy of the context that keeps its values, but is never canceled and has no deadline.
+//
+// Use it for work that must outlive the current request, such as sending notifications after the response
+// was written:
+//
+//	go notify(ctxutil.Detach(ctx), event)
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// DetachWithTimeout detaches the context from its parent (see Detach), and sets a new timeout on it.
+func DetachWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(Detach(ctx), timeout)
+}
+
+// WithMinTimeout guarantees the returned context has at least the given amount of time before its deadline.
+//
+// If the parent has enough time left, the returned context is a regular child of the parent. Otherwise, the
+// returned context is detached from the parent and times out after the given duration. Values are preserved in
+// both cases.
+func WithMinTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if ctx.Err() == nil && (!ok || time.Until(deadline) >= timeout) {
+		return context.WithCancel(ctx)
+	}
+
+	return DetachWithTimeout(ctx, timeout)
+}
+
+// Sleep waits for the given duration, or until the context is done. It returns the error of the context when it is
+// done first, and nil otherwise.
+//
+//	if err := ctxutil.Sleep(ctx, backoff); err != nil {
+//		return err
+//	}
+func Sleep(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type mergedContext struct {
+	context.Context
+
+	secondary context.Context
+}
+
+// Merge returns a context that is canceled as soon as any of the two contexts is canceled. Its deadline is the
+// earliest of both deadlines. Values are looked up in the first context, then in the second one.
+//
+// When the second context is canceled, context.Cause on the merged context returns the cause of the second context.
+//
+// The returned cancel function must be called to release the resources associated with the merge.
+func Merge(first, second context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(first)
+	cancelDeadline := func() {}
+
+	if deadline, ok := second.Deadline(); ok {
+		if firstDeadline, ok := first.Deadline(); !ok || deadline.Before(firstDeadline) {
+			ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		}
+	}
+
+	stop := context.AfterFunc(second, func() {
+		cancelCause(context.Cause(second))
+	})
+
+	merged := &mergedContext{Context: ctx, secondary: second}
+
+	return merged, func() {
+		stop()
+		cancelDeadline()
+		cancelCause(context.Canceled)
+	}
+}
+
+func (m *mergedContext) Value(key any) any {
+	if value := m.Context.Value(key); value != nil {
+		return value
+	}
+
+	return m.secondary.Value(key)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Event is a change published on a channel. Events are stored, so listeners can replay the events they missed
+// while disconnected.
+type Event struct {
+	bun.BaseModel `bun:"table:database_events,alias:event"`
+
+	ID        int64     `bun:"id,pk,autoincrement"`
+	Channel   string    `bun:"channel,notnull"`
+	Payload   string    `bun:"payload,notnull"`
+	CreatedAt time.Time `bun:"created_at,notnull"`
+	// TxID is the transaction that published the event (see AfterPosition).
+	TxID int64 `bun:"tx_id,notnull,nullzero,default:pg_current_xact_id()::text::bigint"`
+}
+
+// ListenCursor stores the last event processed by a listener: its transaction and ID.
+type ListenCursor struct {
+	bun.BaseModel `bun:"table:database_listen_cursors,alias:cursor"`
+
+	Name      string    `bun:"name,pk"`
+	Channel   string    `bun:"channel,pk"`
+	TxID      int64     `bun:"tx_id,notnull"`
+	Position  int64     `bun:"position,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+// CreateTables creates the events and cursors tables, if they do not exist yet.
+func CreateTables(ctx context.Context, db bun.IDB) error {
+	if _, err := db.NewCreateTable().Model((*Event)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := db.NewCreateIndex().
+		Model((*Event)(nil)).
+		Index("database_events_channel_tx_idx").
+		Column("channel", "tx_id", "id").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewCreateTable().Model((*ListenCursor)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+// Notify stores an event on the channel, and wakes up its listeners. Pass the current transaction as db, so the
+// event is only published if the change it describes is committed: Postgres delivers notifications on commit.
+func Notify(ctx context.Context, db bun.IDB, channel, payload string) (*Event, error) {
+	event := &Event{Channel: channel, Payload: payload, CreatedAt: time.Now()}
+
+	if _, err := db.NewInsert().Model(event).Returning("id").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, "SELECT pg_notify(?, ?)", channel, ""); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// PurgeEvents deletes the events older than the given time. Listeners that did not process them yet will skip
+// them.
+func PurgeEvents(ctx context.Context, db bun.IDB, before time.Time) (int64, error) {
+	res, err := db.NewDelete().Model((*Event)(nil)).Where("created_at < ?", before).Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
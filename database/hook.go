@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	stringLiteral  = regexp.MustCompile(`(?:\b[eE])?'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// RedactQuery replaces the string and numeric literals of a query with placeholders, so queries can be logged
+// without leaking user data.
+func RedactQuery(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	return numericLiteral.ReplaceAllString(query, "?")
+}
+
+type QueryHookConfig struct {
+	// SlowThreshold is the duration over which queries are logged. Defaults to 200ms.
+	SlowThreshold time.Duration
+	// ExplainSample is the percentage of slow SELECT queries whose plan is logged. Defaults to 1%.
+	ExplainSample deploy.Percent
+	// Analyze runs EXPLAIN ANALYZE instead of EXPLAIN, which runs the query again. Defaults to true in the staging
+	// environment only.
+	Analyze *bool
+	// ExplainTimeout bounds the EXPLAIN queries. Defaults to 5 seconds.
+	ExplainTimeout time.Duration
+}
+
+func (c QueryHookConfig) withDefaults() QueryHookConfig {
+	if c.SlowThreshold <= 0 {
+		c.SlowThreshold = 200 * time.Millisecond
+	}
+	if c.ExplainSample <= 0 {
+		c.ExplainSample = 1
+	}
+	if c.Analyze == nil {
+		analyze := deploy.ENV == deploy.StagingEnv
+		c.Analyze = &analyze
+	}
+	if c.ExplainTimeout <= 0 {
+		c.ExplainTimeout = 5 * time.Second
+	}
+
+	return c
+}
+
+// QueryHook logs slow queries, and the plan of a sample of them.
+//
+//	db.AddQueryHook(database.NewQueryHook(logger, database.QueryHookConfig{}))
+type QueryHook struct {
+	logger monitor.Logger
+	config QueryHookConfig
+}
+
+func NewQueryHook(logger monitor.Logger, config QueryHookConfig) *QueryHook {
+	return &QueryHook{logger: logger, config: config.withDefaults()}
+}
+
+func (h *QueryHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *QueryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	duration := time.Since(event.StartTime)
+	if duration < h.config.SlowThreshold {
+		return
+	}
+
+	query := RedactQuery(event.Query)
+	h.logger.Warn(fmt.Sprintf("[database] slow query (%s): %s", duration.Round(time.Millisecond), query))
+
+	if event.DB == nil || !isSelect(event.Query) || rand.Float64() >= h.config.ExplainSample.Fraction() {
+		return
+	}
+
+	// The plan is logged asynchronously, so the caller is not slowed down further.
+	go h.explain(ctx, event.DB.DB, event.Query, query)
+}
+
+func (h *QueryHook) explain(ctx context.Context, db *sql.DB, query, redacted string) {
+	ctx, cancel := ctxutil.DetachWithTimeout(ctx, h.config.ExplainTimeout)
+	defer cancel()
+
+	plan, err := Explain(ctx, db, query, *h.config.Analyze)
+	if err != nil {
+		h.logger.Error(err, fmt.Sprintf("[database] failed to explain slow query: %s", redacted))
+		return
+	}
+
+	h.logger.Warn(fmt.Sprintf("[database] plan of slow query: %s\n%s", redacted, plan))
+}
+
+// Explain returns the plan of a query. It runs in a read-only transaction that is always rolled back, so analyzing
+// a query never modifies data.
+//
+// The plan is requested on the database/sql connection pool directly, so it does not go through query hooks again.
+func Explain(ctx context.Context, db *sql.DB, query string, analyze bool) (string, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	options := "FORMAT TEXT"
+	if analyze {
+		options = "ANALYZE, BUFFERS, FORMAT TEXT"
+	}
+
+	rows, err := tx.QueryContext(ctx, "EXPLAIN ("+options+") "+query)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	lines := make([]string, 0)
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	// Plans of analyzed queries contain literal values in their filters.
+	return RedactQuery(strings.Join(lines, "\n")), nil
+}
+
+func isSelect(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH":
+		// WITH can precede data-modifying statements.
+		upper := strings.ToUpper(query)
+		return !strings.Contains(upper, "INSERT ") && !strings.Contains(upper, "UPDATE ") && !strings.Contains(upper, "DELETE ")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"net"
+	"time"
+)
+
+// Handler processes an event. Returning an error stops the processing of the channel: the event is retried on the
+// next wake-up, so handlers must be idempotent.
+type Handler func(ctx context.Context, event *Event) error
+
+type ListenConfig struct {
+	// Name identifies the cursor of the listener. Listeners sharing a name share their progress, so use one name
+	// per consumer service. Defaults to "default".
+	Name string
+	// PollInterval is the delay after which the events table is checked even without notification, in case one
+	// was lost. Defaults to 30 seconds.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events read at once. Defaults to 100.
+	BatchSize int
+	// Logger reports connection and handler errors. Defaults to a dummy logger.
+	Logger monitor.Logger
+}
+
+func (c ListenConfig) withDefaults() ListenConfig {
+	if c.Name == "" {
+		c.Name = "default"
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 30 * time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.Logger == nil {
+		c.Logger = monitor.NewDummyLogger()
+	}
+
+	return c
+}
+
+// Listen calls the handler for every event published on the channel with Notify, until the context is canceled.
+//
+//	go database.Listen(ctx, db, "notes_changed", func(ctx context.Context, event *database.Event) error {
+//		return cache.Invalidate(ctx, event.Payload)
+//	}, database.ListenConfig{Name: "gateway"})
+//
+// Events are processed in transaction order, once every older transaction has ended, so an event published by a long
+// transaction is never skipped. On startup, and after every reconnection, the events published since the last
+// processed one are replayed from the events table.
+func Listen(ctx context.Context, db *bun.DB, channel string, handler Handler, config ListenConfig) error {
+	config = config.withDefaults()
+
+	listener := pgdriver.NewListener(db)
+	defer func() { _ = listener.Close() }()
+
+	backoff := time.Second
+
+	for ctx.Err() == nil {
+		if err := listener.Listen(ctx, channel); err != nil {
+			config.Logger.Error(err, fmt.Sprintf("[database] failed to listen to %s, retrying in %s", channel, backoff))
+			if ctxutil.Sleep(ctx, backoff) != nil {
+				break
+			}
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+
+		backoff = time.Second
+
+		// Replay the backlog, then wait for the next notification.
+		for ctx.Err() == nil {
+			if err := drain(ctx, db, channel, handler, config); err != nil && ctx.Err() == nil {
+				config.Logger.Error(err, fmt.Sprintf("[database] failed to process events of %s", channel))
+			}
+
+			_, _, err := listener.ReceiveTimeout(ctx, config.PollInterval)
+			if err != nil && !isTimeout(err) {
+				// The listener reconnects on the next call, and notifications sent meanwhile are lost: go back to
+				// the outer loop, to listen again and replay the backlog.
+				if ctx.Err() == nil {
+					config.Logger.Warn(fmt.Sprintf("[database] listener of %s disconnected: %s", channel, err))
+				}
+				break
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// drain processes the events that follow the cursor of the listener.
+func drain(ctx context.Context, db *bun.DB, channel string, handler Handler, config ListenConfig) error {
+	cursor := &ListenCursor{Name: config.Name, Channel: channel}
+	if err := db.NewSelect().Model(cursor).WherePK().Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	position := Position{TxID: cursor.TxID, ID: cursor.Position}
+
+	for {
+		events := make([]*Event, 0)
+		query := db.NewSelect().Model(&events).Where("channel = ?", channel)
+		if err := AfterPosition(query, position).Limit(config.BatchSize).Scan(ctx); err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := handler(ctx, event); err != nil {
+				return fmt.Errorf("handle event %d: %w", event.ID, err)
+			}
+
+			if position.Advance(event.TxID, event.ID) {
+				config.Logger.Warn(fmt.Sprintf("[database] event %d of %s was committed out of order", event.ID, channel))
+			}
+			cursor.TxID, cursor.Position = position.TxID, position.ID
+			cursor.UpdatedAt = time.Now()
+
+			_, err := db.NewInsert().
+				Model(cursor).
+				On("CONFLICT (name, channel) DO UPDATE").
+				Set("tx_id = EXCLUDED.tx_id").
+				Set("position = EXCLUDED.position").
+				Set("updated_at = EXCLUDED.updated_at").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(events) < config.BatchSize {
+			return nil
+		}
+	}
+}
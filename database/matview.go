@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownView = errors.New("unknown materialized view")
+	ErrViewStale   = errors.New("materialized view is stale")
+)
+
+// MaterializedView declares a materialized view, and when to refresh it.
+type MaterializedView struct {
+	Name string
+	// Interval refreshes the view periodically. Optional.
+	Interval time.Duration
+	// Channels refreshes the view when an event is published on one of the channels with Notify. Optional.
+	Channels []string
+	// MinInterval is the minimum delay between two refreshes triggered by events, so bursts of events only trigger
+	// one refresh. Defaults to 1 minute.
+	MinInterval time.Duration
+	// MaxStaleness is the age over which the view is reported as unhealthy. Optional.
+	MaxStaleness time.Duration
+	// Blocking disables CONCURRENTLY, for views without a unique index. Readers are blocked during the refresh.
+	Blocking bool
+}
+
+// ViewRefresh stores the last refresh of a materialized view, shared by every instance.
+type ViewRefresh struct {
+	bun.BaseModel `bun:"table:database_view_refreshes,alias:refresh"`
+
+	Name        string        `bun:"name,pk"`
+	RefreshedAt time.Time     `bun:"refreshed_at,notnull"`
+	Duration    time.Duration `bun:"duration,notnull"`
+}
+
+// Refresher refreshes materialized views according to their declaration.
+//
+//	refresher := database.NewRefresher(db, logger, database.MaterializedView{
+//		Name:         "dashboard_daily_stats",
+//		Interval:     15 * time.Minute,
+//		Channels:     []string{"notes_changed"},
+//		MaxStaleness: time.Hour,
+//	})
+//	go refresher.Run(ctx)
+type Refresher struct {
+	db     *bun.DB
+	logger monitor.Logger
+	views  map[string]MaterializedView
+}
+
+func NewRefresher(db *bun.DB, logger monitor.Logger, views ...MaterializedView) *Refresher {
+	refresher := &Refresher{db: db, logger: logger, views: make(map[string]MaterializedView, len(views))}
+
+	for _, view := range views {
+		if view.MinInterval <= 0 {
+			view.MinInterval = time.Minute
+		}
+
+		refresher.views[view.Name] = view
+	}
+
+	return refresher
+}
+
+// CreateViewRefreshTable creates the table storing the last refreshes, if it does not exist yet.
+func CreateViewRefreshTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*ViewRefresh)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+// Refresh refreshes a view now. Only one instance refreshes a view at a time: if another instance is already
+// refreshing it, Refresh returns false without waiting.
+func (r *Refresher) Refresh(ctx context.Context, name string) (bool, error) {
+	view, ok := r.views[name]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnknownView, name)
+	}
+
+	refreshed := false
+	startedAt := time.Now()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// The lock is released with the transaction.
+		var locked bool
+		if err := tx.NewRaw("SELECT pg_try_advisory_xact_lock(hashtext(?))", "matview:"+name).Scan(ctx, &locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		query := "REFRESH MATERIALIZED VIEW CONCURRENTLY ?"
+		if view.Blocking {
+			query = "REFRESH MATERIALIZED VIEW ?"
+		}
+
+		if _, err := tx.ExecContext(ctx, query, bun.Ident(name)); err != nil {
+			return err
+		}
+
+		refresh := &ViewRefresh{Name: name, RefreshedAt: startedAt, Duration: time.Since(startedAt)}
+		_, err := tx.NewInsert().
+			Model(refresh).
+			On("CONFLICT (name) DO UPDATE").
+			Set("refreshed_at = EXCLUDED.refreshed_at").
+			Set("duration = EXCLUDED.duration").
+			Exec(ctx)
+
+		refreshed = err == nil
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("refresh %s: %w", name, err)
+	}
+
+	return refreshed, nil
+}
+
+// Staleness returns the time elapsed since the last refresh of each view. Views never refreshed by the refresher
+// are omitted.
+func (r *Refresher) Staleness(ctx context.Context) (map[string]time.Duration, error) {
+	names := make([]string, 0, len(r.views))
+	for name := range r.views {
+		names = append(names, name)
+	}
+
+	refreshes := make([]*ViewRefresh, 0)
+	if err := r.db.NewSelect().Model(&refreshes).Where("name IN (?)", bun.In(names)).Scan(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Duration, len(refreshes))
+	for _, refresh := range refreshes {
+		out[refresh.Name] = time.Since(refresh.RefreshedAt)
+	}
+
+	return out, nil
+}
+
+// Health reports the views older than their MaxStaleness, for dependency checks.
+func (r *Refresher) Health(ctx context.Context) map[string]error {
+	out := make(map[string]error)
+
+	staleness, err := r.Staleness(ctx)
+	if err != nil {
+		out["MaterializedViews"] = err
+		return out
+	}
+
+	for name, view := range r.views {
+		if view.MaxStaleness <= 0 {
+			continue
+		}
+
+		age, ok := staleness[name]
+		switch {
+		case !ok:
+			out["view:"+name] = fmt.Errorf("%w: never refreshed", ErrViewStale)
+		case age > view.MaxStaleness:
+			out["view:"+name] = fmt.Errorf("%w: refreshed %s ago", ErrViewStale, age.Round(time.Second))
+		default:
+			out["view:"+name] = nil
+		}
+	}
+
+	return out
+}
+
+// Run refreshes the views on their interval and events, until the context is canceled.
+func (r *Refresher) Run(ctx context.Context) {
+	wg := new(sync.WaitGroup)
+
+	for _, view := range r.views {
+		trigger := make(chan struct{}, 1)
+
+		for _, channel := range view.Channels {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_ = Listen(ctx, r.db, channel, func(context.Context, *Event) error {
+					select {
+					case trigger <- struct{}{}:
+					default:
+					}
+					return nil
+				}, ListenConfig{Name: "matview:" + view.Name, Logger: r.logger})
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, view, trigger)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (r *Refresher) loop(ctx context.Context, view MaterializedView, trigger <-chan struct{}) {
+	var interval <-chan time.Time
+	if view.Interval > 0 {
+		ticker := time.NewTicker(view.Interval)
+		defer ticker.Stop()
+		interval = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-interval:
+		case <-trigger:
+		}
+
+		startedAt := time.Now()
+		refreshed, err := r.Refresh(ctx, view.Name)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			r.logger.Error(err, fmt.Sprintf("[database] failed to refresh materialized view %s", view.Name))
+		case refreshed:
+			r.logger.Info(fmt.Sprintf("[database] refreshed materialized view %s in %s", view.Name, time.Since(startedAt)))
+		}
+
+		// Debounce the refreshes triggered by events.
+		if ctxutil.Sleep(ctx, view.MinInterval) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// Rows inserted inside transactions do not become visible in the order of their IDs: a transaction that got a lower
+// ID can commit after one that got a higher ID, so a consumer tailing a table with "id > cursor" skips the late row
+// for good. Tables tailed by consumers store the transaction that inserted each row in a tx_id column:
+//
+//	TxID int64 `bun:"tx_id,notnull,nullzero,default:pg_current_xact_id()::text::bigint"`
+//
+// and consumers read them with AfterPosition, in transaction order and up to the oldest transaction still in
+// flight. Every transaction committing later sorts after the rows already read, so none is skipped.
+
+// Position is the position of a consumer in a table read with AfterPosition.
+type Position struct {
+	TxID int64
+	ID   int64
+}
+
+// AfterPosition restricts a query to the rows following the position, inserted by transactions older than every
+// transaction still in flight, and sorts them in the order of the positions.
+func AfterPosition(query *bun.SelectQuery, position Position) *bun.SelectQuery {
+	return query.
+		Where("(tx_id, id) > (?, ?)", position.TxID, position.ID).
+		Where("tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint").
+		OrderExpr("tx_id ASC, id ASC")
+}
+
+// Advance moves the position to a row read with AfterPosition. It reports whether the row committed out of ID order,
+// after a row with a higher ID: a cursor on the ID alone would have skipped it.
+func (p *Position) Advance(txID, id int64) bool {
+	outOfOrder := id < p.ID
+	p.TxID, p.ID = txID, id
+
+	return outOfOrder
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/in-rich/lib-go/cli"
+	"github.com/uptrace/bun"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrSchemaDrift = errors.New("live schema differs from the expected schema")
+
+// Schema is a snapshot of the structure of a database: its columns, indexes and constraints. Objects maps a key,
+// such as "column:notes.title", to the definition of the object.
+type Schema struct {
+	Fingerprint string            `json:"fingerprint"`
+	Objects     map[string]string `json:"objects"`
+}
+
+// ParseSchema reads a snapshot written by the verify-schema command, usually embedded next to the migrations.
+//
+//	//go:embed schema.json
+//	var expectedSchema []byte
+func ParseSchema(data []byte) (*Schema, error) {
+	schema := new(Schema)
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("parse schema snapshot: %w", err)
+	}
+
+	// Compute the fingerprint again, so snapshots edited by hand cannot hide changes.
+	schema.Fingerprint = fingerprint(schema.Objects)
+	return schema, nil
+}
+
+// DriftError lists the differences between the live schema and the expected one.
+type DriftError struct {
+	// Missing objects are expected, but absent from the live schema.
+	Missing []string
+	// Unexpected objects exist in the live schema, but are not expected.
+	Unexpected []string
+	// Changed objects exist in both schemas with different definitions.
+	Changed []string
+}
+
+func (e *DriftError) Error() string {
+	parts := make([]string, 0, 3)
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, "unexpected "+strings.Join(e.Unexpected, ", "))
+	}
+	if len(e.Changed) > 0 {
+		parts = append(parts, "changed "+strings.Join(e.Changed, ", "))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrSchemaDrift, strings.Join(parts, "; "))
+}
+
+func (e *DriftError) Unwrap() error {
+	return ErrSchemaDrift
+}
+
+type schemaRow struct {
+	Key        string `bun:"key"`
+	Definition string `bun:"definition"`
+}
+
+const schemaQuery = `
+SELECT 'column:' || c.table_name || '.' || c.column_name AS key,
+	c.data_type
+		|| COALESCE('(' || c.character_maximum_length || ')', '')
+		|| CASE WHEN c.is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END
+		|| COALESCE(' DEFAULT ' || c.column_default, '') AS definition
+FROM information_schema.columns c
+JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+UNION ALL
+SELECT 'index:' || i.tablename || '.' || i.indexname, i.indexdef
+FROM pg_indexes i
+WHERE i.schemaname = current_schema()
+UNION ALL
+SELECT 'constraint:' || cl.relname || '.' || co.conname, pg_get_constraintdef(co.oid)
+FROM pg_constraint co
+JOIN pg_class cl ON cl.oid = co.conrelid
+JOIN pg_namespace n ON n.oid = cl.relnamespace
+WHERE n.nspname = current_schema()
+`
+
+// SnapshotSchema reads the structure of the current schema of the database. Objects of the ignored tables, such as
+// the migrations table, are excluded.
+func SnapshotSchema(ctx context.Context, db bun.IDB, ignoredTables ...string) (*Schema, error) {
+	rows := make([]schemaRow, 0)
+	if err := db.NewRaw(schemaQuery).Scan(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("read live schema: %w", err)
+	}
+
+	objects := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if !isIgnored(row.Key, ignoredTables) {
+			objects[row.Key] = row.Definition
+		}
+	}
+
+	return &Schema{Fingerprint: fingerprint(objects), Objects: objects}, nil
+}
+
+func isIgnored(key string, ignoredTables []string) bool {
+	_, object, _ := strings.Cut(key, ":")
+
+	for _, table := range ignoredTables {
+		if strings.HasPrefix(object, table+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fingerprint(objects map[string]string) string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(hash, "%s=%s\n", key, objects[key])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// VerifySchema compares the live schema with the expected one, and returns a *DriftError describing the
+// differences, if any. It can be used as a dependency check:
+//
+//	Dependencies: func() map[string]error {
+//		return map[string]error{"Schema": database.VerifySchema(ctx, db, expected, "bun_migrations")}
+//	},
+func VerifySchema(ctx context.Context, db bun.IDB, expected *Schema, ignoredTables ...string) error {
+	live, err := SnapshotSchema(ctx, db, ignoredTables...)
+	if err != nil {
+		return err
+	}
+
+	if live.Fingerprint == expected.Fingerprint {
+		return nil
+	}
+
+	drift := new(DriftError)
+	for key, definition := range expected.Objects {
+		liveDefinition, ok := live.Objects[key]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, key)
+		case liveDefinition != definition:
+			drift.Changed = append(drift.Changed, key)
+		}
+	}
+	for key := range live.Objects {
+		if _, ok := expected.Objects[key]; !ok {
+			drift.Unexpected = append(drift.Unexpected, key)
+		}
+	}
+
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Unexpected)
+	sort.Strings(drift.Changed)
+
+	return drift
+}
+
+// VerifySchemaCommand creates a verify-schema command, that checks the live database against the expected
+// snapshot. With the -write flag, it writes the live schema to a file instead, to update the snapshot after adding
+// a migration.
+func VerifySchemaCommand[Cfg any](
+	open func(ctx *cli.Context[Cfg]) (*bun.DB, func(), error), expected []byte, ignoredTables ...string,
+) cli.Command[Cfg] {
+	var write string
+
+	return cli.Command[Cfg]{
+		Name:  "verify-schema",
+		Usage: "compare the live database schema with the expected migrations",
+		Flags: func(flags *flag.FlagSet) {
+			flags.StringVar(&write, "write", "", "write the live schema snapshot to the given file instead")
+		},
+		Run: func(ctx *cli.Context[Cfg]) error {
+			db, closer, err := open(ctx)
+			if err != nil {
+				return fmt.Errorf("open database: %w", err)
+			}
+			defer closer()
+
+			if write != "" {
+				live, err := SnapshotSchema(ctx, db, ignoredTables...)
+				if err != nil {
+					return err
+				}
+
+				data, err := json.MarshalIndent(live, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				return os.WriteFile(write, append(data, '\n'), 0o644)
+			}
+
+			schema, err := ParseSchema(expected)
+			if err != nil {
+				return err
+			}
+
+			if err := VerifySchema(ctx, db, schema, ignoredTables...); err != nil {
+				return err
+			}
+
+			ctx.Logger.Info(fmt.Sprintf("[database] schema matches the expected fingerprint %s", schema.Fingerprint))
+			return nil
+		},
+	}
+}
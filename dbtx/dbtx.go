@@ -0,0 +1,106 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/pattern"
+	"github.com/uptrace/bun"
+	"google.golang.org/grpc"
+)
+
+type txKey struct{}
+
+// WithTx attaches a transaction to the context.
+func WithTx(ctx context.Context, tx bun.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction attached to the context, if any.
+func TxFromContext(ctx context.Context) (bun.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(bun.Tx)
+	return tx, ok
+}
+
+// DB returns the transaction of the request when there is one, and db otherwise. Repositories use it for every
+// query, so they join the transaction of the handler without explicit plumbing.
+//
+//	func (r *notesRepository) UpdateNote(ctx context.Context, note *Note) error {
+//		_, err := dbtx.DB(ctx, r.db).NewUpdate().Model(note).WherePK().Exec(ctx)
+//		return err
+//	}
+func DB(ctx context.Context, db bun.IDB) bun.IDB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// Run calls fn within a transaction attached to its context. The transaction is committed if fn succeeds, and
+// rolled back if it returns an error or panics. When the context already holds a transaction, fn joins it instead.
+func Run(ctx context.Context, db *bun.DB, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err = fn(WithTx(ctx, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("rollback transaction: %w", rollbackErr))
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Methods selects the GRPC methods run within a transaction, by full name ("/notes.v1.Notes/UpdateNote"). Names
+// ending with "*" match by prefix ("/notes.v1.Notes/Update*").
+func Methods(names ...string) func(fullMethod string) bool {
+	return pattern.NewSet(names...).Contains
+}
+
+// UnaryServerInterceptor runs the selected methods within a transaction, giving handlers all-or-nothing semantics:
+// the transaction is committed when the handler succeeds, and rolled back when it returns an error or panics.
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(
+//		dbtx.UnaryServerInterceptor(db, dbtx.Methods("/notes.v1.Notes/Update*", "/notes.v1.Notes/DeleteNote"), nil),
+//	))
+//
+// Install it after the panic recovery interceptor, so panics are rolled back before being recovered.
+func UnaryServerInterceptor(
+	db *bun.DB, selected func(fullMethod string) bool, opts *sql.TxOptions,
+) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !selected(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		var res any
+		err := Run(ctx, db, opts, func(ctx context.Context) error {
+			var err error
+			res, err = handler(ctx, req)
+			return err
+		})
+
+		return res, err
+	}
+}
@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type AppConfig struct {
+	// Drain configures the shutdown of GRPC servers. Its propagation delay plus its timeout also bound the whole
+	// shutdown: every component and every OnShutdown function share one deadline.
+	Drain DrainConfig
+	// ForceSeed runs the seed hooks in production. Only set it from an explicit flag of a one-off job.
+	ForceSeed bool
+}
+
+type component struct {
+	name string
+	// start blocks until the component stops.
+	start func() error
+	stop  func(ctx context.Context) error
+}
+
+// App runs the servers and background workers of a service, until the process is asked to terminate.
+//
+//	app := deploy.NewApp(logger, deploy.AppConfig{})
+//	app.AddWorker("outbox", relay.Run)
+//	app.AddGRPCServer(deploy.StartGRPCServer(logger, 50051, depsCheck))
+//
+//	if err := app.Run(ctx); err != nil {
+//		logger.Fatal(err, "[main] service stopped")
+//	}
+//
+// Components are started in the order they are added, and stopped in reverse order: add the workers a server
+// depends on before the server, so they keep running while it drains.
+type App struct {
+	logger     monitor.Logger
+	config     AppConfig
+	components []component
+	hooks      []startupHook
+	shutdown   []component
+}
+
+func NewApp(logger monitor.Logger, config AppConfig) *App {
+	config.Drain = config.Drain.withDefaults()
+	return &App{logger: logger, config: config}
+}
+
+// Add registers a custom component. Start must block until the component stops, and return nil once stopped by
+// the stop function.
+func (a *App) Add(name string, start func() error, stop func(ctx context.Context) error) {
+	a.components = append(a.components, component{name: name, start: start, stop: stop})
+}
+
+// OnShutdown registers a function run once every component is stopped, such as flushing buffered telemetry.
+// Functions run in the order they are registered, and share the shutdown deadline with the components.
+func (a *App) OnShutdown(name string, run func(ctx context.Context) error) {
+	a.shutdown = append(a.shutdown, component{name: name, stop: run})
+}
+
+// AddGRPCServer registers a server created with StartGRPCServer. The health updater is started with the server,
+// and the server is drained with DrainGRPCServer on shutdown.
+func (a *App) AddGRPCServer(listener net.Listener, server *grpc.Server, health func()) {
+	a.Add(
+		fmt.Sprintf("grpc server %s", listener.Addr()),
+		func() error {
+			if health != nil {
+				go health()
+			}
+
+			return server.Serve(listener)
+		},
+		func(ctx context.Context) error {
+			drainGRPCServer(ctx, a.logger, listener, server, a.config.Drain.PropagationDelay)
+			return nil
+		},
+	)
+}
+
+// AddHTTPServer registers an HTTP server. In-flight requests are given until the shutdown deadline to complete.
+func (a *App) AddHTTPServer(server *http.Server) {
+	a.Add(
+		fmt.Sprintf("http server %s", server.Addr),
+		func() error {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+
+			return nil
+		},
+		server.Shutdown,
+	)
+}
+
+// AddWorker registers a background worker. Its context is canceled on shutdown, and the worker is given until the
+// shutdown deadline to return. A worker returning early without error does not stop the application.
+func (a *App) AddWorker(name string, run func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	a.Add(
+		fmt.Sprintf("worker %s", name),
+		func() error {
+			defer close(done)
+
+			if err := run(ctx); err != nil && ctx.Err() == nil {
+				return err
+			}
+
+			return nil
+		},
+		func(stopCtx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return fmt.Errorf("worker %s did not stop: %w", name, stopCtx.Err())
+			}
+		},
+	)
+}
+
+// Run runs the startup hooks, then starts every component, and blocks until the process receives SIGTERM or
+// SIGINT, the context is canceled, or a component fails. Components are then stopped in reverse order, and the
+// functions registered with OnShutdown run, all within the drain propagation delay plus the drain timeout. The
+// error of the failed component is returned, joined with the errors raised while stopping the others. No component
+// is started if a startup hook fails.
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Startup(ctx); err != nil {
+		return err
+	}
+
+	failed := make(chan error, len(a.components))
+	for _, c := range a.components {
+		a.logger.Info(fmt.Sprintf("[deploy] starting %s", c.name))
+
+		go func() {
+			if err := c.start(); err != nil {
+				failed <- fmt.Errorf("%s: %w", c.name, err)
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("[deploy] shutting down")
+	case err = <-failed:
+		a.logger.Error(err, "[deploy] component failed, shutting down")
+	}
+
+	// One deadline bounds the whole shutdown, so it fits in the termination grace period of the platform.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.Drain.total())
+	defer cancel()
+
+	for i := len(a.components) - 1; i >= 0; i-- {
+		c := a.components[i]
+		a.logger.Info(fmt.Sprintf("[deploy] stopping %s", c.name))
+
+		if stopErr := c.stop(shutdownCtx); stopErr != nil {
+			a.logger.Error(stopErr, fmt.Sprintf("[deploy] failed to stop %s", c.name))
+			err = errors.Join(err, stopErr)
+		}
+	}
+
+	for _, hook := range a.shutdown {
+		if hookErr := hook.stop(shutdownCtx); hookErr != nil {
+			a.logger.Error(hookErr, fmt.Sprintf("[deploy] shutdown hook %s failed", hook.name))
+			err = errors.Join(err, hookErr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/samber/lo"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var ErrUnsafeEnvironment = errors.New("unsafe environment configuration")
+
+// EnvironmentConfig describes the sensitive parts of the configuration of a service, checked on startup by
+// AssertEnvironment.
+type EnvironmentConfig struct {
+	Service string
+	Version string
+	// DatabaseDSN is the DSN of the main database of the service, if any.
+	DatabaseDSN string
+	// FirebaseProject is the Firebase project used by the service, if any.
+	FirebaseProject string
+	// ProdFirebaseProjects lists the production Firebase projects, which must never be used outside production.
+	ProdFirebaseProjects []string
+	// SentryDSN is the DSN used to report errors.
+	SentryDSN string
+}
+
+// CheckEnvironment returns the dangerous combinations of the configuration with the current environment.
+func CheckEnvironment(cfg EnvironmentConfig) []error {
+	var violations []error
+
+	if IsReleaseEnv() && cfg.DatabaseDSN != "" && isLocalDSN(cfg.DatabaseDSN) {
+		violations = append(violations, fmt.Errorf("%w: %s environment uses a local database", ErrUnsafeEnvironment, ENV))
+	}
+
+	if ENV != ProdENV && cfg.FirebaseProject != "" && lo.Contains(cfg.ProdFirebaseProjects, cfg.FirebaseProject) {
+		violations = append(violations, fmt.Errorf(
+			"%w: %s environment uses the production Firebase project %s",
+			ErrUnsafeEnvironment, ENV, cfg.FirebaseProject,
+		))
+	}
+
+	if ENV == ProdENV && cfg.SentryDSN == "" {
+		violations = append(violations, fmt.Errorf("%w: Sentry is disabled in production", ErrUnsafeEnvironment))
+	}
+
+	return violations
+}
+
+// AssertEnvironment logs a startup banner describing the environment of the service, and stops the service if
+// CheckEnvironment reports dangerous combinations.
+//
+//	deploy.AssertEnvironment(logger, deploy.EnvironmentConfig{
+//		Service:              "notes",
+//		DatabaseDSN:          cfg.Postgres.DSN,
+//		FirebaseProject:      cfg.Firebase.ProjectID,
+//		ProdFirebaseProjects: []string{"inrich-prod"},
+//		SentryDSN:            cfg.Sentry.DSN,
+//	})
+func AssertEnvironment(logger monitor.Logger, cfg EnvironmentConfig) {
+	logger.Info(fmt.Sprintf(
+		"[deploy] starting %s (version %s) in %s environment: database=%s firebase=%s sentry=%t",
+		lo.CoalesceOrEmpty(cfg.Service, "service"),
+		lo.CoalesceOrEmpty(cfg.Version, "unknown"),
+		ENV,
+		lo.CoalesceOrEmpty(dsnHost(cfg.DatabaseDSN), "none"),
+		lo.CoalesceOrEmpty(cfg.FirebaseProject, "none"),
+		cfg.SentryDSN != "",
+	))
+
+	violations := CheckEnvironment(cfg)
+	if len(violations) == 0 {
+		return
+	}
+
+	for _, violation := range violations {
+		logger.Error(violation, "[deploy] environment check failed")
+	}
+
+	logger.Fatal(errors.Join(violations...), fmt.Sprintf("[deploy] refusing to start: %d unsafe settings", len(violations)))
+}
+
+// dsnHost returns the host of a DSN, without credentials. URL and key/value DSNs are supported.
+func dsnHost(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+
+	if parsed, err := url.Parse(dsn); err == nil && parsed.Scheme != "" {
+		// Unix sockets, such as Cloud SQL ones, are passed as a query parameter.
+		return lo.CoalesceOrEmpty(parsed.Hostname(), parsed.Query().Get("host"))
+	}
+
+	for _, field := range strings.Fields(dsn) {
+		if host, ok := strings.CutPrefix(field, "host="); ok {
+			return host
+		}
+	}
+
+	return ""
+}
+
+// isLocalDSN returns true if the DSN targets the local machine. Drivers default to localhost when no host is set.
+// Unix sockets are not considered local, as Cloud SQL is reached through them.
+func isLocalDSN(dsn string) bool {
+	host := dsnHost(dsn)
+	if host == "" || host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,161 @@
+package deploy
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy configures the retries of CallGRPCEndpoint.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of calls failing with a retryable code. Defaults to 3. Set it to 1 to
+	// disable retries.
+	MaxAttempts int
+	// Codes lists the retried codes. Defaults to Unavailable, returned when the call did not reach the service.
+	// Only add DeadlineExceeded for idempotent methods: a call that timed out may have been processed. Errors with
+	// a RetryInfo detail are always retried, after the delay of the detail.
+	Codes []codes.Code
+	// InitialBackoff is the delay before the first retry. It doubles after each attempt. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including the delays requested by the servers. Defaults to 2
+	// seconds.
+	MaxBackoff time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if len(p.Codes) == 0 {
+		p.Codes = []codes.Code{codes.Unavailable}
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+
+	return p
+}
+
+// backoff returns the delay before the given retry, starting at 1, with up to 20% of jitter so the retries of
+// concurrent callers do not hit the service at once.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+
+	return delay + time.Duration(rand.Float64()*0.2*float64(delay))
+}
+
+// CallOption configures a call performed with CallGRPCEndpoint.
+type CallOption func(options *callOptions)
+
+type callOptions struct {
+	retry       RetryPolicy
+	timeout     time.Duration
+	callOptions []grpc.CallOption
+	metadata    []string
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	options := &callOptions{timeout: 15 * time.Second}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	options.retry = options.retry.withDefaults()
+	return options
+}
+
+// WithRetry replaces the default retry policy of the call.
+func WithRetry(policy RetryPolicy) CallOption {
+	return func(options *callOptions) {
+		options.retry = policy
+	}
+}
+
+// WithoutRetry disables retries, including on Unavailable.
+func WithoutRetry() CallOption {
+	return WithRetry(RetryPolicy{MaxAttempts: 1})
+}
+
+// WithIdempotentRetry also retries calls failing with DeadlineExceeded, for idempotent methods only, such as reads:
+// a call that timed out may have been processed by the service, and would be processed twice.
+func WithIdempotentRetry() CallOption {
+	return WithRetry(RetryPolicy{Codes: []codes.Code{codes.Unavailable, codes.DeadlineExceeded}})
+}
+
+// WithTimeout replaces the default timeout of 15 seconds. The timeout includes retries.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(options *callOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithCallOptions passes options to the generated client method, such as grpc.MaxCallRecvMsgSize.
+func WithCallOptions(opts ...grpc.CallOption) CallOption {
+	return func(options *callOptions) {
+		options.callOptions = append(options.callOptions, opts...)
+	}
+}
+
+// WithMetadata adds key-value pairs to the outgoing metadata of the call, like metadata.AppendToOutgoingContext. It
+// panics when given an odd number of arguments.
+func WithMetadata(kv ...string) CallOption {
+	if len(kv)%2 == 1 {
+		panic("deploy: WithMetadata requires key-value pairs, got an odd number of arguments")
+	}
+
+	return func(options *callOptions) {
+		options.metadata = append(options.metadata, kv...)
+	}
+}
+
+// context applies the timeout and metadata of the call to the context.
+func (o *callOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if len(o.metadata) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, o.metadata...)
+	}
+
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// retry calls the function until it succeeds, fails with a code that is not retryable, or the attempts or the
+// context are exhausted. Errors carrying a RetryInfo detail, such as the errors of the rate limiters, are retried
+// after the delay chosen by the server, capped by MaxBackoff, unless it exceeds the deadline of the context.
+func retry[Out any](ctx context.Context, policy RetryPolicy, call func() (*Out, error)) (*Out, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := call()
+		if err == nil {
+			return res, nil
+		}
+
+		retryAfter, hasRetryInfo := ratelimit.RetryAfter(err)
+		if attempt >= policy.MaxAttempts || (!hasRetryInfo && !slices.Contains(policy.Codes, status.Code(err))) {
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt)
+		if hasRetryInfo {
+			delay = min(retryAfter, policy.MaxBackoff)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+
+		if ctxutil.Sleep(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
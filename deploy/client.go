@@ -0,0 +1,149 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// GRPCMethod is the signature of a method expression of a generated client interface, such as
+// notes_pb.NotesClient.GetNote.
+type GRPCMethod[T any, In any, Out any] func(client T, ctx context.Context, in *In, opts ...grpc.CallOption) (*Out, error)
+
+type ClientConfig struct {
+	// Timeout bounds every call, retries included. Defaults to 15 seconds.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts of calls failing with a retryable code. Defaults to 1 (no retry).
+	// Only enable retries for clients whose methods are idempotent.
+	MaxAttempts int
+	// RetryCodes lists the retried codes. Defaults to Unavailable.
+	RetryCodes []codes.Code
+	// RetryBackoff is the delay before the first retry. It doubles after each attempt, up to 2 seconds, with jitter
+	// as in RetryPolicy. Defaults to 100ms.
+	RetryBackoff time.Duration
+	// PropagateMetadata lists the incoming metadata keys forwarded to the called service. Defaults to the request
+	// and trace identifiers.
+	PropagateMetadata []string
+}
+
+func (c ClientConfig) withDefaults() ClientConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = 15 * time.Second
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 1
+	}
+	if len(c.RetryCodes) == 0 {
+		c.RetryCodes = []codes.Code{codes.Unavailable}
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+	if c.PropagateMetadata == nil {
+		c.PropagateMetadata = []string{"x-request-id", "x-cloud-trace-context", "traceparent"}
+	}
+
+	return c
+}
+
+// Client wraps a generated GRPC client, so every call applies the standard timeout, retries, logging and metadata
+// propagation.
+//
+//	notes := deploy.NewClient(notes_pb.NewNotesClient(conn), logger, deploy.ClientConfig{})
+//	note, err := deploy.Call(ctx, notes, notes_pb.NotesClient.GetNote, &notes_pb.GetNoteRequest{ID: id})
+type Client[T any] struct {
+	client T
+	logger monitor.Logger
+	config ClientConfig
+}
+
+func NewClient[T any](client T, logger monitor.Logger, config ClientConfig) *Client[T] {
+	return &Client[T]{client: client, logger: logger, config: config.withDefaults()}
+}
+
+// Raw returns the wrapped client, for streaming methods.
+func (c *Client[T]) Raw() T {
+	return c.client
+}
+
+// Call invokes a method of the wrapped client with CallGRPCEndpoint, applying the timeout, retry policy and
+// metadata propagation of the client.
+func Call[T any, In any, Out any](
+	ctx context.Context, client *Client[T], method GRPCMethod[T, In, Out], in *In, opts ...grpc.CallOption,
+) (*Out, error) {
+	callback := func(ctx context.Context, in *In, opts ...grpc.CallOption) (*Out, error) {
+		return method(client.client, ctx, in, opts...)
+	}
+
+	res, err := CallGRPCEndpoint(
+		ctx, callback, in,
+		WithTimeout(client.config.Timeout),
+		WithRetry(RetryPolicy{
+			MaxAttempts:    client.config.MaxAttempts,
+			Codes:          client.config.RetryCodes,
+			InitialBackoff: client.config.RetryBackoff,
+		}),
+		WithMetadata(propagatedMetadata(ctx, client.config.PropagateMetadata)...),
+		WithCallOptions(opts...),
+	)
+	if err == nil {
+		return res, nil
+	}
+
+	// Expected client errors are not worth logging.
+	if code := status.Code(err); code != codes.NotFound && code != codes.InvalidArgument && code != codes.AlreadyExists {
+		client.logger.Error(err, fmt.Sprintf("[deploy] call to %s failed", methodName(method)))
+	}
+
+	return nil, err
+}
+
+// propagatedMetadata returns the key-value pairs of the given incoming metadata keys, missing from the outgoing
+// metadata of the context.
+func propagatedMetadata(ctx context.Context, keys []string) []string {
+	incoming, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	outgoing, _ := metadata.FromOutgoingContext(ctx)
+
+	var pairs []string
+	for _, key := range keys {
+		if len(outgoing.Get(key)) > 0 {
+			continue
+		}
+
+		for _, value := range incoming.Get(key) {
+			pairs = append(pairs, key, value)
+		}
+	}
+
+	return pairs
+}
+
+// methodName returns the name of a method expression, such as "NotesClient.GetNote".
+func methodName(method any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(method).Pointer())
+	if fn == nil {
+		return "unknown method"
+	}
+
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
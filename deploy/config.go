@@ -1,41 +1,127 @@
 package deploy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/goccy/go-yaml"
+	"github.com/in-rich/lib-go/monitor"
 	"os"
+	"reflect"
+	"time"
 )
 
 type ConfigFile struct {
 	file []byte
 	env  string
+	// path is set for files read from the disk on every load, instead of being embedded.
+	path   string
+	format ConfigFormat
 }
 
 func ProdConfig(file []byte) ConfigFile {
-	return ConfigFile{file, ProdENV}
+	return ConfigFile{file: file, env: ProdENV}
 }
 
 func StagingConfig(file []byte) ConfigFile {
-	return ConfigFile{file, StagingEnv}
+	return ConfigFile{file: file, env: StagingEnv}
 }
 
 func DevConfig(file []byte) ConfigFile {
-	return ConfigFile{file, DevENV}
+	return ConfigFile{file: file, env: DevENV}
 }
 func GlobalConfig(file []byte) ConfigFile {
-	return ConfigFile{file, ""}
+	return ConfigFile{file: file, env: ""}
 }
 
-func LoadConfig[Cfg any](files ...ConfigFile) *Cfg {
-	var out Cfg
+// FileConfig reads a file from the disk, such as a mounted secret volume, in every environment. The file is read
+// again on every reload of LoadConfigWatch.
+func FileConfig(path string) ConfigFile {
+	return ConfigFile{path: path, env: ""}
+}
+
+// read returns the content of the file.
+func (f ConfigFile) read() ([]byte, error) {
+	if f.path == "" {
+		return f.file, nil
+	}
+
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file %s: %w", f.path, err)
+	}
+
+	return content, nil
+}
+
+func loadConfig[Cfg any](files []ConfigFile) (*Cfg, error) {
+	var (
+		out  Cfg
+		errs []error
+	)
+
+	applyDefaults(reflect.ValueOf(&out), "", &errs)
 
 	for _, file := range files {
 		if file.env == ENV || file.env == "" {
-			expanded := os.ExpandEnv(string(file.file))
-			if err := yaml.Unmarshal([]byte(expanded), &out); err != nil {
-				panic(err)
+			content, err := file.read()
+			if err != nil {
+				return nil, err
+			}
+
+			document, err := file.toYAML([]byte(os.ExpandEnv(string(content))))
+			if err != nil {
+				return nil, err
+			}
+
+			if err := yaml.Unmarshal(document, &out); err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	return &out
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resolveSecrets(ctx, reflect.ValueOf(&out), "", &errs)
+	checkRequired(reflect.ValueOf(&out), "", &errs)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+	}
+
+	return &out, nil
+}
+
+// LoadConfig reads the files of the current environment, in order, into a new configuration. Fields are set to the
+// value of their `default` tag before the files are read. When a file cannot be read, or a field tagged
+// `required:"true"` is still missing once the files are read, LoadConfig exits the process with monitor.ExitFatal,
+// after writing a crash record with the list of every invalid field. Use ReadConfig to handle the error instead.
+//
+//	type Config struct {
+//		Port    int      `yaml:"port" default:"8080"`
+//		Timeout Duration `yaml:"timeout" default:"10s"`
+//		DSN     string   `yaml:"dsn" required:"true"`
+//	}
+//
+// Values starting with SecretScheme are replaced with the secret they reference in GCP Secret Manager, so secrets
+// don't have to be copied into the environment:
+//
+//	dsn: secretmanager://notes-db-dsn
+//	apiKey: secretmanager://projects/inrich/secrets/brevo-key/versions/2
+//
+// Under the dev environment, secrets referenced by name are read from SECRET_ variables instead
+// (SECRET_NOTES_DB_DSN).
+func LoadConfig[Cfg any](files ...ConfigFile) *Cfg {
+	out, err := loadConfig[Cfg](files)
+	if err != nil {
+		monitor.Crash(monitor.ExitFatal, "[deploy] failed to load configuration", err)
+	}
+
+	return out
+}
+
+// ReadConfig reads the configuration like LoadConfig, but returns the error instead of exiting, for configurations
+// loaded after startup.
+func ReadConfig[Cfg any](files ...ConfigFile) (*Cfg, error) {
+	return loadConfig[Cfg](files)
 }
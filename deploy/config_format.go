@@ -0,0 +1,66 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"path/filepath"
+	"strings"
+)
+
+type ConfigFormat string
+
+const (
+	YAMLFormat ConfigFormat = "yaml"
+	// JSONFormat is parsed as YAML, of which it is a subset.
+	JSONFormat ConfigFormat = "json"
+	TOMLFormat ConfigFormat = "toml"
+)
+
+// As declares the format of the file. Files read with FileConfig default to the format of their extension, and
+// other files to YAML.
+//
+//	deploy.LoadConfig[Config](
+//		deploy.GlobalConfig(globalFile),
+//		deploy.ProdConfig(prodFile).As(deploy.TOMLFormat),
+//		deploy.FileConfig("/etc/inrich/generated.json"),
+//	)
+//
+// Every format reads the configuration through its `yaml` tags, so the same struct can be loaded from any format.
+func (f ConfigFile) As(format ConfigFormat) ConfigFile {
+	f.format = format
+	return f
+}
+
+// configFormat returns the declared format of the file, or sniffs one from its extension.
+func (f ConfigFile) configFormat() ConfigFormat {
+	if f.format != "" {
+		return f.format
+	}
+
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".json":
+		return JSONFormat
+	case ".toml":
+		return TOMLFormat
+	default:
+		return YAMLFormat
+	}
+}
+
+// toYAML converts the content of the file to a document yaml.Unmarshal reads.
+func (f ConfigFile) toYAML(content []byte) ([]byte, error) {
+	switch format := f.configFormat(); format {
+	case YAMLFormat, JSONFormat:
+		return content, nil
+	case TOMLFormat:
+		var document map[string]any
+		if err := toml.Unmarshal(content, &document); err != nil {
+			return nil, fmt.Errorf("parse TOML config: %w", err)
+		}
+
+		return json.Marshal(document)
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+}
@@ -0,0 +1,77 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidConfig is returned when a configuration misses required fields, or has invalid defaults.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// applyDefaults sets the fields with a `default` tag to the value of the tag, read as YAML. Defaults are applied
+// before the files are read, so values from the files always win.
+func applyDefaults(value reflect.Value, path string, errs *[]error) {
+	walkConfig(value, path, func(field reflect.Value, structField reflect.StructField, fieldPath string) {
+		def, ok := structField.Tag.Lookup("default")
+		if !ok {
+			return
+		}
+
+		if err := yaml.Unmarshal([]byte(def), field.Addr().Interface()); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: invalid default %q: %w", fieldPath, def, err))
+		}
+	})
+}
+
+// checkRequired reports every field with a `required:"true"` tag left to its zero value.
+func checkRequired(value reflect.Value, path string, errs *[]error) {
+	walkConfig(value, path, func(field reflect.Value, structField reflect.StructField, fieldPath string) {
+		if structField.Tag.Get("required") == "true" && field.IsZero() {
+			*errs = append(*errs, fmt.Errorf("%s: required field is missing", fieldPath))
+		}
+	})
+}
+
+// walkConfig calls visit on every exported field of the struct, recursively. Nil pointers are optional sections,
+// and are not visited.
+func walkConfig(
+	value reflect.Value, path string, visit func(field reflect.Value, structField reflect.StructField, path string),
+) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		field := value.Field(i)
+		fieldPath := joinConfigPath(path, configFieldName(structField))
+
+		visit(field, structField, fieldPath)
+		walkConfig(field, fieldPath, visit)
+	}
+}
+
+// configFieldName returns the name of the field in the configuration files.
+func configFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"yaml", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return field.Name
+}
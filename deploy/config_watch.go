@@ -0,0 +1,203 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"github.com/in-rich/lib-go/monitor"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type WatchConfig struct {
+	// Interval between two checks of the files read from the disk. Defaults to 10 seconds.
+	Interval time.Duration
+}
+
+func (c WatchConfig) withDefaults() WatchConfig {
+	if c.Interval <= 0 {
+		c.Interval = 10 * time.Second
+	}
+
+	return c
+}
+
+// ConfigWatcher holds a configuration reloaded when its files change.
+type ConfigWatcher[Cfg any] struct {
+	logger monitor.Logger
+	config WatchConfig
+	files  []ConfigFile
+
+	current atomic.Pointer[Cfg]
+
+	// reloadMu serializes the reloads.
+	reloadMu sync.Mutex
+	snapshot []byte
+	// loadedAt is the time of the last full load, after which secrets are resolved again.
+	loadedAt time.Time
+
+	mu          sync.Mutex
+	subscribers []func(previous, current *Cfg)
+}
+
+// LoadConfigWatch loads the configuration like LoadConfig, then reloads it whenever the content of a file read
+// with FileConfig changes, or the process receives SIGHUP, until the context is canceled. Secrets and environment
+// variables are also resolved again on SIGHUP, and at least every 5 minutes, so rotated secrets are picked up
+// without a change of the files.
+//
+//	watcher := deploy.LoadConfigWatch[Config](ctx, logger, deploy.WatchConfig{},
+//		deploy.GlobalConfig(globalFile),
+//		deploy.FileConfig("/etc/secrets/config.yaml"),
+//	)
+//
+//	watcher.OnChange(func(previous, current *Config) {
+//		limiter.SetRate(current.RateLimit)
+//	})
+//
+// Always read the configuration with Current, instead of keeping the returned pointer: values are replaced as a
+// whole on every reload, and never modified in place. A reload failing to read or parse a file is logged, and the
+// previous configuration is kept.
+func LoadConfigWatch[Cfg any](ctx context.Context, logger monitor.Logger, config WatchConfig, files ...ConfigFile) *ConfigWatcher[Cfg] {
+	watcher := &ConfigWatcher[Cfg]{logger: logger, config: config.withDefaults(), files: files}
+
+	snapshot, err := watcher.read()
+	if err != nil {
+		monitor.Crash(monitor.ExitFatal, "[deploy] failed to read configuration", err)
+	}
+
+	watcher.current.Store(LoadConfig[Cfg](files...))
+	watcher.snapshot = snapshot
+	watcher.loadedAt = time.Now()
+
+	// Register the signal right away, so a SIGHUP received before the goroutine starts does not kill the process.
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go watcher.run(ctx, hangup)
+
+	return watcher
+}
+
+// Current returns the last loaded configuration.
+func (w *ConfigWatcher[Cfg]) Current() *Cfg {
+	return w.current.Load()
+}
+
+// OnChange registers a callback, called after every reload that changed the resolved configuration. Callbacks run
+// sequentially, on the goroutine of the watcher.
+func (w *ConfigWatcher[Cfg]) OnChange(callback func(previous, current *Cfg)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, callback)
+}
+
+// read returns the concatenated content of the files, to detect changes.
+func (w *ConfigWatcher[Cfg]) read() ([]byte, error) {
+	var snapshot bytes.Buffer
+
+	for _, file := range w.files {
+		content, err := file.read()
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.Write(content)
+		snapshot.WriteByte(0)
+	}
+
+	return snapshot.Bytes(), nil
+}
+
+// Reload loads the configuration again, resolving its secrets and environment variables, and notifies the
+// subscribers if the resolved configuration changed.
+func (w *ConfigWatcher[Cfg]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	snapshot, err := w.read()
+	if err != nil {
+		return err
+	}
+
+	return w.load(snapshot)
+}
+
+// reloadIfChanged reloads the configuration when the content of the files changed, or when secrets are due to be
+// resolved again.
+func (w *ConfigWatcher[Cfg]) reloadIfChanged() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	snapshot, err := w.read()
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(snapshot, w.snapshot) && time.Since(w.loadedAt) < secretCacheTTL {
+		return nil
+	}
+
+	return w.load(snapshot)
+}
+
+// load parses the files, and swaps the configuration if the result differs from the current one. It must be
+// called with reloadMu held.
+func (w *ConfigWatcher[Cfg]) load(snapshot []byte) error {
+	next, err := loadConfig[Cfg](w.files)
+	if err != nil {
+		return err
+	}
+
+	w.snapshot = snapshot
+	w.loadedAt = time.Now()
+
+	if reflect.DeepEqual(next, w.current.Load()) {
+		return nil
+	}
+
+	previous := w.current.Swap(next)
+	w.logger.Info("[deploy] configuration reloaded")
+
+	w.mu.Lock()
+	subscribers := w.subscribers
+	w.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(previous, next)
+	}
+
+	return nil
+}
+
+func (w *ConfigWatcher[Cfg]) run(ctx context.Context, hangup chan os.Signal) {
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			w.logger.Info("[deploy] received SIGHUP, reloading configuration")
+
+			// SIGHUP is typically sent after rotating a secret: do not serve it from the cache.
+			secrets.forget()
+			err = w.Reload()
+		case <-ticker.C:
+			err = w.reloadIfChanged()
+		}
+
+		if err != nil {
+			w.logger.Error(err, "[deploy] failed to reload configuration, keeping the previous one")
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package deploy
+
+import (
+	"cloud.google.com/go/compute/metadata"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/api/run/v2"
+	"google.golang.org/grpc"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrCPUThrottled = errors.New("CPU is only allocated during requests")
+
+type CPUAllocation int
+
+const (
+	// CPUUnknown is reported when the allocation could not be detected. It is handled as CPUAlwaysOn.
+	CPUUnknown CPUAllocation = iota
+	CPUAlwaysOn
+	// CPUThrottled is the request-based mode of Cloud Run: the CPU is throttled to almost zero between requests,
+	// so background goroutines stall.
+	CPUThrottled
+)
+
+func (a CPUAllocation) String() string {
+	switch a {
+	case CPUAlwaysOn:
+		return "always-on"
+	case CPUThrottled:
+		return "throttled"
+	default:
+		return "unknown"
+	}
+}
+
+// DetectCPUAllocation returns the CPU allocation mode of the current Cloud Run service. The CPU_ALWAYS_ALLOCATED
+// variable ("true" or "false") takes precedence, so deployments can skip the detection. Outside Cloud Run, the
+// CPU is always allocated.
+//
+// Detection reads the service definition from the Cloud Run Admin API, so the service account requires the
+// run.services.get permission.
+func DetectCPUAllocation(ctx context.Context) (CPUAllocation, error) {
+	if value := os.Getenv("CPU_ALWAYS_ALLOCATED"); value != "" {
+		alwaysOn, err := strconv.ParseBool(value)
+		if err != nil {
+			return CPUUnknown, fmt.Errorf("invalid CPU_ALWAYS_ALLOCATED value %q: %w", value, err)
+		}
+
+		if alwaysOn {
+			return CPUAlwaysOn, nil
+		}
+		return CPUThrottled, nil
+	}
+
+	service := os.Getenv("K_SERVICE")
+	if service == "" {
+		return CPUAlwaysOn, nil
+	}
+
+	projectID, err := metadata.ProjectIDWithContext(ctx)
+	if err != nil {
+		return CPUUnknown, fmt.Errorf("read project ID: %w", err)
+	}
+
+	// In the form "projects/123456789/regions/europe-west1".
+	region, err := metadata.GetWithContext(ctx, "instance/region")
+	if err != nil {
+		return CPUUnknown, fmt.Errorf("read region: %w", err)
+	}
+	region = region[strings.LastIndex(region, "/")+1:]
+
+	client, err := run.NewService(ctx)
+	if err != nil {
+		return CPUUnknown, fmt.Errorf("create cloud run client: %w", err)
+	}
+
+	definition, err := client.Projects.Locations.Services.
+		Get(fmt.Sprintf("projects/%s/locations/%s/services/%s", projectID, region, service)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return CPUUnknown, fmt.Errorf("read service definition: %w", err)
+	}
+
+	if definition.Template != nil {
+		for _, container := range definition.Template.Containers {
+			if container.Resources != nil && container.Resources.CpuIdle {
+				return CPUThrottled, nil
+			}
+		}
+	}
+
+	return CPUAlwaysOn, nil
+}
+
+type AlwaysOnGuardConfig struct {
+	// StepTimeout bounds a run of the deferred steps. Defaults to 2 seconds.
+	StepTimeout time.Duration
+}
+
+func (c AlwaysOnGuardConfig) withDefaults() AlwaysOnGuardConfig {
+	if c.StepTimeout <= 0 {
+		c.StepTimeout = 2 * time.Second
+	}
+
+	return c
+}
+
+// AlwaysOnGuard protects background work from CPU throttling. Under always-on CPU allocation, background work runs
+// in goroutines as usual. Under throttled allocation, it either fails fast, or runs after requests, and then only
+// progresses while the instance serves requests.
+//
+//	guard := deploy.NewAlwaysOnGuard(ctx, logger, deploy.AlwaysOnGuardConfig{})
+//
+//	// Fail fast: the relay cannot work between requests.
+//	if err := guard.Background(ctx, "outbox relay", relay.Run); err != nil {
+//		logger.Fatal(err, "cannot start outbox relay")
+//	}
+//
+//	// Or defer: run a relay step after each request.
+//	guard.Defer(relay.RelayOnce)
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(guard.UnaryServerInterceptor()))
+type AlwaysOnGuard struct {
+	allocation CPUAllocation
+	logger     monitor.Logger
+	config     AlwaysOnGuardConfig
+
+	mu    sync.Mutex
+	steps []func(ctx context.Context) error
+	// running is set while the steps run, so concurrent requests do not run them again.
+	running atomic.Bool
+}
+
+// NewAlwaysOnGuard detects the CPU allocation of the service. Detection failures are logged, and the CPU is then
+// assumed to be always allocated.
+func NewAlwaysOnGuard(ctx context.Context, logger monitor.Logger, config AlwaysOnGuardConfig) *AlwaysOnGuard {
+	allocation, err := DetectCPUAllocation(ctx)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("[deploy] could not detect CPU allocation, assuming always-on: %s", err))
+	} else {
+		logger.Info(fmt.Sprintf("[deploy] CPU allocation: %s", allocation))
+	}
+
+	return &AlwaysOnGuard{allocation: allocation, logger: logger, config: config.withDefaults()}
+}
+
+// Throttled returns true if the CPU is only allocated during requests.
+func (g *AlwaysOnGuard) Throttled() bool {
+	return g.allocation == CPUThrottled
+}
+
+// Background starts long-running background work in a goroutine. It returns ErrCPUThrottled instead when the CPU
+// is throttled, as the work would silently stall between requests.
+func (g *AlwaysOnGuard) Background(ctx context.Context, name string, run func(ctx context.Context)) error {
+	if g.Throttled() {
+		return fmt.Errorf("cannot run %s in the background: %w; enable always-on CPU allocation", name, ErrCPUThrottled)
+	}
+
+	go run(ctx)
+	return nil
+}
+
+// Defer registers a step of background work, run after the requests when the CPU is throttled. Steps do nothing
+// under always-on allocation: run them in the background instead.
+func (g *AlwaysOnGuard) Defer(step func(ctx context.Context) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.steps = append(g.steps, step)
+}
+
+// runSteps starts the deferred steps in a goroutine, so they do not delay the response. Steps run sequentially,
+// within the step timeout, and are skipped while a previous run is in progress or when the CPU is always allocated.
+func (g *AlwaysOnGuard) runSteps(ctx context.Context) {
+	if !g.Throttled() {
+		return
+	}
+
+	g.mu.Lock()
+	steps := g.steps
+	g.mu.Unlock()
+
+	if len(steps) == 0 || !g.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer g.running.Store(false)
+
+		localCTX, cancel := context.WithTimeout(context.WithoutCancel(ctx), g.config.StepTimeout)
+		defer cancel()
+
+		for _, step := range steps {
+			if err := step(localCTX); err != nil {
+				g.logger.Error(err, "[deploy] deferred background step failed")
+			}
+		}
+	}()
+}
+
+// UnaryServerInterceptor runs the deferred steps after each RPC.
+func (g *AlwaysOnGuard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		res, err := handler(ctx, req)
+		g.runSteps(ctx)
+		return res, err
+	}
+}
+
+// GinMiddleware runs the deferred steps after each HTTP request.
+func (g *AlwaysOnGuard) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		g.runSteps(c.Request.Context())
+	}
+}
@@ -1,8 +1,9 @@
 package deploy
 
 import (
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
 	"github.com/samber/lo"
-	"log"
 	"os"
 )
 
@@ -21,6 +22,7 @@ func IsReleaseEnv() bool {
 // Prevent use of production values in development.
 func init() {
 	if ENV != DevENV && ENV != ProdENV && ENV != StagingEnv {
-		log.Fatalf("unrecognized value for variable variable 'ENV': '%s'", ENV)
+		err := fmt.Errorf("unrecognized value for variable 'ENV': '%s'", ENV)
+		monitor.Crash(monitor.ExitFatal, "[deploy] invalid environment", err)
 	}
 }
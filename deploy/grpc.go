@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	_ "embed"
+	"errors"
 	"fmt"
 	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/monitor/tracing"
 	"github.com/samber/lo"
 	"google.golang.org/api/idtoken"
 	"google.golang.org/grpc"
@@ -16,14 +18,19 @@ import (
 	"google.golang.org/grpc/health"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
-	"log"
+	"google.golang.org/grpc/reflection"
 	"net"
+	"sync"
 	"time"
 )
 
 //go:embed grpc-config.json
 var grpcConfig string
 
+// healthServers holds the health server of each server started with StartGRPCServer, so it can be drained on
+// shutdown.
+var healthServers sync.Map
+
 // GRPCCallback represents the generic signature of exposed RPC services, as generated by the protoc compiler for Go.
 type GRPCCallback[In any, Out any] func(ctx context.Context, in *In, opts ...grpc.CallOption) (*Out, error)
 
@@ -36,11 +43,15 @@ type GRPCCallback[In any, Out any] func(ctx context.Context, in *In, opts ...grp
 //	conn, tokenSource := deploy.OpenGRPCConn("localhost:50051")
 //	defer deploy.CloseGRPCConn(conn)
 //
-// This method automatically retrieves credentials under release environments.
-func OpenGRPCConn(logger monitor.Logger, host string) *grpc.ClientConn {
-	var opts []grpc.DialOption
-
-	if IsReleaseEnv() {
+// This method automatically retrieves credentials under release environments. Use WithClientTLS to connect with
+// mutual TLS instead.
+func OpenGRPCConn(logger monitor.Logger, host string, connOpts ...ConnOption) *grpc.ClientConn {
+	options := newConnOptions(connOpts)
+	opts := append([]grpc.DialOption{grpc.WithStatsHandler(tracing.ClientHandler())}, options.dialOptions...)
+
+	if options.tls != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(options.tls)))
+	} else if IsReleaseEnv() && !options.noIDToken {
 		systemRoots, err := x509.SystemCertPool()
 		if err != nil {
 			logger.Fatal(err, "failed to load system root CA certificates")
@@ -76,7 +87,7 @@ func OpenGRPCConn(logger monitor.Logger, host string) *grpc.ClientConn {
 // CloseGRPCConn closes an existing connection to a GRPC service.
 func CloseGRPCConn(conn *grpc.ClientConn) {
 	if err := conn.Close(); err != nil {
-		log.Fatal(err, "failed to close connection")
+		monitor.Crash(monitor.ExitFatal, "[deploy] failed to close connection", err)
 	}
 }
 
@@ -93,35 +104,51 @@ type DepsCheck struct {
 //
 // You must ensure to properly close the server when you are done, using the CloseGRPCServer method.
 //
-//	listener, server, health := deploy.StartGRPCServer(50051)
+//	listener, server, health := deploy.StartGRPCServer(
+//		logger, 50051, depsCheck,
+//		deploy.WithUnaryInterceptors(authInterceptor, recoveryInterceptor),
+//		deploy.WithListener(cfg.Listener),
+//	)
 //	// Graceful shutdown.
 //	defer deploy.CloseGRPCServer(listener, server)
 //	// Start healthcheck.
 //	go health()
-func StartGRPCServer(logger monitor.Logger, port int, depsCheck DepsCheck) (net.Listener, *grpc.Server, func()) {
+func StartGRPCServer(
+	logger monitor.Logger, port int, depsCheck DepsCheck, opts ...ServerOption,
+) (net.Listener, *grpc.Server, func()) {
 	if port == 0 {
-		log.Fatal("port is required")
+		monitor.Crash(monitor.ExitFatal, "[deploy] failed to start server", errors.New("port is required"))
 	}
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	options := newServerOptions(opts)
+
+	listener, err := options.listener.Listen(context.Background(), port)
 	if err != nil {
 		logger.Fatal(err, "failed to listen")
 	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(append(options.grpcOptions(), grpc.StatsHandler(tracing.ServerHandler()))...)
 
 	// Set healthcheck.
 	// https://github.com/grpc/grpc-go/blob/master/examples/features/health/server/main.go
 	healthcheck := health.NewServer()
 	healthgrpc.RegisterHealthServer(server, healthcheck)
+	healthServers.Store(server, healthcheck)
+
+	// Expose the schema of the services, for debugging tools such as inrichcall. Off unless WithReflection is set.
+	if options.reflection {
+		reflection.Register(server)
+	}
 
 	healthUpdater := func() {
 		dependencies := depsCheck.Dependencies()
 		global := true
 
 		for dependency, err := range dependencies {
+			// Unhealthy dependencies mark the server NOT_SERVING, so the platform routes traffic elsewhere, instead of
+			// killing the process over a transient upstream failure.
 			if err != nil {
-				logger.Fatal(err, fmt.Sprintf("dependency check for %s failed", dependency))
+				logger.Error(err, fmt.Sprintf("dependency check for %s failed", dependency))
 				global = false
 			}
 		}
@@ -150,23 +177,32 @@ func StartGRPCServer(logger monitor.Logger, port int, depsCheck DepsCheck) (net.
 
 // CloseGRPCServer closes an existing GRPC server.
 func CloseGRPCServer(listener net.Listener, server *grpc.Server) {
+	healthServers.Delete(server)
 	server.GracefulStop()
 	_ = listener.Close()
 }
 
-// CallGRPCEndpoint performs a call to a GRPC endpoint, located in a secure cloud environment.
+// CallGRPCEndpoint performs a call to a GRPC endpoint, located in a secure cloud environment. Calls failing with
+// Unavailable are retried with exponential backoff, according to the retry policy (see WithRetry). Idempotent
+// calls can also be retried on DeadlineExceeded, with WithIdempotentRetry.
+//
+//	note, err := deploy.CallGRPCEndpoint(
+//		ctx, notesClient.GetNote, &notes_pb.GetNoteRequest{ID: id}, deploy.WithIdempotentRetry(),
+//	)
+//	_, err = deploy.CallGRPCEndpoint(
+//		ctx, notesClient.CreateNote, req,
+//		deploy.WithoutRetry(), deploy.WithTimeout(time.Minute), deploy.WithMetadata("x-inrich-source", "import"),
+//	)
 func CallGRPCEndpoint[In any, Out any](
-	ctx context.Context, callback GRPCCallback[In, Out], in *In,
+	ctx context.Context, callback GRPCCallback[In, Out], in *In, opts ...CallOption,
 ) (*Out, error) {
-	// Prevent the call from tasking too long.
-	localCTX, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
+	options := newCallOptions(opts)
 
-	// Call the GRPC endpoint.
-	res, err := callback(localCTX, in)
-	if err != nil {
-		return nil, err
-	}
+	// Prevent the call from tasking too long. The timeout includes retries.
+	localCTX, cancel := options.context(ctx)
+	defer cancel()
 
-	return res, nil
+	return retry(localCTX, options.retry, func() (*Out, error) {
+		return callback(localCTX, in, options.callOptions...)
+	})
 }
@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/samber/lo"
+	"net/http"
+	"time"
+)
+
+// HealthReport is the body of the /readyz endpoint.
+type HealthReport struct {
+	// Status is "ok" when every dependency is available, and "unavailable" otherwise.
+	Status string `json:"status"`
+	// Dependencies maps each dependency to "ok", or to the error of its check.
+	Dependencies map[string]string `json:"dependencies"`
+	// Services maps each service to "SERVING" or "NOT_SERVING", using the same rules as the GRPC health service.
+	Services map[string]string `json:"services,omitempty"`
+}
+
+func checkHealth(depsCheck DepsCheck) HealthReport {
+	var dependencies map[string]error
+	if depsCheck.Dependencies != nil {
+		dependencies = depsCheck.Dependencies()
+	}
+
+	report := HealthReport{
+		Status:       "ok",
+		Dependencies: make(map[string]string, len(dependencies)),
+		Services:     make(map[string]string, len(depsCheck.Services)),
+	}
+
+	for dependency, err := range dependencies {
+		report.Dependencies[dependency] = "ok"
+		if err != nil {
+			report.Dependencies[dependency] = err.Error()
+			report.Status = "unavailable"
+		}
+	}
+
+	for service, serviceDeps := range depsCheck.Services {
+		_, hasError := lo.Find(serviceDeps, func(item string) bool {
+			return dependencies[item] != nil
+		})
+
+		report.Services[service] = lo.Ternary(hasError, "NOT_SERVING", "SERVING")
+	}
+
+	return report
+}
+
+// NewHealthHTTPServer creates the HTTP server of StartHealthHTTPServer, without starting it, for use with
+// App.AddHTTPServer.
+func NewHealthHTTPServer(port int, depsCheck DepsCheck) *http.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		report := checkHealth(depsCheck)
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// StartHealthHTTPServer serves liveness and readiness probes over plain HTTP, on a port separate from the GRPC
+// server, for platforms that cannot probe GRPC services.
+//
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck)
+//	healthServer := deploy.StartHealthHTTPServer(logger, 8081, depsCheck)
+//	defer healthServer.Close()
+//
+// GET /healthz answers 200 as long as the process runs. GET /readyz runs the dependency checks, and answers 200
+// when they all pass, or 503 otherwise, with a HealthReport. Reports include the errors of the checks: do not
+// expose the port publicly.
+func StartHealthHTTPServer(logger monitor.Logger, port int, depsCheck DepsCheck) *http.Server {
+	server := NewHealthHTTPServer(port, depsCheck)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal(err, "failed to start health server")
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+type IPStack string
+
+const (
+	// DualStack accepts both IPv4 and IPv6 connections. It is the default.
+	DualStack IPStack = ""
+	IPv4Only  IPStack = "ipv4"
+	IPv6Only  IPStack = "ipv6"
+)
+
+// ListenerConfig configures the socket of the servers deployed outside Cloud Run, such as on-prem installations
+// running on VMs.
+//
+//	listener:
+//	  address: 10.0.0.12
+//	  stack: ipv4
+//	  reusePort: true
+//	  keepAlive: 30s
+type ListenerConfig struct {
+	// Address is the host or IP the server binds to. Defaults to every interface.
+	Address string  `yaml:"address" json:"address"`
+	Stack   IPStack `yaml:"stack" json:"stack"`
+	// ReusePort sets SO_REUSEPORT on the socket, so a new process can bind the port before the previous one stops,
+	// for zero-downtime restarts. Only supported on Unix systems.
+	ReusePort bool `yaml:"reusePort" json:"reusePort"`
+	// KeepAlive is the period of the TCP keepalive probes of accepted connections. Defaults to the system default
+	// (15 seconds); a negative value disables keepalive.
+	KeepAlive Duration `yaml:"keepAlive" json:"keepAlive"`
+}
+
+func (c ListenerConfig) network() (string, error) {
+	switch c.Stack {
+	case DualStack:
+		return "tcp", nil
+	case IPv4Only:
+		return "tcp4", nil
+	case IPv6Only:
+		return "tcp6", nil
+	default:
+		return "", fmt.Errorf("unknown IP stack %q", c.Stack)
+	}
+}
+
+// Listen opens a TCP listener on the port.
+func (c ListenerConfig) Listen(ctx context.Context, port int) (net.Listener, error) {
+	network, err := c.network()
+	if err != nil {
+		return nil, err
+	}
+
+	config := net.ListenConfig{KeepAlive: c.KeepAlive.Duration()}
+	if c.ReusePort {
+		config.Control = func(_, _ string, conn syscall.RawConn) error {
+			return reusePort(conn)
+		}
+	}
+
+	return config.Listen(ctx, network, net.JoinHostPort(c.Address, strconv.Itoa(port)))
+}
+
+// WithListener configures the socket the server listens on.
+func WithListener(config ListenerConfig) ServerOption {
+	return func(options *serverOptions) {
+		options.listener = config
+	}
+}
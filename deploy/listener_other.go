@@ -0,0 +1,12 @@
+//go:build !unix
+
+package deploy
+
+import (
+	"errors"
+	"syscall"
+)
+
+func reusePort(syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT is not supported on this system")
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package deploy
+
+import (
+	"golang.org/x/sys/unix"
+	"syscall"
+)
+
+func reusePort(conn syscall.RawConn) error {
+	var sockErr error
+
+	err := conn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
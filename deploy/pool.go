@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("connection pool is closed")
+
+// GRPCClientPool shares the connections to other services across a process. Connections are opened with
+// OpenGRPCConn on first use, and reused for every later call to the same host.
+//
+//	pool := deploy.NewGRPCClientPool(logger)
+//	defer pool.CloseAll()
+//
+//	notes := notes_pb.NewNotesClient(pool.Get(config.NotesHost))
+//
+// The pool can also report the state of its connections as a dependency of the health check (see Check).
+type GRPCClientPool struct {
+	logger monitor.Logger
+	opts   []ConnOption
+
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+}
+
+// NewGRPCClientPool creates a pool opening its connections with the given options.
+func NewGRPCClientPool(logger monitor.Logger, opts ...ConnOption) *GRPCClientPool {
+	return &GRPCClientPool{logger: logger, opts: opts, conns: make(map[string]*grpc.ClientConn)}
+}
+
+// Get returns the connection to the host, opening it if needed. Calls performed on connections returned after the
+// pool was closed fail with codes.Canceled.
+func (p *GRPCClientPool) Get(host string) *grpc.ClientConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if conn, ok := p.conns[host]; ok {
+		return conn
+	}
+
+	conn := OpenGRPCConn(p.logger, host, p.opts...)
+	if p.closed {
+		_ = conn.Close()
+		return conn
+	}
+
+	// Connect right away, so the health check reports unreachable hosts before the first call.
+	conn.Connect()
+	p.conns[host] = conn
+
+	return conn
+}
+
+// CloseAll closes every connection of the pool. Later calls to Get return closed connections.
+func (p *GRPCClientPool) CloseAll() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for host, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close connection to %s: %w", host, err))
+		}
+	}
+
+	p.conns = make(map[string]*grpc.ClientConn)
+	p.closed = true
+
+	return errors.Join(errs...)
+}
+
+// Check returns the state of every connection, keyed by host, for use in DepsCheck.Dependencies. Connections
+// that failed to connect, or were closed, hold an error, and mark the services depending on them NOT_SERVING.
+//
+//	depsCheck := deploy.DepsCheck{
+//		Dependencies: pool.Check,
+//		Services:     deploy.DepCheckServices{"gateway.v1.Gateway": {config.NotesHost}},
+//	}
+func (p *GRPCClientPool) Check() map[string]error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make(map[string]error, len(p.conns))
+	for host, conn := range p.conns {
+		switch state := conn.GetState(); state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			states[host] = fmt.Errorf("connection to %s is in state %s", host, state)
+		default:
+			states[host] = nil
+		}
+	}
+
+	if p.closed {
+		states["grpc pool"] = ErrPoolClosed
+	}
+
+	return states
+}
@@ -0,0 +1,102 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// ProbeTimeout bounds the health check of a single service in ProbeAll.
+var ProbeTimeout = 5 * time.Second
+
+// ProbeResult is the outcome of the health check of a service.
+type ProbeResult struct {
+	// Status is the serving status reported by the service. It is UNKNOWN when the service could not be reached.
+	Status  healthpb.HealthCheckResponse_ServingStatus
+	Latency time.Duration
+	// Error describes why the service could not be reached, if any. It may name internal hosts and addresses:
+	// log it, but never expose it.
+	Error error
+}
+
+// errorKind returns a generic description of the error, safe for public status pages.
+func (r ProbeResult) errorKind() string {
+	switch {
+	case r.Error == nil:
+		return ""
+	case errors.Is(r.Error, context.DeadlineExceeded) || status.Code(r.Error) == codes.DeadlineExceeded:
+		return "timeout"
+	case status.Code(r.Error) == codes.Unavailable:
+		return "unreachable"
+	default:
+		return "error"
+	}
+}
+
+// MarshalJSON renders the result for status pages, with the status name and the latency in milliseconds. The
+// error is reduced to "timeout", "unreachable" or "error", so internal hosts and addresses are not exposed.
+func (r ProbeResult) MarshalJSON() ([]byte, error) {
+	message := r.errorKind()
+
+	return json.Marshal(map[string]any{
+		"status":    r.Status.String(),
+		"latencyMs": r.Latency.Milliseconds(),
+		"error":     message,
+	})
+}
+
+// Healthy returns true if the service reported it is serving.
+func (r ProbeResult) Healthy() bool {
+	return r.Error == nil && r.Status == healthpb.HealthCheckResponse_SERVING
+}
+
+// ProbeAll calls the standard health service of every connection concurrently, and returns the result per service.
+// Each call is bounded by ProbeTimeout.
+//
+//	results := deploy.ProbeAll(ctx, map[string]*grpc.ClientConn{
+//		"notes": notesConn,
+//		"users": usersConn,
+//	})
+func ProbeAll(ctx context.Context, conns map[string]*grpc.ClientConn) map[string]ProbeResult {
+	results := make(map[string]ProbeResult, len(conns))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, conn := range conns {
+		wg.Add(1)
+		go func(name string, conn *grpc.ClientConn) {
+			defer wg.Done()
+
+			result := probe(ctx, conn)
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, conn)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func probe(ctx context.Context, conn *grpc.ClientConn) ProbeResult {
+	localCTX, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := healthpb.NewHealthClient(conn).Check(localCTX, &healthpb.HealthCheckRequest{})
+	latency := time.Since(start)
+
+	if err != nil {
+		return ProbeResult{Status: healthpb.HealthCheckResponse_UNKNOWN, Latency: latency, Error: err}
+	}
+
+	return ProbeResult{Status: res.GetStatus(), Latency: latency}
+}
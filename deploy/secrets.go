@@ -0,0 +1,193 @@
+package deploy
+
+import (
+	"cloud.google.com/go/compute/metadata"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"google.golang.org/api/secretmanager/v1"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// SecretScheme prefixes the configuration values read from GCP Secret Manager.
+const SecretScheme = "secretmanager://"
+
+// secretCacheTTL bounds the age of the cached secrets, so configurations reloaded by LoadConfigWatch pick up
+// rotated secrets. It is also the maximum delay between two resolutions of the secrets by LoadConfigWatch.
+const secretCacheTTL = 5 * time.Minute
+
+type cachedSecret struct {
+	value     string
+	expiresAt time.Time
+}
+
+type secretResolver struct {
+	mu      sync.Mutex
+	service *secretmanager.Service
+	project string
+	cache   map[string]cachedSecret
+}
+
+var secrets = &secretResolver{cache: make(map[string]cachedSecret)}
+
+// secretEnvName returns the environment variable holding a secret under the dev environment ("db-dsn" is read from
+// SECRET_DB_DSN).
+func secretEnvName(name string) string {
+	return "SECRET_" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, name)
+}
+
+// versionName returns the full resource name of a reference, which is either a secret name, or a full resource
+// name with an optional version:
+//
+//	secretmanager://db-dsn
+//	secretmanager://projects/inrich/secrets/db-dsn
+//	secretmanager://projects/inrich/secrets/db-dsn/versions/3
+func (r *secretResolver) versionName(ctx context.Context, ref string) (string, error) {
+	if !strings.HasPrefix(ref, "projects/") {
+		if r.project == "" {
+			project, err := metadata.ProjectIDWithContext(ctx)
+			if err != nil {
+				return "", fmt.Errorf("resolve project of secret %s: %w", ref, err)
+			}
+
+			r.project = project
+		}
+
+		ref = "projects/" + r.project + "/secrets/" + ref
+	}
+
+	if !strings.Contains(ref, "/versions/") {
+		ref += "/versions/latest"
+	}
+
+	return ref, nil
+}
+
+// forget clears the cache, so the next resolutions read the latest versions.
+func (r *secretResolver) forget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clear(r.cache)
+}
+
+func (r *secretResolver) resolve(ctx context.Context, ref string) (string, error) {
+	if ENV == DevENV && !strings.HasPrefix(ref, "projects/") {
+		name := secretEnvName(ref)
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: set %s in the dev environment", ref, name)
+		}
+
+		return value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cached, ok := r.cache[ref]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	if r.service == nil {
+		service, err := secretmanager.NewService(ctx)
+		if err != nil {
+			return "", fmt.Errorf("create secret manager client: %w", err)
+		}
+
+		r.service = service
+	}
+
+	name, err := r.versionName(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := r.service.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("access secret %s: %w", name, err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decode secret %s: %w", name, err)
+	}
+
+	r.cache[ref] = cachedSecret{value: string(value), expiresAt: time.Now().Add(secretCacheTTL)}
+	return string(value), nil
+}
+
+// resolveSecrets replaces every string of the value starting with SecretScheme with the secret it references.
+// Under the dev environment, secrets referenced by name are read from environment variables instead (see
+// secretEnvName), so services run locally without access to Secret Manager.
+func resolveSecrets(ctx context.Context, value reflect.Value, path string, errs *[]error) {
+	switch value.Kind() {
+	case reflect.String:
+		ref, ok := strings.CutPrefix(value.String(), SecretScheme)
+		if !ok {
+			return
+		}
+
+		secret, err := secrets.resolve(ctx, ref)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+
+		value.SetString(secret)
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+
+		if value.Kind() == reflect.Interface {
+			// Values held by interfaces are not settable: resolve a copy.
+			elem := reflect.New(value.Elem().Type()).Elem()
+			elem.Set(value.Elem())
+			resolveSecrets(ctx, elem, path, errs)
+			value.Set(elem)
+			return
+		}
+
+		resolveSecrets(ctx, value.Elem(), path, errs)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Type().Field(i); field.IsExported() {
+				resolveSecrets(ctx, value.Field(i), joinConfigPath(path, configFieldName(field)), errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			resolveSecrets(ctx, value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			// Map values are not settable: resolve a copy.
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			resolveSecrets(ctx, elem, joinConfigPath(path, fmt.Sprint(iter.Key().Interface())), errs)
+			value.SetMapIndex(iter.Key(), elem)
+		}
+	}
+}
+
+func joinConfigPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc"
+)
+
+// ServerOption configures the server created by StartGRPCServer.
+type ServerOption func(options *serverOptions)
+
+type serverOptions struct {
+	unary  []grpc.UnaryServerInterceptor
+	stream []grpc.StreamServerInterceptor
+	server []grpc.ServerOption
+
+	listener   ListenerConfig
+	metrics    *monitor.Metrics
+	reflection bool
+}
+
+func newServerOptions(opts []ServerOption) *serverOptions {
+	options := new(serverOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+func (o *serverOptions) grpcOptions() []grpc.ServerOption {
+	out := make([]grpc.ServerOption, 0, len(o.server)+2)
+	out = append(out, o.server...)
+
+	unary, stream := o.unary, o.stream
+	if o.metrics != nil {
+		// Outermost, so the calls rejected by other interceptors are recorded too.
+		unary = append([]grpc.UnaryServerInterceptor{o.metrics.UnaryServerInterceptor()}, unary...)
+		stream = append([]grpc.StreamServerInterceptor{o.metrics.StreamServerInterceptor()}, stream...)
+	}
+
+	if len(unary) > 0 {
+		out = append(out, grpc.ChainUnaryInterceptor(unary...))
+	}
+	if len(stream) > 0 {
+		out = append(out, grpc.ChainStreamInterceptor(stream...))
+	}
+
+	return out
+}
+
+// WithUnaryInterceptors adds unary interceptors to the server. Interceptors run in the order they are given,
+// across every call of the option: the first one is the outermost.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(options *serverOptions) {
+		options.unary = append(options.unary, interceptors...)
+	}
+}
+
+// WithStreamInterceptors adds stream interceptors to the server, in the same order as WithUnaryInterceptors.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) ServerOption {
+	return func(options *serverOptions) {
+		options.stream = append(options.stream, interceptors...)
+	}
+}
+
+// WithServerOptions passes raw options to grpc.NewServer, such as message size limits or keepalive settings.
+func WithServerOptions(opts ...grpc.ServerOption) ServerOption {
+	return func(options *serverOptions) {
+		options.server = append(options.server, opts...)
+	}
+}
+
+// WithMetrics records the count, latency and status code of every call of the server in the metrics. Expose the
+// metrics on an HTTP port with Metrics.Handler.
+//
+//	metrics := monitor.NewMetrics(monitor.MetricsConfig{Namespace: "inrich"})
+//	go http.ListenAndServe(":9090", metrics.Handler())
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck, deploy.WithMetrics(metrics))
+func WithMetrics(metrics *monitor.Metrics) ServerOption {
+	return func(options *serverOptions) {
+		options.metrics = metrics
+	}
+}
+
+// WithReflection registers the GRPC reflection service, which serves the schema of every service of the server to
+// debugging tools such as inrichcall. Reflection is not authenticated by reqsign or authz: only enable it on servers
+// unreachable from untrusted callers, typically in development and staging.
+//
+//	deploy.StartGRPCServer(logger, 50051, depsCheck, deploy.WithReflection())
+func WithReflection() ServerOption {
+	return func(options *serverOptions) {
+		options.reflection = true
+	}
+}
@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrSeedFenced = errors.New("seeding is disabled in this environment")
+
+// StartupPhase orders the startup hooks of an App.
+type StartupPhase int
+
+const (
+	// MigratePhase hooks update the schema of the databases.
+	MigratePhase StartupPhase = iota
+	// SeedPhase hooks insert reference data. They only run in the dev and staging environments, unless
+	// AppConfig.ForceSeed is set.
+	SeedPhase
+	// WarmPhase hooks fill the caches, once the data is in place.
+	WarmPhase
+)
+
+func (p StartupPhase) String() string {
+	switch p {
+	case MigratePhase:
+		return "migrate"
+	case SeedPhase:
+		return "seed"
+	case WarmPhase:
+		return "warm"
+	default:
+		return fmt.Sprintf("phase %d", int(p))
+	}
+}
+
+type startupHook struct {
+	phase StartupPhase
+	name  string
+	run   func(ctx context.Context) error
+}
+
+// OnStartup registers a hook run by Startup, before the components of the application are started. Hooks run
+// phase by phase, then in the order they are registered within a phase.
+//
+//	app.OnStartup(deploy.MigratePhase, "schema", migrations.Up)
+//	app.OnStartup(deploy.SeedPhase, "plans", seedPlans)
+//	app.OnStartup(deploy.WarmPhase, "plans cache", plansCache.Load)
+func (a *App) OnStartup(phase StartupPhase, name string, run func(ctx context.Context) error) {
+	a.hooks = append(a.hooks, startupHook{phase: phase, name: name, run: run})
+}
+
+// Startup runs the startup hooks, and stops at the first failure. It is called by Run, and can be called alone by
+// one-off jobs, such as a migration job run before a deployment.
+//
+// Seed hooks are skipped in production, so reference data meant for development environments never reaches it by
+// accident. Set AppConfig.ForceSeed to run them anyway.
+func (a *App) Startup(ctx context.Context) error {
+	hooks := slices.Clone(a.hooks)
+	slices.SortStableFunc(hooks, func(first, second startupHook) int {
+		return int(first.phase) - int(second.phase)
+	})
+
+	for _, hook := range hooks {
+		if hook.phase == SeedPhase && !a.seedAllowed() {
+			a.logger.Warn(fmt.Sprintf("[deploy] skipping %s hook %s: %s", hook.phase, hook.name, ErrSeedFenced))
+			continue
+		}
+
+		a.logger.Info(fmt.Sprintf("[deploy] running %s hook %s", hook.phase, hook.name))
+		if err := hook.run(ctx); err != nil {
+			return fmt.Errorf("%s hook %s: %w", hook.phase, hook.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *App) seedAllowed() bool {
+	if ENV == DevENV || ENV == StagingEnv {
+		return true
+	}
+
+	if a.config.ForceSeed {
+		a.logger.Warn(fmt.Sprintf("[deploy] seeding forced in the %s environment", ENV))
+		return true
+	}
+
+	return false
+}
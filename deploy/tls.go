@@ -0,0 +1,128 @@
+package deploy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"os"
+)
+
+var ErrInvalidCA = errors.New("no certificate found in CA file")
+
+// TLSConfig locates the PEM files used for mutual TLS, for services deployed outside Cloud Run, where requests are
+// not authenticated with Google ID tokens.
+//
+//	tls:
+//	  certFile: /etc/certs/tls.crt
+//	  keyFile: /etc/certs/tls.key
+//	  caFile: /etc/certs/ca.crt
+type TLSConfig struct {
+	// CertFile and KeyFile hold the certificate of the service, presented to its peers.
+	CertFile string `yaml:"certFile" json:"certFile"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile"`
+	// CAFile holds the authorities the certificates of the peers must be signed by.
+	CAFile string `yaml:"caFile" json:"caFile"`
+}
+
+func (c TLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("load key pair: %w", err)
+	}
+
+	ca, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return tls.Certificate{}, nil, fmt.Errorf("%w: %s", ErrInvalidCA, c.CAFile)
+	}
+
+	return certificate, pool, nil
+}
+
+// Server returns the TLS configuration of a server requiring client certificates signed by the CA.
+func (c TLSConfig) Server() (*tls.Config, error) {
+	certificate, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Client returns the TLS configuration of a client presenting its certificate, and verifying the certificate of
+// the server against the CA.
+func (c TLSConfig) Client() (*tls.Config, error) {
+	certificate, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// WithServerTLS serves the GRPC server over TLS. Use the configuration returned by TLSConfig.Server for mutual
+// TLS.
+//
+//	serverTLS, err := config.TLS.Server()
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck, deploy.WithServerTLS(serverTLS))
+func WithServerTLS(config *tls.Config) ServerOption {
+	return WithServerOptions(grpc.Creds(credentials.NewTLS(config)))
+}
+
+// ConnOption configures the connections opened by OpenGRPCConn.
+type ConnOption func(options *connOptions)
+
+type connOptions struct {
+	tls         *tls.Config
+	noIDToken   bool
+	dialOptions []grpc.DialOption
+}
+
+func newConnOptions(opts []ConnOption) *connOptions {
+	options := new(connOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithClientTLS connects over TLS with the given configuration, in every environment, instead of using Google ID
+// tokens. Use the configuration returned by TLSConfig.Client for mutual TLS.
+func WithClientTLS(config *tls.Config) ConnOption {
+	return func(options *connOptions) {
+		options.tls = config
+	}
+}
+
+// WithoutIDToken does not attach Google ID tokens to the calls of release environments, for installations where
+// calls are authenticated otherwise, such as reqsign. The connection uses WithClientTLS when given, and is
+// plaintext otherwise.
+func WithoutIDToken() ConnOption {
+	return func(options *connOptions) {
+		options.noIDToken = true
+	}
+}
+
+// WithDialOptions passes raw options to grpc.NewClient, such as interceptors.
+func WithDialOptions(opts ...grpc.DialOption) ConnOption {
+	return func(options *connOptions) {
+		options.dialOptions = append(options.dialOptions, opts...)
+	}
+}
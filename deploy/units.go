@@ -0,0 +1,275 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that can be read from configuration files, as a Go duration string ("500ms", "1h30m")
+// or a number of days ("7d"). Bare numbers are rejected, as their unit is ambiguous.
+type Duration time.Duration
+
+func ParseDuration(value string) (Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("invalid duration: empty value")
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		parsed, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+
+		return Duration(parsed * float64(24*time.Hour)), nil
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil && value != "0" {
+		return 0, fmt.Errorf("invalid duration %q: missing unit (e.g. 500ms, 30s, 5m, 1h, 7d)", value)
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+	}
+
+	return Duration(parsed), nil
+}
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// InRange returns an error if the duration is outside the given bounds (inclusive).
+func (d Duration) InRange(min, max time.Duration) error {
+	if time.Duration(d) < min || time.Duration(d) > max {
+		return fmt.Errorf("duration %s out of range [%s, %s]", d, min, max)
+	}
+
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, d.UnmarshalText)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalYAMLText(unmarshal, d.UnmarshalText)
+}
+
+// ByteSize is a number of bytes that can be read from configuration files, with decimal (KB, MB, GB, TB) or binary
+// (KiB, MiB, GiB, TiB) units. Bare numbers are read as bytes.
+type ByteSize int64
+
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+
+	KiB ByteSize = 1024
+	MiB          = 1024 * KiB
+	GiB          = 1024 * MiB
+	TiB          = 1024 * GiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KB,
+	"kb":  KB,
+	"m":   MB,
+	"mb":  MB,
+	"g":   GB,
+	"gb":  GB,
+	"t":   TB,
+	"tb":  TB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+}
+
+func ParseByteSize(value string) (ByteSize, error) {
+	value = strings.TrimSpace(value)
+
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+
+	number, unit := value[:i], strings.ToLower(strings.TrimSpace(value[i:]))
+
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", value, err)
+	}
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", value, value[i:])
+	}
+
+	size := parsed * float64(multiplier)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid byte size %q: overflow", value)
+	}
+
+	return ByteSize(size), nil
+}
+
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
+
+func (b ByteSize) String() string {
+	units := []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}, {TB, "TB"}, {GB, "GB"}, {MB, "MB"}, {KB, "KB"}}
+
+	// Only use units that represent the size exactly, so the output can be parsed back to the same value.
+	for _, unit := range units {
+		if b != 0 && b%unit.size == 0 {
+			return strconv.FormatInt(int64(b/unit.size), 10) + unit.name
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// InRange returns an error if the size is outside the given bounds (inclusive).
+func (b ByteSize) InRange(min, max ByteSize) error {
+	if b < min || b > max {
+		return fmt.Errorf("byte size %s out of range [%s, %s]", b, min, max)
+	}
+
+	return nil
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	parsed, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = parsed
+	return nil
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, b.UnmarshalText)
+}
+
+func (b *ByteSize) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalYAMLText(unmarshal, b.UnmarshalText)
+}
+
+// Percent is a percentage that can be read from configuration files, with or without the percent sign ("15%" or
+// 15). Values must be between 0 and 100.
+type Percent float64
+
+func ParsePercent(value string) (Percent, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q: %w", value, err)
+	}
+
+	if parsed < 0 || parsed > 100 {
+		return 0, fmt.Errorf("invalid percentage %q: must be between 0%% and 100%%", value)
+	}
+
+	return Percent(parsed), nil
+}
+
+// Fraction returns the percentage as a value between 0 and 1.
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// InRange returns an error if the percentage is outside the given bounds (inclusive).
+func (p Percent) InRange(min, max Percent) error {
+	if p < min || p > max {
+		return fmt.Errorf("percentage %s out of range [%s, %s]", p, min, max)
+	}
+
+	return nil
+}
+
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Percent) UnmarshalText(text []byte) error {
+	parsed, err := ParsePercent(string(text))
+	if err != nil {
+		return err
+	}
+
+	*p = parsed
+	return nil
+}
+
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, p.UnmarshalText)
+}
+
+func (p *Percent) UnmarshalYAML(unmarshal func(any) error) error {
+	return unmarshalYAMLText(unmarshal, p.UnmarshalText)
+}
+
+// unmarshalJSONText accepts both JSON strings and JSON numbers.
+func unmarshalJSONText(data []byte, unmarshalText func([]byte) error) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return unmarshalText([]byte(text))
+	}
+
+	return unmarshalText(data)
+}
+
+// unmarshalYAMLText accepts both YAML strings and YAML numbers.
+func unmarshalYAMLText(unmarshal func(any) error, unmarshalText func([]byte) error) error {
+	var value any
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	return unmarshalText([]byte(fmt.Sprint(value)))
+}
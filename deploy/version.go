@@ -0,0 +1,130 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"golang.org/x/mod/semver"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const libraryModule = "github.com/in-rich/lib-go"
+
+// VersionPolicy is the document published at the version policy URL, listing the library versions that must no
+// longer run:
+//
+//	{
+//		"minimum": "v1.8.0",
+//		"broken": ["v1.9.0"],
+//		"message": "v1.9.0 leaks database connections, upgrade to v1.9.1"
+//	}
+type VersionPolicy struct {
+	Minimum string   `json:"minimum"`
+	Broken  []string `json:"broken"`
+	Message string   `json:"message"`
+}
+
+// LibraryVersion returns the version of lib-go the binary was built with, or "(devel)" when it cannot be
+// determined, as with local replace directives.
+func LibraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+
+	if info.Main.Path == libraryModule {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == libraryModule {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+
+			return dep.Version
+		}
+	}
+
+	return "(devel)"
+}
+
+// Violation returns a description of why the version is rejected by the policy, or an empty string. Versions that
+// are not valid semantic versions, such as local builds, are never rejected.
+func (p VersionPolicy) Violation(version string) string {
+	if !semver.IsValid(version) {
+		return ""
+	}
+
+	for _, broken := range p.Broken {
+		if semver.Compare(version, broken) == 0 {
+			return fmt.Sprintf("lib-go %s is known to be broken", version)
+		}
+	}
+
+	if semver.IsValid(p.Minimum) && semver.Compare(version, p.Minimum) < 0 {
+		return fmt.Sprintf("lib-go %s is older than the minimum supported version %s", version, p.Minimum)
+	}
+
+	return ""
+}
+
+// CheckLibraryVersion compares the running lib-go version against the policy published at the given URL. Services
+// running a rejected version refuse to start in release environments, and log a warning otherwise.
+//
+//	deploy.CheckLibraryVersion(ctx, logger, "https://storage.googleapis.com/inrich-public/lib-go/policy.json")
+//
+// The check is best-effort: when the policy cannot be fetched, a warning is logged and the service starts.
+func CheckLibraryVersion(ctx context.Context, logger monitor.Logger, url string) {
+	policy, err := fetchVersionPolicy(ctx, url)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("[deploy] could not fetch the lib-go version policy: %s", err))
+		return
+	}
+
+	version := LibraryVersion()
+	violation := policy.Violation(version)
+	if violation == "" {
+		return
+	}
+
+	if policy.Message != "" {
+		violation += ": " + policy.Message
+	}
+
+	if IsReleaseEnv() {
+		logger.Fatal(fmt.Errorf("%s", violation), "[deploy] refusing to start with an unsupported lib-go version")
+	}
+
+	logger.Warn("[deploy] !!! " + violation + " !!!")
+}
+
+func fetchVersionPolicy(ctx context.Context, url string) (*VersionPolicy, error) {
+	localCTX, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(localCTX, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	policy := new(VersionPolicy)
+	if err := json.NewDecoder(res.Body).Decode(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
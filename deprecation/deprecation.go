@@ -0,0 +1,251 @@
+package deprecation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/pattern"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Use is a call to a deprecated method, or a request setting a deprecated field.
+type Use struct {
+	Method string
+	// Field is the full name of the deprecated field set by the request, empty for deprecated methods.
+	Field  string
+	Caller string
+	Reason string
+}
+
+type Config struct {
+	// Interval is the minimum delay between two warnings for the same method, field and caller. Defaults to 1 hour.
+	Interval time.Duration
+	// Caller identifies the caller of a request. Defaults to the email of the ID token of the request (verified by
+	// Cloud Run), or its user agent.
+	Caller func(ctx context.Context) string
+	// OnUse is called for every use, throttled or not, for example to count them.
+	OnUse func(use Use)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = time.Hour
+	}
+	if c.Caller == nil {
+		c.Caller = DefaultCaller
+	}
+
+	return c
+}
+
+// DefaultCaller returns the email of the ID token of the request, or its user agent. The token is not verified
+// again: Cloud Run rejects requests with invalid tokens before they reach the service.
+func DefaultCaller(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		if token, ok := strings.CutPrefix(values[0], "Bearer "); ok {
+			if parts := strings.Split(token, "."); len(parts) == 3 {
+				var claims struct {
+					Email string `json:"email"`
+				}
+
+				if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+					if json.Unmarshal(payload, &claims) == nil && claims.Email != "" {
+						return claims.Email
+					}
+				}
+			}
+		}
+	}
+
+	if values := md.Get("user-agent"); len(values) > 0 {
+		return values[0]
+	}
+
+	return "unknown"
+}
+
+// Tracker warns about the uses of deprecated methods and fields, to find the callers of old APIs before they are
+// removed. Methods and fields are deprecated with the standard proto option:
+//
+//	rpc GetNoteLegacy(GetNoteRequest) returns (Note) {
+//		option deprecated = true;
+//	}
+//
+//	message ListNotesRequest {
+//		string author = 1 [deprecated = true];
+//	}
+//
+// Methods can also be deprecated by name, with a reason:
+//
+//	tracker := deprecation.NewTracker(logger, deprecation.Config{})
+//	tracker.Deprecate("/notes.v1.Notes/ExportNotes", "use /exports.v1.Exports/CreateExport, removed in March")
+//
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck,
+//		deploy.WithUnaryInterceptors(tracker.UnaryServerInterceptor()),
+//		deploy.WithStreamInterceptors(tracker.StreamServerInterceptor()),
+//	)
+//
+// Warnings are throttled per method, field and caller, and never fail the call.
+type Tracker struct {
+	logger monitor.Logger
+	config Config
+
+	mu      sync.Mutex
+	methods pattern.Matcher[string]
+	warned  map[Use]time.Time
+}
+
+func NewTracker(logger monitor.Logger, config Config) *Tracker {
+	return &Tracker{
+		logger: logger,
+		config: config.withDefaults(),
+		warned: make(map[Use]time.Time),
+	}
+}
+
+// Deprecate marks a full method name as deprecated. Methods ending with "*" match by prefix.
+func (t *Tracker) Deprecate(method, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.methods.Add(method, reason)
+}
+
+// methodDeprecation returns the reason a method is deprecated, and false if it is not.
+func (t *Tracker) methodDeprecation(method string) (string, bool) {
+	t.mu.Lock()
+	reason, _, ok := t.methods.Match(method)
+	t.mu.Unlock()
+
+	if ok {
+		return reason, true
+	}
+
+	// "/notes.v1.Notes/GetNote" is registered as "notes.v1.Notes.GetNote".
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", "."))
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return "", false
+	}
+
+	if methodDescriptor, ok := descriptor.(protoreflect.MethodDescriptor); ok {
+		if options, ok := methodDescriptor.Options().(*descriptorpb.MethodOptions); ok && options.GetDeprecated() {
+			return "deprecated in " + methodDescriptor.ParentFile().Path(), true
+		}
+	}
+
+	return "", false
+}
+
+// deprecatedFields appends the full names of the deprecated fields set in the message, recursively.
+func deprecatedFields(msg protoreflect.Message, out []string) []string {
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if options, ok := field.Options().(*descriptorpb.FieldOptions); ok && options.GetDeprecated() {
+			out = append(out, string(field.FullName()))
+		}
+
+		switch {
+		case field.IsList() && field.Message() != nil:
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				out = deprecatedFields(list.Get(i).Message(), out)
+			}
+		case field.IsMap() && field.MapValue().Message() != nil:
+			value.Map().Range(func(_ protoreflect.MapKey, entry protoreflect.Value) bool {
+				out = deprecatedFields(entry.Message(), out)
+				return true
+			})
+		case !field.IsList() && !field.IsMap() && field.Message() != nil:
+			out = deprecatedFields(value.Message(), out)
+		}
+
+		return true
+	})
+
+	return out
+}
+
+// report logs a use, unless the same use was logged within the interval.
+func (t *Tracker) report(use Use) {
+	if t.config.OnUse != nil {
+		t.config.OnUse(use)
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	if last, ok := t.warned[use]; ok && now.Sub(last) < t.config.Interval {
+		t.mu.Unlock()
+		return
+	}
+
+	// Forget the expired warnings, so the map does not grow with every caller ever seen.
+	if len(t.warned) > 10000 {
+		for key, last := range t.warned {
+			if now.Sub(last) >= t.config.Interval {
+				delete(t.warned, key)
+			}
+		}
+	}
+	t.warned[use] = now
+	t.mu.Unlock()
+
+	logger := t.logger.With("method", use.Method).With("caller", use.Caller)
+	if use.Field != "" {
+		logger.With("field", use.Field).Warn(fmt.Sprintf("[deprecation] deprecated field %s set", use.Field))
+		return
+	}
+
+	logger.With("reason", use.Reason).Warn(fmt.Sprintf("[deprecation] deprecated method %s called", use.Method))
+}
+
+// Check reports the deprecated method and fields used by a request.
+func (t *Tracker) Check(ctx context.Context, method string, req any) {
+	var caller string
+	callerOnce := func() string {
+		if caller == "" {
+			caller = t.config.Caller(ctx)
+		}
+
+		return caller
+	}
+
+	if reason, ok := t.methodDeprecation(method); ok {
+		t.report(Use{Method: method, Caller: callerOnce(), Reason: reason})
+	}
+
+	if msg, ok := req.(proto.Message); ok {
+		for _, field := range deprecatedFields(msg.ProtoReflect(), nil) {
+			t.report(Use{Method: method, Field: field, Caller: callerOnce()})
+		}
+	}
+}
+
+// UnaryServerInterceptor reports the deprecated methods and request fields used by the calls.
+func (t *Tracker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t.Check(ctx, info.FullMethod, req)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor reports the deprecated methods opened as streams. The messages of streams are not
+// checked.
+func (t *Tracker) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t.Check(stream.Context(), info.FullMethod, nil)
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,160 @@
+package devstack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidManifest = errors.New("invalid devstack manifest")
+
+// Service is a service of the local stack.
+type Service struct {
+	Name string `yaml:"name"`
+	// Command is the binary and its arguments, such as ["./bin/server"] or ["go", "run", "./cmd/server"].
+	Command []string `yaml:"command"`
+	// Dir is the working directory of the service, relative to the manifest. Defaults to the manifest directory.
+	Dir string `yaml:"dir"`
+	// Port is the port the service listens on. It is passed to the service in the PORT variable.
+	Port int               `yaml:"port"`
+	Env  map[string]string `yaml:"env"`
+	// DependsOn lists the services started before this one.
+	DependsOn []string `yaml:"dependsOn"`
+}
+
+// Manifest describes the local stack.
+//
+//	services:
+//	  - name: users
+//	    dir: ../users-service
+//	    command: [go, run, ./cmd/server]
+//	    port: 50051
+//	  - name: gateway
+//	    dir: ../gateway
+//	    command: [go, run, ./cmd/server]
+//	    port: 8080
+//	    dependsOn: [users]
+type Manifest struct {
+	Services []Service `yaml:"services"`
+	// RestartDelay is the initial delay before a crashed service is restarted. It doubles on consecutive crashes.
+	// Defaults to 1 second.
+	RestartDelay time.Duration `yaml:"restartDelay"`
+	// StopTimeout is the delay given to services to exit after SIGTERM, before they are killed. Defaults to 10
+	// seconds.
+	StopTimeout time.Duration `yaml:"stopTimeout"`
+}
+
+// LoadManifest reads a manifest file. Relative service directories are resolved against the manifest directory.
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := new(Manifest)
+	if err := yaml.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	base := filepath.Dir(path)
+	names := make(map[string]struct{}, len(manifest.Services))
+
+	for i := range manifest.Services {
+		service := &manifest.Services[i]
+
+		if service.Name == "" || len(service.Command) == 0 {
+			return nil, fmt.Errorf("%w: service %d requires a name and a command", ErrInvalidManifest, i)
+		}
+		if _, ok := names[service.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate service %s", ErrInvalidManifest, service.Name)
+		}
+		names[service.Name] = struct{}{}
+
+		if !filepath.IsAbs(service.Dir) {
+			service.Dir = filepath.Join(base, service.Dir)
+		}
+	}
+
+	for _, service := range manifest.Services {
+		for _, dependency := range service.DependsOn {
+			if _, ok := names[dependency]; !ok {
+				return nil, fmt.Errorf("%w: %s depends on unknown service %s", ErrInvalidManifest, service.Name, dependency)
+			}
+		}
+	}
+
+	if manifest.RestartDelay <= 0 {
+		manifest.RestartDelay = time.Second
+	}
+	if manifest.StopTimeout <= 0 {
+		manifest.StopTimeout = 10 * time.Second
+	}
+
+	return manifest, nil
+}
+
+// HostEnv returns the name of the variable holding the local address of a service, passed to every service of the
+// stack. Reference it in development configuration files, so OpenGRPCConn reaches the local instance:
+//
+//	# config.dev.yaml
+//	users:
+//	  host: ${USERS_SERVICE_HOST}
+func HostEnv(name string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(name))
+	return normalized + "_SERVICE_HOST"
+}
+
+// order returns the services sorted so that dependencies come first.
+func (m *Manifest) order(selected []string) ([]Service, error) {
+	byName := make(map[string]Service, len(m.Services))
+	for _, service := range m.Services {
+		byName[service.Name] = service
+	}
+
+	if len(selected) == 0 {
+		for _, service := range m.Services {
+			selected = append(selected, service.Name)
+		}
+	}
+
+	ordered := make([]Service, 0, len(m.Services))
+	state := make(map[string]int) // 1: visiting, 2: done.
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		service, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown service %s", ErrInvalidManifest, name)
+		}
+
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("%w: dependency cycle on %s", ErrInvalidManifest, name)
+		case 2:
+			return nil
+		}
+
+		state[name] = 1
+		for _, dependency := range service.DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+
+		ordered = append(ordered, service)
+		return nil
+	}
+
+	for _, name := range selected {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,202 @@
+package devstack
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/in-rich/lib-go/deploy"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var palette = []color.Attribute{
+	color.FgCyan, color.FgGreen, color.FgYellow, color.FgBlue, color.FgMagenta,
+	color.FgHiCyan, color.FgHiGreen, color.FgHiYellow, color.FgHiBlue, color.FgHiMagenta,
+}
+
+// Stack runs the services of a manifest locally.
+type Stack struct {
+	manifest *Manifest
+	output   io.Writer
+	mu       sync.Mutex
+	width    int
+}
+
+// NewStack creates a stack. Service output is written to output, one prefixed and colorized line at a time.
+func NewStack(manifest *Manifest, output io.Writer) *Stack {
+	stack := &Stack{manifest: manifest, output: output}
+	for _, service := range manifest.Services {
+		stack.width = max(stack.width, len(service.Name))
+	}
+
+	return stack
+}
+
+// Run starts the selected services, or every service if none is selected, and restarts them when they crash. It
+// returns once the context is canceled and every service has stopped.
+func (s *Stack) Run(ctx context.Context, selected ...string) error {
+	services, err := s.manifest.order(selected)
+	if err != nil {
+		return err
+	}
+
+	env := s.discoveryEnv()
+
+	var wg sync.WaitGroup
+	for i, service := range services {
+		prefix := color.New(palette[i%len(palette)]).Sprintf("%-*s |", s.width, service.Name)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.supervise(ctx, service, prefix, env)
+		}()
+
+		if service.Port > 0 {
+			s.waitPort(ctx, service, prefix)
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// discoveryEnv exposes the address of every service to every other service.
+func (s *Stack) discoveryEnv() []string {
+	env := make([]string, 0, len(s.manifest.Services))
+	for _, service := range s.manifest.Services {
+		if service.Port > 0 {
+			env = append(env, fmt.Sprintf("%s=localhost:%d", HostEnv(service.Name), service.Port))
+		}
+	}
+
+	return env
+}
+
+func (s *Stack) printf(prefix, format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _ = fmt.Fprintf(s.output, "%s %s\n", prefix, fmt.Sprintf(format, args...))
+}
+
+// waitPort waits for a service to accept connections, so its dependents do not start against a closed port.
+func (s *Stack) waitPort(ctx context.Context, service Service, prefix string) {
+	address := net.JoinHostPort("localhost", strconv.Itoa(service.Port))
+	deadline := time.Now().Add(time.Minute)
+
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			_ = conn.Close()
+			return
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	if ctx.Err() == nil {
+		s.printf(prefix, color.YellowString("still not listening on %s, starting dependents anyway", address))
+	}
+}
+
+func (s *Stack) supervise(ctx context.Context, service Service, prefix string, env []string) {
+	delay := s.manifest.RestartDelay
+
+	for ctx.Err() == nil {
+		startedAt := time.Now()
+		err := s.start(ctx, service, prefix, env)
+		if ctx.Err() != nil {
+			s.printf(prefix, "stopped")
+			return
+		}
+
+		// Reset the backoff of services that ran for a while before crashing.
+		if time.Since(startedAt) > time.Minute {
+			delay = s.manifest.RestartDelay
+		}
+
+		s.printf(prefix, color.RedString("exited (%v), restarting in %s", err, delay))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+func (s *Stack) start(ctx context.Context, service Service, prefix string, env []string) error {
+	cmd := exec.Command(service.Command[0], service.Command[1:]...)
+	cmd.Dir = service.Dir
+	// Run every service as a development service, so it uses the console logger.
+	cmd.Env = append(os.Environ(), "ENV="+deploy.DevENV)
+	cmd.Env = append(cmd.Env, env...)
+	if service.Port > 0 {
+		cmd.Env = append(cmd.Env, "PORT="+strconv.Itoa(service.Port))
+	}
+	for key, value := range service.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	s.printf(prefix, "started (pid %d)", cmd.Process.Pid)
+
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			s.printf(prefix, "%s", scanner.Text())
+		}
+		close(done)
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+
+			select {
+			case <-stopped:
+			case <-time.After(s.manifest.StopTimeout):
+				s.printf(prefix, color.YellowString("did not stop within %s, killing", s.manifest.StopTimeout))
+				_ = cmd.Process.Kill()
+			}
+		case <-stopped:
+		}
+	}()
+
+	<-done
+	err = cmd.Wait()
+	close(stopped)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("exit code %d", exitErr.ExitCode())
+	}
+
+	return err
+}
@@ -0,0 +1,166 @@
+package etag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+)
+
+const (
+	// IfMatchMetadataKey carries the ETag expected by the client, following the HTTP If-Match semantics.
+	IfMatchMetadataKey = "if-match"
+	// ETagMetadataKey carries the ETag of the returned resource, in the response header.
+	ETagMetadataKey = "etag"
+	// Any matches every version of a resource.
+	Any = "*"
+)
+
+var (
+	// ErrConflict is wrapped by the errors returned when the resource was modified concurrently.
+	ErrConflict = errors.New("resource was modified concurrently")
+	// ErrMissingPrecondition is wrapped by the errors returned when an If-Match value is required, but missing.
+	ErrMissingPrecondition = errors.New("if-match precondition is required")
+)
+
+// FromMessage computes the ETag of a resource, as the hash of its deterministic serialization.
+func FromMessage(msg proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("serialize resource: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return strconv.Quote(hex.EncodeToString(sum[:16])), nil
+}
+
+// FromVersion computes the ETag of a resource from its version number.
+func FromVersion(version int64) string {
+	return strconv.Quote("v" + strconv.FormatInt(version, 10))
+}
+
+// ParseVersion reads the version number of an ETag created with FromVersion.
+func ParseVersion(etag string) (int64, error) {
+	unquoted, err := strconv.Unquote(etag)
+	if err != nil || !strings.HasPrefix(unquoted, "v") {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid etag %s", etag)
+	}
+
+	version, err := strconv.ParseInt(unquoted[1:], 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid etag %s", etag)
+	}
+
+	return version, nil
+}
+
+// IfMatch returns the ETag expected by the client, if any.
+func IfMatch(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(IfMatchMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+
+	return values[0], true
+}
+
+// Check compares the ETag expected by the client to the current ETag of the resource. Requests without If-Match
+// metadata are accepted, unless required is true.
+//
+// Errors are GRPC statuses: Aborted when the resource was modified since the client read it (the client should read
+// the resource again before retrying), and FailedPrecondition when a required If-Match value is missing.
+func Check(ctx context.Context, current string, required bool) error {
+	expected, ok := IfMatch(ctx)
+	if !ok {
+		if required {
+			return MissingPreconditionError()
+		}
+
+		return nil
+	}
+
+	if expected == Any {
+		return nil
+	}
+
+	for _, candidate := range strings.Split(expected, ",") {
+		if strings.TrimSpace(candidate) == current {
+			return nil
+		}
+	}
+
+	return ConflictError(current)
+}
+
+// ConflictError returns the Aborted status reporting a concurrent modification. The current ETag, when known, is
+// sent back in the error message, so clients can tell which version they conflict with.
+func ConflictError(current string) error {
+	message := ErrConflict.Error()
+	if current != "" {
+		message += ": current etag is " + current
+	}
+
+	return &statusError{status: status.New(codes.Aborted, message), cause: ErrConflict}
+}
+
+// MissingPreconditionError returns the FailedPrecondition status reporting a missing If-Match value.
+func MissingPreconditionError() error {
+	return &statusError{
+		status: status.New(codes.FailedPrecondition, ErrMissingPrecondition.Error()),
+		cause:  ErrMissingPrecondition,
+	}
+}
+
+// statusError is a GRPC status that also matches its cause with errors.Is.
+type statusError struct {
+	status *status.Status
+	cause  error
+}
+
+func (e *statusError) Error() string {
+	return e.status.Message()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.cause
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// Set sends the ETag of the returned resource in the response header.
+func Set(ctx context.Context, etag string) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(ETagMetadataKey, etag))
+}
+
+// WithIfMatch attaches the expected ETag to an outgoing call.
+//
+//	var header metadata.MD
+//	note, err := client.UpdateNote(etag.WithIfMatch(ctx, previous), req, grpc.Header(&header))
+//	next := etag.FromHeader(header)
+func WithIfMatch(ctx context.Context, etag string) context.Context {
+	if etag == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, IfMatchMetadataKey, etag)
+}
+
+// FromHeader returns the ETag of a response header, or an empty string.
+func FromHeader(header metadata.MD) string {
+	if values := header.Get(ETagMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
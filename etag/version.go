@@ -0,0 +1,39 @@
+package etag
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+)
+
+// UpdateVersion runs an update query only if the version column of the row still holds the version read by the
+// caller. The version pointer references the version field of the updated model: it is incremented before the
+// query runs, so the new version is written with the other columns.
+//
+//	_, err := etag.UpdateVersion(ctx, db.NewUpdate().Model(note).Column("content", "version").WherePK(), "version", &note.Version)
+//
+// A ConflictError is returned when no row was updated, because the row was modified, or deleted, since it was
+// read. The version field is restored in that case.
+func UpdateVersion(ctx context.Context, query *bun.UpdateQuery, column string, version *int64) (int64, error) {
+	expected := *version
+	*version = expected + 1
+
+	res, err := query.Where("? = ?", bun.Ident(column), expected).Exec(ctx)
+	if err == nil {
+		var affected int64
+		if affected, err = res.RowsAffected(); err == nil && affected == 0 {
+			err = ConflictError("")
+		}
+	}
+
+	if err != nil {
+		*version = expected
+		return 0, err
+	}
+
+	return *version, nil
+}
+
+// CheckVersion is a shortcut for Check, with the ETag computed from the given version number.
+func CheckVersion(ctx context.Context, version int64, required bool) error {
+	return Check(ctx, FromVersion(version), required)
+}
@@ -0,0 +1,99 @@
+package fakes
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/httpcache"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ httpcache.Store = (*Cache)(nil)
+
+type cacheEntry struct {
+	entry     *httpcache.Entry
+	expiresAt time.Time
+}
+
+// Cache is an httpcache.Store expiring entries on a manual clock.
+//
+//	clock := fakes.NewClock()
+//	cache := fakes.NewCache(clock)
+//	router.Use(httpcache.Middleware(cache, httpcache.Config{}))
+//	...
+//	clock.Advance(time.Hour)
+//	if keys := cache.Keys(); len(keys) > 0 {
+//		t.Errorf("entries did not expire: %v", keys)
+//	}
+type Cache struct {
+	clock *Clock
+
+	mu          sync.Mutex
+	entries     map[string]cacheEntry
+	invalidated []string
+}
+
+// NewCache creates an empty cache. Entries expire when the clock passes their TTL.
+func NewCache(clock *Clock) *Cache {
+	return &Cache{clock: clock, entries: make(map[string]cacheEntry)}
+}
+
+func (c *Cache) Get(_ context.Context, key string) (*httpcache.Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, httpcache.ErrNotFound
+	}
+
+	return entry.entry, nil
+}
+
+func (c *Cache) Set(_ context.Context, key string, entry *httpcache.Entry, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{entry: entry, expiresAt: c.clock.Now().Add(ttl)}
+	return nil
+}
+
+func (c *Cache) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidated = append(c.invalidated, tags...)
+	for key, entry := range c.entries {
+		if slices.ContainsFunc(entry.entry.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			delete(c.entries, key)
+		}
+	}
+
+	return nil
+}
+
+// Keys returns the keys of the entries that have not expired, sorted.
+func (c *Cache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	keys := make([]string, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if now.Before(entry.expiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// Invalidated returns the tags passed to InvalidateTags, in order.
+func (c *Cache) Invalidated() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.invalidated)
+}
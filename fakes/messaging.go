@@ -0,0 +1,85 @@
+package fakes
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/realtime"
+	"slices"
+	"sync"
+)
+
+var _ realtime.Backend = (*Backend)(nil)
+
+// Backend is a realtime.Backend delivering messages within the process, and recording every published message.
+//
+//	backend := fakes.NewBackend()
+//	bus := realtime.NewBus(backend, "notes", logger)
+//	...
+//	for _, message := range backend.Published() {
+//		t.Logf("%s: %s", message.Channel, message.Data)
+//	}
+type Backend struct {
+	backend realtime.Backend
+
+	mu        sync.Mutex
+	published []realtime.Message
+	fail      error
+}
+
+func NewBackend() *Backend {
+	return &Backend{backend: realtime.NewMemoryBackend()}
+}
+
+func (b *Backend) Publish(ctx context.Context, channel string, data []byte) error {
+	b.mu.Lock()
+	if b.fail != nil {
+		err := b.fail
+		b.mu.Unlock()
+		return err
+	}
+
+	b.published = append(b.published, realtime.Message{Channel: channel, Data: slices.Clone(data)})
+	b.mu.Unlock()
+
+	return b.backend.Publish(ctx, channel, data)
+}
+
+func (b *Backend) Subscribe(ctx context.Context, channels ...string) (realtime.Subscription, error) {
+	return b.backend.Subscribe(ctx, channels...)
+}
+
+// Fail makes the next publications fail with err, until Fail is called with nil.
+func (b *Backend) Fail(err error) {
+	b.mu.Lock()
+	b.fail = err
+	b.mu.Unlock()
+}
+
+// Published returns the messages published on every channel, in order.
+func (b *Backend) Published() []realtime.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return slices.Clone(b.published)
+}
+
+// PublishedOn returns the messages published on a channel, in order.
+func (b *Backend) PublishedOn(channel string) []realtime.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var messages []realtime.Message
+	for _, message := range b.published {
+		if message.Channel == channel {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets the published messages.
+func (b *Backend) Reset() {
+	b.mu.Lock()
+	b.published = nil
+	b.mu.Unlock()
+}
@@ -0,0 +1,98 @@
+package fakes
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/grpcrecord"
+	"github.com/in-rich/lib-go/statesnap"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	_ statesnap.Store = (*SnapshotStore)(nil)
+	_ grpcrecord.Sink = (*RecordSink)(nil)
+)
+
+// SnapshotStore is a statesnap.Store keeping the snapshots in memory.
+type SnapshotStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	saves   int
+}
+
+func NewSnapshotStore() *SnapshotStore {
+	return &SnapshotStore{objects: make(map[string][]byte)}
+}
+
+func (s *SnapshotStore) Load(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, statesnap.ErrNotFound
+	}
+
+	return slices.Clone(data), nil
+}
+
+func (s *SnapshotStore) Save(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = slices.Clone(data)
+	s.saves++
+
+	return nil
+}
+
+// Keys returns the keys of the stored snapshots, sorted.
+func (s *SnapshotStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(s.objects))
+}
+
+// Object returns the snapshot stored under a key, or nil.
+func (s *SnapshotStore) Object(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.objects[key])
+}
+
+// Saves returns the number of calls to Save.
+func (s *SnapshotStore) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saves
+}
+
+// RecordSink is a grpcrecord.Sink keeping the records in memory.
+type RecordSink struct {
+	mu      sync.Mutex
+	records []*grpcrecord.Record
+}
+
+func NewRecordSink() *RecordSink {
+	return new(RecordSink)
+}
+
+func (s *RecordSink) Write(_ context.Context, record *grpcrecord.Record) error {
+	s.mu.Lock()
+	s.records = append(s.records, record)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Records returns the written records, in order.
+func (s *RecordSink) Records() []*grpcrecord.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.records)
+}
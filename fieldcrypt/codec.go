@@ -0,0 +1,155 @@
+package fieldcrypt
+
+import (
+	"fmt"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Codec is a GRPC codec encrypting selected fields of proto messages before they are sent, and decrypting them
+// when they are received. Both sides of the call must use the codec, with the same keys.
+//
+//	codec := fieldcrypt.NewCodec(cipher, "messages.v1.Message.content")
+//
+//	server := grpc.NewServer(grpc.ForceServerCodec(codec))
+//	conn, err := grpc.NewClient(host, grpc.WithDefaultCallOptions(grpc.ForceCodec(codec)))
+//
+// Only string and bytes fields can be encrypted. String fields hold the base64 ciphertext on the wire.
+type Codec struct {
+	cipher *Cipher
+	fields map[protoreflect.FullName]struct{}
+}
+
+var _ encoding.Codec = (*Codec)(nil)
+
+// NewCodec creates a codec encrypting the fields with the given full names ("messages.v1.Message.content").
+func NewCodec(cipher *Cipher, fields ...string) *Codec {
+	codec := &Codec{cipher: cipher, fields: make(map[protoreflect.FullName]struct{}, len(fields))}
+	for _, field := range fields {
+		codec.fields[protoreflect.FullName(field)] = struct{}{}
+	}
+
+	return codec
+}
+
+// Name replaces the default proto codec.
+func (c *Codec) Name() string {
+	return "proto"
+}
+
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("fieldcrypt: cannot marshal %T, not a proto message", v)
+	}
+
+	// Encrypt a copy, so the caller still holds the plaintext message.
+	clone := proto.Clone(msg)
+	if err := c.walk(clone.ProtoReflect(), true); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(clone)
+}
+
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("fieldcrypt: cannot unmarshal into %T, not a proto message", v)
+	}
+
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+
+	return c.walk(msg.ProtoReflect(), false)
+}
+
+func (c *Codec) walk(msg protoreflect.Message, encrypt bool) error {
+	var err error
+
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if _, ok := c.fields[field.FullName()]; ok {
+			err = c.transformField(msg, field, value, encrypt)
+			return err == nil
+		}
+
+		if field.Kind() != protoreflect.MessageKind && field.Kind() != protoreflect.GroupKind {
+			return true
+		}
+
+		switch {
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = c.walk(list.Get(i).Message(), encrypt)
+			}
+		case field.IsMap():
+			if field.MapValue().Kind() == protoreflect.MessageKind {
+				value.Map().Range(func(_ protoreflect.MapKey, entry protoreflect.Value) bool {
+					err = c.walk(entry.Message(), encrypt)
+					return err == nil
+				})
+			}
+		default:
+			err = c.walk(value.Message(), encrypt)
+		}
+
+		return err == nil
+	})
+
+	return err
+}
+
+func (c *Codec) transformField(msg protoreflect.Message, field protoreflect.FieldDescriptor, value protoreflect.Value, encrypt bool) error {
+	if field.IsMap() {
+		return fmt.Errorf("fieldcrypt: map field %s cannot be encrypted", field.FullName())
+	}
+
+	transform := func(value protoreflect.Value) (protoreflect.Value, error) {
+		switch field.Kind() {
+		case protoreflect.StringKind:
+			var out string
+			var err error
+			if encrypt {
+				out, err = c.cipher.EncryptString(value.String())
+			} else {
+				out, err = c.cipher.DecryptString(value.String())
+			}
+			return protoreflect.ValueOfString(out), err
+		case protoreflect.BytesKind:
+			var out []byte
+			var err error
+			if encrypt {
+				out, err = c.cipher.Encrypt(value.Bytes())
+			} else {
+				out, err = c.cipher.Decrypt(value.Bytes())
+			}
+			return protoreflect.ValueOfBytes(out), err
+		default:
+			return value, fmt.Errorf("fieldcrypt: field %s of kind %s cannot be encrypted", field.FullName(), field.Kind())
+		}
+	}
+
+	if field.IsList() {
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			transformed, err := transform(list.Get(i))
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.FullName(), err)
+			}
+			list.Set(i, transformed)
+		}
+
+		return nil
+	}
+
+	transformed, err := transform(value)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", field.FullName(), err)
+	}
+
+	msg.Set(field, transformed)
+	return nil
+}
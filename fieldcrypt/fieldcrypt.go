@@ -0,0 +1,146 @@
+package fieldcrypt
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/protoutil"
+	"github.com/in-rich/lib-go/tokens"
+	"sync/atomic"
+)
+
+var (
+	ErrNoCipher   = errors.New("fieldcrypt: no default cipher configured")
+	ErrCiphertext = errors.New("fieldcrypt: invalid ciphertext")
+)
+
+// Cipher encrypts sensitive values with a keyring. Ciphertexts are bound to the purpose of the cipher, so a value
+// encrypted for a field cannot be copied to another field protected by a different purpose.
+type Cipher struct {
+	keys    *tokens.Keyring
+	purpose string
+}
+
+func NewCipher(keys *tokens.Keyring, purpose string) *Cipher {
+	return &Cipher{keys: keys, purpose: purpose}
+}
+
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	return c.keys.Seal(plaintext, c.purpose)
+}
+
+func (c *Cipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	plaintext, err := c.keys.Open(ciphertext, c.purpose)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCiphertext, err)
+	}
+
+	return plaintext, nil
+}
+
+// EncryptString encrypts a string to its base64 representation, for text columns and string fields.
+func (c *Cipher) EncryptString(plaintext string) (string, error) {
+	ciphertext, err := c.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (c *Cipher) DecryptString(ciphertext string) (string, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCiphertext, err)
+	}
+
+	plaintext, err := c.Decrypt(raw)
+	return string(plaintext), err
+}
+
+var defaultCipher atomic.Pointer[Cipher]
+
+// SetDefault sets the cipher used by the String column type. Call it once on startup, before the database is
+// used.
+func SetDefault(cipher *Cipher) {
+	defaultCipher.Store(cipher)
+}
+
+func getDefault() (*Cipher, error) {
+	cipher := defaultCipher.Load()
+	if cipher == nil {
+		return nil, ErrNoCipher
+	}
+
+	return cipher, nil
+}
+
+// String is a sensitive text, encrypted with the default cipher when written to the database, and decrypted when
+// read. It is redacted when printed or serialized, so it never ends up in logs: use Reveal to read its value.
+//
+//	type Message struct {
+//		bun.BaseModel `bun:"table:messages"`
+//
+//		ID      string            `bun:"id,pk"`
+//		Content fieldcrypt.String `bun:"content,type:text,notnull"`
+//	}
+type String string
+
+// Reveal returns the plaintext value.
+func (s String) Reveal() string {
+	return string(s)
+}
+
+func (s String) String() string {
+	return protoutil.Redacted
+}
+
+func (s String) GoString() string {
+	return protoutil.Redacted
+}
+
+func (s String) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + protoutil.Redacted + `"`), nil
+}
+
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(protoutil.Redacted), nil
+}
+
+func (s String) Value() (driver.Value, error) {
+	cipher, err := getDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.EncryptString(string(s))
+}
+
+func (s *String) Scan(src any) error {
+	var ciphertext string
+	switch value := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		ciphertext = value
+	case []byte:
+		ciphertext = string(value)
+	default:
+		return fmt.Errorf("fieldcrypt: cannot scan %T into String", src)
+	}
+
+	cipher, err := getDefault()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := cipher.DecryptString(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	*s = String(plaintext)
+	return nil
+}
@@ -0,0 +1,76 @@
+package firestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"os"
+	"time"
+)
+
+type Config struct {
+	ProjectID string
+	// EmulatorHost connects to the Firestore emulator ("localhost:8080") instead of the real service, for local
+	// environments. Ignored when empty.
+	EmulatorHost string
+	// DatabaseID selects a named database. Defaults to the "(default)" database.
+	DatabaseID string
+}
+
+// OpenClient connects to Firestore. The returned function closes the client.
+//
+//	client, closer, err := firestore.OpenClient(ctx, firestore.Config{
+//		ProjectID:    cfg.Firebase.ProjectID,
+//		EmulatorHost: lo.Ternary(deploy.IsReleaseEnv(), "", "localhost:8080"),
+//	})
+//	defer closer()
+func OpenClient(ctx context.Context, config Config, opts ...option.ClientOption) (*firestore.Client, func(), error) {
+	if config.EmulatorHost != "" {
+		// The client library only reads the emulator host from the environment.
+		if err := os.Setenv("FIRESTORE_EMULATOR_HOST", config.EmulatorHost); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var (
+		client *firestore.Client
+		err    error
+	)
+
+	if config.DatabaseID != "" {
+		client, err = firestore.NewClientWithDatabase(ctx, config.ProjectID, config.DatabaseID, opts...)
+	} else {
+		client, err = firestore.NewClient(ctx, config.ProjectID, opts...)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("create firestore client: %w", err)
+	}
+
+	closer := func() {
+		_ = client.Close()
+	}
+
+	return client, closer, nil
+}
+
+// ServiceCheck verifies that Firestore can be reached, for dependency checks.
+//
+//	depsCheck := deploy.DepsCheck{
+//		Dependencies: func() map[string]error {
+//			return map[string]error{"Firestore": firestore.ServiceCheck(client)}
+//		},
+//	}
+func ServiceCheck(client *firestore.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.Collections(ctx).Next()
+	if err != nil && !errors.Is(err, iterator.Done) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package firestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("document not found")
+	ErrAlreadyExists = errors.New("document already exists")
+)
+
+// Document is a decoded document, with its metadata.
+type Document[T any] struct {
+	ID         string
+	Data       *T
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+// Collection gives typed access to the documents of a collection. Documents are decoded with the firestore struct
+// tags of T.
+//
+//	type Preferences struct {
+//		Theme string `firestore:"theme"`
+//	}
+//
+//	preferences := firestore.NewCollection[Preferences](client, "preferences")
+//	prefs, err := preferences.Get(ctx, userID)
+type Collection[T any] struct {
+	client *firestore.Client
+	ref    *firestore.CollectionRef
+}
+
+// NewCollection creates a typed collection. Nested collections use slash-separated paths, such as
+// "users/abc/devices".
+func NewCollection[T any](client *firestore.Client, path string) *Collection[T] {
+	return &Collection[T]{client: client, ref: client.Collection(path)}
+}
+
+// Ref returns the underlying collection reference, for operations not covered by the typed helpers.
+func (c *Collection[T]) Ref() *firestore.CollectionRef {
+	return c.ref
+}
+
+// convertError maps Firestore statuses to the errors of this package.
+func convertError(err error, id string) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	case codes.AlreadyExists:
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, id)
+	default:
+		return err
+	}
+}
+
+func decode[T any](snapshot *firestore.DocumentSnapshot) (*Document[T], error) {
+	data := new(T)
+	if err := snapshot.DataTo(data); err != nil {
+		return nil, fmt.Errorf("decode document %s: %w", snapshot.Ref.ID, err)
+	}
+
+	return &Document[T]{
+		ID:         snapshot.Ref.ID,
+		Data:       data,
+		CreateTime: snapshot.CreateTime,
+		UpdateTime: snapshot.UpdateTime,
+	}, nil
+}
+
+// Get returns the document with the given ID, or ErrNotFound.
+func (c *Collection[T]) Get(ctx context.Context, id string) (*Document[T], error) {
+	snapshot, err := c.ref.Doc(id).Get(ctx)
+	if err != nil {
+		return nil, convertError(err, id)
+	}
+
+	return decode[T](snapshot)
+}
+
+// Create stores a new document, or returns ErrAlreadyExists.
+func (c *Collection[T]) Create(ctx context.Context, id string, data *T) error {
+	_, err := c.ref.Doc(id).Create(ctx, data)
+	return convertError(err, id)
+}
+
+// Set stores a document, replacing the existing one if any.
+func (c *Collection[T]) Set(ctx context.Context, id string, data *T) error {
+	_, err := c.ref.Doc(id).Set(ctx, data)
+	return convertError(err, id)
+}
+
+// Update modifies some fields of an existing document, or returns ErrNotFound.
+func (c *Collection[T]) Update(ctx context.Context, id string, updates ...firestore.Update) error {
+	_, err := c.ref.Doc(id).Update(ctx, updates)
+	return convertError(err, id)
+}
+
+// Delete removes a document. Deleting a missing document is not an error.
+func (c *Collection[T]) Delete(ctx context.Context, id string) error {
+	_, err := c.ref.Doc(id).Delete(ctx)
+	return convertError(err, id)
+}
+
+// Query returns the documents matching a query built on the collection.
+//
+//	devices, err := collection.Query(ctx, func(q firestore.Query) firestore.Query {
+//		return q.Where("active", "==", true).OrderBy("lastSeen", firestore.Desc).Limit(20)
+//	})
+func (c *Collection[T]) Query(
+	ctx context.Context, build func(query firestore.Query) firestore.Query,
+) ([]*Document[T], error) {
+	query := c.ref.Query
+	if build != nil {
+		query = build(query)
+	}
+
+	documents := make([]*Document[T], 0)
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		snapshot, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := decode[T](snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, document)
+	}
+}
+
+// SetAll stores many documents with a bulk writer, which batches and retries the writes. The returned error joins
+// the failures of individual documents.
+func (c *Collection[T]) SetAll(ctx context.Context, documents map[string]*T) error {
+	writer := c.client.BulkWriter(ctx)
+	jobs := make(map[string]*firestore.BulkWriterJob, len(documents))
+
+	var errs []error
+	for id, data := range documents {
+		job, err := writer.Set(c.ref.Doc(id), data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("write document %s: %w", id, err))
+			continue
+		}
+
+		jobs[id] = job
+	}
+
+	return errors.Join(append(errs, wait(writer, jobs)...)...)
+}
+
+// DeleteAll deletes many documents with a bulk writer.
+func (c *Collection[T]) DeleteAll(ctx context.Context, ids ...string) error {
+	writer := c.client.BulkWriter(ctx)
+	jobs := make(map[string]*firestore.BulkWriterJob, len(ids))
+
+	var errs []error
+	for _, id := range ids {
+		job, err := writer.Delete(c.ref.Doc(id))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete document %s: %w", id, err))
+			continue
+		}
+
+		jobs[id] = job
+	}
+
+	return errors.Join(append(errs, wait(writer, jobs)...)...)
+}
+
+// wait flushes the bulk writer, and returns the failures of its jobs.
+func wait(writer *firestore.BulkWriter, jobs map[string]*firestore.BulkWriterJob) []error {
+	writer.End()
+
+	var errs []error
+	for id, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			errs = append(errs, fmt.Errorf("write document %s: %w", id, convertError(err, id)))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,258 @@
+package flags
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/slo"
+	"github.com/uptrace/bun"
+	"hash/fnv"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrUnknownFlag   = errors.New("unknown flag")
+	ErrNotActive     = errors.New("rollout is not active")
+	ErrInvalidStages = errors.New("rollout stages must have increasing percentages")
+)
+
+// SystemActor is the actor of the changes made automatically, such as rollbacks triggered by SLO alerts.
+const SystemActor = "system"
+
+type Config struct {
+	// RefreshInterval is the delay after which rollouts changed by other instances are picked up. Defaults to 30
+	// seconds.
+	RefreshInterval time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = 30 * time.Second
+	}
+
+	return c
+}
+
+// Flags evaluates flags under progressive rollout, and records every change of a rollout in an audit log.
+//
+//	flagSet := flags.NewFlags(db, logger, flags.Config{})
+//	go flagSet.Run(ctx)
+//	tracker := slo.NewTracker(logger, cfg.SLO, flagSet.HandleAlert)
+//
+//	if flagSet.Enabled("new-editor", userID) {
+//		...
+//	}
+//
+// Subjects are bucketed by a hash of the flag and subject, so a subject exposed at 1% stays exposed at 10%.
+type Flags struct {
+	db     bun.IDB
+	logger monitor.Logger
+	config Config
+
+	rollouts atomic.Pointer[map[string]*Rollout]
+}
+
+func NewFlags(db bun.IDB, logger monitor.Logger, config Config) *Flags {
+	flags := &Flags{db: db, logger: logger, config: config.withDefaults()}
+	flags.rollouts.Store(&map[string]*Rollout{})
+
+	return flags
+}
+
+// Refresh loads the rollouts from the database.
+func (f *Flags) Refresh(ctx context.Context) error {
+	var rollouts []*Rollout
+	if err := f.db.NewSelect().Model(&rollouts).Scan(ctx); err != nil {
+		return err
+	}
+
+	byFlag := make(map[string]*Rollout, len(rollouts))
+	for _, rollout := range rollouts {
+		byFlag[rollout.Flag] = rollout
+	}
+
+	f.rollouts.Store(&byFlag)
+	return nil
+}
+
+// Run refreshes the rollouts at every interval, until the context is canceled.
+func (f *Flags) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+			f.logger.Error(err, "[flags] failed to refresh rollouts")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rollouts returns the known rollouts.
+func (f *Flags) Rollouts() []*Rollout {
+	rollouts := *f.rollouts.Load()
+
+	out := make([]*Rollout, 0, len(rollouts))
+	for _, rollout := range rollouts {
+		out = append(out, rollout)
+	}
+
+	slices.SortFunc(out, func(a, b *Rollout) int { return b.StartedAt.Compare(a.StartedAt) })
+	return out
+}
+
+// Enabled returns true if the subject, such as a user ID, is exposed to the flag. Unknown flags are disabled.
+func (f *Flags) Enabled(flag, subject string) bool {
+	rollout, ok := (*f.rollouts.Load())[flag]
+	if !ok {
+		return false
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(flag + ":" + subject))
+	bucket := float64(hash.Sum32() % 10000)
+
+	return bucket < rollout.Percent(time.Now()).Fraction()*10000
+}
+
+// change applies a change to a rollout and records it in the audit log, in one transaction.
+func (f *Flags) change(ctx context.Context, rollout *Rollout, action Action, actor, reason string) error {
+	err := f.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().
+			Model(rollout).
+			On("CONFLICT (flag) DO UPDATE").
+			Set("stages = EXCLUDED.stages").
+			Set("objectives = EXCLUDED.objectives").
+			Set("state = EXCLUDED.state").
+			Set("started_at = EXCLUDED.started_at").
+			Set("updated_at = EXCLUDED.updated_at").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewInsert().Model(&AuditEntry{
+			Flag:      rollout.Flag,
+			Action:    action,
+			Actor:     actor,
+			Reason:    reason,
+			Rollout:   rollout,
+			CreatedAt: rollout.UpdatedAt,
+		}).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	f.logger.Info(fmt.Sprintf("[flags] %s %s of %s: %s", actor, action, rollout.Flag, reason))
+
+	// Apply the change locally right away, other instances pick it up on their next refresh.
+	return f.Refresh(ctx)
+}
+
+func (f *Flags) load(ctx context.Context, flag string) (*Rollout, error) {
+	rollout := &Rollout{Flag: flag}
+	if err := f.db.NewSelect().Model(rollout).WherePK().Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, flag)
+		}
+
+		return nil, err
+	}
+
+	return rollout, nil
+}
+
+// Start starts the rollout of a flag from its first stage, replacing any previous rollout of the flag.
+func (f *Flags) Start(ctx context.Context, rollout Rollout, actor, reason string) error {
+	if len(rollout.Stages) == 0 || !slices.IsSortedFunc(rollout.Stages, func(a, b Stage) int {
+		return cmp.Compare(a.Percent, b.Percent)
+	}) {
+		return ErrInvalidStages
+	}
+
+	now := time.Now()
+	rollout.State = StateActive
+	rollout.StartedAt = now
+	rollout.UpdatedAt = now
+	if rollout.Objectives == nil {
+		rollout.Objectives = []string{}
+	}
+
+	return f.change(ctx, &rollout, ActionStart, actor, reason)
+}
+
+// Complete exposes the flag to every subject.
+func (f *Flags) Complete(ctx context.Context, flag, actor, reason string) error {
+	return f.transition(ctx, flag, StateCompleted, ActionComplete, actor, reason)
+}
+
+// Rollback hides the flag from every subject.
+func (f *Flags) Rollback(ctx context.Context, flag, actor, reason string) error {
+	return f.transition(ctx, flag, StateRolledBack, ActionRollback, actor, reason)
+}
+
+func (f *Flags) transition(ctx context.Context, flag string, state State, action Action, actor, reason string) error {
+	rollout, err := f.load(ctx, flag)
+	if err != nil {
+		return err
+	}
+
+	if rollout.State != StateActive {
+		return fmt.Errorf("%w: %s is %s", ErrNotActive, flag, rollout.State)
+	}
+
+	rollout.State = state
+	rollout.UpdatedAt = time.Now()
+
+	return f.change(ctx, rollout, action, actor, reason)
+}
+
+// HandleAlert rolls back the active rollouts watching the endpoint of the alert. Pass it as the alert callback of
+// slo.NewTracker.
+func (f *Flags) HandleAlert(alert slo.Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, rollout := range f.Rollouts() {
+		if rollout.State != StateActive || !slices.Contains(rollout.Objectives, alert.Endpoint) {
+			continue
+		}
+
+		rollout := *rollout
+		rollout.State = StateRolledBack
+		rollout.UpdatedAt = time.Now()
+
+		reason := fmt.Sprintf("%s burn rate of %s reached %.1f", alert.Indicator, alert.Endpoint, alert.BurnRate)
+		if err := f.change(ctx, &rollout, ActionAutoRollback, SystemActor, reason); err != nil {
+			f.logger.Error(err, fmt.Sprintf("[flags] failed to roll back %s", rollout.Flag))
+		}
+	}
+}
+
+// Audit returns the last changes of a flag, most recent first. An empty flag returns the changes of every flag.
+func (f *Flags) Audit(ctx context.Context, flag string, limit int) ([]*AuditEntry, error) {
+	entries := make([]*AuditEntry, 0)
+
+	query := f.db.NewSelect().Model(&entries).OrderExpr("id DESC").Limit(limit)
+	if flag != "" {
+		query = query.Where("flag = ?", flag)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/in-rich/lib-go/introspect"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ActorHeader identifies the operator calling the admin endpoints. It is recorded in the audit log.
+const ActorHeader = "X-Inrich-Actor"
+
+type changeRequest struct {
+	Flag       string   `json:"flag"`
+	Stages     []Stage  `json:"stages"`
+	Objectives []string `json:"objectives"`
+	Reason     string   `json:"reason"`
+}
+
+// Mount registers the admin endpoints of the flags on the mux:
+//
+//	GET  prefix + "/flags"                   lists the rollouts.
+//	GET  prefix + "/flags/audit?flag=&limit=" lists the last changes, most recent first.
+//	POST prefix + "/flags/start"             starts a rollout: {"flag", "stages", "objectives", "reason"}.
+//	POST prefix + "/flags/complete"          completes a rollout: {"flag", "reason"}.
+//	POST prefix + "/flags/rollback"          rolls a rollout back: {"flag", "reason"}.
+//
+// Changes require the ActorHeader, so the audit log records who made them.
+func (f *Flags) Mount(mux *http.ServeMux, prefix string, allowlist *introspect.IPAllowlist) {
+	if allowlist == nil {
+		panic("flags: an IP allowlist is required to mount the flags endpoints")
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	mux.Handle(prefix+"/flags", allowlist.Middleware(http.HandlerFunc(f.list)))
+	mux.Handle(prefix+"/flags/audit", allowlist.Middleware(http.HandlerFunc(f.audit)))
+	mux.Handle(prefix+"/flags/start", allowlist.Middleware(f.changeHandler(ActionStart)))
+	mux.Handle(prefix+"/flags/complete", allowlist.Middleware(f.changeHandler(ActionComplete)))
+	mux.Handle(prefix+"/flags/rollback", allowlist.Middleware(f.changeHandler(ActionRollback)))
+}
+
+func (f *Flags) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(f.Rollouts())
+}
+
+func (f *Flags) audit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	entries, err := f.Audit(r.Context(), r.URL.Query().Get("flag"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+func (f *Flags) changeHandler(action Action) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		actor := r.Header.Get(ActorHeader)
+		if actor == "" {
+			http.Error(w, "missing "+ActorHeader+" header", http.StatusBadRequest)
+			return
+		}
+
+		var req changeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Flag == "" {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		switch action {
+		case ActionStart:
+			err = f.Start(r.Context(), Rollout{Flag: req.Flag, Stages: req.Stages, Objectives: req.Objectives}, actor, req.Reason)
+		case ActionComplete:
+			err = f.Complete(r.Context(), req.Flag, actor, req.Reason)
+		default:
+			err = f.Rollback(r.Context(), req.Flag, actor, req.Reason)
+		}
+
+		switch {
+		case errors.Is(err, ErrUnknownFlag):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrNotActive):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrInvalidStages):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package flags
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type State string
+
+const (
+	StateActive     State = "active"
+	StateCompleted  State = "completed"
+	StateRolledBack State = "rolled_back"
+)
+
+// Stage exposes the flag to a percentage of the subjects, for a duration.
+type Stage struct {
+	Percent deploy.Percent `json:"percent"`
+	// Duration of the stage, before moving to the next one. The last stage lasts until the rollout is completed or
+	// rolled back.
+	Duration deploy.Duration `json:"duration"`
+}
+
+// Rollout is the progressive exposure of a flag.
+type Rollout struct {
+	bun.BaseModel `bun:"table:flags_rollouts,alias:rollout"`
+
+	Flag   string  `bun:"flag,pk" json:"flag"`
+	Stages []Stage `bun:"stages,type:jsonb,notnull" json:"stages"`
+	// Objectives lists the SLO endpoints watched during the rollout: a burn rate alert on one of them rolls the
+	// flag back (see Flags.HandleAlert).
+	Objectives []string  `bun:"objectives,type:jsonb,notnull" json:"objectives"`
+	State      State     `bun:"state,notnull" json:"state"`
+	StartedAt  time.Time `bun:"started_at,notnull" json:"startedAt"`
+	UpdatedAt  time.Time `bun:"updated_at,notnull" json:"updatedAt"`
+}
+
+// Percent returns the percentage of subjects exposed to the flag at the given time.
+func (r *Rollout) Percent(now time.Time) deploy.Percent {
+	switch r.State {
+	case StateCompleted:
+		return 100
+	case StateRolledBack:
+		return 0
+	}
+
+	if len(r.Stages) == 0 {
+		return 0
+	}
+
+	end := r.StartedAt
+	for _, stage := range r.Stages {
+		end = end.Add(stage.Duration.Duration())
+		if now.Before(end) {
+			return stage.Percent
+		}
+	}
+
+	return r.Stages[len(r.Stages)-1].Percent
+}
+
+type Action string
+
+const (
+	ActionStart        Action = "start"
+	ActionComplete     Action = "complete"
+	ActionRollback     Action = "rollback"
+	ActionAutoRollback Action = "auto_rollback"
+)
+
+// AuditEntry records a change of a rollout.
+type AuditEntry struct {
+	bun.BaseModel `bun:"table:flags_audit,alias:audit"`
+
+	ID        int64     `bun:"id,pk,autoincrement" json:"id"`
+	Flag      string    `bun:"flag,notnull" json:"flag"`
+	Action    Action    `bun:"action,notnull" json:"action"`
+	Actor     string    `bun:"actor,notnull" json:"actor"`
+	Reason    string    `bun:"reason,notnull" json:"reason"`
+	Rollout   *Rollout  `bun:"rollout,type:jsonb" json:"rollout,omitempty"`
+	CreatedAt time.Time `bun:"created_at,notnull" json:"createdAt"`
+}
+
+// CreateTables creates the rollouts and audit tables, if they do not exist yet.
+func CreateTables(ctx context.Context, db bun.IDB) error {
+	if _, err := db.NewCreateTable().Model((*Rollout)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := db.NewCreateTable().Model((*AuditEntry)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewCreateIndex().
+		Model((*AuditEntry)(nil)).
+		Index("flags_audit_flag_idx").
+		Column("flag", "created_at").
+		IfNotExists().
+		Exec(ctx)
+	return err
+}
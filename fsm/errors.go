@@ -0,0 +1,42 @@
+package fsm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid transition")
+	ErrGuardRejected     = errors.New("transition rejected by guard")
+)
+
+// InvalidTransitionError is returned when no transition is declared between two states.
+type InvalidTransitionError[S comparable] struct {
+	Machine string
+	From    S
+	To      S
+}
+
+func (e *InvalidTransitionError[S]) Error() string {
+	return fmt.Sprintf("%s: invalid transition from %v to %v", e.Machine, e.From, e.To)
+}
+
+func (e *InvalidTransitionError[S]) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// GuardError is returned when the guard of a transition rejects it.
+type GuardError[S comparable] struct {
+	Machine string
+	From    S
+	To      S
+	Err     error
+}
+
+func (e *GuardError[S]) Error() string {
+	return fmt.Sprintf("%s: transition from %v to %v rejected: %s", e.Machine, e.From, e.To, e.Err)
+}
+
+func (e *GuardError[S]) Unwrap() []error {
+	return []error{ErrGuardRejected, e.Err}
+}
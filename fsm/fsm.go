@@ -0,0 +1,135 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Guard decides whether a transition is allowed for a given entity. A non-nil error rejects the transition.
+type Guard[S comparable, T any] func(ctx context.Context, entity T, from, to S) error
+
+// Hook runs around a transition. Hooks registered with Machine.Before can abort the transition by returning an
+// error. Errors returned by hooks registered with Machine.After are returned to the caller, once the transition
+// is applied.
+type Hook[S comparable, T any] func(ctx context.Context, entity T, from, to S) error
+
+// Transition declares that an entity may move from any of the From states to the To state.
+type Transition[S comparable, T any] struct {
+	From  []S
+	To    S
+	Guard Guard[S, T]
+}
+
+// Machine holds the lifecycle of an entity, such as the status of a subscription.
+//
+//	var subscriptionFSM = fsm.New[Status, *Subscription]("subscription",
+//		fsm.Transition[Status, *Subscription]{From: []Status{Trial}, To: Active},
+//		fsm.Transition[Status, *Subscription]{From: []Status{Trial, Active}, To: Canceled},
+//	)
+//
+//	err := subscriptionFSM.Apply(ctx, sub, sub.Status, Canceled, func(ctx context.Context) error {
+//		sub.Status = Canceled
+//		return repository.Update(ctx, sub)
+//	})
+type Machine[S comparable, T any] struct {
+	name        string
+	transitions map[S]map[S]Transition[S, T]
+	before      []Hook[S, T]
+	after       []Hook[S, T]
+}
+
+// New creates a state machine. The name is used in error messages.
+func New[S comparable, T any](name string, transitions ...Transition[S, T]) *Machine[S, T] {
+	machine := &Machine[S, T]{
+		name:        name,
+		transitions: make(map[S]map[S]Transition[S, T]),
+	}
+
+	for _, transition := range transitions {
+		for _, from := range transition.From {
+			if _, ok := machine.transitions[from]; !ok {
+				machine.transitions[from] = make(map[S]Transition[S, T])
+			}
+
+			if _, ok := machine.transitions[from][transition.To]; ok {
+				panic(fmt.Sprintf("fsm %s: duplicate transition from %v to %v", name, from, transition.To))
+			}
+
+			machine.transitions[from][transition.To] = transition
+		}
+	}
+
+	return machine
+}
+
+// Before registers a hook run before every transition, once guards passed.
+func (m *Machine[S, T]) Before(hook Hook[S, T]) *Machine[S, T] {
+	m.before = append(m.before, hook)
+	return m
+}
+
+// After registers a hook run after every successful transition, to emit events or write audit logs.
+func (m *Machine[S, T]) After(hook Hook[S, T]) *Machine[S, T] {
+	m.after = append(m.after, hook)
+	return m
+}
+
+// Can returns true if a transition is declared between two states. Guards are not evaluated.
+func (m *Machine[S, T]) Can(from, to S) bool {
+	_, ok := m.transitions[from][to]
+	return ok
+}
+
+// Next returns the states reachable from the given state.
+func (m *Machine[S, T]) Next(from S) []S {
+	out := make([]S, 0, len(m.transitions[from]))
+	for to := range m.transitions[from] {
+		out = append(out, to)
+	}
+
+	return out
+}
+
+// Check validates a transition for an entity, including guards, without applying it.
+func (m *Machine[S, T]) Check(ctx context.Context, entity T, from, to S) error {
+	transition, ok := m.transitions[from][to]
+	if !ok {
+		return &InvalidTransitionError[S]{Machine: m.name, From: from, To: to}
+	}
+
+	if transition.Guard != nil {
+		if err := transition.Guard(ctx, entity, from, to); err != nil {
+			return &GuardError[S]{Machine: m.name, From: from, To: to, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Apply validates a transition, then calls apply to perform it (update the entity, persist it). Before hooks run
+// prior to apply, and after hooks run only if apply succeeded.
+func (m *Machine[S, T]) Apply(ctx context.Context, entity T, from, to S, apply func(ctx context.Context) error) error {
+	if err := m.Check(ctx, entity, from, to); err != nil {
+		return err
+	}
+
+	for _, hook := range m.before {
+		if err := hook(ctx, entity, from, to); err != nil {
+			return err
+		}
+	}
+
+	if apply != nil {
+		if err := apply(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range m.after {
+		if err := hook(ctx, entity, from, to); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package grpcrecord
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/protoutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"regexp"
+	"time"
+)
+
+// SessionMetadataKey carries the recording session to downstream services, so their own recorder captures the
+// rest of the call tree. Downstream services only honor the sessions started by a recorder for a target.
+const SessionMetadataKey = "x-inrich-recording-session"
+
+// sessionPattern validates sessions, which end up in the names of the recordings.
+var sessionPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+const (
+	KindServer = "server"
+	KindClient = "client"
+)
+
+// Record is a recorded RPC, with redacted payloads.
+type Record struct {
+	Session   string         `json:"session"`
+	Service   string         `json:"service"`
+	Kind      string         `json:"kind"`
+	Method    string         `json:"method"`
+	RequestID string         `json:"requestID,omitempty"`
+	User      string         `json:"user,omitempty"`
+	Request   map[string]any `json:"request,omitempty"`
+	Response  map[string]any `json:"response,omitempty"`
+	Code      string         `json:"code"`
+	Error     string         `json:"error,omitempty"`
+	StartedAt time.Time      `json:"startedAt"`
+	Duration  string         `json:"duration"`
+}
+
+type RecorderConfig struct {
+	// Service is the name of the current service, stored in the records.
+	Service string
+	// Renderer redacts the payloads. Defaults to a renderer that only redacts fields with the debug_redact option.
+	Renderer *protoutil.Renderer
+	// RequestIDKey is the incoming metadata key holding the request ID. Defaults to "x-request-id".
+	RequestIDKey string
+	// User extracts the user performing the RPC from the context. Optional.
+	User func(ctx context.Context) string
+	// WriteTimeout bounds the upload of a record. Defaults to 10 seconds.
+	WriteTimeout time.Duration
+	// SessionTTL is how long a session started by a targeted RPC is honored by downstream services. It must cover
+	// the call tree of the RPC. Defaults to 5 minutes.
+	SessionTTL time.Duration
+}
+
+func (c RecorderConfig) withDefaults() RecorderConfig {
+	if c.Renderer == nil {
+		c.Renderer = protoutil.NewRenderer(protoutil.RenderConfig{})
+	}
+	if c.RequestIDKey == "" {
+		c.RequestIDKey = "x-request-id"
+	}
+	if c.User == nil {
+		c.User = func(context.Context) string { return "" }
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+	if c.SessionTTL <= 0 {
+		c.SessionTTL = 5 * time.Minute
+	}
+
+	return c
+}
+
+type sessionKey struct{}
+
+// Recorder records the RPCs of targeted requests and users. Targets are looked up in their store on every RPC.
+// Records are uploaded in the background, and never delay or fail the RPC.
+//
+//	targets := grpcrecord.NewTargets(grpcrecord.NewRedisStore(redisClient, "grpcrecord:"))
+//	recorder := grpcrecord.NewRecorder(sink, targets, logger, grpcrecord.RecorderConfig{Service: "notes"})
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(recorder.UnaryServerInterceptor()))
+//	conn, _ := grpc.NewClient(host, grpc.WithChainUnaryInterceptor(recorder.UnaryClientInterceptor()))
+type Recorder struct {
+	sink    Sink
+	targets *Targets
+	logger  monitor.Logger
+	config  RecorderConfig
+}
+
+func NewRecorder(sink Sink, targets *Targets, logger monitor.Logger, config RecorderConfig) *Recorder {
+	return &Recorder{
+		sink:    sink,
+		targets: targets,
+		logger:  logger,
+		config:  config.withDefaults(),
+	}
+}
+
+// SessionFromContext returns the recording session of the current call, or an empty string if the call is not
+// recorded.
+func SessionFromContext(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey{}).(string)
+	return session
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func newSession() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// session returns the recording session of an incoming RPC: the session received from the caller when a recorder
+// started it, or a new session when the RPC matches a target. It returns an empty string if the RPC is not recorded.
+func (r *Recorder) session(ctx context.Context, md metadata.MD, requestID, user string) (string, error) {
+	if r.targets == nil {
+		return "", nil
+	}
+
+	// Sessions sent by arbitrary clients are ignored, so they cannot trigger recordings.
+	session := firstMetadata(md, SessionMetadataKey)
+	if sessionPattern.MatchString(session) {
+		if ok, err := r.targets.Session(ctx, session); ok || err != nil {
+			return session, err
+		}
+	}
+
+	if ok, err := r.targets.Match(ctx, requestID, user); !ok || err != nil {
+		return "", err
+	}
+
+	session = requestID
+	if !sessionPattern.MatchString(session) {
+		session = newSession()
+	}
+
+	return session, r.targets.StartSession(ctx, session, r.config.SessionTTL)
+}
+
+// UnaryServerInterceptor records RPCs received from a recorded session, or matching a target.
+func (r *Recorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestID := firstMetadata(md, r.config.RequestIDKey)
+		user := r.config.User(ctx)
+
+		session, err := r.session(ctx, md, requestID, user)
+		if err != nil {
+			r.logger.Error(err, fmt.Sprintf("[grpcrecord] failed to look up the recording targets of %s", info.FullMethod))
+			return handler(ctx, req)
+		}
+		if session == "" {
+			return handler(ctx, req)
+		}
+
+		ctx = context.WithValue(ctx, sessionKey{}, session)
+		ctx = metadata.AppendToOutgoingContext(ctx, SessionMetadataKey, session)
+
+		startedAt := time.Now()
+		res, err := handler(ctx, req)
+		r.write(ctx, &Record{
+			Session:   session,
+			Kind:      KindServer,
+			Method:    info.FullMethod,
+			RequestID: requestID,
+			User:      user,
+		}, startedAt, req, res, err)
+
+		return res, err
+	}
+}
+
+// UnaryClientInterceptor records the downstream calls performed while handling a recorded RPC.
+func (r *Recorder) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		session := SessionFromContext(ctx)
+		if session == "" {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if len(md.Get(SessionMetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, SessionMetadataKey, session)
+		}
+
+		startedAt := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		r.write(ctx, &Record{Session: session, Kind: KindClient, Method: method}, startedAt, req, reply, err)
+
+		return err
+	}
+}
+
+func (r *Recorder) write(ctx context.Context, record *Record, startedAt time.Time, req, res any, err error) {
+	record.Service = r.config.Service
+	record.StartedAt = startedAt
+	record.Duration = time.Since(startedAt).String()
+	record.Code = status.Code(err).String()
+
+	if err != nil {
+		record.Error = status.Convert(err).Message()
+	}
+	if msg, ok := req.(proto.Message); ok {
+		record.Request = r.config.Renderer.Map(msg)
+	}
+	if msg, ok := res.(proto.Message); ok && err == nil {
+		record.Response = r.config.Renderer.Map(msg)
+	}
+
+	go func() {
+		writeCTX, cancel := ctxutil.DetachWithTimeout(ctx, r.config.WriteTimeout)
+		defer cancel()
+
+		if err := r.sink.Write(writeCTX, record); err != nil {
+			r.logger.Error(err, fmt.Sprintf("[grpcrecord] failed to write record of %s", record.Method))
+		}
+	}()
+}
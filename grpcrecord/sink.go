@@ -0,0 +1,65 @@
+package grpcrecord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	"strings"
+)
+
+var ErrInvalidSession = errors.New("invalid recording session")
+
+// Sink stores recorded RPCs.
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+}
+
+type gcsSink struct {
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func (s *gcsSink) Write(ctx context.Context, record *Record) error {
+	if !sessionPattern.MatchString(record.Session) {
+		return fmt.Errorf("%w: %q", ErrInvalidSession, record.Session)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	// Objects of a session are listed in chronological order.
+	name := fmt.Sprintf(
+		"%s%s/%s-%s-%s.json",
+		s.prefix, record.Session, record.StartedAt.UTC().Format("20060102T150405.000000000"), record.Kind,
+		strings.ReplaceAll(strings.TrimPrefix(record.Method, "/"), "/", "."),
+	)
+
+	_, err = s.service.Objects.
+		Insert(s.bucket, &storage.Object{Name: name, ContentType: "application/json"}).
+		Media(bytes.NewReader(data)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("upload recording %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// NewGCSSink creates a sink that uploads one JSON object per RPC to a GCS bucket, grouped by session under the
+// given prefix. Configure a lifecycle rule on the bucket, so recordings are not kept forever.
+func NewGCSSink(ctx context.Context, bucket, prefix string, opts ...option.ClientOption) (Sink, error) {
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create storage client: %w", err)
+	}
+
+	return &gcsSink{service: service, bucket: bucket, prefix: prefix}, nil
+}
@@ -0,0 +1,88 @@
+package grpcrecord
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store holds the recording targets and sessions until they expire. Use a store shared by every instance of every
+// recorded service, such as NewRedisStore, so a target enabled on one instance applies to all of them.
+type Store interface {
+	// Set stores a key, until the ttl expires.
+	Set(ctx context.Context, key string, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Active returns true if the key is stored and did not expire.
+	Active(ctx context.Context, key string) (bool, error)
+	// List returns the expiration of the stored keys starting with the prefix.
+	List(ctx context.Context, prefix string) (map[string]time.Time, error)
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+	s.keys[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.keys, key)
+	}
+	return nil
+}
+
+func (s *memoryStore) Active(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.keys[key]
+	if ok && time.Now().After(expiresAt) {
+		delete(s.keys, key)
+		return false, nil
+	}
+
+	return ok, nil
+}
+
+func (s *memoryStore) List(_ context.Context, prefix string) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+
+	keys := make(map[string]time.Time)
+	for key, expiresAt := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys[key] = expiresAt
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *memoryStore) evict() {
+	now := time.Now()
+	for key, expiresAt := range s.keys {
+		if now.After(expiresAt) {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// NewMemoryStore creates a store local to the current process. Targets enabled on an instance only apply to that
+// instance, and downstream services ignore its sessions: only use it for local development and tests.
+func NewMemoryStore() Store {
+	return &memoryStore{keys: make(map[string]time.Time)}
+}
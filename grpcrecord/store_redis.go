@@ -0,0 +1,59 @@
+package grpcrecord
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+// globEscaper escapes the special characters of the patterns of SCAN.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, 1, ttl).Err()
+}
+
+func (s *redisStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+
+	return s.client.Del(ctx, prefixed...).Err()
+}
+
+func (s *redisStore) Active(ctx context.Context, key string) (bool, error) {
+	count, err := s.client.Exists(ctx, s.prefix+key).Result()
+	return count > 0, err
+}
+
+func (s *redisStore) List(ctx context.Context, prefix string) (map[string]time.Time, error) {
+	keys := make(map[string]time.Time)
+	now := time.Now()
+
+	iter := s.client.Scan(ctx, 0, globEscaper.Replace(s.prefix+prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		ttl, err := s.client.PTTL(ctx, iter.Val()).Result()
+		if err != nil {
+			return nil, err
+		}
+		// Keys that expired since the scan are skipped.
+		if ttl > 0 {
+			keys[strings.TrimPrefix(iter.Val(), s.prefix)] = now.Add(ttl)
+		}
+	}
+
+	return keys, iter.Err()
+}
+
+// NewRedisStore creates a store shared by every service connected to the same Redis server. Keys are prefixed with
+// the given prefix, such as "grpcrecord:".
+func NewRedisStore(client redis.UniversalClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
@@ -0,0 +1,190 @@
+package grpcrecord
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/in-rich/lib-go/introspect"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Target is a request ID or a user whose RPCs are recorded, until the target expires.
+type Target struct {
+	RequestID string    `json:"requestID,omitempty"`
+	User      string    `json:"user,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Keys of the targets and sessions in the store.
+const (
+	requestPrefix = "request:"
+	userPrefix    = "user:"
+	sessionPrefix = "session:"
+)
+
+// Targets lists the requests and users being recorded, and the sessions started for them. Recording is opt-in:
+// nothing is recorded while the list is empty. Targets and sessions are kept in the store, so every instance
+// sharing the store applies them.
+type Targets struct {
+	store Store
+}
+
+func NewTargets(store Store) *Targets {
+	return &Targets{store: store}
+}
+
+// EnableRequest records the RPCs of the given request ID, for the given duration.
+func (t *Targets) EnableRequest(ctx context.Context, requestID string, ttl time.Duration) error {
+	return t.store.Set(ctx, requestPrefix+requestID, ttl)
+}
+
+// EnableUser records the RPCs of the given user, for the given duration.
+func (t *Targets) EnableUser(ctx context.Context, user string, ttl time.Duration) error {
+	return t.store.Set(ctx, userPrefix+user, ttl)
+}
+
+// Disable stops recording the given request ID or user. Empty values are ignored.
+func (t *Targets) Disable(ctx context.Context, requestID, user string) error {
+	keys := make([]string, 0, 2)
+	if requestID != "" {
+		keys = append(keys, requestPrefix+requestID)
+	}
+	if user != "" {
+		keys = append(keys, userPrefix+user)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return t.store.Delete(ctx, keys...)
+}
+
+// Match returns true if the request ID or the user is being recorded.
+func (t *Targets) Match(ctx context.Context, requestID, user string) (bool, error) {
+	if requestID != "" {
+		if ok, err := t.store.Active(ctx, requestPrefix+requestID); ok || err != nil {
+			return ok, err
+		}
+	}
+	if user != "" {
+		return t.store.Active(ctx, userPrefix+user)
+	}
+
+	return false, nil
+}
+
+// StartSession registers a recording session started for a target, for the given duration.
+func (t *Targets) StartSession(ctx context.Context, session string, ttl time.Duration) error {
+	return t.store.Set(ctx, sessionPrefix+session, ttl)
+}
+
+// Session returns true if the recording session was started for a target, and did not expire.
+func (t *Targets) Session(ctx context.Context, session string) (bool, error) {
+	return t.store.Active(ctx, sessionPrefix+session)
+}
+
+// List returns the active targets.
+func (t *Targets) List(ctx context.Context) ([]Target, error) {
+	requests, err := t.store.List(ctx, requestPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := t.store.List(ctx, userPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Target, 0, len(requests)+len(users))
+	for key, expiresAt := range requests {
+		targets = append(targets, Target{RequestID: strings.TrimPrefix(key, requestPrefix), ExpiresAt: expiresAt})
+	}
+	for key, expiresAt := range users {
+		targets = append(targets, Target{User: strings.TrimPrefix(key, userPrefix), ExpiresAt: expiresAt})
+	}
+
+	return targets, nil
+}
+
+type enableRequest struct {
+	RequestID string `json:"requestID"`
+	User      string `json:"user"`
+	// TTL is a Go duration string. Defaults to 1 hour.
+	TTL string `json:"ttl"`
+}
+
+// Mount registers the admin endpoint of the targets on the mux, at prefix + "/recording":
+//
+//	GET    lists the active targets.
+//	POST   {"requestID": "...", "user": "...", "ttl": "30m"} enables recording.
+//	DELETE ?requestID=...&user=... disables recording.
+//
+// Recordings contain user data, so the endpoint is always protected by the allowlist.
+func (t *Targets) Mount(mux *http.ServeMux, prefix string, allowlist *introspect.IPAllowlist) {
+	if allowlist == nil {
+		panic("grpcrecord: an IP allowlist is required to mount the recording endpoint")
+	}
+
+	mux.Handle(strings.TrimSuffix(prefix, "/")+"/recording", allowlist.Middleware(http.HandlerFunc(t.serveHTTP)))
+}
+
+func (t *Targets) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		targets, err := t.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(targets)
+	case http.MethodPost:
+		body := new(enableRequest)
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if body.RequestID == "" && body.User == "" {
+			http.Error(w, "requestID or user is required", http.StatusBadRequest)
+			return
+		}
+
+		ttl := time.Hour
+		if body.TTL != "" {
+			parsed, err := time.ParseDuration(body.TTL)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "invalid ttl", http.StatusBadRequest)
+				return
+			}
+			ttl = parsed
+		}
+
+		if body.RequestID != "" {
+			if err := t.EnableRequest(r.Context(), body.RequestID, ttl); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if body.User != "" {
+			if err := t.EnableUser(r.Context(), body.User, ttl); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := t.Disable(r.Context(), r.URL.Query().Get("requestID"), r.URL.Query().Get("user")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint token")
+
+// ServerStream is the sending side of a server-streaming GRPC call, as generated by the protoc compiler for Go.
+type ServerStream[T any] interface {
+	Send(*T) error
+	Context() context.Context
+}
+
+type checkpoint struct {
+	// After is the key of the last item sent.
+	After string `json:"a"`
+	// Sent is the number of items sent since the beginning of the export.
+	Sent int64 `json:"s"`
+}
+
+func encodeCheckpoint(value checkpoint) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCheckpoint(token string) (checkpoint, error) {
+	var value checkpoint
+	if token == "" {
+		return value, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return value, ErrInvalidCheckpoint
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, ErrInvalidCheckpoint
+	}
+
+	return value, nil
+}
+
+// Export streams a large dataset to a client, with periodic checkpoint tokens. A client that gets disconnected
+// sends the last token it received to resume the export right after the items it already received.
+//
+//	func (h *handler) ExportNotes(in *pb.ExportNotesRequest, stream pb.Notes_ExportNotesServer) error {
+//		return handlers.Export[*entities.Note, pb.ExportNotesResponse]{
+//			Fetch: func(ctx context.Context, after string, limit int) ([]*entities.Note, error) {
+//				return h.repository.ListNotesAfter(ctx, in.GetAuthorId(), after, limit)
+//			},
+//			Key: func(note *entities.Note) string { return note.ID },
+//			Message: func(note *entities.Note) (*pb.ExportNotesResponse, error) {
+//				return &pb.ExportNotesResponse{Note: toProto(note)}, nil
+//			},
+//			Checkpoint: func(token string) *pb.ExportNotesResponse {
+//				return &pb.ExportNotesResponse{Checkpoint: token}
+//			},
+//			Limiter: exportLimiter,
+//		}.Run(stream, in.GetResumeToken())
+//	}
+//
+// Checkpoint tokens only hold the position in the dataset: Fetch must still filter the items the caller is
+// allowed to read.
+type Export[Item any, Msg any] struct {
+	// Fetch returns the items following the given key, ordered by key, up to limit items. The key is empty for the
+	// first batch. Fetch returns less than limit items once the dataset is exhausted.
+	Fetch func(ctx context.Context, after string, limit int) ([]Item, error)
+	// Key returns the key of an item, as passed to Fetch.
+	Key func(item Item) string
+	// Message converts an item to a stream message.
+	Message func(item Item) (*Msg, error)
+	// Checkpoint creates the stream message carrying a checkpoint token.
+	Checkpoint func(token string) *Msg
+
+	// BatchSize is the number of items fetched at once. Defaults to 500.
+	BatchSize int
+	// CheckpointInterval is the minimum delay between two checkpoints. A checkpoint is always sent after the last
+	// item. Defaults to 5 seconds.
+	CheckpointInterval time.Duration
+	// Limiter throttles the batches fetched, to avoid saturating the database. Share the limiter between the
+	// exports of a service to bound their total load. Optional.
+	Limiter ratelimit.Limiter
+	// LimiterKey is the key of the limiter. Defaults to "export".
+	LimiterKey string
+}
+
+// Run streams the export, starting after the given checkpoint token. An empty token starts from the beginning.
+func (e Export[Item, Msg]) Run(stream ServerStream[Msg], resume string) error {
+	if e.BatchSize <= 0 {
+		e.BatchSize = 500
+	}
+	if e.CheckpointInterval <= 0 {
+		e.CheckpointInterval = 5 * time.Second
+	}
+	if e.LimiterKey == "" {
+		e.LimiterKey = "export"
+	}
+
+	position, err := decodeCheckpoint(resume)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	lastCheckpoint := time.Now()
+
+	for {
+		if e.Limiter != nil {
+			if err := ratelimit.Wait(ctx, e.Limiter, e.LimiterKey); err != nil {
+				return err
+			}
+		}
+
+		items, err := e.Fetch(ctx, position.After, e.BatchSize)
+		if err != nil {
+			return fmt.Errorf("fetch items after %q: %w", position.After, err)
+		}
+
+		for _, item := range items {
+			msg, err := e.Message(item)
+			if err != nil {
+				return fmt.Errorf("convert item %s: %w", e.Key(item), err)
+			}
+
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+
+			position.After = e.Key(item)
+			position.Sent++
+		}
+
+		done := len(items) < e.BatchSize
+		if done || time.Since(lastCheckpoint) >= e.CheckpointInterval {
+			if err := stream.Send(e.Checkpoint(encodeCheckpoint(position))); err != nil {
+				return err
+			}
+
+			lastCheckpoint = time.Now()
+		}
+
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"strings"
+)
+
+// WildcardPath is the path of update masks replacing the whole resource.
+const WildcardPath = "*"
+
+// resolvePath returns the fields of a path, such as "author.name". Read masks can go through repeated and map
+// fields ("notes.title" selects the title of every note), update masks can't.
+func resolvePath(
+	descriptor protoreflect.MessageDescriptor, path string, throughRepeated bool,
+) ([]protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	fields := make([]protoreflect.FieldDescriptor, 0, len(names))
+
+	for i, name := range names {
+		if descriptor == nil {
+			return nil, fmt.Errorf("%s is not a message", strings.Join(names[:i], "."))
+		}
+
+		field := descriptor.Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			return nil, fmt.Errorf("unknown field %s in %s", name, descriptor.FullName())
+		}
+		fields = append(fields, field)
+
+		switch {
+		case field.IsMap():
+			descriptor = field.MapValue().Message()
+		default:
+			descriptor = field.Message()
+		}
+
+		if (field.IsList() || field.IsMap()) && !throughRepeated && i < len(names)-1 {
+			return nil, fmt.Errorf("%s is repeated, only the whole field can be updated", name)
+		}
+	}
+
+	return fields, nil
+}
+
+func invalidMask(name string, violations []*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		descriptions = append(descriptions, violation.GetDescription())
+	}
+
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", name, strings.Join(descriptions, ", ")))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		return detailed.Err()
+	}
+
+	return st.Err()
+}
+
+func validateMask(mask *fieldmaskpb.FieldMask, msg proto.Message, name string, throughRepeated bool) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	for _, path := range mask.GetPaths() {
+		if path == WildcardPath && !throughRepeated && len(mask.GetPaths()) == 1 {
+			continue
+		}
+
+		if _, err := resolvePath(msg.ProtoReflect().Descriptor(), path, throughRepeated); err != nil {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       name,
+				Description: fmt.Sprintf("%q: %s", path, err),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return invalidMask(name, violations)
+	}
+
+	return nil
+}
+
+// ValidateUpdateMask checks the paths of the update mask of a request against the updated resource, and returns
+// an InvalidArgument error listing every unknown path. An empty mask, or a mask with the single "*" path, updates
+// the whole resource.
+//
+//	if err := handlers.ValidateUpdateMask(in.GetUpdateMask(), in.GetNote()); err != nil {
+//		return nil, err
+//	}
+func ValidateUpdateMask(mask *fieldmaskpb.FieldMask, resource proto.Message) error {
+	return validateMask(mask, resource, "update_mask", false)
+}
+
+// ApplyUpdate sets the fields of the mask in the stored resource to their value in the update. Fields of the mask
+// unset in the update are cleared. An empty mask or "*" replaces the whole resource. The mask must be validated
+// with ValidateUpdateMask first.
+//
+//	note, err := repository.GetNote(ctx, in.GetNote().GetId())
+//	handlers.ApplyUpdate(note, in.GetNote(), in.GetUpdateMask())
+//	err = repository.SaveNote(ctx, note)
+func ApplyUpdate(stored, update proto.Message, mask *fieldmaskpb.FieldMask) {
+	paths := mask.GetPaths()
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == WildcardPath) {
+		proto.Reset(stored)
+		proto.Merge(stored, update)
+		return
+	}
+
+	// Values are copied without sharing memory with the update.
+	update = proto.Clone(update)
+
+	for _, path := range paths {
+		fields, err := resolvePath(stored.ProtoReflect().Descriptor(), path, false)
+		if err != nil {
+			continue
+		}
+
+		dst, src := stored.ProtoReflect(), update.ProtoReflect()
+		for _, field := range fields[:len(fields)-1] {
+			dst = dst.Mutable(field).Message()
+			src = src.Get(field).Message()
+		}
+
+		leaf := fields[len(fields)-1]
+		if src.Has(leaf) {
+			dst.Set(leaf, src.Get(leaf))
+		} else {
+			dst.Clear(leaf)
+		}
+	}
+}
+
+type maskNode struct {
+	// all keeps the whole field.
+	all      bool
+	children map[protoreflect.Name]*maskNode
+}
+
+func (n *maskNode) add(fields []protoreflect.FieldDescriptor) {
+	for _, field := range fields {
+		if n.all {
+			return
+		}
+		if n.children == nil {
+			n.children = make(map[protoreflect.Name]*maskNode)
+		}
+
+		child, ok := n.children[field.Name()]
+		if !ok {
+			child = new(maskNode)
+			n.children[field.Name()] = child
+		}
+		n = child
+	}
+
+	n.all, n.children = true, nil
+}
+
+func (n *maskNode) prune(msg protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		child, ok := n.children[field.Name()]
+		switch {
+		case !ok:
+			cleared = append(cleared, field)
+		case child.all:
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				child.prune(list.Get(i).Message())
+			}
+		case field.IsMap():
+			value.Map().Range(func(_ protoreflect.MapKey, entry protoreflect.Value) bool {
+				child.prune(entry.Message())
+				return true
+			})
+		default:
+			child.prune(value.Message())
+		}
+
+		return true
+	})
+
+	for _, field := range cleared {
+		msg.Clear(field)
+	}
+}
+
+// ApplyReadMask clears the fields of the response outside the read mask supplied by the client, for lighter
+// responses. Paths can select fields of repeated and map fields ("notes.title"). An empty mask keeps the whole
+// response, and unknown paths are rejected with InvalidArgument, before the response is modified.
+//
+//	res := &notes_pb.ListNotesResponse{Notes: notes}
+//	if err := handlers.ApplyReadMask(in.GetReadMask(), res); err != nil {
+//		return nil, err
+//	}
+func ApplyReadMask(mask *fieldmaskpb.FieldMask, response proto.Message) error {
+	if len(mask.GetPaths()) == 0 {
+		return nil
+	}
+
+	if err := validateMask(mask, response, "read_mask", true); err != nil {
+		return err
+	}
+
+	root := new(maskNode)
+	for _, path := range mask.GetPaths() {
+		fields, _ := resolvePath(response.ProtoReflect().Descriptor(), path, true)
+		root.add(fields)
+	}
+
+	root.prune(response.ProtoReflect())
+	return nil
+}
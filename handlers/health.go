@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/samber/lo"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+type HealthConfig struct {
+	// Interval between two runs of the dependency checks. Results are shared by every call in the meantime, so
+	// watchers do not multiply the checks. Defaults to 5 seconds.
+	Interval time.Duration
+}
+
+func (c HealthConfig) withDefaults() HealthConfig {
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+	}
+
+	return c
+}
+
+// HealthHandler implements the GRPC health service from a dependency check: a service is serving when all of its
+// dependencies are, and the server as a whole ("") when every dependency is.
+//
+//	healthpb.RegisterHealthServer(server, handlers.NewHealthHandler(depsCheck, handlers.HealthConfig{}))
+//
+// Unlike the health service registered by deploy.StartGRPCServer, dependency failures do not stop the process:
+// they are only reported. Do not use both on the same server.
+type HealthHandler struct {
+	healthpb.UnimplementedHealthServer
+
+	depsCheck deploy.DepsCheck
+	config    HealthConfig
+
+	mu        sync.Mutex
+	statuses  map[string]healthpb.HealthCheckResponse_ServingStatus
+	checkedAt time.Time
+}
+
+func NewHealthHandler(depsCheck deploy.DepsCheck, config HealthConfig) *HealthHandler {
+	return &HealthHandler{depsCheck: depsCheck, config: config.withDefaults()}
+}
+
+// status returns the status of a service, running the checks again if the last results are too old.
+func (h *HealthHandler) status(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.statuses == nil || time.Since(h.checkedAt) >= h.config.Interval {
+		h.refresh()
+	}
+
+	serving, ok := h.statuses[service]
+	return serving, ok
+}
+
+// refresh runs the checks. The caller must hold the lock.
+func (h *HealthHandler) refresh() {
+	var dependencies map[string]error
+	if h.depsCheck.Dependencies != nil {
+		dependencies = h.depsCheck.Dependencies()
+	}
+
+	statuses := make(map[string]healthpb.HealthCheckResponse_ServingStatus, len(h.depsCheck.Services)+1)
+
+	global := true
+	for _, err := range dependencies {
+		if err != nil {
+			global = false
+		}
+	}
+	statuses[""] = lo.Ternary(global, healthpb.HealthCheckResponse_SERVING, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	for service, serviceDeps := range h.depsCheck.Services {
+		_, hasError := lo.Find(serviceDeps, func(item string) bool {
+			return dependencies[item] != nil
+		})
+
+		statuses[service] = lo.Ternary(
+			hasError, healthpb.HealthCheckResponse_NOT_SERVING, healthpb.HealthCheckResponse_SERVING,
+		)
+	}
+
+	h.statuses = statuses
+	h.checkedAt = time.Now()
+}
+
+func (h *HealthHandler) Check(_ context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	serving, ok := h.status(in.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", in.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{Status: serving}, nil
+}
+
+// Watch sends the current status of the service right away, then every change of status, until the client
+// cancels the call. Unknown services are reported as SERVICE_UNKNOWN.
+func (h *HealthHandler) Watch(in *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		serving, ok := h.status(in.GetService())
+		if !ok {
+			serving = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		if serving != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: serving}); err != nil {
+				return err
+			}
+
+			last = serving
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package healthprobe
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/in-rich/lib-go/cli"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"strings"
+	"time"
+)
+
+var ErrNotServing = errors.New("service is not serving")
+
+// Exit codes of the health command, so orchestrators can tell a failing service from a failing probe.
+const (
+	// ExitConnection is returned when the server cannot be reached.
+	ExitConnection = 3
+	// ExitUnhealthy is returned when the server reports a status other than SERVING.
+	ExitUnhealthy = 4
+)
+
+// TLS modes of the probe connection.
+const (
+	// TLSAuto connects without TLS to loopback addresses, and like deploy.OpenGRPCConn otherwise.
+	TLSAuto = "auto"
+	// TLSOn connects like deploy.OpenGRPCConn in release environments: with TLS and an identity token.
+	TLSOn = "on"
+	// TLSOff always connects without TLS.
+	TLSOff = "off"
+)
+
+// Dial opens the connection used to probe a server.
+func Dial(logger monitor.Logger, addr, mode string) (*grpc.ClientConn, error) {
+	switch mode {
+	case TLSOff:
+		return grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	case TLSOn:
+		return deploy.OpenGRPCConn(logger, addr), nil
+	case TLSAuto, "":
+		if isLoopback(addr) {
+			return Dial(logger, addr, TLSOff)
+		}
+
+		return Dial(logger, addr, TLSOn)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q", mode)
+	}
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Check queries the GRPC health service of the connection, and returns ErrNotServing unless the service reports
+// SERVING. An empty service checks the overall status of the server.
+func Check(ctx context.Context, conn *grpc.ClientConn, service string) error {
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return cli.Exit(ExitConnection, fmt.Errorf("health check failed: %w", err))
+	}
+
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return cli.Exit(ExitUnhealthy, fmt.Errorf("%w: %s", ErrNotServing, res.GetStatus()))
+	}
+
+	return nil
+}
+
+// Command creates a health command, for services to embed in their binary, so container images without shell
+// can declare a health check:
+//
+//	HEALTHCHECK CMD ["/service", "health", "-addr", "localhost:50051"]
+func Command[Cfg any]() cli.Command[Cfg] {
+	var (
+		addr    string
+		service string
+		mode    string
+		timeout time.Duration
+	)
+
+	return cli.Command[Cfg]{
+		Name:  "health",
+		Usage: "check the GRPC health service of a running server",
+		Flags: func(flags *flag.FlagSet) {
+			flags.StringVar(&addr, "addr", "localhost:50051", "address of the server")
+			flags.StringVar(&service, "service", "", "name of the service to check, empty for the whole server")
+			flags.StringVar(&mode, "tls", TLSAuto, "TLS mode: auto, on, or off")
+			flags.DurationVar(&timeout, "timeout", 5*time.Second, "timeout of the check")
+		},
+		Run: func(ctx *cli.Context[Cfg]) error {
+			conn, err := Dial(ctx.Logger, addr, mode)
+			if err != nil {
+				return cli.UsageError("%s", err)
+			}
+			defer deploy.CloseGRPCConn(conn)
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return Check(checkCtx, conn, service)
+		},
+	}
+}
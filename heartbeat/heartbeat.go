@@ -0,0 +1,231 @@
+package heartbeat
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/monitor"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Interval between two heartbeats. Defaults to 30 seconds.
+	Interval time.Duration
+	// Instance identifies the instance. Defaults to the hostname.
+	Instance string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.Instance == "" {
+		c.Instance, _ = os.Hostname()
+	}
+
+	return c
+}
+
+// Worker reports the progress of a background worker.
+type Worker struct {
+	name string
+
+	mu         sync.Mutex
+	progressAt time.Time
+	detail     string
+}
+
+// Progress records that the worker made progress, such as processing an item.
+func (w *Worker) Progress() {
+	w.mu.Lock()
+	w.progressAt = time.Now()
+	w.mu.Unlock()
+}
+
+// ProgressDetail records progress, with a detail shown in the dashboard, such as the item being processed.
+func (w *Worker) ProgressDetail(detail string) {
+	w.mu.Lock()
+	w.progressAt = time.Now()
+	w.detail = detail
+	w.mu.Unlock()
+}
+
+// Publisher periodically publishes the heartbeats of the workers of the instance, so stuck workers are detected
+// from the ops dashboard.
+//
+//	publisher := heartbeat.NewPublisher(heartbeat.NewRedisStore(redisClient, "inrich:heartbeats"), logger, heartbeat.Config{})
+//	go publisher.Run(ctx)
+//
+//	worker := publisher.Worker("linkedin-sync")
+//	for account := range accounts {
+//		sync(ctx, account)
+//		worker.ProgressDetail(account.ID)
+//	}
+type Publisher struct {
+	store  Store
+	logger monitor.Logger
+	config Config
+
+	mu      sync.Mutex
+	workers map[string]*Worker
+}
+
+func NewPublisher(store Store, logger monitor.Logger, config Config) *Publisher {
+	return &Publisher{
+		store:   store,
+		logger:  logger,
+		config:  config.withDefaults(),
+		workers: make(map[string]*Worker),
+	}
+}
+
+// Worker returns the worker with the given name, registering it on first use. Workers start with progress at the
+// time they are registered.
+func (p *Publisher) Worker(name string) *Worker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	worker, ok := p.workers[name]
+	if !ok {
+		worker = &Worker{name: name, progressAt: time.Now()}
+		p.workers[name] = worker
+	}
+
+	return worker
+}
+
+// PublishOnce publishes the heartbeats of every worker.
+func (p *Publisher) PublishOnce(ctx context.Context) error {
+	p.mu.Lock()
+	workers := make([]*Worker, 0, len(p.workers))
+	for _, worker := range p.workers {
+		workers = append(workers, worker)
+	}
+	p.mu.Unlock()
+
+	if len(workers) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	beats := make([]Beat, 0, len(workers))
+
+	for _, worker := range workers {
+		worker.mu.Lock()
+		beats = append(beats, Beat{
+			Instance:   p.config.Instance,
+			Worker:     worker.name,
+			ProgressAt: worker.progressAt,
+			BeatAt:     now,
+			Detail:     worker.detail,
+		})
+		worker.mu.Unlock()
+	}
+
+	return p.store.Put(ctx, beats)
+}
+
+// Run publishes the heartbeats at every interval, until the context is canceled.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PublishOnce(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error(err, "[heartbeat] failed to publish heartbeats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type State string
+
+const (
+	StateHealthy State = "healthy"
+	// StateStuck is a worker whose instance publishes heartbeats, but which made no progress for too long.
+	StateStuck State = "stuck"
+	// StateDead is a worker whose instance stopped publishing heartbeats.
+	StateDead State = "dead"
+)
+
+// Status is the state of a worker on an instance, for the ops dashboard.
+type Status struct {
+	Beat
+	State State `json:"state"`
+}
+
+type QueryConfig struct {
+	// MaxIdle is the time without progress after which a worker is stuck. Defaults to 10 minutes.
+	MaxIdle time.Duration
+	// DeadAfter is the time without heartbeat after which a worker is dead. Defaults to 3 minutes, a few
+	// heartbeat intervals.
+	DeadAfter time.Duration
+	// Retention is the time after which dead workers are no longer listed. Defaults to 1 hour.
+	Retention time.Duration
+}
+
+func (c QueryConfig) withDefaults() QueryConfig {
+	if c.MaxIdle <= 0 {
+		c.MaxIdle = 10 * time.Minute
+	}
+	if c.DeadAfter <= 0 {
+		c.DeadAfter = 3 * time.Minute
+	}
+	if c.Retention <= 0 {
+		c.Retention = time.Hour
+	}
+
+	return c
+}
+
+// Query returns the state of the workers of every instance, stuck and dead workers first. Workers can be
+// filtered by name prefix.
+//
+//	statuses, err := heartbeat.Query(ctx, store, heartbeat.QueryConfig{MaxIdle: 5 * time.Minute}, "linkedin-")
+func Query(ctx context.Context, store Store, config QueryConfig, prefix string) ([]Status, error) {
+	config = config.withDefaults()
+	now := time.Now()
+
+	beats, err := store.List(ctx, now.Add(-config.Retention))
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(beats))
+	for _, beat := range beats {
+		if !strings.HasPrefix(beat.Worker, prefix) {
+			continue
+		}
+
+		state := StateHealthy
+		switch {
+		case now.Sub(beat.BeatAt) > config.DeadAfter:
+			state = StateDead
+		case now.Sub(beat.ProgressAt) > config.MaxIdle:
+			state = StateStuck
+		}
+
+		statuses = append(statuses, Status{Beat: beat, State: state})
+	}
+
+	rank := map[State]int{StateStuck: 0, StateDead: 1, StateHealthy: 2}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		if rank[a.State] != rank[b.State] {
+			return rank[a.State] - rank[b.State]
+		}
+		if a.Worker != b.Worker {
+			return strings.Compare(a.Worker, b.Worker)
+		}
+
+		return strings.Compare(a.Instance, b.Instance)
+	})
+
+	return statuses, nil
+}
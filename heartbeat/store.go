@@ -0,0 +1,159 @@
+package heartbeat
+
+import (
+	gcpfirestore "cloud.google.com/go/firestore"
+	"context"
+	"encoding/json"
+	"github.com/in-rich/lib-go/firestore"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+// Beat is the last heartbeat of a worker on an instance.
+type Beat struct {
+	Instance string `json:"instance" firestore:"instance"`
+	Worker   string `json:"worker" firestore:"worker"`
+	// ProgressAt is the last time the worker reported progress.
+	ProgressAt time.Time `json:"progressAt" firestore:"progressAt"`
+	// BeatAt is the time of the heartbeat. Instances that stopped publishing heartbeats are dead.
+	BeatAt time.Time `json:"beatAt" firestore:"beatAt"`
+	// Detail is the last detail reported by the worker, such as the account being synced. Optional.
+	Detail string `json:"detail,omitempty" firestore:"detail,omitempty"`
+}
+
+func (b Beat) id() string {
+	return b.Worker + "--" + b.Instance
+}
+
+// Store keeps the last heartbeat of every worker.
+type Store interface {
+	Put(ctx context.Context, beats []Beat) error
+	// List returns the heartbeats published after the given time.
+	List(ctx context.Context, since time.Time) ([]Beat, error)
+}
+
+type redisStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+func (s *redisStore) Put(ctx context.Context, beats []Beat) error {
+	values := make([]any, 0, 2*len(beats))
+	for _, beat := range beats {
+		data, err := json.Marshal(beat)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, beat.id(), data)
+	}
+
+	return s.client.HSet(ctx, s.key, values...).Err()
+}
+
+func (s *redisStore) List(ctx context.Context, since time.Time) ([]Beat, error) {
+	entries, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	beats := make([]Beat, 0, len(entries))
+	var expired []string
+
+	for id, data := range entries {
+		var beat Beat
+		if err := json.Unmarshal([]byte(data), &beat); err != nil {
+			return nil, err
+		}
+
+		if beat.BeatAt.Before(since) {
+			expired = append(expired, id)
+			continue
+		}
+
+		beats = append(beats, beat)
+	}
+
+	// Forget the instances that disappeared, such as scaled down Cloud Run instances.
+	if len(expired) > 0 {
+		_ = s.client.HDel(ctx, s.key, expired...).Err()
+	}
+
+	return beats, nil
+}
+
+// NewRedisStore creates a store keeping the heartbeats in a single Redis hash.
+func NewRedisStore(client redis.UniversalClient, key string) Store {
+	return &redisStore{client: client, key: key}
+}
+
+type firestoreStore struct {
+	collection *firestore.Collection[Beat]
+}
+
+func (s *firestoreStore) Put(ctx context.Context, beats []Beat) error {
+	documents := make(map[string]*Beat, len(beats))
+	for i := range beats {
+		documents[beats[i].id()] = &beats[i]
+	}
+
+	return s.collection.SetAll(ctx, documents)
+}
+
+func (s *firestoreStore) List(ctx context.Context, since time.Time) ([]Beat, error) {
+	documents, err := s.collection.Query(ctx, func(query gcpfirestore.Query) gcpfirestore.Query {
+		return query.Where("beatAt", ">=", since)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	beats := make([]Beat, 0, len(documents))
+	for _, document := range documents {
+		beats = append(beats, *document.Data)
+	}
+
+	return beats, nil
+}
+
+// NewFirestoreStore creates a store keeping one document per worker and instance in the given collection. Set a
+// TTL policy on the beatAt field to delete the documents of dead instances.
+func NewFirestoreStore(client *gcpfirestore.Client, path string) Store {
+	return &firestoreStore{collection: firestore.NewCollection[Beat](client, path)}
+}
+
+type memoryStore struct {
+	mu    sync.Mutex
+	beats map[string]Beat
+}
+
+func (s *memoryStore) Put(_ context.Context, beats []Beat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, beat := range beats {
+		s.beats[beat.id()] = beat
+	}
+
+	return nil
+}
+
+func (s *memoryStore) List(_ context.Context, since time.Time) ([]Beat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	beats := make([]Beat, 0, len(s.beats))
+	for _, beat := range s.beats {
+		if !beat.BeatAt.Before(since) {
+			beats = append(beats, beat)
+		}
+	}
+
+	return beats, nil
+}
+
+// NewMemoryStore creates a store keeping the heartbeats in memory, for local development.
+func NewMemoryStore() Store {
+	return &memoryStore{beats: make(map[string]Beat)}
+}
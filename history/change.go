@@ -0,0 +1,100 @@
+package history
+
+import (
+	"encoding/json"
+	"github.com/uptrace/bun"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// FieldChange holds the previous and new values of a single field.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Change is a single entry in the timeline of an entity.
+type Change struct {
+	bun.BaseModel `bun:"table:changes,alias:change"`
+
+	ID        int64                  `bun:"id,pk,autoincrement"`
+	Entity    string                 `bun:"entity,notnull"`
+	EntityID  string                 `bun:"entity_id,notnull"`
+	Action    Action                 `bun:"action,notnull"`
+	Actor     string                 `bun:"actor"`
+	Before    json.RawMessage        `bun:"before,type:jsonb"`
+	After     json.RawMessage        `bun:"after,type:jsonb"`
+	Diff      map[string]FieldChange `bun:"diff,type:jsonb"`
+	CreatedAt time.Time              `bun:"created_at,notnull,default:current_timestamp"`
+	// TxID is the transaction that recorded the change, so consumers can tail the changes without skipping late
+	// commits (see database.AfterPosition).
+	TxID int64 `bun:"tx_id,notnull,nullzero,default:pg_current_xact_id()::text::bigint"`
+}
+
+// Fields returns the sorted list of fields modified by the change.
+func (c *Change) Fields() []string {
+	fields := make([]string, 0, len(c.Diff))
+	for field := range c.Diff {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func snapshot(value any) (json.RawMessage, map[string]any, error) {
+	if value == nil {
+		return nil, nil, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Non-object values (scalars, arrays) are stored as-is, and diffed as a single field.
+	fields := make(map[string]any)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		var scalar any
+		_ = json.Unmarshal(raw, &scalar)
+		fields = map[string]any{"": scalar}
+	}
+
+	return raw, fields, nil
+}
+
+func diff(before, after map[string]any, ignored map[string]struct{}) map[string]FieldChange {
+	out := make(map[string]FieldChange)
+
+	for field, beforeValue := range before {
+		if _, ok := ignored[field]; ok {
+			continue
+		}
+
+		afterValue, ok := after[field]
+		if !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			out[field] = FieldChange{Before: beforeValue, After: afterValue}
+		}
+	}
+
+	for field, afterValue := range after {
+		if _, ok := ignored[field]; ok {
+			continue
+		}
+
+		if _, ok := before[field]; !ok {
+			out[field] = FieldChange{After: afterValue}
+		}
+	}
+
+	return out
+}
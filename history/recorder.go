@@ -0,0 +1,186 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var ErrUnknownEntity = errors.New("entity is not configured for history")
+
+type actorKey struct{}
+
+// WithActor attaches the identity of the user performing the changes to the context.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor attached to the context with WithActor, if any.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// EntityConfig declares an entity whose changes are recorded.
+type EntityConfig struct {
+	Name string
+	// IgnoredFields are excluded from the diff, such as technical timestamps. Names are the JSON field names of
+	// the snapshots.
+	IgnoredFields []string
+}
+
+// Recorder writes the changes performed on configured entities to the changes table.
+type Recorder struct {
+	db       bun.IDB
+	entities map[string]map[string]struct{}
+}
+
+func NewRecorder(db bun.IDB, entities ...EntityConfig) *Recorder {
+	recorder := &Recorder{
+		db:       db,
+		entities: make(map[string]map[string]struct{}, len(entities)),
+	}
+
+	for _, entity := range entities {
+		ignored := make(map[string]struct{}, len(entity.IgnoredFields))
+		for _, field := range entity.IgnoredFields {
+			ignored[field] = struct{}{}
+		}
+
+		recorder.entities[entity.Name] = ignored
+	}
+
+	return recorder
+}
+
+// CreateTable creates the changes table and its indexes, if they do not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	if _, err := db.NewCreateTable().Model((*Change)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := db.NewCreateIndex().
+		Model((*Change)(nil)).
+		Index("changes_entity_idx").
+		Column("entity", "entity_id", "id").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewCreateIndex().
+		Model((*Change)(nil)).
+		Index("changes_tx_idx").
+		Column("tx_id", "id").
+		IfNotExists().
+		Exec(ctx)
+
+	return err
+}
+
+// Record stores the difference between two snapshots of an entity. The before snapshot is nil on creation, and
+// the after snapshot is nil on deletion. Snapshots are serialized to JSON.
+//
+// The actor is read from the context (see WithActor). Updates that do not modify any tracked field are not
+// recorded, and return a nil change.
+//
+// Pass the current transaction as db to record the change atomically with the modification. If db is nil, the
+// recorder database is used.
+func (r *Recorder) Record(ctx context.Context, db bun.IDB, entity, entityID string, before, after any) (*Change, error) {
+	ignored, ok := r.entities[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEntity, entity)
+	}
+
+	if db == nil {
+		db = r.db
+	}
+
+	beforeRaw, beforeFields, err := snapshot(before)
+	if err != nil {
+		return nil, fmt.Errorf("serialize before snapshot: %w", err)
+	}
+
+	afterRaw, afterFields, err := snapshot(after)
+	if err != nil {
+		return nil, fmt.Errorf("serialize after snapshot: %w", err)
+	}
+
+	change := &Change{
+		Entity:    entity,
+		EntityID:  entityID,
+		Actor:     ActorFromContext(ctx),
+		Before:    beforeRaw,
+		After:     afterRaw,
+		Diff:      diff(beforeFields, afterFields, ignored),
+		CreatedAt: time.Now(),
+	}
+
+	switch {
+	case before == nil:
+		change.Action = ActionCreate
+	case after == nil:
+		change.Action = ActionDelete
+	default:
+		change.Action = ActionUpdate
+		if len(change.Diff) == 0 {
+			return nil, nil
+		}
+	}
+
+	if _, err := db.NewInsert().Model(change).Returning("id").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+type TimelineQuery struct {
+	// Before only returns changes older than the given change ID, for pagination. Ignored when 0.
+	Before int64
+	// Limit is the maximum number of changes returned. Defaults to 50.
+	Limit int
+	// Actor only returns the changes performed by the given actor, when set.
+	Actor string
+}
+
+// Timeline returns the changes of an entity, most recent first.
+func (r *Recorder) Timeline(ctx context.Context, entity, entityID string, query TimelineQuery) ([]*Change, error) {
+	if query.Limit <= 0 {
+		query.Limit = 50
+	}
+
+	changes := make([]*Change, 0)
+
+	q := r.db.NewSelect().
+		Model(&changes).
+		Where("entity = ?", entity).
+		Where("entity_id = ?", entityID).
+		OrderExpr("id DESC").
+		Limit(query.Limit)
+
+	if query.Before > 0 {
+		q = q.Where("id < ?", query.Before)
+	}
+	if query.Actor != "" {
+		q = q.Where("actor = ?", query.Actor)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// LastChange returns the most recent change of an entity, or nil if the entity has no recorded history.
+func (r *Recorder) LastChange(ctx context.Context, entity, entityID string) (*Change, error) {
+	changes, err := r.Timeline(ctx, entity, entityID, TimelineQuery{Limit: 1})
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	return changes[0], nil
+}
@@ -0,0 +1,165 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ginTagsKey = "github.com/in-rich/lib-go/httpcache/tags"
+
+type Config struct {
+	// TTL is the maximum lifetime of a cached response. Defaults to 1 minute.
+	TTL time.Duration
+	// User identifies the user the response belongs to, so users never share cached responses. Defaults to a
+	// hash of the Authorization header and cookies.
+	User func(c *gin.Context) string
+	// Tags returns the tags of the response of a request, used for invalidation. Handlers can add more tags with
+	// AddTags.
+	Tags func(c *gin.Context) []string
+}
+
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = time.Minute
+	}
+	if c.User == nil {
+		c.User = defaultUser
+	}
+
+	return c
+}
+
+func defaultUser(c *gin.Context) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.GetHeader("Authorization")))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.GetHeader("Cookie")))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// AddTags attaches tags to the response of the current request. It must be called before the response is sent.
+func AddTags(c *gin.Context, tags ...string) {
+	existing := c.GetStringSlice(ginTagsKey)
+	c.Set(ginTagsKey, append(existing, tags...))
+}
+
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Middleware caches successful GET responses, per route and per user.
+//
+//	router.GET("/notes", httpcache.Middleware(store, httpcache.Config{
+//		TTL: 30 * time.Second,
+//		Tags: func(c *gin.Context) []string {
+//			return []string{"notes:" + userID(c)}
+//		},
+//	}), listNotesHandler)
+//
+// Responses are served from the cache until they expire, or until one of their tags is invalidated. Requests
+// sent with "Cache-Control: no-cache" bypass the cache, and responses with "Cache-Control: no-store" or
+// "private, no-cache" are never stored. The X-Cache response header tells whether the response was a HIT or a MISS.
+//
+// Store errors are reported with c.Error, and the request is served as if the cache was empty.
+func Middleware(store Store, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := cacheKey(c, config.User(c))
+
+		if !strings.Contains(c.GetHeader("Cache-Control"), "no-cache") {
+			entry, err := store.Get(ctx, key)
+			switch {
+			case err == nil:
+				for name, values := range entry.Header {
+					c.Writer.Header()[name] = values
+				}
+
+				c.Header("X-Cache", "HIT")
+				c.Header("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+				c.Data(entry.Status, entry.Header.Get("Content-Type"), entry.Body)
+				c.Abort()
+				return
+			case !errors.Is(err, ErrNotFound):
+				_ = c.Error(err)
+			}
+		}
+
+		if config.Tags != nil {
+			AddTags(c, config.Tags(c)...)
+		}
+
+		c.Header("X-Cache", "MISS")
+
+		writer := &recorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if c.Writer.Status() != http.StatusOK || len(c.Errors) > 0 || !storable(c.Writer.Header()) {
+			return
+		}
+
+		header := c.Writer.Header().Clone()
+		header.Del("X-Cache")
+		header.Del("Set-Cookie")
+
+		entry := &Entry{
+			Status:   c.Writer.Status(),
+			Header:   header,
+			Body:     writer.body.Bytes(),
+			Tags:     c.GetStringSlice(ginTagsKey),
+			StoredAt: time.Now(),
+		}
+
+		if err := store.Set(ctx, key, entry, config.TTL); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+func storable(header http.Header) bool {
+	control := header.Get("Cache-Control")
+	return !strings.Contains(control, "no-store") && !strings.Contains(control, "no-cache") &&
+		header.Get("Set-Cookie") == ""
+}
+
+func cacheKey(c *gin.Context, user string) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.FullPath()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Request.URL.RequestURI()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.GetHeader("Accept")))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.GetHeader("Accept-Language")))
+	hash.Write([]byte{0})
+	hash.Write([]byte(user))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,96 @@
+package httpcache
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+	"net/http"
+)
+
+// Invalidation is the payload of the Pub/Sub messages published by the Invalidator.
+type Invalidation struct {
+	Tags []string `json:"tags"`
+}
+
+// Invalidator publishes cache invalidations on a Pub/Sub topic, so backend services can expire the responses
+// cached by the gateway when their data changes.
+type Invalidator struct {
+	service *pubsub.Service
+	topic   string
+}
+
+// NewInvalidator creates an invalidator publishing on the given topic, in the form
+// "projects/{project}/topics/{topic}".
+func NewInvalidator(ctx context.Context, topic string, opts ...option.ClientOption) (*Invalidator, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create pubsub client: %w", err)
+	}
+
+	return &Invalidator{service: service, topic: topic}, nil
+}
+
+// Invalidate publishes an invalidation for the given tags.
+func (i *Invalidator) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(Invalidation{Tags: tags})
+	if err != nil {
+		return err
+	}
+
+	_, err = i.service.Projects.Topics.Publish(i.topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{Data: base64.StdEncoding.EncodeToString(data)}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("publish invalidation: %w", err)
+	}
+
+	return nil
+}
+
+type pushEnvelope struct {
+	Message struct {
+		// Data is base64-encoded in the push payload.
+		Data []byte `json:"data"`
+	} `json:"message"`
+}
+
+// PushHandler applies the invalidations received from a Pub/Sub push subscription to the store.
+//
+//	router.POST("/internal/cache/invalidate", httpcache.PushHandler(store, logger))
+//
+// Malformed messages are acknowledged and logged, so they are not redelivered forever. Store errors return a
+// 500 status, so Pub/Sub retries the delivery.
+func PushHandler(store Store, logger monitor.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		envelope := new(pushEnvelope)
+		invalidation := new(Invalidation)
+
+		if err := c.ShouldBindJSON(envelope); err != nil {
+			logger.Error(err, "[httpcache] invalid push envelope")
+			c.Status(http.StatusNoContent)
+			return
+		}
+		if err := json.Unmarshal(envelope.Message.Data, invalidation); err != nil {
+			logger.Error(err, "[httpcache] invalid invalidation message")
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		if err := store.InvalidateTags(c.Request.Context(), invalidation.Tags...); err != nil {
+			logger.Error(err, "[httpcache] failed to invalidate tags")
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,121 @@
+package httpcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("cache entry not found")
+
+// Entry is a cached HTTP response.
+type Entry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	Tags     []string    `json:"tags"`
+	StoredAt time.Time   `json:"storedAt"`
+}
+
+// Store holds cached responses, and indexes them by tag for invalidation.
+type Store interface {
+	// Get returns ErrNotFound if the key does not exist or has expired.
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+	// InvalidateTags removes every entry holding at least one of the given tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+type memoryEntry struct {
+	entry     *Entry
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	tags    map[string]map[string]struct{}
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		s.remove(key)
+		return nil, ErrNotFound
+	}
+
+	return entry.entry, nil
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+	s.remove(key)
+	s.entries[key] = memoryEntry{entry: entry, expiresAt: time.Now().Add(ttl)}
+
+	for _, tag := range entry.Tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+
+		keys[key] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) InvalidateTags(_ context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			s.remove(key)
+		}
+	}
+
+	return nil
+}
+
+// remove deletes an entry and its tag references. The caller must hold the lock.
+func (s *memoryStore) remove(key string) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(s.entries, key)
+	for _, tag := range entry.entry.Tags {
+		delete(s.tags[tag], key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+func (s *memoryStore) evict() {
+	now := time.Now()
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			s.remove(key)
+		}
+	}
+}
+
+// NewMemoryStore creates a store that keeps responses in the memory of the current instance. Invalidations only
+// reach the instance they are applied on: use a Redis store when the gateway runs multiple instances.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]memoryEntry),
+		tags:    make(map[string]map[string]struct{}),
+	}
+}
@@ -0,0 +1,92 @@
+package httpcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (s *redisStore) entryKey(key string) string {
+	return s.prefix + "entry:" + key
+}
+
+func (s *redisStore) tagKey(tag string) string {
+	return s.prefix + "tag:" + tag
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := s.client.Get(ctx, s.entryKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := new(Entry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.entryKey(key), data, ttl)
+
+		for _, tag := range entry.Tags {
+			// Tag sets live at least as long as their most recent entry. Stale members are harmless, as deleting a
+			// missing entry is a no-op.
+			pipe.SAdd(ctx, s.tagKey(tag), key)
+			pipe.ExpireGT(ctx, s.tagKey(tag), ttl)
+			pipe.ExpireNX(ctx, s.tagKey(tag), ttl)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *redisStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := s.client.SMembers(ctx, s.tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+
+		// Keys are deleted one by one, as they may live on different slots of a cluster.
+		_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(ctx, s.entryKey(key))
+			}
+			pipe.Del(ctx, s.tagKey(tag))
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewRedisStore creates a store that shares cached responses between every instance through Redis. Keys are
+// prefixed with the given prefix, such as "httpcache:gateway:".
+func NewRedisStore(client redis.UniversalClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
@@ -0,0 +1,86 @@
+package introspect
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPAllowlist restricts access to a set of IP ranges.
+type IPAllowlist struct {
+	networks []*net.IPNet
+	// trustForwardedFor reads the client IP from the X-Forwarded-For header set by the load balancer.
+	trustForwardedFor bool
+}
+
+// NewIPAllowlist parses a list of IPs or CIDR ranges. Only enable trustForwardedFor when the service runs behind
+// a load balancer that overwrites the X-Forwarded-For header, such as Cloud Run.
+func NewIPAllowlist(ranges []string, trustForwardedFor bool) (*IPAllowlist, error) {
+	allowlist := &IPAllowlist{trustForwardedFor: trustForwardedFor}
+
+	for _, value := range ranges {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range %q: %w", value, err)
+		}
+
+		allowlist.networks = append(allowlist.networks, network)
+	}
+
+	return allowlist, nil
+}
+
+func (a *IPAllowlist) clientIP(r *http.Request) net.IP {
+	if a.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// The load balancer appends the IP of the client to the header. Previous values are user-controlled.
+			parts := strings.Split(forwarded, ",")
+			return net.ParseIP(strings.TrimSpace(parts[len(parts)-1]))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// Allowed returns true if the client of the request belongs to an allowed range.
+func (a *IPAllowlist) Allowed(r *http.Request) bool {
+	ip := a.clientIP(r)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range a.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Middleware rejects requests from clients outside the allowlist with a 403 status.
+func (a *IPAllowlist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Allowed(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
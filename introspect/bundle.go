@@ -0,0 +1,155 @@
+package introspect
+
+import (
+	"encoding/json"
+	"github.com/in-rich/lib-go/deploy"
+	"net/http"
+	"runtime"
+	rdebug "runtime/debug"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// Bundle exposes JSON introspection endpoints, to help incident triage.
+//
+//	bundle := &introspect.Bundle{
+//		Config:    cfg,
+//		Health:    depsCheck.Dependencies,
+//		Errors:    errorRing,
+//		Allowlist: allowlist,
+//	}
+//	bundle.Mount(opsMux, "/debug")
+type Bundle struct {
+	// Config is the configuration of the service, rendered with sensitive values redacted.
+	Config any
+	// SensitiveKeys overrides the key fragments used to redact the configuration. Defaults to DefaultSensitiveKeys.
+	SensitiveKeys []string
+	// Flags returns the current state of feature flags. Optional.
+	Flags func() map[string]any
+	// Health reports the status of the dependencies of the service. Optional.
+	Health deploy.DepCheckCallback
+	// Errors holds the most recent errors. Optional.
+	Errors *ErrorRing
+	// Allowlist restricts access to the endpoints. Required: Mount panics without an allowlist, so introspection
+	// data is never publicly exposed by mistake.
+	Allowlist *IPAllowlist
+
+	startedAt time.Time
+}
+
+// Mount registers the endpoints of the bundle on the mux, under the given prefix.
+func (b *Bundle) Mount(mux *http.ServeMux, prefix string) {
+	if b.Allowlist == nil {
+		panic("introspect: an IP allowlist is required to mount the debug bundle")
+	}
+
+	b.startedAt = time.Now()
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	routes := map[string]http.HandlerFunc{
+		"/config":     b.config,
+		"/flags":      b.flags,
+		"/health":     b.health,
+		"/errors":     b.errors,
+		"/goroutines": b.goroutines,
+		"/build":      b.build,
+	}
+
+	for path, handler := range routes {
+		mux.Handle(prefix+path, b.Allowlist.Middleware(handler))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
+
+func (b *Bundle) config(w http.ResponseWriter, _ *http.Request) {
+	sensitive := b.SensitiveKeys
+	if sensitive == nil {
+		sensitive = DefaultSensitiveKeys
+	}
+
+	config, err := Redact(b.Config, sensitive)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"env": deploy.ENV, "config": config})
+}
+
+func (b *Bundle) flags(w http.ResponseWriter, _ *http.Request) {
+	if b.Flags == nil {
+		writeJSON(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b.Flags())
+}
+
+func (b *Bundle) health(w http.ResponseWriter, _ *http.Request) {
+	out := make(map[string]any)
+	status := http.StatusOK
+
+	if b.Health != nil {
+		for dependency, err := range b.Health() {
+			if err != nil {
+				out[dependency] = map[string]any{"healthy": false, "error": err.Error()}
+				status = http.StatusServiceUnavailable
+			} else {
+				out[dependency] = map[string]any{"healthy": true}
+			}
+		}
+	}
+
+	writeJSON(w, status, out)
+}
+
+func (b *Bundle) errors(w http.ResponseWriter, _ *http.Request) {
+	if b.Errors == nil {
+		writeJSON(w, http.StatusOK, []ErrorEntry{})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b.Errors.Entries())
+}
+
+func (b *Bundle) goroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func (b *Bundle) build(w http.ResponseWriter, _ *http.Request) {
+	out := map[string]any{
+		"goVersion":  runtime.Version(),
+		"goroutines": runtime.NumGoroutine(),
+		"startedAt":  b.startedAt,
+		"uptime":     time.Since(b.startedAt).Round(time.Second).String(),
+	}
+
+	if info, ok := rdebug.ReadBuildInfo(); ok {
+		out["path"] = info.Path
+		out["version"] = info.Main.Version
+
+		settings := make(map[string]string)
+		for _, setting := range info.Settings {
+			settings[setting.Key] = setting.Value
+		}
+		out["settings"] = settings
+
+		deps := make(map[string]string)
+		for _, dep := range info.Deps {
+			deps[dep.Path] = dep.Version
+		}
+		out["dependencies"] = deps
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,88 @@
+package introspect
+
+import (
+	"github.com/in-rich/lib-go/monitor"
+	"sync"
+	"time"
+)
+
+// ErrorEntry is an error reported through a logger wrapped by an ErrorRing.
+type ErrorEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Error   string    `json:"error"`
+}
+
+// ErrorRing keeps the most recent errors reported to a logger.
+type ErrorRing struct {
+	mu      sync.Mutex
+	entries []ErrorEntry
+	next    int
+	full    bool
+}
+
+func NewErrorRing(size int) *ErrorRing {
+	if size <= 0 {
+		size = 100
+	}
+
+	return &ErrorRing{entries: make([]ErrorEntry, size)}
+}
+
+func (r *ErrorRing) record(err error, msg string) {
+	entry := ErrorEntry{Time: time.Now(), Message: msg}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Entries returns the recorded errors, most recent first.
+func (r *ErrorRing) Entries() []ErrorEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+
+	out := make([]ErrorEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+
+	return out
+}
+
+type ringLogger struct {
+	monitor.Logger
+	ring *ErrorRing
+}
+
+func (l *ringLogger) Error(err error, msg string) {
+	l.ring.record(err, msg)
+	l.Logger.Error(err, msg)
+}
+
+func (l *ringLogger) Fatal(err error, msg string) {
+	l.ring.record(err, msg)
+	l.Logger.Fatal(err, msg)
+}
+
+func (l *ringLogger) With(key string, value any) monitor.Logger {
+	return &ringLogger{Logger: l.Logger.With(key, value), ring: l.ring}
+}
+
+// Wrap returns a logger that records errors in the ring, before forwarding them to the given logger.
+func (r *ErrorRing) Wrap(logger monitor.Logger) monitor.Logger {
+	return &ringLogger{Logger: logger, ring: r}
+}
@@ -0,0 +1,231 @@
+package jwtsign
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpired      = errors.New("token expired")
+	ErrUnknownKey   = errors.New("token signed with an unknown or expired key")
+)
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+type claims struct {
+	ID        string          `json:"jti"`
+	Issuer    string          `json:"iss,omitempty"`
+	Audience  string          `json:"aud,omitempty"`
+	Subject   string          `json:"sub,omitempty"`
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Token is a verified token.
+type Token[T any] struct {
+	ID        string
+	KeyID     string
+	Subject   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Data      T
+}
+
+type SignerConfig struct {
+	// Issuer is written to, and required in, the "iss" claim of tokens. Optional.
+	Issuer string
+	// Audience is written to, and required in, the "aud" claim of tokens. Optional.
+	Audience string
+	// TTL is the lifetime of signed tokens. Defaults to 5 minutes.
+	TTL time.Duration
+	// Leeway tolerates clock skew between services when checking expiration. Defaults to 30 seconds.
+	Leeway time.Duration
+}
+
+func (c SignerConfig) withDefaults() SignerConfig {
+	if c.TTL <= 0 {
+		c.TTL = 5 * time.Minute
+	}
+	if c.Leeway <= 0 {
+		c.Leeway = 30 * time.Second
+	}
+
+	return c
+}
+
+// Signer signs and verifies short-lived internal JWTs (HS256).
+//
+//	source, err := jwtsign.SecretManagerSource(ctx, "projects/inrich/secrets/internal-jwt-keys/versions/latest")
+//	signer, err := jwtsign.NewSigner(ctx, source, logger, jwtsign.SignerConfig{Issuer: "gateway"})
+//	go signer.Refresh(ctx, 5*time.Minute)
+//
+//	token, err := jwtsign.Sign(signer, userID, claims)
+//	verified, err := jwtsign.Verify[Claims](signer, token)
+type Signer struct {
+	source KeySource
+	logger monitor.Logger
+	config SignerConfig
+	keys   atomic.Pointer[KeySet]
+}
+
+// NewSigner creates a signer, and loads its initial key set from the source.
+func NewSigner(ctx context.Context, source KeySource, logger monitor.Logger, config SignerConfig) (*Signer, error) {
+	signer := &Signer{
+		source: source,
+		logger: logger,
+		config: config.withDefaults(),
+	}
+
+	if err := signer.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("load signing keys: %w", err)
+	}
+
+	return signer, nil
+}
+
+func encodeSegment(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func signature(secret []byte, signingInput string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+// Sign creates a token for the given subject, holding the given data in its "data" claim. The token is signed
+// with the active key.
+func Sign[T any](signer *Signer, subject string, data T) (string, error) {
+	keys := signer.keys.Load()
+	key := keys.keys[keys.active]
+
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("serialize token data: %w", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	encodedHeader, err := encodeSegment(header{Algorithm: "HS256", Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	encodedClaims, err := encodeSegment(claims{
+		ID:        hex.EncodeToString(id),
+		Issuer:    signer.config.Issuer,
+		Audience:  signer.config.Audience,
+		Subject:   subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(signer.config.TTL).Unix(),
+		Data:      rawData,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodedHeader + "." + encodedClaims
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature(key.Secret, signingInput)), nil
+}
+
+// Verify checks the signature and the claims of a token, and decodes its data. Tokens signed with any key of the
+// key set are accepted, as long as the key has not expired.
+func Verify[T any](signer *Signer, token string) (*Token[T], error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	decodedHeader := new(header)
+	if err := decodeSegment(parts[0], decodedHeader); err != nil {
+		return nil, err
+	}
+	if decodedHeader.Algorithm != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, decodedHeader.Algorithm)
+	}
+
+	now := time.Now()
+
+	key, ok := signer.keys.Load().keys[decodedHeader.KeyID]
+	if !ok || key.Expired(now) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, decodedHeader.KeyID)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, signature(key.Secret, parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrInvalidToken)
+	}
+
+	decodedClaims := new(claims)
+	if err := decodeSegment(parts[1], decodedClaims); err != nil {
+		return nil, err
+	}
+
+	if signer.config.Issuer != "" && decodedClaims.Issuer != signer.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, decodedClaims.Issuer)
+	}
+	if signer.config.Audience != "" && decodedClaims.Audience != signer.config.Audience {
+		return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidToken, decodedClaims.Audience)
+	}
+
+	expiresAt := time.Unix(decodedClaims.ExpiresAt, 0)
+	if now.After(expiresAt.Add(signer.config.Leeway)) {
+		return nil, ErrExpired
+	}
+
+	result := &Token[T]{
+		ID:        decodedClaims.ID,
+		KeyID:     key.ID,
+		Subject:   decodedClaims.Subject,
+		IssuedAt:  time.Unix(decodedClaims.IssuedAt, 0),
+		ExpiresAt: expiresAt,
+	}
+
+	if len(decodedClaims.Data) > 0 {
+		if err := json.Unmarshal(decodedClaims.Data, &result.Data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+	}
+
+	return result, nil
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package jwtsign
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid signing key")
+
+// Key is a HMAC-SHA256 secret used to sign tokens.
+type Key struct {
+	// ID is written in the "kid" header of tokens, so they are verified with the key that signed them.
+	ID string `json:"id"`
+	// Secret must be at least 32 bytes long. It is base64-encoded in JSON.
+	Secret []byte `json:"secret"`
+	// NotAfter is the time after which the key stops verifying tokens, for retiring keys. Ignored when zero.
+	NotAfter time.Time `json:"notAfter,omitempty"`
+}
+
+// Expired returns true if the key can no longer verify tokens at the given time.
+func (k Key) Expired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && now.After(k.NotAfter)
+}
+
+// KeySet holds the key used to sign new tokens, and the retiring keys still accepted for verification.
+//
+// To rotate keys without redeploying every service at once, add the new key to the set as a retiring key, wait
+// for every instance to refresh, then make it active. Keep the previous active key with a NotAfter set past the
+// lifetime of the tokens it signed.
+type KeySet struct {
+	active string
+	keys   map[string]Key
+}
+
+func NewKeySet(active Key, retiring ...Key) (*KeySet, error) {
+	set := &KeySet{active: active.ID, keys: make(map[string]Key, len(retiring)+1)}
+
+	for _, key := range append([]Key{active}, retiring...) {
+		if key.ID == "" {
+			return nil, fmt.Errorf("%w: key ID is required", ErrInvalidKey)
+		}
+		if len(key.Secret) < 32 {
+			return nil, fmt.Errorf("%w %s: secret must be at least 32 bytes long", ErrInvalidKey, key.ID)
+		}
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate key ID %s", ErrInvalidKey, key.ID)
+		}
+
+		set.keys[key.ID] = key
+	}
+
+	if active.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w %s: the active key is expired", ErrInvalidKey, active.ID)
+	}
+
+	return set, nil
+}
+
+// Active returns the key signing new tokens.
+func (k *KeySet) Active() Key {
+	return k.keys[k.active]
+}
+
+// Key returns the key with the given ID, expired or not.
+func (k *KeySet) Key(id string) (Key, bool) {
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// keySetDocument is the JSON representation of a key set, as stored in Secret Manager:
+//
+//	{
+//		"active": "2024-10",
+//		"keys": [
+//			{"id": "2024-10", "secret": "base64..."},
+//			{"id": "2024-07", "secret": "base64...", "notAfter": "2024-10-02T00:00:00Z"}
+//		]
+//	}
+type keySetDocument struct {
+	Active string `json:"active"`
+	Keys   []Key  `json:"keys"`
+}
+
+// ParseKeySet decodes a key set from its JSON representation.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	document := new(keySetDocument)
+	if err := json.Unmarshal(data, document); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	var active *Key
+	retiring := make([]Key, 0, len(document.Keys))
+
+	for i, key := range document.Keys {
+		if key.ID == document.Active {
+			active = &document.Keys[i]
+			continue
+		}
+
+		retiring = append(retiring, key)
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("%w: active key %q is not in the key set", ErrInvalidKey, document.Active)
+	}
+
+	return NewKeySet(*active, retiring...)
+}
+
+// ParseSecret decodes a base64 secret, as found in configuration files.
+func ParseSecret(secret string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return decoded, nil
+}
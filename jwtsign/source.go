@@ -0,0 +1,106 @@
+package jwtsign
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/secretmanager/v1"
+	"os"
+	"time"
+)
+
+var ErrMissingKeyEnv = errors.New("key set environment variable is not set")
+
+// KeySource loads the current key set.
+type KeySource func(ctx context.Context) (*KeySet, error)
+
+// StaticSource always returns the same key set, for local environments.
+func StaticSource(set *KeySet) KeySource {
+	return func(context.Context) (*KeySet, error) {
+		return set, nil
+	}
+}
+
+// SecretManagerSource loads the key set from a Secret Manager secret version holding its JSON representation,
+// such as "projects/inrich/secrets/internal-jwt-keys/versions/latest".
+func SecretManagerSource(ctx context.Context, version string, opts ...option.ClientOption) (KeySource, error) {
+	service, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create secret manager client: %w", err)
+	}
+
+	return func(ctx context.Context) (*KeySet, error) {
+		res, err := service.Projects.Secrets.Versions.Access(version).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("access secret %s: %w", version, err)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode secret %s: %w", version, err)
+		}
+
+		return ParseKeySet(data)
+	}, nil
+}
+
+// FileSource loads the key set from a file holding its JSON representation, such as a mounted secret. The file is
+// read again on every reload, so rotated keys are picked up by Refresh.
+func FileSource(path string) KeySource {
+	return func(context.Context) (*KeySet, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read key set %s: %w", path, err)
+		}
+
+		return ParseKeySet(data)
+	}
+}
+
+// EnvSource loads the key set from an environment variable holding its JSON representation.
+func EnvSource(name string) KeySource {
+	return func(context.Context) (*KeySet, error) {
+		data, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingKeyEnv, name)
+		}
+
+		return ParseKeySet([]byte(data))
+	}
+}
+
+// Refresh reloads the key set of the signer periodically, until the context is canceled. On failure, the signer
+// keeps its current key set, and the error is logged.
+func (s *Signer) Refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error(err, "[jwtsign] failed to refresh signing keys")
+			}
+		}
+	}
+}
+
+// Reload loads the key set from the source immediately.
+func (s *Signer) Reload(ctx context.Context) error {
+	set, err := s.source(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.keys.Store(set)
+	return nil
+}
+
+// Keys returns the current key set of the signer, for other uses of the same keys, such as request signing.
+func (s *Signer) Keys() *KeySet {
+	return s.keys.Load()
+}
@@ -0,0 +1,105 @@
+package locale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/text/language"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+type layouts struct {
+	date     string
+	dateTime string
+	time     string
+}
+
+var layoutsByLanguage = map[language.Base]layouts{
+	mustBase(language.French):  {date: "02/01/2006", dateTime: "02/01/2006 15:04", time: "15:04"},
+	mustBase(language.English): {date: "01/02/2006", dateTime: "01/02/2006 3:04 PM", time: "3:04 PM"},
+}
+
+func mustBase(tag language.Tag) language.Base {
+	base, _ := tag.Base()
+	return base
+}
+
+func (l Locale) layouts() layouts {
+	base, _ := l.Language.Base()
+	if out, ok := layoutsByLanguage[base]; ok {
+		return out
+	}
+
+	return layoutsByLanguage[mustBase(Supported[0])]
+}
+
+// In converts a time to the timezone of the locale.
+func (l Locale) In(t time.Time) time.Time {
+	return t.In(l.Location)
+}
+
+func (l Locale) FormatDate(t time.Time) string {
+	return l.In(t).Format(l.layouts().date)
+}
+
+func (l Locale) FormatDateTime(t time.Time) string {
+	return l.In(t).Format(l.layouts().dateTime)
+}
+
+func (l Locale) FormatTime(t time.Time) string {
+	return l.In(t).Format(l.layouts().time)
+}
+
+// FormatDate formats the date of a timestamp, in the language and timezone of the user of the context.
+func FormatDate(ctx context.Context, t time.Time) string {
+	return FromContext(ctx).FormatDate(t)
+}
+
+// FormatDateTime formats a timestamp, in the language and timezone of the user of the context.
+func FormatDateTime(ctx context.Context, t time.Time) string {
+	return FromContext(ctx).FormatDateTime(t)
+}
+
+// FormatTime formats the time of day of a timestamp, in the language and timezone of the user of the context.
+func FormatTime(ctx context.Context, t time.Time) string {
+	return FromContext(ctx).FormatTime(t)
+}
+
+// Parse reads a date supplied by a client. Timestamps with an explicit offset (RFC 3339) keep their offset. Dates
+// without offset, either ISO ("2006-01-02", "2006-01-02T15:04") or in the layout of the locale, are read in the
+// timezone of the locale, never in UTC.
+func (l Locale) Parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+
+	local := l.layouts()
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05", local.date, local.dateTime} {
+		if parsed, err := time.ParseInLocation(layout, value, l.Location); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, value)
+}
+
+// Parse reads a date supplied by the user of the context (see Locale.Parse).
+func Parse(ctx context.Context, value string) (time.Time, error) {
+	return FromContext(ctx).Parse(value)
+}
+
+// Day returns the bounds of the day containing t, in the timezone of the locale: start is inclusive, end is
+// exclusive. Use it to group or filter data by day in reports, rather than truncating UTC timestamps. Days are not
+// always 24 hours long, because of daylight saving time.
+func (l Locale) Day(t time.Time) (start, end time.Time) {
+	t = l.In(t)
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, l.Location)
+	end = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, l.Location)
+
+	return start, end
+}
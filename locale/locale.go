@@ -0,0 +1,99 @@
+package locale
+
+import (
+	"context"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/metadata"
+	"strings"
+	"time"
+	// Container images do not always ship the timezone database.
+	_ "time/tzdata"
+)
+
+// Metadata keys forwarding the locale of the user to downstream services.
+const (
+	LanguageMetadataKey = "x-inrich-language"
+	TimezoneMetadataKey = "x-inrich-timezone"
+)
+
+// Supported lists the languages the services format dates for. The first one is the fallback.
+var Supported = []language.Tag{language.French, language.English}
+
+var matcher = language.NewMatcher(Supported)
+
+// Locale is the language and timezone of a user.
+type Locale struct {
+	Language language.Tag
+	Location *time.Location
+}
+
+// Default is used when the locale of the user cannot be resolved.
+var Default = Locale{Language: language.French, Location: mustLoadLocation("Europe/Paris")}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return location
+}
+
+// Resolve builds a locale from a language preference, such as an Accept-Language header or a profile setting
+// ("fr-FR"), and an IANA timezone name ("Europe/Paris"). Missing or invalid values fall back to the values of
+// fallback.
+func Resolve(languages, timezone string, fallback Locale) Locale {
+	out := fallback
+
+	if languages = strings.TrimSpace(languages); languages != "" {
+		if tags, _, err := language.ParseAcceptLanguage(languages); err == nil && len(tags) > 0 {
+			_, index, confidence := matcher.Match(tags...)
+			if confidence != language.No {
+				out.Language = Supported[index]
+			}
+		}
+	}
+
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		// LoadLocation also accepts file paths, which are never valid timezone names.
+		if location, err := time.LoadLocation(timezone); err == nil && !strings.Contains(timezone, "..") {
+			out.Location = location
+		}
+	}
+
+	return out
+}
+
+type localeKey struct{}
+
+// WithLocale attaches the locale of the user to the context. The locale is also forwarded to the downstream GRPC
+// calls performed with the returned context.
+func WithLocale(ctx context.Context, locale Locale) context.Context {
+	ctx = context.WithValue(ctx, localeKey{}, locale)
+	return metadata.AppendToOutgoingContext(
+		ctx, LanguageMetadataKey, locale.Language.String(), TimezoneMetadataKey, locale.Location.String(),
+	)
+}
+
+// FromContext returns the locale attached with WithLocale, or forwarded by the caller service. It returns Default
+// if the context holds no locale.
+func FromContext(ctx context.Context) Locale {
+	if locale, ok := ctx.Value(localeKey{}).(Locale); ok {
+		return locale
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Default
+	}
+
+	return Resolve(first(md.Get(LanguageMetadataKey)), first(md.Get(TimezoneMetadataKey)), Default)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,44 @@
+package locale
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// Resolver returns the locale of an authenticated user, such as the settings of their profile. It returns false
+// when the user has no preference, in which case the locale is read from the request.
+type Resolver func(ctx context.Context) (Locale, bool)
+
+// UnaryServerInterceptor attaches the locale of the user to the context of every RPC. The locale is resolved with
+// the resolver when given, and otherwise read from the metadata forwarded by the caller service.
+func UnaryServerInterceptor(resolve Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if resolve != nil {
+			if locale, ok := resolve(ctx); ok {
+				return handler(WithLocale(ctx, locale), req)
+			}
+		}
+
+		return handler(WithLocale(ctx, FromContext(ctx)), req)
+	}
+}
+
+// GinMiddleware attaches the locale of the user to the request context. The locale is resolved with the resolver
+// when given, then from the Accept-Language and X-Timezone headers.
+func GinMiddleware(resolve Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		locale, ok := Locale{}, false
+		if resolve != nil {
+			locale, ok = resolve(ctx)
+		}
+		if !ok {
+			locale = Resolve(c.GetHeader("Accept-Language"), c.GetHeader("X-Timezone"), Default)
+		}
+
+		c.Request = c.Request.WithContext(WithLocale(ctx, locale))
+		c.Next()
+	}
+}
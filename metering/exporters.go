@@ -0,0 +1,110 @@
+package metering
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+)
+
+// batchSize is the maximum number of records per Pub/Sub message, and per BigQuery insert.
+const batchSize = 500
+
+type pubsubExporter struct {
+	service *pubsub.Service
+	topic   string
+}
+
+func (e *pubsubExporter) Export(ctx context.Context, records []Record) error {
+	messages := make([]*pubsub.PubsubMessage, 0, len(records)/batchSize+1)
+
+	for start := 0; start < len(records); start += batchSize {
+		data, err := json.Marshal(records[start:min(start+batchSize, len(records))])
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, &pubsub.PubsubMessage{Data: base64.StdEncoding.EncodeToString(data)})
+	}
+
+	_, err := e.service.Projects.Topics.Publish(e.topic, &pubsub.PublishRequest{Messages: messages}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("publish usage: %w", err)
+	}
+
+	return nil
+}
+
+// NewPubSubExporter creates an exporter publishing usage records on a Pub/Sub topic, in the form
+// "projects/{project}/topics/{topic}". Each message holds a JSON array of up to 500 records.
+func NewPubSubExporter(ctx context.Context, topic string, opts ...option.ClientOption) (Exporter, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create pubsub client: %w", err)
+	}
+
+	return &pubsubExporter{service: service, topic: topic}, nil
+}
+
+type bigQueryExporter struct {
+	service   *bigquery.Service
+	projectID string
+	datasetID string
+	tableID   string
+}
+
+func (e *bigQueryExporter) Export(ctx context.Context, records []Record) error {
+	for start := 0; start < len(records); start += batchSize {
+		batch := records[start:min(start+batchSize, len(records))]
+		rows := make([]*bigquery.TableDataInsertAllRequestRows, 0, len(batch))
+
+		for _, record := range batch {
+			rows = append(rows, &bigquery.TableDataInsertAllRequestRows{
+				Json: map[string]bigquery.JsonValue{
+					"tenant":           record.Tenant,
+					"service":          record.Service,
+					"start":            record.Start,
+					"end":              record.End,
+					"requests":         record.Requests,
+					"compute_time_ms":  record.ComputeTimeMs,
+					"downstream_bytes": record.DownstreamBytes,
+				},
+			})
+		}
+
+		res, err := e.service.Tabledata.
+			InsertAll(e.projectID, e.datasetID, e.tableID, &bigquery.TableDataInsertAllRequest{Rows: rows}).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return fmt.Errorf("insert usage rows: %w", err)
+		}
+
+		if len(res.InsertErrors) > 0 {
+			return fmt.Errorf("insert usage rows: %d rows rejected", len(res.InsertErrors))
+		}
+	}
+
+	return nil
+}
+
+// NewBigQueryExporter creates an exporter streaming usage records to a BigQuery table, with the columns tenant,
+// service, start, end (TIMESTAMP), requests, compute_time_ms and downstream_bytes (INTEGER).
+//
+// A window may be exported in several records, when usage is recorded after a flush: sum the rows by tenant and
+// window when querying the table.
+func NewBigQueryExporter(
+	ctx context.Context, projectID, datasetID, tableID string, opts ...option.ClientOption,
+) (Exporter, error) {
+	service, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create bigquery client: %w", err)
+	}
+
+	return &bigQueryExporter{service: service, projectID: projectID, datasetID: datasetID, tableID: tableID}, nil
+}
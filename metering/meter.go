@@ -0,0 +1,205 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"sync"
+	"time"
+)
+
+// Usage is the resource consumption attributed to a tenant.
+type Usage struct {
+	Requests int64
+	// ComputeTime is the time spent handling requests.
+	ComputeTime time.Duration
+	// DownstreamBytes is the size of the payloads exchanged with downstream services.
+	DownstreamBytes int64
+}
+
+func (u *Usage) add(other Usage) {
+	u.Requests += other.Requests
+	u.ComputeTime += other.ComputeTime
+	u.DownstreamBytes += other.DownstreamBytes
+}
+
+// Record is the usage of a tenant over a window, as exported.
+type Record struct {
+	Tenant  string    `json:"tenant"`
+	Service string    `json:"service"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+
+	Requests        int64 `json:"requests"`
+	ComputeTimeMs   int64 `json:"computeTimeMs"`
+	DownstreamBytes int64 `json:"downstreamBytes"`
+}
+
+// Exporter sends usage records to the billing pipeline.
+type Exporter interface {
+	Export(ctx context.Context, records []Record) error
+}
+
+type Config struct {
+	// Service is the name of the current service, stored in the records.
+	Service string
+	// Window is the duration aggregated in a single record. Defaults to 1 minute.
+	Window time.Duration
+	// FlushInterval is the delay between two exports. Defaults to 30 seconds.
+	FlushInterval time.Duration
+	// MaxTenants bounds the number of records kept in memory when exports fail. Usage of new tenants is dropped
+	// past this limit. Defaults to 50000.
+	MaxTenants int
+	// ShutdownTimeout bounds the final export, once the context of Run is canceled. Defaults to 10 seconds.
+	ShutdownTimeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 30 * time.Second
+	}
+	if c.MaxTenants <= 0 {
+		c.MaxTenants = 50000
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = 10 * time.Second
+	}
+
+	return c
+}
+
+type windowKey struct {
+	tenant string
+	start  time.Time
+}
+
+// Meter buffers the usage of tenants locally, and exports it periodically.
+//
+//	meter := metering.NewMeter(exporter, logger, metering.Config{Service: "notes"})
+//	go meter.Run(ctx)
+//
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(meter.UnaryServerInterceptor(tenantFromClaims)))
+//	conn, _ := grpc.NewClient(host, grpc.WithChainUnaryInterceptor(meter.UnaryClientInterceptor()))
+type Meter struct {
+	exporter Exporter
+	logger   monitor.Logger
+	config   Config
+
+	mu      sync.Mutex
+	windows map[windowKey]*Usage
+	dropped int64
+}
+
+func NewMeter(exporter Exporter, logger monitor.Logger, config Config) *Meter {
+	return &Meter{
+		exporter: exporter,
+		logger:   logger,
+		config:   config.withDefaults(),
+		windows:  make(map[windowKey]*Usage),
+	}
+}
+
+// Add attributes usage to the tenant of the context. Usage without tenant is ignored.
+func (m *Meter) Add(ctx context.Context, usage Usage) {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return
+	}
+
+	m.AddTenant(tenant, usage)
+}
+
+// AddTenant attributes usage to a tenant.
+func (m *Meter) AddTenant(tenant string, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add(windowKey{tenant: tenant, start: time.Now().UTC().Truncate(m.config.Window)}, usage)
+}
+
+// add merges usage into a window. The caller must hold the lock.
+func (m *Meter) add(key windowKey, usage Usage) {
+	window, ok := m.windows[key]
+	if !ok {
+		if len(m.windows) >= m.config.MaxTenants {
+			m.dropped += usage.Requests
+			return
+		}
+
+		window = new(Usage)
+		m.windows[key] = window
+	}
+
+	window.add(usage)
+}
+
+// Flush exports the buffered usage. On failure, the usage is merged back for the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.windows
+	dropped := m.dropped
+	m.windows = make(map[windowKey]*Usage, len(pending))
+	m.dropped = 0
+	m.mu.Unlock()
+
+	if dropped > 0 {
+		m.logger.Warn(fmt.Sprintf("[metering] usage of %d requests dropped, the buffer is full", dropped))
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	records := make([]Record, 0, len(pending))
+	for key, usage := range pending {
+		records = append(records, Record{
+			Tenant:          key.tenant,
+			Service:         m.config.Service,
+			Start:           key.start,
+			End:             key.start.Add(m.config.Window),
+			Requests:        usage.Requests,
+			ComputeTimeMs:   usage.ComputeTime.Milliseconds(),
+			DownstreamBytes: usage.DownstreamBytes,
+		})
+	}
+
+	if err := m.exporter.Export(ctx, records); err != nil {
+		m.mu.Lock()
+		for key, usage := range pending {
+			m.add(key, *usage)
+		}
+		m.mu.Unlock()
+
+		return fmt.Errorf("export %d usage records: %w", len(records), err)
+	}
+
+	return nil
+}
+
+// Run exports the usage periodically, until the context is canceled. The remaining usage is then exported one
+// last time, within the shutdown timeout.
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCTX, cancel := ctxutil.DetachWithTimeout(ctx, m.config.ShutdownTimeout)
+			defer cancel()
+
+			if err := m.Flush(flushCTX); err != nil {
+				m.logger.Error(err, "[metering] final export failed, usage is lost")
+			}
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Error(err, "[metering] export failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package metering
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+// TenantResolver returns the tenant of an authenticated request, or an empty string.
+type TenantResolver func(ctx context.Context) string
+
+// UnaryServerInterceptor counts the requests and the compute time of every RPC. The tenant is resolved with the
+// resolver when given, and otherwise read from the metadata forwarded by the caller service.
+func (m *Meter) UnaryServerInterceptor(resolve TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if resolve != nil {
+			if tenant := resolve(ctx); tenant != "" {
+				ctx = WithTenant(ctx, tenant)
+			}
+		} else if tenant := TenantFromContext(ctx); tenant != "" {
+			ctx = WithTenant(ctx, tenant)
+		}
+
+		start := time.Now()
+		res, err := handler(ctx, req)
+		m.Add(ctx, Usage{Requests: 1, ComputeTime: time.Since(start)})
+
+		return res, err
+	}
+}
+
+// UnaryClientInterceptor counts the bytes exchanged with downstream services, for the tenant of the context.
+func (m *Meter) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		var size int
+		if msg, ok := req.(proto.Message); ok {
+			size += proto.Size(msg)
+		}
+		if msg, ok := reply.(proto.Message); ok && err == nil {
+			size += proto.Size(msg)
+		}
+
+		m.Add(ctx, Usage{DownstreamBytes: int64(size)})
+		return err
+	}
+}
+
+// GinMiddleware counts the requests and the compute time of every HTTP request. The tenant is resolved from the
+// request once the previous middlewares, such as authentication, have run. Handlers performing downstream calls
+// must use the request context, to which the tenant is attached.
+func (m *Meter) GinMiddleware(resolve func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := resolve(c)
+		if tenant == "" {
+			c.Next()
+			return
+		}
+
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenant))
+
+		start := time.Now()
+		c.Next()
+		m.AddTenant(tenant, Usage{Requests: 1, ComputeTime: time.Since(start)})
+	}
+}
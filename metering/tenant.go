@@ -0,0 +1,32 @@
+package metering
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+)
+
+// TenantMetadataKey carries the tenant to downstream services, so their usage is attributed to the same tenant.
+const TenantMetadataKey = "x-inrich-tenant"
+
+type tenantKey struct{}
+
+// WithTenant attaches the tenant the usage of the request is attributed to. The tenant is also forwarded to the
+// downstream GRPC calls performed with the returned context.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	return metadata.AppendToOutgoingContext(ctx, TenantMetadataKey, tenant)
+}
+
+// TenantFromContext returns the tenant attached with WithTenant, or forwarded by the caller service.
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(TenantMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+type loggerKey struct{}
+
+// traceLogger is implemented by the loggers formatting trace IDs so their entries are grouped by trace, such as
+// the GCP loggers.
+type traceLogger interface {
+	withTrace(traceID string) Logger
+}
+
+func (l *gcpLogger) withTrace(traceID string) Logger {
+	if l.projectID == "" {
+		return l.With("trace", traceID)
+	}
+
+	// https://cloud.google.com/run/docs/logging#correlate-logs
+	return l.With("logging.googleapis.com/trace", "projects/"+l.projectID+"/traces/"+traceID)
+}
+
+// WithLogger attaches a logger to the context, for FromContext.
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger attached to the context by WithLogger or the request middlewares, so deep
+// application code logs entries correlated with the request, without the logger being passed down. It returns a
+// console logger when the context has no logger.
+func FromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return logger
+	}
+
+	return NewConsoleLogger()
+}
+
+// traceID returns the trace of a request, from the X-Cloud-Trace-Context ("TRACE_ID/SPAN_ID;o=1") or W3C
+// traceparent ("00-TRACE_ID-SPAN_ID-01") headers.
+func traceID(cloudTrace, traceparent string) string {
+	if id, _, _ := strings.Cut(cloudTrace, "/"); id != "" {
+		return id
+	}
+
+	if parts := strings.Split(traceparent, "-"); len(parts) == 4 {
+		return parts[1]
+	}
+
+	return ""
+}
+
+// requestLogger returns the logger of a request, with its trace, request ID and user.
+func requestLogger(logger Logger, trace, requestID, user string) Logger {
+	if trace != "" {
+		if tl, ok := logger.(traceLogger); ok {
+			logger = tl.withTrace(trace)
+		} else {
+			logger = logger.With("trace", trace)
+		}
+	}
+	if requestID != "" {
+		logger = logger.With("requestId", requestID)
+	}
+	if user != "" {
+		logger = logger.With("user", user)
+	}
+
+	return logger
+}
+
+// ContextMiddleware attaches a request-scoped logger to the context of the request, for FromContext. The user is
+// resolved once the previous middlewares, such as authentication, have run. Resolve may be nil.
+//
+//	router.Use(authMiddleware, monitor.ContextMiddleware(logger, func(c *gin.Context) string {
+//		return c.GetString("userID")
+//	}))
+func ContextMiddleware(logger Logger, resolve func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user string
+		if resolve != nil {
+			user = resolve(c)
+		}
+
+		scoped := requestLogger(
+			logger,
+			traceID(c.GetHeader("X-Cloud-Trace-Context"), c.GetHeader("Traceparent")),
+			c.GetHeader("X-Request-Id"),
+			user,
+		)
+
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), scoped))
+		c.Next()
+	}
+}
+
+func contextLogger(ctx context.Context, logger Logger, resolve func(ctx context.Context) string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+
+		return ""
+	}
+
+	var user string
+	if resolve != nil {
+		user = resolve(ctx)
+	}
+
+	scoped := requestLogger(
+		logger,
+		traceID(first("x-cloud-trace-context"), first("traceparent")),
+		first("x-request-id"),
+		user,
+	)
+
+	return WithLogger(ctx, scoped)
+}
+
+// UnaryContextInterceptor attaches a request-scoped logger to the context of every call, like ContextMiddleware.
+// Resolve may be nil.
+func UnaryContextInterceptor(logger Logger, resolve func(ctx context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(contextLogger(ctx, logger, resolve), req)
+	}
+}
+
+// StreamContextInterceptor attaches a request-scoped logger to the context of every stream, like
+// ContextMiddleware. Resolve may be nil.
+func StreamContextInterceptor(logger Logger, resolve func(ctx context.Context) string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := contextLogger(stream.Context(), logger, resolve)
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
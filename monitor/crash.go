@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Exit codes of crashed processes. Code 2 is left to the Go runtime, which uses it for panics that are not
+// recovered, and to cli.ExitUsage.
+const (
+	// ExitFatal is returned after a logger reports a fatal error.
+	ExitFatal = 3
+	// ExitPanic is returned after a panic is recovered by RecoverCrash.
+	ExitPanic = 4
+)
+
+// CrashFileEnv overrides the path of the crash file. It defaults to /dev/termination-log when it exists, as on
+// Kubernetes, and to a file of the temporary directory otherwise.
+const CrashFileEnv = "CRASH_FILE"
+
+// crashFlushTimeout bounds the time spent sending the crash to Sentry.
+const crashFlushTimeout = 2 * time.Second
+
+// BuildInfo identifies the binary that crashed.
+type BuildInfo struct {
+	GoVersion string `json:"goVersion,omitempty"`
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// CrashRecord is the last entry written by a crashing process, on stderr and in the crash file. Its severity and
+// message make it the last line of the logs of the instance in Cloud Logging.
+type CrashRecord struct {
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+	ExitCode int       `json:"exitCode"`
+	Reason   string    `json:"reason"`
+	Error    string    `json:"error,omitempty"`
+	Stack    string    `json:"stack"`
+	Build    BuildInfo `json:"build"`
+	// Service and Revision are set on Cloud Run.
+	Service  string `json:"service,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+var crashOnce sync.Once
+
+func readBuildInfo() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{}
+	}
+
+	build := BuildInfo{GoVersion: info.GoVersion, Module: info.Main.Path, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+
+	return build
+}
+
+func crashFile() string {
+	if path := os.Getenv(CrashFileEnv); path != "" {
+		return path
+	}
+	if _, err := os.Stat("/dev/termination-log"); err == nil {
+		return "/dev/termination-log"
+	}
+
+	return filepath.Join(os.TempDir(), "inrich-crash.json")
+}
+
+// crash writes the crash record, sends the error to Sentry, and exits the process.
+func crash(code int, reason string, err error, stack []byte) {
+	crashOnce.Do(func() {
+		record := CrashRecord{
+			Severity: "CRITICAL",
+			Time:     time.Now(),
+			ExitCode: code,
+			Reason:   reason,
+			Stack:    string(stack),
+			Build:    readBuildInfo(),
+			Service:  os.Getenv("K_SERVICE"),
+			Revision: os.Getenv("K_REVISION"),
+		}
+
+		record.Message = fmt.Sprintf("crash (exit %d): %s", code, reason)
+		if err != nil {
+			record.Error = err.Error()
+			record.Message = fmt.Sprintf("%s: %s", record.Message, err.Error())
+		}
+
+		data, _ := json.Marshal(record)
+		_, _ = os.Stderr.Write(append(data, '\n'))
+		_ = os.WriteFile(crashFile(), data, 0o644)
+
+		if err != nil {
+			sentry.CaptureException(err)
+		}
+		sentry.Flush(crashFlushTimeout)
+	})
+
+	os.Exit(code)
+}
+
+// Crash exits the process with the given code, after writing a crash record and reporting the error to Sentry.
+// Only the first call writes a record, when several goroutines crash at once.
+func Crash(code int, reason string, err error) {
+	crash(code, reason, err, debug.Stack())
+}
+
+// RecoverCrash exits the process with ExitPanic when the calling goroutine panics, after writing a crash record
+// with the stack of the panic. Defer it first in main, and in long-lived goroutines:
+//
+//	func main() {
+//		defer monitor.RecoverCrash()
+//		...
+//	}
+func RecoverCrash() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	err, ok := recovered.(error)
+	if !ok {
+		err = errors.New(fmt.Sprint(recovered))
+	}
+
+	crash(ExitPanic, "panic", err, debug.Stack())
+}
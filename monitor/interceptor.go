@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// reportFunc logs the outcome of a GRPC call.
+type reportFunc func(ctx context.Context, service string, latency time.Duration, err error)
+
+// withHub attaches a dedicated Sentry hub to the context of the call, unless one is already present, so the scope
+// set by the handler does not leak to other calls.
+func withHub(ctx context.Context) context.Context {
+	if sentry.HasHubOnContext(ctx) {
+		return ctx
+	}
+
+	return sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone())
+}
+
+func unaryInterceptor(report reportFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = withHub(ctx)
+
+		start := time.Now()
+		res, err := handler(ctx, req)
+		report(ctx, info.FullMethod, time.Since(start), err)
+
+		return res, err
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamInterceptor(report reportFunc) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withHub(stream.Context())
+
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		report(ctx, info.FullMethod, time.Since(start), err)
+
+		return err
+	}
+}
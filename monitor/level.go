@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the minimum severity of the logged entries.
+type Level int32
+
+const (
+	// DebugLevel logs every entry, including the successful requests logged by the gin and GRPC loggers.
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	// ErrorLevel only logs errors. Fatal entries are always logged.
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+}
+
+// ParseLevel reads a level from its name ("debug", "info", "warn" or "error"), case-insensitively.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug", "trace":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return DebugLevel, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+var level atomic.Int32
+
+// The initial level is read from the LOG_LEVEL variable, and defaults to DebugLevel.
+func init() {
+	if parsed, err := ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+		level.Store(int32(parsed))
+	}
+}
+
+// SetLevel changes the minimum level of every logger of the process, at runtime.
+func SetLevel(l Level) {
+	level.Store(int32(l))
+}
+
+// GetLevel returns the minimum level of the loggers.
+func GetLevel() Level {
+	return Level(level.Load())
+}
+
+func enabled(l Level) bool {
+	return l >= GetLevel()
+}
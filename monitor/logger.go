@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 	"io"
 )
 
@@ -11,6 +12,11 @@ type Logger interface {
 	Error(err error, msg string)
 	Warn(msg string)
 	Info(msg string)
+	// With returns a logger adding a structured field to every entry, instead of formatting it in the message.
+	// Calls can be chained:
+	//
+	//	logger.With("account", accountID).With("attempt", attempt).Warn("[sync] reconnecting")
+	With(key string, value any) Logger
 
 	io.Writer
 }
@@ -23,4 +29,8 @@ type GinLogger interface {
 type GRPCLogger interface {
 	Logger
 	Report(ctx context.Context, service string, err error)
+	// UnaryInterceptor reports every unary call handled by the server, with its latency.
+	UnaryInterceptor() grpc.UnaryServerInterceptor
+	// StreamInterceptor reports every stream handled by the server, once it ends.
+	StreamInterceptor() grpc.StreamServerInterceptor
 }
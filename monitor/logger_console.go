@@ -5,46 +5,97 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"log"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type consoleLogger struct{}
+type consoleField struct {
+	key   string
+	value any
+}
+
+type consoleLogger struct {
+	fields []consoleField
+}
+
+// suffix returns the fields of the logger, as readable key=value pairs.
+func (l *consoleLogger) suffix() string {
+	if len(l.fields) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(l.fields))
+	for _, field := range l.fields {
+		value := fmt.Sprint(field.value)
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+
+		parts = append(parts, field.key+"="+value)
+	}
+
+	return " " + color.New(color.Faint).Sprint(strings.Join(parts, " "))
+}
 
 func (l *consoleLogger) Fatal(err error, msg string) {
 	colorizer := color.New(color.FgMagenta).SprintFunc()
 
 	if msg == "" {
-		log.Fatal(colorizer(err.Error()))
+		log.Print(colorizer(err.Error()) + l.suffix())
 	} else {
-		log.Fatal(colorizer(fmt.Sprintf("%s: %s\n", msg, err.Error())))
+		log.Print(colorizer(fmt.Sprintf("%s: %s", msg, err.Error())) + l.suffix())
 	}
+
+	crash(ExitFatal, msg, err, debug.Stack())
 }
 
 func (l *consoleLogger) Error(err error, msg string) {
+	if !enabled(ErrorLevel) {
+		return
+	}
+
 	colorizer := color.New(color.FgRed).SprintFunc()
 
 	if msg == "" {
-		log.Printf(colorizer("%s\n", err.Error()))
+		log.Println(colorizer(err.Error()) + l.suffix())
 	} else {
-		log.Printf(colorizer("%s: %s\n", msg, err.Error()))
+		log.Println(colorizer(fmt.Sprintf("%s: %s", msg, err.Error())) + l.suffix())
 	}
 }
 
 func (l *consoleLogger) Warn(msg string) {
+	if !enabled(WarnLevel) {
+		return
+	}
+
 	colorizer := color.New(color.FgYellow).SprintFunc()
-	log.Println(colorizer(msg))
+	log.Println(colorizer(msg) + l.suffix())
 }
 
 func (l *consoleLogger) Info(msg string) {
-	log.Println(msg)
+	if !enabled(InfoLevel) {
+		return
+	}
+
+	log.Println(msg + l.suffix())
+}
+
+func (l *consoleLogger) With(key string, value any) Logger {
+	return &consoleLogger{fields: append(slices.Clip(l.fields), consoleField{key: key, value: value})}
 }
 
 func (l *consoleLogger) Write(p []byte) (n int, err error) {
-	log.Println(string(p))
+	if enabled(InfoLevel) {
+		log.Println(string(p))
+	}
+
 	return len(p), nil
 }
 
@@ -68,12 +119,19 @@ func (l *consoleGinLogger) Middleware() gin.HandlerFunc {
 
 		colorizer := color.New(color.FgBlue).SprintFunc()
 		prefix := "✓"
+		logLevel := DebugLevel
 		if c.Writer.Status() > 499 {
 			colorizer = color.New(color.FgRed).SprintFunc()
 			prefix = "✗"
+			logLevel = ErrorLevel
 		} else if c.Writer.Status() > 399 || len(c.Errors) > 0 {
 			colorizer = color.New(color.FgYellow).SprintFunc()
 			prefix = "⟁"
+			logLevel = WarnLevel
+		}
+
+		if !enabled(logLevel) {
+			return
 		}
 
 		message := strings.Join([]string{
@@ -100,13 +158,27 @@ type consoleGRPCLogger struct {
 	consoleLogger
 }
 
-func (l *consoleGRPCLogger) Report(_ context.Context, service string, err error) {
+func (l *consoleGRPCLogger) Report(ctx context.Context, service string, err error) {
+	l.report(ctx, service, 0, err)
+}
+
+func (l *consoleGRPCLogger) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(l.report)
+}
+
+func (l *consoleGRPCLogger) StreamInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(l.report)
+}
+
+func (l *consoleGRPCLogger) report(_ context.Context, service string, latency time.Duration, err error) {
 	colorizer := color.New(color.FgBlue).SprintFunc()
 	prefix := "✓"
 	code := codes.OK
+	logLevel := DebugLevel
 
 	if err != nil {
 		code = status.Code(err)
+		logLevel = ErrorLevel
 
 		if code == codes.Unknown {
 			colorizer = color.New(color.FgRed).SprintFunc()
@@ -114,14 +186,24 @@ func (l *consoleGRPCLogger) Report(_ context.Context, service string, err error)
 		} else if code == codes.Unavailable {
 			colorizer = color.New(color.FgYellow).SprintFunc()
 			prefix = "⟁"
+			logLevel = WarnLevel
 		}
 	}
 
-	message := strings.Join([]string{
+	if !enabled(logLevel) {
+		return
+	}
+
+	parts := []string{
 		"-",
 		colorizer(color.New(color.Bold).Sprintf("%s %s", prefix, code)),
 		colorizer(fmt.Sprintf("[%s]", service)),
-	}, " ")
+	}
+	if latency > 0 {
+		parts = append(parts, color.New(color.Faint).Sprint(fmt.Sprintf("(processed in %s)", latency)))
+	}
+
+	message := strings.Join(parts, " ")
 
 	log.Println(message)
 
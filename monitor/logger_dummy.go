@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 type dummyLogger struct{}
@@ -23,6 +24,10 @@ func (d *dummyLogger) Info(_ string) {
 
 }
 
+func (d *dummyLogger) With(_ string, _ any) Logger {
+	return d
+}
+
 func (d *dummyLogger) Write(_ []byte) (int, error) {
 	return 0, nil
 }
@@ -37,6 +42,18 @@ func (d *dummyLogger) Report(_ context.Context, _ string, _ error) {
 
 }
 
+func (d *dummyLogger) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(ctx, req)
+	}
+}
+
+func (d *dummyLogger) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, stream)
+	}
+}
+
 func NewDummyLogger() Logger {
 	return &dummyLogger{}
 }
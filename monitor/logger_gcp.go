@@ -7,8 +7,10 @@ import (
 	sentrygin "github.com/getsentry/sentry-go/gin"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"runtime/debug"
 	"strings"
 	"time"
 )
@@ -19,26 +21,69 @@ type gcpLogger struct {
 }
 
 func (l *gcpLogger) Fatal(err error, msg string) {
-	l.logger.Fatal().Err(err).Msg(msg)
+	l.logger.WithLevel(zerolog.FatalLevel).Err(err).Msg(msg)
+	crash(ExitFatal, msg, err, debug.Stack())
 }
 
 func (l *gcpLogger) Error(err error, msg string) {
+	if !enabled(ErrorLevel) {
+		return
+	}
+
 	l.logger.Error().Err(err).Msg(msg)
 }
 
 func (l *gcpLogger) Warn(msg string) {
+	if !enabled(WarnLevel) {
+		return
+	}
+
 	l.logger.Warn().Msg(msg)
 }
 
 func (l *gcpLogger) Info(msg string) {
+	if !enabled(InfoLevel) {
+		return
+	}
+
 	l.logger.Info().Msg(msg)
 }
 
+func (l *gcpLogger) With(key string, value any) Logger {
+	return &gcpLogger{
+		logger:    l.logger.With().Fields([]any{key, value}).Logger(),
+		projectID: l.projectID,
+	}
+}
+
 func (l *gcpLogger) Write(p []byte) (n int, err error) {
-	l.logger.Info().Msg(string(p))
+	if enabled(InfoLevel) {
+		l.logger.Info().Msg(string(p))
+	}
+
 	return len(p), nil
 }
 
+// event starts an entry of the given level, or returns a nil event, on which every method is a no-op, when the
+// level is filtered out.
+func (l *gcpLogger) event(logLevel zerolog.Level) *zerolog.Event {
+	minLevel := DebugLevel
+	switch {
+	case logLevel >= zerolog.ErrorLevel:
+		minLevel = ErrorLevel
+	case logLevel == zerolog.WarnLevel:
+		minLevel = WarnLevel
+	case logLevel == zerolog.InfoLevel:
+		minLevel = InfoLevel
+	}
+
+	if !enabled(minLevel) {
+		return nil
+	}
+
+	return l.logger.WithLevel(logLevel)
+}
+
 func newGCPLogger(logger zerolog.Logger, projectID string) *gcpLogger {
 	return &gcpLogger{
 		logger:    logger,
@@ -87,7 +132,7 @@ func (l *gcpGinLogger) Middleware() gin.HandlerFunc {
 			}
 		}
 
-		ll := l.logger.WithLevel(logLevel).
+		ll := l.event(logLevel).
 			Dict(
 				"httpRequest", zerolog.Dict().
 					Str("requestMethod", c.Request.Method).
@@ -132,6 +177,18 @@ type gcpGRPCLogger struct {
 }
 
 func (l *gcpGRPCLogger) Report(ctx context.Context, service string, err error) {
+	l.report(ctx, service, 0, err)
+}
+
+func (l *gcpGRPCLogger) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(l.report)
+}
+
+func (l *gcpGRPCLogger) StreamInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(l.report)
+}
+
+func (l *gcpGRPCLogger) report(ctx context.Context, service string, latency time.Duration, err error) {
 	logLevel := zerolog.TraceLevel
 	severity := "INFO" // For GCP.
 	code := codes.OK
@@ -142,12 +199,15 @@ func (l *gcpGRPCLogger) Report(ctx context.Context, service string, err error) {
 		code = status.Code(err)
 	}
 
-	ll := l.logger.WithLevel(logLevel).
-		Dict(
-			"grpcRequest", zerolog.Dict().
-				Str("service", service).
-				Uint32("code", uint32(code)),
-		).
+	request := zerolog.Dict().
+		Str("service", service).
+		Uint32("code", uint32(code))
+	if latency > 0 {
+		request = request.Str("latency", latency.String())
+	}
+
+	ll := l.event(logLevel).
+		Dict("grpcRequest", request).
 		Err(err).
 		Str("severity", severity)
 
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MetricsPath is the path of the endpoint scraped by Prometheus.
+const MetricsPath = "/metrics"
+
+// unmatchedRoute labels the requests matching no route, so scanners do not create a series per path.
+const unmatchedRoute = "unmatched"
+
+type MetricsConfig struct {
+	// Namespace prefixes the name of every metric, for example "inrich". Optional.
+	Namespace string
+	// Buckets of the latency histograms, in seconds. Defaults to prometheus.DefBuckets.
+	Buckets []float64
+}
+
+func (c MetricsConfig) withDefaults() MetricsConfig {
+	if len(c.Buckets) == 0 {
+		c.Buckets = prometheus.DefBuckets
+	}
+
+	return c
+}
+
+// Metrics records the requests of a service, and exposes them to Prometheus with the Go runtime and process
+// metrics.
+//
+//	metrics := monitor.NewMetrics(monitor.MetricsConfig{Namespace: "inrich"})
+//	router := gin.New()
+//	router.Use(metrics.GinMiddleware())
+//	metrics.Register(router)
+//
+// Requests are labeled by route template (c.FullPath), never by raw path, to keep the number of series bounded.
+// GRPC servers record their calls with the interceptors of the metrics, installed by deploy.WithMetrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpLatency  *prometheus.HistogramVec
+	httpInFlight prometheus.Gauge
+
+	grpcCalls   *prometheus.CounterVec
+	grpcLatency *prometheus.HistogramVec
+}
+
+func NewMetrics(config MetricsConfig) *Metrics {
+	config = config.withDefaults()
+
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests handled, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by method, route and status.",
+			Buckets:   config.Buckets,
+		}, []string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: config.Namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being handled.",
+		}),
+		grpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "grpc_server_handled_total",
+			Help:      "Number of GRPC calls handled, by method, type and status code.",
+		}, []string{"method", "type", "code"}),
+		grpcLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "grpc_server_handling_seconds",
+			Help:      "Latency of the GRPC calls, by method, type and status code.",
+			Buckets:   config.Buckets,
+		}, []string{"method", "type", "code"}),
+	}
+
+	metrics.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.httpRequests,
+		metrics.httpLatency,
+		metrics.httpInFlight,
+		metrics.grpcCalls,
+		metrics.grpcLatency,
+	)
+
+	return metrics
+}
+
+// Registry returns the registry of the metrics, to register the metrics of the service.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Register exposes the metrics on GET /metrics. Requests to the endpoint itself are not recorded.
+func (m *Metrics) Register(router gin.IRoutes) {
+	router.GET(MetricsPath, gin.WrapH(m.Handler()))
+}
+
+// GinMiddleware records the count, latency and status of every request, and the number of requests in flight.
+func (m *Metrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == MetricsPath {
+			c.Next()
+			return
+		}
+
+		m.httpInFlight.Inc()
+		defer m.httpInFlight.Dec()
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		code := strconv.Itoa(c.Writer.Status())
+		m.httpRequests.WithLabelValues(c.Request.Method, route, code).Inc()
+		m.httpLatency.WithLabelValues(c.Request.Method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) observeGRPC(method, callType string, start time.Time, err error) {
+	code := status.Code(err).String()
+	m.grpcCalls.WithLabelValues(method, callType, code).Inc()
+	m.grpcLatency.WithLabelValues(method, callType, code).Observe(time.Since(start).Seconds())
+}
+
+// UnaryServerInterceptor records the count, latency and status code of every unary call.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		m.observeGRPC(info.FullMethod, "unary", start, err)
+
+		return res, err
+	}
+}
+
+// StreamServerInterceptor records the count, duration and status code of every stream, once it ends.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		m.observeGRPC(info.FullMethod, "stream", start, err)
+
+		return err
+	}
+}
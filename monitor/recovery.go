@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// RecoveryBody is the body of the responses to requests whose handler panicked. It never contains the panic value,
+// which may hold internal data.
+var RecoveryBody = gin.H{"error": "internal server error"}
+
+// brokenConnection reports whether the panic is caused by the client going away, which is not worth reporting.
+func brokenConnection(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+
+	message := strings.ToLower(syscallErr.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
+
+// RecoveryMiddleware replaces gin.Recovery: it catches the panics of the handlers, logs them with their stack
+// through the logger, reports them to Sentry, and responds with a 500 and RecoveryBody. Install it after the
+// Sentry and logger middlewares, so the panic is reported with the request, and the 500 is logged:
+//
+//	router := gin.New()
+//	router.Use(monitor.SentryGinMiddleware(), ginLogger.Middleware(), monitor.RecoveryMiddleware(ginLogger))
+func RecoveryMiddleware(logger Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// Handlers panic with ErrAbortHandler to abort the response on purpose.
+			if recovered == http.ErrAbortHandler {
+				c.Abort()
+				return
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = errors.New(fmt.Sprint(recovered))
+			}
+
+			if brokenConnection(err) {
+				_ = c.Error(err)
+				c.Abort()
+				return
+			}
+
+			logger.
+				With("stack", string(debug.Stack())).
+				Error(err, fmt.Sprintf("[monitor] panic handling %s %s", c.Request.Method, c.FullPath()))
+
+			hub := sentrygin.GetHubFromContext(c)
+			if hub == nil {
+				hub = sentry.CurrentHub().Clone()
+				hub.Scope().SetRequest(c.Request)
+			}
+			hub.RecoverWithContext(c.Request.Context(), recovered)
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, RecoveryBody)
+		}()
+
+		c.Next()
+	}
+}
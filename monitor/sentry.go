@@ -0,0 +1,233 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"time"
+)
+
+// Default traces sample rates, per environment.
+var sentryTracesSampleRates = map[string]float64{
+	"dev":     0,
+	"staging": 0.1,
+	"prod":    0.02,
+}
+
+const scrubbed = "[Filtered]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// URNs identify LinkedIn members and companies ("urn:li:fsd_profile:ACoAAB...").
+	urnPattern = regexp.MustCompile(`urn:[A-Za-z0-9][A-Za-z0-9\-]{0,31}:[^\s"',;)]+`)
+)
+
+// SentryConfig holds the Sentry settings of a service. Every service gets the same privacy behavior, unless a
+// value is explicitly overridden.
+type SentryConfig struct {
+	DSN string `yaml:"dsn" json:"dsn"`
+	// Environment defaults to the ENV variable, or "dev".
+	Environment string `yaml:"environment" json:"environment"`
+	Release     string `yaml:"release" json:"release"`
+	// TracesSampleRate overrides the default rate of the environment: 0 in dev, 0.1 in staging, 0.02 in prod.
+	TracesSampleRate *float64 `yaml:"tracesSampleRate" json:"tracesSampleRate"`
+	// SendDefaultPII sends user IPs, cookies and headers. Defaults to false.
+	SendDefaultPII bool `yaml:"sendDefaultPII" json:"sendDefaultPII"`
+	// DisableScrubbing keeps emails and URNs in events. Defaults to false.
+	DisableScrubbing bool `yaml:"disableScrubbing" json:"disableScrubbing"`
+}
+
+// SentryOptions returns the client options of the configuration, for sentry.Init.
+func SentryOptions(config SentryConfig) sentry.ClientOptions {
+	environment := config.Environment
+	if environment == "" {
+		environment = os.Getenv("ENV")
+	}
+	if environment == "" {
+		environment = "dev"
+	}
+
+	rate := sentryTracesSampleRates[environment]
+	if config.TracesSampleRate != nil {
+		rate = *config.TracesSampleRate
+	}
+
+	options := sentry.ClientOptions{
+		Dsn:              config.DSN,
+		Environment:      environment,
+		Release:          config.Release,
+		EnableTracing:    rate > 0,
+		TracesSampleRate: rate,
+		SendDefaultPII:   config.SendDefaultPII,
+	}
+
+	if !config.DisableScrubbing {
+		options.BeforeSend = func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			return ScrubEvent(event, !config.SendDefaultPII)
+		}
+		options.BeforeSendTransaction = func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			return ScrubEvent(event, !config.SendDefaultPII)
+		}
+		options.BeforeBreadcrumb = func(breadcrumb *sentry.Breadcrumb, _ *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+			breadcrumb.Message = ScrubString(breadcrumb.Message)
+			scrubMap(breadcrumb.Data)
+			return breadcrumb
+		}
+	}
+
+	return options
+}
+
+// ScrubString replaces the emails and URNs of a string.
+func ScrubString(value string) string {
+	value = emailPattern.ReplaceAllString(value, scrubbed)
+	return urnPattern.ReplaceAllString(value, scrubbed)
+}
+
+func scrubMap(values map[string]any) {
+	for key, value := range values {
+		if text, ok := value.(string); ok {
+			values[key] = ScrubString(text)
+		}
+	}
+}
+
+// ScrubEvent removes the emails and URNs of an event. When removeUser is set, the identifying data of the user
+// and of the request are also removed, keeping only the user ID.
+func ScrubEvent(event *sentry.Event, removeUser bool) *sentry.Event {
+	event.Message = ScrubString(event.Message)
+	event.Transaction = ScrubString(event.Transaction)
+
+	for i := range event.Exception {
+		event.Exception[i].Value = ScrubString(event.Exception[i].Value)
+	}
+	for _, breadcrumb := range event.Breadcrumbs {
+		breadcrumb.Message = ScrubString(breadcrumb.Message)
+		scrubMap(breadcrumb.Data)
+	}
+	for key, value := range event.Tags {
+		event.Tags[key] = ScrubString(value)
+	}
+	scrubMap(event.Extra)
+
+	if request := event.Request; request != nil {
+		request.URL = ScrubString(request.URL)
+		request.QueryString = ScrubString(request.QueryString)
+		request.Data = ScrubString(request.Data)
+
+		if removeUser {
+			request.Cookies = ""
+			request.Env = nil
+			for name := range request.Headers {
+				if name != "User-Agent" && name != "Content-Type" && name != "Accept" {
+					delete(request.Headers, name)
+				}
+			}
+		}
+	}
+
+	if removeUser {
+		event.User = sentry.User{ID: event.User.ID}
+	} else {
+		event.User.Email = ScrubString(event.User.Email)
+	}
+
+	return event
+}
+
+// sentryRelease returns the release of the running binary: the SENTRY_RELEASE variable, the Cloud Run revision, or
+// the VCS revision the binary was built from.
+func sentryRelease() string {
+	if release := os.Getenv("SENTRY_RELEASE"); release != "" {
+		return release
+	}
+	if revision := os.Getenv("K_REVISION"); revision != "" {
+		return revision
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+// InitSentry initializes the global Sentry client. Values missing from the configuration are read from the
+// SENTRY_DSN, SENTRY_ENVIRONMENT and SENTRY_RELEASE variables; the environment then defaults to ENV, and the
+// release to the Cloud Run revision. Sentry is disabled when no DSN is found.
+//
+// The returned function flushes the buffered events, and must run before the process exits:
+//
+//	flush, err := monitor.InitSentry(cfg.Sentry)
+//	if err != nil {
+//		logger.Fatal(err, "[main] failed to initialize sentry")
+//	}
+//	app.OnShutdown("sentry", flush)
+//
+//	router.Use(monitor.SentryGinMiddleware(), ginLogger.Middleware(), monitor.RecoveryMiddleware(ginLogger))
+//
+// GRPC servers get a hub per call from the interceptors of GRPCLogger, or from SentryUnaryInterceptor and
+// SentryStreamInterceptor.
+func InitSentry(config SentryConfig) (func(ctx context.Context) error, error) {
+	if config.DSN == "" {
+		config.DSN = os.Getenv("SENTRY_DSN")
+	}
+	if config.Environment == "" {
+		config.Environment = os.Getenv("SENTRY_ENVIRONMENT")
+	}
+	if config.Release == "" {
+		config.Release = sentryRelease()
+	}
+
+	if err := sentry.Init(SentryOptions(config)); err != nil {
+		return nil, fmt.Errorf("init sentry: %w", err)
+	}
+
+	flush := func(ctx context.Context) error {
+		timeout := 5 * time.Second
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+
+		if !sentry.Flush(timeout) {
+			return fmt.Errorf("flush sentry: events still buffered after %s", timeout)
+		}
+
+		return nil
+	}
+
+	return flush, nil
+}
+
+// SentryGinMiddleware attaches a dedicated Sentry hub to every request, used by the gin loggers and
+// RecoveryMiddleware to report errors with the request. Panics escaping the inner middlewares are reported, then
+// propagated.
+func SentryGinMiddleware() gin.HandlerFunc {
+	return sentrygin.New(sentrygin.Options{Repanic: true})
+}
+
+// SentryUnaryInterceptor attaches a dedicated Sentry hub to every unary call, for servers not using the
+// interceptors of GRPCLogger.
+func SentryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withHub(ctx), req)
+	}
+}
+
+// SentryStreamInterceptor attaches a dedicated Sentry hub to every stream, for servers not using the interceptors
+// of GRPCLogger.
+func SentryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: stream, ctx: withHub(stream.Context())})
+	}
+}
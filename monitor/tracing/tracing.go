@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cloudtrace "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/trace"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/stats"
+	"os"
+)
+
+// instrumentationName names the tracer of the spans started with Start.
+const instrumentationName = "github.com/in-rich/lib-go"
+
+// Default sample rates, per environment.
+var sampleRates = map[string]float64{
+	"dev":     1,
+	"staging": 0.1,
+	"prod":    0.02,
+}
+
+type Config struct {
+	// ServiceName defaults to the K_SERVICE variable set by Cloud Run.
+	ServiceName string `yaml:"serviceName" json:"serviceName"`
+	// ProjectID receives the traces in Cloud Trace. Required in release environments.
+	ProjectID string `yaml:"projectID" json:"projectID"`
+	// SampleRate overrides the default rate of the environment: 1 in dev, 0.1 in staging, 0.02 in prod. Spans
+	// whose parent was sampled by the caller are always sampled.
+	SampleRate *float64 `yaml:"sampleRate" json:"sampleRate"`
+}
+
+func environment() string {
+	if env := os.Getenv("ENV"); env != "" {
+		return env
+	}
+
+	return "dev"
+}
+
+func (c Config) serviceName() string {
+	if c.ServiceName != "" {
+		return c.ServiceName
+	}
+	if name := os.Getenv("K_SERVICE"); name != "" {
+		return name
+	}
+
+	return "local"
+}
+
+func (c Config) sampleRate() float64 {
+	if c.SampleRate != nil {
+		return *c.SampleRate
+	}
+
+	return sampleRates[environment()]
+}
+
+func newExporter(config Config) (sdktrace.SpanExporter, error) {
+	if environment() == "dev" {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+
+	if config.ProjectID == "" {
+		return nil, errors.New("a project ID is required to export traces to Cloud Trace")
+	}
+
+	return cloudtrace.New(cloudtrace.WithProjectID(config.ProjectID))
+}
+
+// Init registers the global tracer provider, exporting spans to Cloud Trace in release environments, and to the
+// standard output in dev. The returned function flushes the pending spans, and must be called on shutdown.
+//
+//	shutdown, err := tracing.Init(ctx, cfg.Tracing)
+//	defer shutdown(context.Background())
+//
+// GRPC servers started with deploy.StartGRPCServer and connections opened with deploy.OpenGRPCConn trace their
+// calls once Init is called, and propagate the trace context to other services. Gin routers must use GinMiddleware.
+func Init(ctx context.Context, config Config) (func(ctx context.Context) error, error) {
+	exporter, err := newExporter(config)
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(config.serviceName())))
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.sampleRate()))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span of the context, for operations worth tracing within a request.
+//
+//	ctx, span := tracing.Start(ctx, "notes.render")
+//	defer span.End()
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// GinMiddleware starts a span for every request, continuing the trace of the caller.
+func GinMiddleware(config Config) gin.HandlerFunc {
+	return otelgin.Middleware(config.serviceName())
+}
+
+// ServerHandler traces the calls handled by a GRPC server. It is installed by deploy.StartGRPCServer.
+func ServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler()
+}
+
+// ClientHandler traces the calls performed on a GRPC connection. It is installed by deploy.OpenGRPCConn.
+func ClientHandler() stats.Handler {
+	return otelgrpc.NewClientHandler()
+}
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WatchdogConfig struct {
+	// Interval between two samples. Defaults to 1 minute.
+	Interval time.Duration
+	// MaxHeap is the number of heap bytes in use above which profiles are dumped. Zero disables the threshold.
+	MaxHeap uint64
+	// MaxGoroutines is the number of goroutines above which profiles are dumped. Zero disables the threshold.
+	MaxGoroutines int
+	// MaxFDs is the number of open file descriptors above which profiles are dumped. Zero disables the threshold.
+	MaxFDs int
+	// Cooldown is the minimum delay between two dumps, so a leaking instance does not flood the bucket. Defaults to
+	// 30 minutes.
+	Cooldown time.Duration
+	// Bucket receives the heap and goroutine profiles. Profiles are not dumped when empty, and exceeded thresholds
+	// are only logged.
+	Bucket string
+	// Prefix of the profile objects. Defaults to "profiles/".
+	Prefix  string
+	Options []option.ClientOption
+}
+
+func (c WatchdogConfig) withDefaults() WatchdogConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Minute
+	}
+	if c.Prefix == "" {
+		c.Prefix = "profiles/"
+	}
+
+	return c
+}
+
+// Sample is a snapshot of the resources used by the process.
+type Sample struct {
+	At          time.Time
+	HeapInuse   uint64
+	HeapAlloc   uint64
+	HeapObjects uint64
+	Goroutines  int
+	// FDs is the number of open file descriptors, or -1 when unavailable (outside Linux).
+	FDs int
+}
+
+// Exceeded returns the thresholds of the configuration exceeded by the sample.
+func (s Sample) Exceeded(config WatchdogConfig) []string {
+	var exceeded []string
+
+	if config.MaxHeap > 0 && s.HeapInuse > config.MaxHeap {
+		exceeded = append(exceeded, fmt.Sprintf("heap %d > %d", s.HeapInuse, config.MaxHeap))
+	}
+	if config.MaxGoroutines > 0 && s.Goroutines > config.MaxGoroutines {
+		exceeded = append(exceeded, fmt.Sprintf("goroutines %d > %d", s.Goroutines, config.MaxGoroutines))
+	}
+	if config.MaxFDs > 0 && s.FDs > config.MaxFDs {
+		exceeded = append(exceeded, fmt.Sprintf("fds %d > %d", s.FDs, config.MaxFDs))
+	}
+
+	return exceeded
+}
+
+func (s Sample) String() string {
+	return fmt.Sprintf(
+		"heapInuse=%d heapAlloc=%d heapObjects=%d goroutines=%d fds=%d",
+		s.HeapInuse, s.HeapAlloc, s.HeapObjects, s.Goroutines, s.FDs,
+	)
+}
+
+// Watchdog periodically samples the heap, goroutines and open file descriptors of the process, to diagnose slow
+// leaks. When a threshold is exceeded, heap and goroutine profiles are uploaded to GCS, to be inspected with
+// "go tool pprof".
+//
+//	watchdog, err := monitor.NewWatchdog(ctx, logger, monitor.WatchdogConfig{
+//		MaxHeap:       512 << 20,
+//		MaxGoroutines: 10000,
+//		Bucket:        "inrich-profiles",
+//	})
+//	go watchdog.Run(ctx)
+type Watchdog struct {
+	logger  Logger
+	config  WatchdogConfig
+	service *storage.Service
+
+	mu       sync.Mutex
+	lastDump time.Time
+}
+
+func NewWatchdog(ctx context.Context, logger Logger, config WatchdogConfig) (*Watchdog, error) {
+	watchdog := &Watchdog{logger: logger, config: config.withDefaults()}
+
+	if watchdog.config.Bucket != "" {
+		service, err := storage.NewService(ctx, watchdog.config.Options...)
+		if err != nil {
+			return nil, fmt.Errorf("create storage client: %w", err)
+		}
+
+		watchdog.service = service
+	}
+
+	return watchdog, nil
+}
+
+// withSample returns the logger of the watchdog, with the sample as fields.
+func (w *Watchdog) withSample(sample Sample) Logger {
+	return w.logger.
+		With("heapInuse", sample.HeapInuse).
+		With("heapAlloc", sample.HeapAlloc).
+		With("heapObjects", sample.HeapObjects).
+		With("goroutines", sample.Goroutines).
+		With("fds", sample.FDs)
+}
+
+// Sample returns the current usage of the process.
+func (w *Watchdog) Sample() Sample {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return Sample{
+		At:          time.Now(),
+		HeapInuse:   stats.HeapInuse,
+		HeapAlloc:   stats.HeapAlloc,
+		HeapObjects: stats.HeapObjects,
+		Goroutines:  runtime.NumGoroutine(),
+		FDs:         countFDs(),
+	}
+}
+
+func countFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	return len(entries)
+}
+
+// CheckOnce samples the process, logs the sample, and dumps the profiles if a threshold is exceeded.
+func (w *Watchdog) CheckOnce(ctx context.Context) Sample {
+	sample := w.Sample()
+
+	exceeded := sample.Exceeded(w.config)
+	if len(exceeded) == 0 {
+		w.withSample(sample).Info("[watchdog] process sample")
+		return sample
+	}
+
+	w.withSample(sample).Warn("[watchdog] thresholds exceeded: " + strings.Join(exceeded, ", "))
+
+	if w.service == nil {
+		return sample
+	}
+
+	w.mu.Lock()
+	if time.Since(w.lastDump) < w.config.Cooldown {
+		w.mu.Unlock()
+		return sample
+	}
+	w.lastDump = time.Now()
+	w.mu.Unlock()
+
+	if err := w.Dump(ctx); err != nil {
+		w.logger.Error(err, "[watchdog] failed to dump profiles")
+	}
+
+	return sample
+}
+
+// Dump uploads the heap and goroutine profiles of the process to the bucket, regardless of the thresholds.
+func (w *Watchdog) Dump(ctx context.Context) error {
+	if w.service == nil {
+		return errors.New("no bucket configured")
+	}
+
+	// Objects are grouped by service and instance, sorted by time.
+	name := fmt.Sprintf(
+		"%s%s/%s/%s", w.config.Prefix, serviceName(), instanceName(), time.Now().UTC().Format("20060102T150405Z"),
+	)
+
+	var errs []error
+	for _, profile := range []string{"heap", "goroutine"} {
+		var buf bytes.Buffer
+		if err := pprof.Lookup(profile).WriteTo(&buf, 0); err != nil {
+			errs = append(errs, fmt.Errorf("write %s profile: %w", profile, err))
+			continue
+		}
+
+		object := &storage.Object{Name: name + "-" + profile + ".pprof", ContentType: "application/octet-stream"}
+		_, err := w.service.Objects.Insert(w.config.Bucket, object).Media(&buf).Context(ctx).Do()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("upload %s profile: %w", profile, err))
+			continue
+		}
+
+		w.logger.Info(fmt.Sprintf("[watchdog] uploaded %s profile to gs://%s/%s", profile, w.config.Bucket, object.Name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run checks the process at every interval, until the context is canceled.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.CheckOnce(ctx)
+		}
+	}
+}
+
+func serviceName() string {
+	if name := os.Getenv("K_SERVICE"); name != "" {
+		return name
+	}
+
+	return "local"
+}
+
+func instanceName() string {
+	host, _ := os.Hostname()
+	return host
+}
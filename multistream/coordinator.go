@@ -0,0 +1,415 @@
+package multistream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPreempted is the cause of the context of a stream disconnected to give its slot to a waiting stream.
+var ErrPreempted = errors.New("stream preempted by a waiting stream")
+
+type State string
+
+const (
+	StateWaiting   State = "waiting"
+	StateConnected State = "connected"
+	StateBackoff   State = "backoff"
+)
+
+// Handler consumes the upstream stream of a key, such as the event stream of a LinkedIn account. It returns when
+// the stream ends or fails, and is called again to reconnect. The handler must return once its context is canceled,
+// and resume from its own cursor on the next call.
+type Handler[K comparable] func(ctx context.Context, key K, progress *Progress) error
+
+// Progress counts the items received on a stream.
+type Progress struct {
+	items atomic.Int64
+}
+
+// Add reports items received on the stream.
+func (p *Progress) Add(n int) {
+	p.items.Add(int64(n))
+}
+
+type Config struct {
+	// MaxConcurrent is the number of streams connected at once. Other streams wait for a slot, in order. Defaults
+	// to 100.
+	MaxConcurrent int
+	// Slice is the time after which a connected stream gives its slot to a waiting stream, so every stream
+	// progresses when there are more streams than slots. Defaults to 10 minutes.
+	Slice time.Duration
+	// InitialBackoff is the delay before reconnecting a failed stream. It doubles after each consecutive failure,
+	// up to MaxBackoff. Defaults to 1 second and 5 minutes.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// ResetAfter resets the backoff of streams that stayed connected for the given duration before failing.
+	// Defaults to 1 minute.
+	ResetAfter time.Duration
+	// ReportInterval is the interval of the aggregated progress logs. Defaults to 1 minute.
+	ReportInterval time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 100
+	}
+	if c.Slice <= 0 {
+		c.Slice = 10 * time.Minute
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Minute
+	}
+	if c.ResetAfter <= 0 {
+		c.ResetAfter = time.Minute
+	}
+	if c.ReportInterval <= 0 {
+		c.ReportInterval = time.Minute
+	}
+
+	return c
+}
+
+func (c Config) backoff(failures int) time.Duration {
+	delay := c.InitialBackoff << min(failures-1, 30)
+	if delay <= 0 || delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+
+	// Spread the reconnections of streams failing together, such as on an upstream outage.
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Status is the state of a stream.
+type Status[K comparable] struct {
+	Key         K
+	State       State
+	Failures    int
+	LastError   error
+	Items       int64
+	ConnectedAt time.Time
+}
+
+type stream[K comparable] struct {
+	key      K
+	progress Progress
+	cancel   context.CancelFunc
+
+	// Guarded by the mutex of the coordinator.
+	state       State
+	failures    int
+	lastError   error
+	connectedAt time.Time
+	preempt     context.CancelCauseFunc
+}
+
+// Coordinator keeps one long-lived upstream stream per key, such as per LinkedIn account of the sync service.
+// Streams reconnect with exponential backoff when they fail, share a bounded number of connection slots fairly,
+// and their progress is logged as a whole.
+//
+//	coordinator := multistream.NewCoordinator(syncAccount, logger, multistream.Config{MaxConcurrent: 200})
+//	for _, account := range accounts {
+//		coordinator.Add(account.ID)
+//	}
+//	go coordinator.Run(ctx)
+//
+//	func syncAccount(ctx context.Context, accountID string, progress *multistream.Progress) error {
+//		events, err := linkedin.Subscribe(ctx, accountID, cursors.Get(accountID))
+//		...
+//		progress.Add(len(batch))
+//	}
+type Coordinator[K comparable] struct {
+	handler Handler[K]
+	logger  monitor.Logger
+	config  Config
+
+	mu      sync.Mutex
+	ctx     context.Context
+	wg      sync.WaitGroup
+	streams map[K]*stream[K]
+	active  int
+	waiters []*waiter[K]
+}
+
+type waiter[K comparable] struct {
+	stream *stream[K]
+	ready  chan struct{}
+}
+
+func NewCoordinator[K comparable](handler Handler[K], logger monitor.Logger, config Config) *Coordinator[K] {
+	return &Coordinator[K]{
+		handler: handler,
+		logger:  logger,
+		config:  config.withDefaults(),
+		streams: make(map[K]*stream[K]),
+	}
+}
+
+// Add starts managing the stream of a key. Streams added before Run connect once it starts. Adding a key twice
+// has no effect.
+func (c *Coordinator[K]) Add(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.streams[key]; ok {
+		return
+	}
+
+	s := &stream[K]{key: key, state: StateWaiting}
+	c.streams[key] = s
+
+	if c.ctx != nil {
+		c.start(s)
+	}
+}
+
+// Remove disconnects the stream of a key, and stops reconnecting it.
+func (c *Coordinator[K]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.streams[key]; ok {
+		if s.cancel != nil {
+			s.cancel()
+		}
+		delete(c.streams, key)
+	}
+}
+
+// start launches the loop of a stream. It must be called with the lock held, once Run started.
+func (c *Coordinator[K]) start(s *stream[K]) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	s.cancel = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		c.loop(ctx, s)
+	}()
+}
+
+// Run connects the streams until the context is canceled, then waits for every handler to return.
+func (c *Coordinator[K]) Run(ctx context.Context) {
+	c.mu.Lock()
+	c.ctx = ctx
+	for _, s := range c.streams {
+		c.start(s)
+	}
+	c.mu.Unlock()
+
+	report := time.NewTicker(c.config.ReportInterval)
+	defer report.Stop()
+
+	// Check for streams to preempt often enough to respect the slice.
+	preempt := time.NewTicker(min(c.config.Slice/4, 10*time.Second))
+	defer preempt.Stop()
+
+	var reported int64
+	lastReport := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.wg.Wait()
+			return
+		case <-preempt.C:
+			c.preemptExpired()
+		case now := <-report.C:
+			reported = c.report(reported, now.Sub(lastReport))
+			lastReport = now
+		}
+	}
+}
+
+func (c *Coordinator[K]) loop(ctx context.Context, s *stream[K]) {
+	for {
+		if !c.acquire(ctx, s) {
+			return
+		}
+
+		streamCtx, preempt := context.WithCancelCause(ctx)
+
+		c.mu.Lock()
+		s.state = StateConnected
+		s.connectedAt = time.Now()
+		s.preempt = preempt
+		c.mu.Unlock()
+
+		err := c.handler(streamCtx, s.key, &s.progress)
+		preempted := errors.Is(context.Cause(streamCtx), ErrPreempted)
+		preempt(nil)
+
+		c.release()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.mu.Lock()
+		connected := time.Since(s.connectedAt)
+		s.preempt = nil
+
+		if preempted || err == nil {
+			// Preempted and completed streams go back to the end of the queue, without delay.
+			s.state = StateWaiting
+			s.failures = 0
+			c.mu.Unlock()
+			continue
+		}
+
+		if connected >= c.config.ResetAfter {
+			s.failures = 0
+		}
+		s.failures++
+		s.lastError = err
+		s.state = StateBackoff
+		delay := c.config.backoff(s.failures)
+		c.mu.Unlock()
+
+		c.logger.Warn(fmt.Sprintf(
+			"[multistream] stream %v failed (%d in a row), reconnecting in %s: %s", s.key, s.failures, delay, err,
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		c.mu.Lock()
+		s.state = StateWaiting
+		c.mu.Unlock()
+	}
+}
+
+// acquire waits for a connection slot, in the order streams asked for one. It returns false if the context is
+// canceled first.
+func (c *Coordinator[K]) acquire(ctx context.Context, s *stream[K]) bool {
+	c.mu.Lock()
+	if c.active < c.config.MaxConcurrent && len(c.waiters) == 0 {
+		c.active++
+		c.mu.Unlock()
+		return true
+	}
+
+	w := &waiter[K]{stream: s, ready: make(chan struct{})}
+	c.waiters = append(c.waiters, w)
+	c.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if index := slices.Index(c.waiters, w); index >= 0 {
+		c.waiters = slices.Delete(c.waiters, index, index+1)
+		return false
+	}
+
+	// The slot was handed over while the context was canceled: pass it on.
+	c.handOver()
+	return false
+}
+
+// release gives the slot of a stream to the first waiting stream.
+func (c *Coordinator[K]) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handOver()
+}
+
+// handOver gives a slot to the first waiting stream, or frees it. It must be called with the lock held.
+func (c *Coordinator[K]) handOver() {
+	if len(c.waiters) == 0 {
+		c.active--
+		return
+	}
+
+	next := c.waiters[0]
+	c.waiters = c.waiters[1:]
+	close(next.ready)
+}
+
+// preemptExpired disconnects the streams connected for longer than the slice, oldest first, as long as other
+// streams are waiting for a slot.
+func (c *Coordinator[K]) preemptExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.waiters) == 0 {
+		return
+	}
+
+	var expired []*stream[K]
+	for _, s := range c.streams {
+		if s.state == StateConnected && s.preempt != nil && time.Since(s.connectedAt) >= c.config.Slice {
+			expired = append(expired, s)
+		}
+	}
+
+	slices.SortFunc(expired, func(a, b *stream[K]) int { return a.connectedAt.Compare(b.connectedAt) })
+
+	for _, s := range expired[:min(len(expired), len(c.waiters))] {
+		s.preempt(ErrPreempted)
+		s.preempt = nil
+	}
+}
+
+// Statuses returns the state of every stream.
+func (c *Coordinator[K]) Statuses() []Status[K] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]Status[K], 0, len(c.streams))
+	for _, s := range c.streams {
+		out = append(out, Status[K]{
+			Key:         s.key,
+			State:       s.state,
+			Failures:    s.failures,
+			LastError:   s.lastError,
+			Items:       s.progress.items.Load(),
+			ConnectedAt: s.connectedAt,
+		})
+	}
+
+	return out
+}
+
+// report logs the aggregated progress of the streams, and returns the total number of items.
+func (c *Coordinator[K]) report(previous int64, elapsed time.Duration) int64 {
+	counts := make(map[State]int)
+	var items int64
+
+	for _, status := range c.Statuses() {
+		counts[status.State]++
+		items += status.Items
+	}
+
+	// Items of removed streams are no longer counted.
+	received := max(items-previous, 0)
+
+	c.logger.Info(fmt.Sprintf(
+		"[multistream] %d connected, %d waiting, %d in backoff: %d items received (%.1f items/s)",
+		counts[StateConnected], counts[StateWaiting], counts[StateBackoff], received,
+		float64(received)/elapsed.Seconds(),
+	))
+
+	return items
+}
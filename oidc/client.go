@@ -0,0 +1,165 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"strings"
+)
+
+var (
+	ErrMissingIDToken = errors.New("token response does not contain an ID token")
+	ErrNonceMismatch  = errors.New("ID token nonce does not match")
+)
+
+type ProviderConfig struct {
+	// IssuerURL is the URL of the provider, used for discovery (IssuerURL + "/.well-known/openid-configuration").
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes requested in addition to "openid". Defaults to "email" and "profile".
+	Scopes []string
+	// GroupsClaim is the path of the claim holding the groups of the user. Nested claims are separated by dots,
+	// such as "realm_access.roles". Defaults to "groups".
+	GroupsClaim string
+}
+
+// Identity is the user authenticated by the provider.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	// Claims holds every claim of the ID token.
+	Claims map[string]any
+	// RawIDToken is the ID token, as returned by the provider.
+	RawIDToken string
+}
+
+// Client authenticates users against an OIDC provider, with the authorization code flow and PKCE.
+type Client struct {
+	oauth       oauth2.Config
+	verifier    *gooidc.IDTokenVerifier
+	groupsClaim string
+}
+
+// NewClient discovers the provider configuration, and creates a client for it.
+func NewClient(ctx context.Context, config ProviderConfig) (*Client, error) {
+	provider, err := gooidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discover provider %s: %w", config.IssuerURL, err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+
+	groupsClaim := config.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	return &Client{
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{gooidc.ScopeOpenID}, scopes...),
+		},
+		verifier:    provider.Verifier(&gooidc.Config{ClientID: config.ClientID}),
+		groupsClaim: groupsClaim,
+	}, nil
+}
+
+// AuthCodeURL returns the URL of the provider login page. The state, nonce and verifier must be kept by the
+// caller until the callback, and be unique to each login attempt. Use oauth2.GenerateVerifier to create the
+// verifier.
+func (c *Client) AuthCodeURL(state, nonce, verifier string) string {
+	return c.oauth.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange trades the authorization code received on the callback for the identity of the user. The ID token is
+// fully validated: signature, issuer, audience, expiration and nonce.
+func (c *Client) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	token, err := c.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+
+	return c.Verify(ctx, rawIDToken, nonce)
+}
+
+// Verify validates a raw ID token, and extracts the identity it holds. The nonce is not checked if empty.
+func (c *Client) Verify(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify ID token: %w", err)
+	}
+
+	if nonce != "" && idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	claims := make(map[string]any)
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode ID token claims: %w", err)
+	}
+
+	identity := &Identity{
+		Subject:    idToken.Subject,
+		Claims:     claims,
+		RawIDToken: rawIDToken,
+		Groups:     extractGroups(claims, c.groupsClaim),
+	}
+
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+
+	return identity, nil
+}
+
+// extractGroups reads the groups claim, either as a list of strings or as a comma-separated string.
+func extractGroups(claims map[string]any, path string) []string {
+	var value any = claims
+	for _, part := range strings.Split(path, ".") {
+		node, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = node[part]
+	}
+
+	switch groups := value.(type) {
+	case []any:
+		out := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if name, ok := group.(string); ok && name != "" {
+				out = append(out, name)
+			}
+		}
+		return out
+	case string:
+		out := make([]string, 0)
+		for _, group := range strings.Split(groups, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				out = append(out, group)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+	"net/http"
+	"time"
+)
+
+var ErrInvalidState = errors.New("invalid or expired login state")
+
+// flowCookie holds the secrets of a login attempt, between the redirection to the provider and the callback.
+type flowCookie struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// ReturnTo is the path the user is sent back to once logged in.
+	ReturnTo string `json:"returnTo,omitempty"`
+}
+
+type HandlersConfig struct {
+	// CookieName is the name of the cookie holding the login attempt. Defaults to "oidc_flow".
+	CookieName string
+	// CookiePath must include the callback route. Defaults to "/".
+	CookiePath string
+	// Secure only sends the flow cookie over HTTPS. Must be enabled in release environments.
+	Secure bool
+	// MaxAge is the time the user has to complete the login on the provider. Defaults to 10 minutes.
+	MaxAge time.Duration
+}
+
+func (c HandlersConfig) withDefaults() HandlersConfig {
+	if c.CookieName == "" {
+		c.CookieName = "oidc_flow"
+	}
+	if c.CookiePath == "" {
+		c.CookiePath = "/"
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = 10 * time.Minute
+	}
+
+	return c
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// LoginHandler redirects the user to the provider. The optional return_to query parameter is passed to the
+// onLogin callback of CallbackHandler, once the user is authenticated.
+func (c *Client) LoginHandler(config HandlersConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+
+	return func(ctx *gin.Context) {
+		state, err := randomString()
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		nonce, err := randomString()
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		flow := flowCookie{
+			State:    state,
+			Nonce:    nonce,
+			Verifier: oauth2.GenerateVerifier(),
+			ReturnTo: ctx.Query("return_to"),
+		}
+
+		raw, err := json.Marshal(flow)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		// The cookie must be sent back on the cross-site redirection from the provider.
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie(
+			config.CookieName, base64.RawURLEncoding.EncodeToString(raw), int(config.MaxAge.Seconds()),
+			config.CookiePath, "", config.Secure, true,
+		)
+
+		ctx.Redirect(http.StatusFound, c.AuthCodeURL(flow.State, flow.Nonce, flow.Verifier))
+	}
+}
+
+// CallbackHandler completes the login on the redirect URL. Once the user is authenticated, onLogin is called to
+// open a session and send the response. Failed logins are aborted with a 401 status.
+//
+// The returnTo value comes from the client: only redirect to it after checking it is a local path.
+func (c *Client) CallbackHandler(
+	config HandlersConfig, onLogin func(ctx *gin.Context, identity *Identity, returnTo string),
+) gin.HandlerFunc {
+	config = config.withDefaults()
+
+	return func(ctx *gin.Context) {
+		cookie, err := ctx.Cookie(config.CookieName)
+
+		// The login attempt is single-use.
+		ctx.SetCookie(config.CookieName, "", -1, config.CookiePath, "", config.Secure, true)
+
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusUnauthorized, ErrInvalidState)
+			return
+		}
+
+		var flow flowCookie
+		raw, err := base64.RawURLEncoding.DecodeString(cookie)
+		if err == nil {
+			err = json.Unmarshal(raw, &flow)
+		}
+		if err != nil || flow.State == "" || flow.State != ctx.Query("state") {
+			_ = ctx.AbortWithError(http.StatusUnauthorized, ErrInvalidState)
+			return
+		}
+
+		if providerErr := ctx.Query("error"); providerErr != "" {
+			_ = ctx.AbortWithError(
+				http.StatusUnauthorized,
+				errors.New("provider error: "+providerErr+": "+ctx.Query("error_description")),
+			)
+			return
+		}
+
+		identity, err := c.Exchange(ctx.Request.Context(), ctx.Query("code"), flow.Nonce, flow.Verifier)
+		if err != nil {
+			_ = ctx.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		onLogin(ctx, identity, flow.ReturnTo)
+	}
+}
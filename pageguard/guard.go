@@ -0,0 +1,201 @@
+package pageguard
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/pattern"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TruncatedHeader is set on truncated responses, to the truncated field and its original number of items
+// ("notes:12000").
+const TruncatedHeader = "x-inrich-truncated"
+
+// Offense is an unpaginated response above the thresholds.
+type Offense struct {
+	Method string
+	// Field is the repeated field holding the largest number of items.
+	Field     string
+	Items     int
+	Bytes     int
+	Truncated bool
+}
+
+type Config struct {
+	// MaxItems is the number of items of a repeated field above which a response is an offense. Defaults to 1000.
+	MaxItems int `yaml:"maxItems" json:"maxItems"`
+	// MaxBytes is the size above which a response is an offense. Defaults to 4MiB.
+	MaxBytes deploy.ByteSize `yaml:"maxBytes" json:"maxBytes"`
+	// Strict truncates the repeated fields of offending responses to fit the thresholds, and sets the
+	// TruncatedHeader. Defaults to false: offenses are only logged.
+	Strict bool `yaml:"strict" json:"strict"`
+	// Exempt lists the methods allowed to return large responses. Keys ending with "*" match by prefix.
+	Exempt []string `yaml:"exempt" json:"exempt"`
+	// Interval is the minimum delay between two logs of the same method. Defaults to 10 minutes.
+	Interval deploy.Duration `yaml:"interval" json:"interval"`
+	// OnOffense is called for every offense, throttled or not, for example to count them.
+	OnOffense func(offense Offense) `yaml:"-" json:"-"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxItems <= 0 {
+		c.MaxItems = 1000
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = 4 * deploy.MiB
+	}
+	if c.Interval <= 0 {
+		c.Interval = deploy.Duration(10 * time.Minute)
+	}
+
+	return c
+}
+
+// paginated returns true if the request follows the pagination conventions (AIP-158), with a page_size or
+// page_token field.
+func paginated(req protoreflect.Message) bool {
+	fields := req.Descriptor().Fields()
+	return fields.ByName("page_size") != nil || fields.ByName("page_token") != nil
+}
+
+// largestList returns the repeated message field of the message with the most items.
+func largestList(msg protoreflect.Message) (protoreflect.FieldDescriptor, int) {
+	var (
+		largest protoreflect.FieldDescriptor
+		items   int
+	)
+
+	msg.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if field.IsList() && field.Message() != nil && value.List().Len() > items {
+			largest, items = field, value.List().Len()
+		}
+
+		return true
+	})
+
+	return largest, items
+}
+
+// Guard detects the list endpoints returning unpaginated responses above a size threshold, as a guardrail while
+// old endpoints migrate to proper pagination.
+//
+//	guard := pageguard.NewGuard(logger, cfg.PageGuard)
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck,
+//		deploy.WithUnaryInterceptors(guard.UnaryServerInterceptor()),
+//	)
+//
+// Responses of requests with a page_size or page_token field are never checked.
+type Guard struct {
+	logger monitor.Logger
+	config Config
+	exempt *pattern.Set
+
+	mu     sync.Mutex
+	logged map[string]time.Time
+}
+
+func NewGuard(logger monitor.Logger, config Config) *Guard {
+	return &Guard{
+		logger: logger,
+		config: config.withDefaults(),
+		exempt: pattern.NewSet(config.Exempt...),
+		logged: make(map[string]time.Time),
+	}
+}
+
+// Check inspects the response of an unpaginated request, and truncates it in strict mode. It returns the offense,
+// or nil if the response is within the thresholds.
+func (g *Guard) Check(method string, res proto.Message) *Offense {
+	field, items := largestList(res.ProtoReflect())
+	if field == nil {
+		return nil
+	}
+
+	size := proto.Size(res)
+	if items <= g.config.MaxItems && int64(size) <= g.config.MaxBytes.Bytes() {
+		return nil
+	}
+
+	offense := &Offense{Method: method, Field: string(field.Name()), Items: items, Bytes: size}
+	if g.config.Strict {
+		g.truncate(res.ProtoReflect(), field)
+		offense.Truncated = true
+	}
+
+	return offense
+}
+
+// truncate shortens the list to the maximum number of items, then halves it until the response fits the maximum
+// size.
+func (g *Guard) truncate(msg protoreflect.Message, field protoreflect.FieldDescriptor) {
+	list := msg.Mutable(field).List()
+	if list.Len() > g.config.MaxItems {
+		list.Truncate(g.config.MaxItems)
+	}
+
+	for list.Len() > 1 && int64(proto.Size(msg.Interface())) > g.config.MaxBytes.Bytes() {
+		list.Truncate(list.Len() / 2)
+	}
+}
+
+func (g *Guard) report(offense *Offense) {
+	if g.config.OnOffense != nil {
+		g.config.OnOffense(*offense)
+	}
+
+	now := time.Now()
+
+	g.mu.Lock()
+	last, ok := g.logged[offense.Method]
+	throttled := ok && now.Sub(last) < g.config.Interval.Duration()
+	if !throttled {
+		g.logged[offense.Method] = now
+	}
+	g.mu.Unlock()
+
+	if throttled {
+		return
+	}
+
+	g.logger.
+		With("method", offense.Method).
+		With("field", offense.Field).
+		With("items", offense.Items).
+		With("bytes", offense.Bytes).
+		With("truncated", offense.Truncated).
+		Warn(fmt.Sprintf("[pageguard] unpaginated response of %s returned %d items", offense.Method, offense.Items))
+}
+
+// UnaryServerInterceptor checks the successful responses of unpaginated requests.
+func (g *Guard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		res, err := handler(ctx, req)
+		if err != nil || g.exempt.Contains(info.FullMethod) {
+			return res, err
+		}
+
+		reqMsg, okReq := req.(proto.Message)
+		resMsg, okRes := res.(proto.Message)
+		if !okReq || !okRes || paginated(reqMsg.ProtoReflect()) {
+			return res, err
+		}
+
+		if offense := g.Check(info.FullMethod, resMsg); offense != nil {
+			g.report(offense)
+
+			if offense.Truncated {
+				_ = grpc.SetHeader(ctx, metadata.Pairs(TruncatedHeader, offense.Field+":"+strconv.Itoa(offense.Items)))
+			}
+		}
+
+		return res, nil
+	}
+}
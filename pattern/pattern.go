@@ -0,0 +1,127 @@
+package pattern
+
+import (
+	"slices"
+	"strings"
+)
+
+// Wildcard ends the patterns matching by prefix: "/notes.v1.Notes/*" matches every method of the service, and
+// "/notes.v1.Notes/Update*" every method whose name starts with Update.
+const Wildcard = "*"
+
+// Matcher maps name patterns, such as full GRPC method names, to values. Patterns ending with Wildcard match by
+// prefix, other patterns match exactly. The most specific pattern applies: the exact pattern first, then the
+// longest prefix.
+//
+//	matcher := pattern.NewMatcher(map[string]time.Duration{
+//		"/notes.v1.Notes/*":          5 * time.Second,
+//		"/notes.v1.Notes/ExportNotes": 2 * time.Minute,
+//	})
+//	timeout, key, ok := matcher.Match("/notes.v1.Notes/GetNote") // 5s, "/notes.v1.Notes/*", true
+//
+// The zero value is an empty matcher. A matcher is not safe for concurrent use while patterns are added.
+type Matcher[V any] struct {
+	exact map[string]V
+	// prefixes are sorted longest first, so the most specific one matches first.
+	prefixes []string
+	byPrefix map[string]V
+}
+
+// NewMatcher creates a matcher holding the given patterns.
+func NewMatcher[V any](patterns map[string]V) *Matcher[V] {
+	matcher := new(Matcher[V])
+	for key, value := range patterns {
+		matcher.Add(key, value)
+	}
+
+	return matcher
+}
+
+// Add registers a pattern, replacing the value of the same pattern if any.
+func (m *Matcher[V]) Add(pattern string, value V) {
+	if m.exact == nil {
+		m.exact = make(map[string]V)
+		m.byPrefix = make(map[string]V)
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, Wildcard)
+	if !ok {
+		m.exact[pattern] = value
+		return
+	}
+
+	if _, exists := m.byPrefix[prefix]; !exists {
+		i, _ := slices.BinarySearchFunc(m.prefixes, prefix, func(candidate, target string) int {
+			return len(target) - len(candidate)
+		})
+		m.prefixes = slices.Insert(m.prefixes, i, prefix)
+	}
+	m.byPrefix[prefix] = value
+}
+
+// Pattern returns the value registered under a pattern, such as "/notes.v1.Notes/*". It does not match names.
+func (m *Matcher[V]) Pattern(pattern string) (V, bool) {
+	if prefix, ok := strings.CutSuffix(pattern, Wildcard); ok {
+		value, found := m.byPrefix[prefix]
+		return value, found
+	}
+
+	value, found := m.exact[pattern]
+	return value, found
+}
+
+// Match returns the value of the most specific pattern matching the name, and the pattern.
+func (m *Matcher[V]) Match(name string) (V, string, bool) {
+	if value, ok := m.exact[name]; ok {
+		return value, name, true
+	}
+
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return m.byPrefix[prefix], prefix + Wildcard, true
+		}
+	}
+
+	var zero V
+	return zero, "", false
+}
+
+// MatchAll returns the values of every pattern matching the name, the most specific first.
+func (m *Matcher[V]) MatchAll(name string) []V {
+	var values []V
+	if value, ok := m.exact[name]; ok {
+		values = append(values, value)
+	}
+
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			values = append(values, m.byPrefix[prefix])
+		}
+	}
+
+	return values
+}
+
+// Set is a list of name patterns, matched like the patterns of a Matcher.
+//
+//	skip := pattern.NewSet("/grpc.health.v1.Health/*", "/notes.v1.Notes/Ping")
+//	skip.Contains("/grpc.health.v1.Health/Check") // true
+type Set struct {
+	matcher Matcher[struct{}]
+}
+
+// NewSet creates a set holding the given patterns.
+func NewSet(patterns ...string) *Set {
+	set := new(Set)
+	for _, pattern := range patterns {
+		set.matcher.Add(pattern, struct{}{})
+	}
+
+	return set
+}
+
+// Contains reports whether a pattern of the set matches the name.
+func (s *Set) Contains(name string) bool {
+	_, _, ok := s.matcher.Match(name)
+	return ok
+}
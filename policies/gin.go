@@ -0,0 +1,65 @@
+package policies
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// GinMiddleware applies the HTTP policies to the requests of a router: authentication, rate limit, maximum body
+// size and timeout, in that order. It must be installed after the authentication middleware, and before the
+// routes. Retry policies do not apply to HTTP routes.
+//
+// The caller hook receives the request context, with the client IP used by default.
+func (c Config) GinMiddleware(hooks Hooks) gin.HandlerFunc {
+	m := newMatcher(c.HTTP, c.Default)
+	buckets := new(limiters)
+
+	return func(ctx *gin.Context) {
+		policy, key := m.match(ctx.Request.Method + " " + ctx.FullPath())
+		requestCTX := ctx.Request.Context()
+
+		if policy.Auth == AuthRequired && (hooks.Authenticated == nil || !hooks.Authenticated(requestCTX)) {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if policy.RateLimit != nil {
+			caller := ctx.ClientIP()
+			if hooks.Caller != nil {
+				caller = hooks.Caller(requestCTX)
+			}
+
+			allowed, retryAfter, err := buckets.get(key, policy.RateLimit).Take(requestCTX, caller)
+			if err != nil {
+				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+			if !allowed {
+				ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				ctx.AbortWithStatus(http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		if policy.MaxBody > 0 {
+			if ctx.Request.ContentLength > policy.MaxBody.Bytes() {
+				ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, policy.MaxBody.Bytes())
+		}
+
+		if policy.Timeout > 0 {
+			timeoutCTX, cancel := context.WithTimeout(requestCTX, policy.Timeout.Duration())
+			defer cancel()
+
+			ctx.Request = ctx.Request.WithContext(timeoutCTX)
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package policies
+
+import (
+	"context"
+	"github.com/in-rich/lib-go/ratelimit"
+	"github.com/samber/lo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hooks connect the policies to the authentication of the service.
+type Hooks struct {
+	// Authenticated returns true if the caller is authenticated. Required when a policy requires authentication.
+	Authenticated func(ctx context.Context) bool
+	// Caller identifies the caller for rate limiting. Defaults to the peer address.
+	Caller func(ctx context.Context) string
+}
+
+func (h Hooks) caller(ctx context.Context) string {
+	if h.Caller != nil {
+		return h.Caller(ctx)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host := p.Addr.String()
+		if i := strings.LastIndex(host, ":"); i > 0 {
+			host = host[:i]
+		}
+		return host
+	}
+
+	return ""
+}
+
+// UnaryServerInterceptor applies the GRPC policies to the RPCs received by the server: authentication, rate limit,
+// maximum request size and timeout, in that order.
+func (c Config) UnaryServerInterceptor(hooks Hooks) grpc.UnaryServerInterceptor {
+	m := newMatcher(c.GRPC, c.Default)
+	buckets := new(limiters)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		policy, key := m.match(info.FullMethod)
+
+		if policy.Auth == AuthRequired && (hooks.Authenticated == nil || !hooks.Authenticated(ctx)) {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+
+		if policy.RateLimit != nil {
+			allowed, retryAfter, err := buckets.get(key, policy.RateLimit).Take(ctx, hooks.caller(ctx))
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, ratelimit.Exhausted(
+					retryAfter, "rate limit exceeded, retry in %s", retryAfter.Round(time.Millisecond),
+				)
+			}
+		}
+
+		if msg, ok := req.(proto.Message); ok && policy.MaxBody > 0 && int64(proto.Size(msg)) > policy.MaxBody.Bytes() {
+			return nil, status.Errorf(codes.ResourceExhausted, "request larger than %s", policy.MaxBody)
+		}
+
+		if policy.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, policy.Timeout.Duration())
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor applies the timeout and retry policies to the calls performed by a client. Retried calls
+// must be idempotent.
+func (c Config) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	m := newMatcher(c.GRPC, c.Default)
+
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		policy, _ := m.match(method)
+
+		if policy.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, policy.Timeout.Duration())
+			defer cancel()
+		}
+
+		if policy.Retry == nil || policy.Retry.MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		retryable := lo.Ternary(len(policy.Retry.Codes) > 0, policy.Retry.Codes, []string{"UNAVAILABLE"})
+
+		var err error
+		for attempt := 0; attempt < policy.Retry.MaxAttempts; attempt++ {
+			if err = invoker(ctx, method, req, reply, cc, opts...); err == nil {
+				return nil
+			}
+
+			if !isRetryable(err, retryable) || attempt == policy.Retry.MaxAttempts-1 {
+				return err
+			}
+
+			timer := time.NewTimer(policy.Retry.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		return err
+	}
+}
+
+func isRetryable(err error, retryable []string) bool {
+	code := status.Code(err)
+
+	for _, name := range retryable {
+		name = strings.ToUpper(name)
+		// Accept both the canonical name (UNAVAILABLE) and the Go name (Unavailable).
+		if strings.EqualFold(code.String(), strings.ReplaceAll(name, "_", "")) || name == strconv.Itoa(int(code)) {
+			return true
+		}
+	}
+
+	return false
+}
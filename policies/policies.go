@@ -0,0 +1,140 @@
+package policies
+
+import (
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/pattern"
+	"github.com/in-rich/lib-go/ratelimit"
+	"sync"
+	"time"
+)
+
+const (
+	AuthNone     = "none"
+	AuthOptional = "optional"
+	AuthRequired = "required"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int             `yaml:"maxAttempts" json:"maxAttempts"`
+	Backoff     deploy.Duration `yaml:"backoff" json:"backoff"`
+	// Codes lists the GRPC codes retried, by name ("UNAVAILABLE"). Defaults to UNAVAILABLE.
+	Codes []string `yaml:"codes" json:"codes"`
+}
+
+type RateLimitPolicy struct {
+	// Rate is the number of requests allowed per second, per caller.
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// Policy is the operational policy of an endpoint. Zero fields inherit the default policy.
+type Policy struct {
+	Timeout   deploy.Duration  `yaml:"timeout" json:"timeout"`
+	Retry     *RetryPolicy     `yaml:"retry" json:"retry"`
+	MaxBody   deploy.ByteSize  `yaml:"maxBody" json:"maxBody"`
+	RateLimit *RateLimitPolicy `yaml:"rateLimit" json:"rateLimit"`
+	// Auth is one of "none", "optional" or "required".
+	Auth string `yaml:"auth" json:"auth"`
+}
+
+func (p Policy) merge(defaults Policy) Policy {
+	if p.Timeout == 0 {
+		p.Timeout = defaults.Timeout
+	}
+	if p.Retry == nil {
+		p.Retry = defaults.Retry
+	}
+	if p.MaxBody == 0 {
+		p.MaxBody = defaults.MaxBody
+	}
+	if p.RateLimit == nil {
+		p.RateLimit = defaults.RateLimit
+	}
+	if p.Auth == "" {
+		p.Auth = defaults.Auth
+	}
+
+	return p
+}
+
+// Config is the policies block of the configuration of a service, read with deploy.LoadConfig.
+//
+//	policies:
+//	  default:
+//	    timeout: 10s
+//	    auth: required
+//	  grpc:
+//	    /notes.v1.Notes/*:
+//	      timeout: 5s
+//	    /notes.v1.Notes/ExportNotes:
+//	      timeout: 2m
+//	      rateLimit: {rate: 0.1, burst: 2}
+//	  http:
+//	    GET /health:
+//	      auth: none
+//	    POST /notes/*:
+//	      maxBody: 1MiB
+//
+// GRPC keys are full method names, and HTTP keys are a method followed by a gin route. Keys ending with "*"
+// match by prefix. The most specific key applies, and its zero fields inherit the default policy.
+type Config struct {
+	Default Policy            `yaml:"default" json:"default"`
+	GRPC    map[string]Policy `yaml:"grpc" json:"grpc"`
+	HTTP    map[string]Policy `yaml:"http" json:"http"`
+}
+
+type matcher struct {
+	policies *pattern.Matcher[Policy]
+	defaults Policy
+}
+
+func newMatcher(policies map[string]Policy, defaults Policy) *matcher {
+	m := &matcher{policies: new(pattern.Matcher[Policy]), defaults: defaults}
+	for key, policy := range policies {
+		m.policies.Add(key, policy.merge(defaults))
+	}
+
+	return m
+}
+
+// match returns the policy of an endpoint, and the key it matched.
+func (m *matcher) match(endpoint string) (Policy, string) {
+	if policy, key, ok := m.policies.Match(endpoint); ok {
+		return policy, key
+	}
+
+	return m.defaults, "default"
+}
+
+// limiters holds a token bucket per matched policy key.
+type limiters struct {
+	mu      sync.Mutex
+	buckets map[string]*ratelimit.TokenBucket
+}
+
+func (l *limiters) get(key string, policy *RateLimitPolicy) *ratelimit.TokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*ratelimit.TokenBucket)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = ratelimit.NewTokenBucket(policy.Rate, max(policy.Burst, 1))
+		l.buckets[key] = bucket
+	}
+
+	return bucket
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.Backoff.Duration()
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	return delay << attempt
+}
@@ -0,0 +1,244 @@
+package priority
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownLane = errors.New("unknown lane")
+	ErrLaneFull    = errors.New("lane is full")
+	ErrStopped     = errors.New("lanes are stopped")
+)
+
+// Lane is a queue of the consumer, with its share of the workers.
+type Lane struct {
+	Name string
+	// Weight is the share of the workers given to the lane when the other lanes also have pending items. A lane of
+	// weight 4 is served 4 times as often as a lane of weight 1. Defaults to 1.
+	Weight int
+	// Capacity is the maximum number of pending items. Defaults to 1000.
+	Capacity int
+	// MaxWait serves the items that waited longer than the given duration before any other, regardless of the
+	// weights. Optional.
+	MaxWait time.Duration
+}
+
+type Config struct {
+	// Workers is the number of items processed concurrently. Defaults to 4.
+	Workers int
+}
+
+type pending[T any] struct {
+	item       T
+	enqueuedAt time.Time
+}
+
+type lane[T any] struct {
+	Lane
+	items []pending[T]
+	// current is the smooth weighted round-robin counter of the lane.
+	current int
+}
+
+// Lanes processes items from several lanes, such as interactive syncs triggered by users and nightly batches, with
+// weighted fair dequeuing: bulk work in a low priority lane cannot starve the other lanes, and still progresses when
+// higher priority lanes are busy.
+//
+//	lanes := priority.NewLanes(syncAccount, logger, priority.Config{Workers: 8},
+//		priority.Lane{Name: "interactive", Weight: 8, MaxWait: 5 * time.Second},
+//		priority.Lane{Name: "batch", Weight: 1, Capacity: 100000},
+//	)
+//	go lanes.Run(ctx)
+//
+//	err := lanes.Push("interactive", accountID)
+//
+// Items are held in memory: items still pending when the lanes stop are dropped, so only use lanes for work that
+// can be triggered again.
+type Lanes[T any] struct {
+	handler func(ctx context.Context, item T) error
+	logger  monitor.Logger
+	config  Config
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	lanes   []*lane[T]
+	byName  map[string]*lane[T]
+	stopped bool
+}
+
+func NewLanes[T any](
+	handler func(ctx context.Context, item T) error, logger monitor.Logger, config Config, lanes ...Lane,
+) *Lanes[T] {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+
+	out := &Lanes[T]{
+		handler: handler,
+		logger:  logger,
+		config:  config,
+		byName:  make(map[string]*lane[T], len(lanes)),
+	}
+	out.cond = sync.NewCond(&out.mu)
+
+	for _, declaration := range lanes {
+		if declaration.Weight <= 0 {
+			declaration.Weight = 1
+		}
+		if declaration.Capacity <= 0 {
+			declaration.Capacity = 1000
+		}
+
+		l := &lane[T]{Lane: declaration}
+		out.lanes = append(out.lanes, l)
+		out.byName[declaration.Name] = l
+	}
+
+	return out
+}
+
+// Push adds an item to a lane. It returns ErrLaneFull rather than blocking when the lane reached its capacity.
+func (l *Lanes[T]) Push(name string, item T) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		return ErrStopped
+	}
+
+	target, ok := l.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLane, name)
+	}
+
+	if len(target.items) >= target.Capacity {
+		return fmt.Errorf("%w: %s", ErrLaneFull, name)
+	}
+
+	target.items = append(target.items, pending[T]{item: item, enqueuedAt: time.Now()})
+	l.cond.Signal()
+
+	return nil
+}
+
+// Pending returns the number of items waiting in each lane.
+func (l *Lanes[T]) Pending() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make(map[string]int, len(l.lanes))
+	for _, target := range l.lanes {
+		out[target.Name] = len(target.items)
+	}
+
+	return out
+}
+
+// Run processes items until the context is canceled, then waits for the items being processed.
+func (l *Lanes[T]) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+
+		l.mu.Lock()
+		l.stopped = true
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	}()
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < l.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (l *Lanes[T]) work(ctx context.Context) {
+	for {
+		name, item, ok := l.next()
+		if !ok {
+			return
+		}
+
+		if err := l.handler(ctx, item); err != nil && ctx.Err() == nil {
+			l.logger.Error(err, fmt.Sprintf("[priority] failed to process item of lane %s", name))
+		}
+	}
+}
+
+// next blocks until an item is available, and returns false once the lanes are stopped.
+func (l *Lanes[T]) next() (string, T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for {
+		if l.stopped {
+			var zero T
+			return "", zero, false
+		}
+
+		if selected := l.selectLane(); selected != nil {
+			next := selected.items[0]
+			selected.items[0] = pending[T]{}
+			selected.items = selected.items[1:]
+
+			return selected.Name, next.item, true
+		}
+
+		l.cond.Wait()
+	}
+}
+
+// selectLane picks the lane of the next item, among the lanes with pending items. It must be called with the lock
+// held.
+func (l *Lanes[T]) selectLane() *lane[T] {
+	now := time.Now()
+
+	// Items waiting for longer than their lane allows are served first, the most overdue one first.
+	var overdue *lane[T]
+	var overdueBy time.Duration
+	for _, target := range l.lanes {
+		if len(target.items) == 0 || target.MaxWait <= 0 {
+			continue
+		}
+
+		if late := now.Sub(target.items[0].enqueuedAt) - target.MaxWait; late > overdueBy {
+			overdue, overdueBy = target, late
+		}
+	}
+	if overdue != nil {
+		return overdue
+	}
+
+	// Smooth weighted round-robin: every lane with pending items earns its weight, and the richest lane is
+	// served and pays the total. The order of lanes is interleaved, instead of serving bursts of the same lane.
+	var selected *lane[T]
+	total := 0
+	for _, target := range l.lanes {
+		if len(target.items) == 0 {
+			continue
+		}
+
+		target.current += target.Weight
+		total += target.Weight
+
+		if selected == nil || target.current > selected.current {
+			selected = target
+		}
+	}
+
+	if selected != nil {
+		selected.current -= total
+	}
+
+	return selected
+}
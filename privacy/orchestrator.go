@@ -0,0 +1,207 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrEmptyUserID = errors.New("user id is required")
+
+// ExportArchive gathers the data of a user, for every registered entity.
+type ExportArchive struct {
+	UserID      string                     `json:"userID"`
+	GeneratedAt time.Time                  `json:"generatedAt"`
+	Entities    map[string]json.RawMessage `json:"entities"`
+	// Errors lists the entities that could not be exported, with the reason.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Complete returns true if every entity was successfully exported.
+func (a *ExportArchive) Complete() bool {
+	return len(a.Errors) == 0
+}
+
+// WriteZip writes the archive as a zip file, with one JSON file per entity and a manifest.json file describing
+// the export.
+func (a *ExportArchive) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	entities := make([]string, 0, len(a.Entities))
+	for entity := range a.Entities {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+
+	manifest, err := json.MarshalIndent(map[string]any{
+		"userID":      a.UserID,
+		"generatedAt": a.GeneratedAt,
+		"entities":    entities,
+		"errors":      a.Errors,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeZipFile(archive, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, entity := range entities {
+		if err := writeZipFile(archive, entity+".json", a.Entities[entity]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+	return err
+}
+
+// ErasureReport details the outcome of an erasure request, for every registered entity.
+type ErasureReport struct {
+	UserID      string           `json:"userID"`
+	RequestedAt time.Time        `json:"requestedAt"`
+	CompletedAt time.Time        `json:"completedAt"`
+	Erased      map[string]int64 `json:"erased"`
+	// Errors lists the entities that could not be erased, with the reason.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Complete returns true if every entity was successfully erased.
+func (r *ErasureReport) Complete() bool {
+	return len(r.Errors) == 0
+}
+
+// Orchestrator runs data subject requests over every handler of a registry, concurrently.
+type Orchestrator struct {
+	registry *Registry
+	logger   monitor.Logger
+}
+
+func NewOrchestrator(registry *Registry, logger monitor.Logger) *Orchestrator {
+	return &Orchestrator{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// Export collects the data of a user from every registered exporter. A failing exporter does not abort the
+// export: the failure is recorded in the archive, and the archive must be checked with ExportArchive.Complete.
+func (o *Orchestrator) Export(ctx context.Context, userID string) (*ExportArchive, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	exporters, _ := o.registry.snapshot()
+	archive := &ExportArchive{
+		UserID:      userID,
+		GeneratedAt: time.Now(),
+		Entities:    make(map[string]json.RawMessage, len(exporters)),
+		Errors:      make(map[string]string),
+	}
+
+	o.logger.Info(fmt.Sprintf("[privacy] export requested for user %s (%d entities)", userID, len(exporters)))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for entity, exporter := range exporters {
+		wg.Add(1)
+		go func(entity string, exporter ExportFunc) {
+			defer wg.Done()
+
+			data, err := exporter(ctx, userID)
+			var raw []byte
+			if err == nil {
+				raw, err = json.Marshal(data)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				archive.Errors[entity] = err.Error()
+				o.logger.Error(err, fmt.Sprintf("[privacy] failed to export %s for user %s", entity, userID))
+				return
+			}
+
+			archive.Entities[entity] = raw
+		}(entity, exporter)
+	}
+
+	wg.Wait()
+
+	o.logger.Info(fmt.Sprintf(
+		"[privacy] export completed for user %s: %d entities exported, %d failed",
+		userID, len(archive.Entities), len(archive.Errors),
+	))
+
+	return archive, nil
+}
+
+// Erase removes the data of a user through every registered eraser. A failing eraser does not abort the
+// erasure: the failure is recorded in the report, and the report must be checked with ErasureReport.Complete.
+func (o *Orchestrator) Erase(ctx context.Context, userID string) (*ErasureReport, error) {
+	if userID == "" {
+		return nil, ErrEmptyUserID
+	}
+
+	_, erasers := o.registry.snapshot()
+	report := &ErasureReport{
+		UserID:      userID,
+		RequestedAt: time.Now(),
+		Erased:      make(map[string]int64, len(erasers)),
+		Errors:      make(map[string]string),
+	}
+
+	o.logger.Info(fmt.Sprintf("[privacy] erasure requested for user %s (%d entities)", userID, len(erasers)))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for entity, eraser := range erasers {
+		wg.Add(1)
+		go func(entity string, eraser EraseFunc) {
+			defer wg.Done()
+
+			count, err := eraser(ctx, userID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				report.Errors[entity] = err.Error()
+				o.logger.Error(err, fmt.Sprintf("[privacy] failed to erase %s for user %s", entity, userID))
+				return
+			}
+
+			report.Erased[entity] = count
+		}(entity, eraser)
+	}
+
+	wg.Wait()
+	report.CompletedAt = time.Now()
+
+	o.logger.Info(fmt.Sprintf(
+		"[privacy] erasure completed for user %s: %d entities erased, %d failed",
+		userID, len(report.Erased), len(report.Errors),
+	))
+
+	return report, nil
+}
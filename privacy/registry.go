@@ -0,0 +1,131 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"sort"
+	"sync"
+)
+
+// ExportFunc returns every piece of data owned by the user for a given entity. The returned value must be
+// serializable to JSON.
+type ExportFunc func(ctx context.Context, userID string) (any, error)
+
+// EraseFunc removes every piece of data owned by the user for a given entity, and returns the number of erased
+// records.
+type EraseFunc func(ctx context.Context, userID string) (int64, error)
+
+// Registry holds the exporters and erasers of each entity handled by a service.
+type Registry struct {
+	mu        sync.RWMutex
+	exporters map[string]ExportFunc
+	erasers   map[string]EraseFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		exporters: make(map[string]ExportFunc),
+		erasers:   make(map[string]EraseFunc),
+	}
+}
+
+// RegisterExporter registers the exporter of an entity. It panics if an exporter is already registered for this
+// entity.
+func (r *Registry) RegisterExporter(entity string, exporter ExportFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.exporters[entity]; ok {
+		panic(fmt.Sprintf("privacy: exporter already registered for entity %s", entity))
+	}
+
+	r.exporters[entity] = exporter
+}
+
+// RegisterEraser registers the eraser of an entity. It panics if an eraser is already registered for this entity.
+func (r *Registry) RegisterEraser(entity string, eraser EraseFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.erasers[entity]; ok {
+		panic(fmt.Sprintf("privacy: eraser already registered for entity %s", entity))
+	}
+
+	r.erasers[entity] = eraser
+}
+
+// Entities returns the sorted list of entities that have an exporter or an eraser.
+func (r *Registry) Entities() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for entity := range r.exporters {
+		seen[entity] = struct{}{}
+	}
+	for entity := range r.erasers {
+		seen[entity] = struct{}{}
+	}
+
+	entities := make([]string, 0, len(seen))
+	for entity := range seen {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+
+	return entities
+}
+
+func (r *Registry) snapshot() (map[string]ExportFunc, map[string]EraseFunc) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exporters := make(map[string]ExportFunc, len(r.exporters))
+	for entity, exporter := range r.exporters {
+		exporters[entity] = exporter
+	}
+
+	erasers := make(map[string]EraseFunc, len(r.erasers))
+	for entity, eraser := range r.erasers {
+		erasers[entity] = eraser
+	}
+
+	return exporters, erasers
+}
+
+// RemoteExporter creates an exporter that delegates the export to another service, through GRPC.
+//
+//	registry.RegisterExporter("notes", privacy.RemoteExporter(
+//		notesClient.ExportUserNotes,
+//		func(userID string) *notes_pb.ExportUserNotesRequest {
+//			return &notes_pb.ExportUserNotesRequest{UserId: userID}
+//		},
+//		func(out *notes_pb.ExportUserNotesResponse) any { return out.GetNotes() },
+//	))
+func RemoteExporter[In any, Out any](
+	callback deploy.GRPCCallback[In, Out], request func(userID string) *In, response func(out *Out) any,
+) ExportFunc {
+	return func(ctx context.Context, userID string) (any, error) {
+		out, err := deploy.CallGRPCEndpoint(ctx, callback, request(userID))
+		if err != nil {
+			return nil, err
+		}
+
+		return response(out), nil
+	}
+}
+
+// RemoteEraser creates an eraser that delegates the erasure to another service, through GRPC.
+func RemoteEraser[In any, Out any](
+	callback deploy.GRPCCallback[In, Out], request func(userID string) *In, response func(out *Out) int64,
+) EraseFunc {
+	return func(ctx context.Context, userID string) (int64, error) {
+		out, err := deploy.CallGRPCEndpoint(ctx, callback, request(userID))
+		if err != nil {
+			return 0, err
+		}
+
+		return response(out), nil
+	}
+}
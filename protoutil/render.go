@@ -0,0 +1,179 @@
+package protoutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+type RenderConfig struct {
+	// RedactFields lists fields whose value is never rendered, either by short name ("password") or by full name
+	// ("notes.v1.Note.content"). Fields with the debug_redact option are always redacted.
+	RedactFields []string
+	// MaxBytes is the maximum number of bytes rendered for bytes fields. Defaults to 64.
+	MaxBytes int
+	// MaxString is the maximum number of characters rendered for string fields. Unlimited if 0.
+	MaxString int
+}
+
+// Renderer renders proto messages for logging.
+type Renderer struct {
+	redacted  map[string]struct{}
+	maxBytes  int
+	maxString int
+}
+
+func NewRenderer(config RenderConfig) *Renderer {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 64
+	}
+
+	renderer := &Renderer{
+		redacted:  make(map[string]struct{}, len(config.RedactFields)),
+		maxBytes:  config.MaxBytes,
+		maxString: config.MaxString,
+	}
+
+	for _, field := range config.RedactFields {
+		renderer.redacted[field] = struct{}{}
+	}
+
+	return renderer
+}
+
+var defaultRenderer = NewRenderer(RenderConfig{})
+
+// Render renders a message to JSON with the default renderer, which only redacts fields with the debug_redact
+// option.
+func Render(msg proto.Message) string {
+	return defaultRenderer.JSON(msg)
+}
+
+// JSON renders a message to JSON. Keys are sorted, so the output of a given message is stable.
+func (r *Renderer) JSON(msg proto.Message) string {
+	out, err := json.Marshal(r.Map(msg))
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+
+	return string(out)
+}
+
+// Map renders a message to a generic map, suitable for structured loggers. Field names are the proto names.
+func (r *Renderer) Map(msg proto.Message) map[string]any {
+	if msg == nil {
+		return nil
+	}
+
+	return r.message(msg.ProtoReflect())
+}
+
+func (r *Renderer) message(msg protoreflect.Message) map[string]any {
+	if !msg.IsValid() {
+		return nil
+	}
+
+	out := make(map[string]any)
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if r.isRedacted(fd) {
+			out[string(fd.Name())] = Redacted
+			return true
+		}
+
+		switch {
+		case fd.IsList():
+			list := value.List()
+			items := make([]any, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				items[i] = r.value(fd, list.Get(i))
+			}
+			out[string(fd.Name())] = items
+		case fd.IsMap():
+			entries := make(map[string]any, value.Map().Len())
+			value.Map().Range(func(key protoreflect.MapKey, entry protoreflect.Value) bool {
+				entries[key.String()] = r.value(fd.MapValue(), entry)
+				return true
+			})
+			out[string(fd.Name())] = entries
+		default:
+			out[string(fd.Name())] = r.value(fd, value)
+		}
+
+		return true
+	})
+
+	return out
+}
+
+func (r *Renderer) value(fd protoreflect.FieldDescriptor, value protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := value.Message()
+
+		// Well-known types have a canonical JSON form, that is more readable than their raw fields.
+		if strings.HasPrefix(string(msg.Descriptor().FullName()), "google.protobuf.") {
+			if raw, err := protojson.Marshal(msg.Interface()); err == nil {
+				return json.RawMessage(raw)
+			}
+		}
+
+		return r.message(msg)
+	case protoreflect.EnumKind:
+		if enum := fd.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+
+		return int32(value.Enum())
+	case protoreflect.BytesKind:
+		return r.bytes(value.Bytes())
+	case protoreflect.StringKind:
+		return r.string(value.String())
+	default:
+		return value.Interface()
+	}
+}
+
+func (r *Renderer) bytes(value []byte) string {
+	if len(value) <= r.maxBytes {
+		return base64.StdEncoding.EncodeToString(value)
+	}
+
+	return fmt.Sprintf(
+		"%s...(truncated, %d bytes)",
+		base64.StdEncoding.EncodeToString(value[:r.maxBytes]), len(value),
+	)
+}
+
+func (r *Renderer) string(value string) string {
+	if r.maxString <= 0 {
+		return value
+	}
+
+	runes := []rune(value)
+	if len(runes) <= r.maxString {
+		return value
+	}
+
+	return fmt.Sprintf("%s...(truncated, %d chars)", string(runes[:r.maxString]), len(runes))
+}
+
+func (r *Renderer) isRedacted(fd protoreflect.FieldDescriptor) bool {
+	if options, ok := fd.Options().(*descriptorpb.FieldOptions); ok && options.GetDebugRedact() {
+		return true
+	}
+
+	if _, ok := r.redacted[string(fd.Name())]; ok {
+		return true
+	}
+
+	_, ok := r.redacted[string(fd.FullName())]
+	return ok
+}
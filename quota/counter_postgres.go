@@ -0,0 +1,94 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// CounterRow stores a counter in Postgres.
+type CounterRow struct {
+	bun.BaseModel `bun:"table:quota_counters,alias:quota_counter"`
+
+	Key       string     `bun:"key,pk"`
+	Used      int64      `bun:"used,notnull"`
+	ExpiresAt *time.Time `bun:"expires_at"`
+}
+
+// CreateTable creates the counters table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*CounterRow)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+// PurgeExpired removes the counters of finished windows.
+func PurgeExpired(ctx context.Context, db bun.IDB) (int64, error) {
+	res, err := db.NewDelete().Model((*CounterRow)(nil)).Where("expires_at < ?", time.Now()).Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+type postgresCounter struct {
+	db bun.IDB
+}
+
+func (c *postgresCounter) IncrementIfBelow(
+	ctx context.Context, key string, n, max int64, expiresAt time.Time,
+) (int64, bool, error) {
+	row := &CounterRow{Key: key, Used: n}
+	if !expiresAt.IsZero() {
+		row.ExpiresAt = &expiresAt
+	}
+
+	if n > max {
+		used, err := c.Get(ctx, key)
+		return used, false, err
+	}
+
+	// The conditional upsert is atomic: concurrent operations cannot both pass the check.
+	err := c.db.NewInsert().
+		Model(row).
+		On("CONFLICT (key) DO UPDATE").
+		Set("used = quota_counter.used + EXCLUDED.used").
+		Where("quota_counter.used + EXCLUDED.used <= ?", max).
+		Returning("used").
+		Scan(ctx, &row.Used)
+	if errors.Is(err, sql.ErrNoRows) {
+		used, err := c.Get(ctx, key)
+		return used, false, err
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return row.Used, true, nil
+}
+
+func (c *postgresCounter) Decrement(ctx context.Context, key string, n int64) error {
+	_, err := c.db.NewUpdate().
+		Model((*CounterRow)(nil)).
+		Set("used = GREATEST(used - ?, 0)", n).
+		Where("key = ?", key).
+		Exec(ctx)
+
+	return err
+}
+
+func (c *postgresCounter) Get(ctx context.Context, key string) (int64, error) {
+	row := &CounterRow{Key: key}
+	err := c.db.NewSelect().Model(row).WherePK().Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return row.Used, err
+}
+
+func NewPostgresCounter(db bun.IDB) Counter {
+	return &postgresCounter{db: db}
+}
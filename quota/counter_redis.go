@@ -0,0 +1,74 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Keys: counter key. Args: n, max, expiration (unix seconds, 0 for none). Returns {value, applied}.
+var incrementScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local n = tonumber(ARGV[1])
+
+if current + n > tonumber(ARGV[2]) then
+	return {current, 0}
+end
+
+local value = redis.call("INCRBY", KEYS[1], n)
+if tonumber(ARGV[3]) > 0 then
+	redis.call("EXPIREAT", KEYS[1], ARGV[3])
+end
+
+return {value, 1}
+`)
+
+// Keys: counter key. Args: n.
+var decrementScript = redis.NewScript(`
+local value = redis.call("DECRBY", KEYS[1], ARGV[1])
+if value < 0 then
+	redis.call("SET", KEYS[1], 0, "KEEPTTL")
+end
+
+return 0
+`)
+
+type redisCounter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (c *redisCounter) IncrementIfBelow(
+	ctx context.Context, key string, n, max int64, expiresAt time.Time,
+) (int64, bool, error) {
+	var expiration int64
+	if !expiresAt.IsZero() {
+		// Keep counters a bit after the end of their window, for reporting.
+		expiration = expiresAt.Add(24 * time.Hour).Unix()
+	}
+
+	res, err := incrementScript.Run(ctx, c.client, []string{c.prefix + key}, n, max, expiration).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+
+	return res[0], res[1] == 1, nil
+}
+
+func (c *redisCounter) Decrement(ctx context.Context, key string, n int64) error {
+	return decrementScript.Run(ctx, c.client, []string{c.prefix + key}, n).Err()
+}
+
+func (c *redisCounter) Get(ctx context.Context, key string) (int64, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return value, err
+}
+
+func NewRedisCounter(client redis.UniversalClient, prefix string) Counter {
+	return &redisCounter{client: client, prefix: prefix}
+}
@@ -0,0 +1,119 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// NearLimitEvent is emitted when the consumption of a resource crosses the near-limit threshold.
+type NearLimitEvent struct {
+	Key   Key
+	Usage Usage
+}
+
+type EnforcerConfig struct {
+	// NearLimitThreshold is the fraction of a limit above which near-limit events are emitted. Defaults to 0.8.
+	NearLimitThreshold float64
+	// OnNearLimit is called once when an operation crosses the threshold. Optional.
+	OnNearLimit func(ctx context.Context, event NearLimitEvent)
+}
+
+// Enforcer checks and records the consumption of resources against plan limits.
+type Enforcer struct {
+	plans   map[string]Plan
+	counter Counter
+	config  EnforcerConfig
+}
+
+func NewEnforcer(counter Counter, config EnforcerConfig, plans ...Plan) *Enforcer {
+	if config.NearLimitThreshold <= 0 || config.NearLimitThreshold > 1 {
+		config.NearLimitThreshold = 0.8
+	}
+
+	enforcer := &Enforcer{
+		plans:   make(map[string]Plan, len(plans)),
+		counter: counter,
+		config:  config,
+	}
+
+	for _, plan := range plans {
+		enforcer.plans[plan.Name] = plan
+	}
+
+	return enforcer
+}
+
+func (e *Enforcer) limit(key Key) (Limit, error) {
+	plan, ok := e.plans[key.Plan]
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %s", ErrUnknownPlan, key.Plan)
+	}
+
+	limit, ok := plan.Limits[key.Resource]
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %s in %s", ErrUnknownResource, key.Resource, key.Plan)
+	}
+
+	return limit, nil
+}
+
+func counterKey(key Key, bucket string) string {
+	return fmt.Sprintf("%s:%s:%s", key.Subject, key.Resource, bucket)
+}
+
+// CheckAndConsume consumes n units of a resource, if it does not exceed the limit of the plan. Otherwise, it
+// returns an *ExceededError and nothing is consumed.
+func (e *Enforcer) CheckAndConsume(ctx context.Context, key Key, n int64) (Usage, error) {
+	limit, err := e.limit(key)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	bucket, resetsAt := limit.Period.bucket(time.Now())
+
+	used, ok, err := e.counter.IncrementIfBelow(ctx, counterKey(key, bucket), n, limit.Max, resetsAt)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	usage := Usage{Used: used, Limit: limit, ResetsAt: resetsAt}
+	if !ok {
+		return usage, &ExceededError{Key: key, Usage: usage, Requested: n}
+	}
+
+	threshold := int64(float64(limit.Max) * e.config.NearLimitThreshold)
+	if e.config.OnNearLimit != nil && used >= threshold && used-n < threshold {
+		e.config.OnNearLimit(ctx, NearLimitEvent{Key: key, Usage: usage})
+	}
+
+	return usage, nil
+}
+
+// Release gives back n units of a resource, such as when a note is deleted.
+func (e *Enforcer) Release(ctx context.Context, key Key, n int64) error {
+	limit, err := e.limit(key)
+	if err != nil {
+		return err
+	}
+
+	bucket, _ := limit.Period.bucket(time.Now())
+	return e.counter.Decrement(ctx, counterKey(key, bucket), n)
+}
+
+// Usage returns the current consumption of a resource.
+func (e *Enforcer) Usage(ctx context.Context, key Key) (Usage, error) {
+	limit, err := e.limit(key)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	bucket, resetsAt := limit.Period.bucket(time.Now())
+
+	used, err := e.counter.Get(ctx, counterKey(key, bucket))
+	if err != nil {
+		return Usage{}, err
+	}
+
+	return Usage{Used: used, Limit: limit, ResetsAt: resetsAt}, nil
+}
@@ -0,0 +1,131 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time"
+)
+
+var (
+	ErrQuotaExceeded   = errors.New("quota exceeded")
+	ErrUnknownPlan     = errors.New("unknown plan")
+	ErrUnknownResource = errors.New("resource has no limit in plan")
+)
+
+// Period is the window after which the consumption of a resource resets.
+type Period string
+
+const (
+	// PeriodLifetime never resets. Consumption is given back with Enforcer.Release, such as when a note is deleted.
+	PeriodLifetime Period = ""
+	PeriodDay      Period = "day"
+	PeriodMonth    Period = "month"
+)
+
+// bucket returns the identifier of the current window, and its end.
+func (p Period) bucket(now time.Time) (string, time.Time) {
+	now = now.UTC()
+
+	switch p {
+	case PeriodDay:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+	case PeriodMonth:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	default:
+		return "lifetime", time.Time{}
+	}
+}
+
+type Limit struct {
+	Max    int64
+	Period Period
+}
+
+// Plan holds the limits of each resource, such as "notes" or "synced_messages".
+type Plan struct {
+	Name   string
+	Limits map[string]Limit
+}
+
+// Key identifies the consumption of a resource by a subject, such as a team.
+type Key struct {
+	Subject  string
+	Plan     string
+	Resource string
+}
+
+// Usage is the consumption of a resource after a successful operation.
+type Usage struct {
+	Used  int64
+	Limit Limit
+	// ResetsAt is the end of the current window. Zero for lifetime limits.
+	ResetsAt time.Time
+}
+
+// Remaining returns the amount of the resource that can still be consumed.
+func (u Usage) Remaining() int64 {
+	if u.Used >= u.Limit.Max {
+		return 0
+	}
+
+	return u.Limit.Max - u.Used
+}
+
+// ExceededError is returned when an operation would exceed the quota. Nothing is consumed in this case.
+type ExceededError struct {
+	Key       Key
+	Usage     Usage
+	Requested int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf(
+		"quota exceeded for %s of %s: %d used, %d requested, limit %d",
+		e.Key.Resource, e.Key.Subject, e.Usage.Used, e.Requested, e.Usage.Limit.Max,
+	)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// GRPCStatus converts the error to a ResourceExhausted status, so handlers can return it as is. The status holds
+// a QuotaFailure detail, and a RetryInfo detail with the time left until the window resets, if the limit has one.
+func (e *ExceededError) GRPCStatus() *status.Status {
+	st := status.New(codes.ResourceExhausted, e.Error())
+
+	details := []protoadapt.MessageV1{&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     e.Key.Subject,
+			Description: fmt.Sprintf("%s limit of plan %s: %d", e.Key.Resource, e.Key.Plan, e.Usage.Limit.Max),
+		}},
+	}}
+	if !e.Usage.ResetsAt.IsZero() {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Until(e.Usage.ResetsAt))})
+	}
+
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+
+	return st
+}
+
+// Counter stores consumption counters.
+type Counter interface {
+	// IncrementIfBelow atomically adds n to the counter, only if the result does not exceed max. It returns the
+	// value of the counter after the operation, and whether the increment was applied. Counters with a non-zero
+	// expiration can be deleted after it.
+	IncrementIfBelow(ctx context.Context, key string, n, max int64, expiresAt time.Time) (int64, bool, error)
+	// Decrement removes n from the counter, without going below 0.
+	Decrement(ctx context.Context, key string, n int64) error
+	Get(ctx context.Context, key string) (int64, error)
+}
@@ -0,0 +1,195 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+type AdaptiveConfig struct {
+	// InitialLimit is the concurrency limit on startup. Defaults to 20.
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit. Default to 4 and 200.
+	MinLimit int
+	MaxLimit int
+	// Tolerance is the ratio of the recent latency over the baseline latency tolerated before the limit
+	// decreases. Defaults to 1.5.
+	Tolerance float64
+	// Smoothing is the weight of each update of the limit, between 0 and 1. Defaults to 0.2.
+	Smoothing float64
+	// Window is the number of samples averaged into the recent latency. Defaults to 20.
+	Window int
+	// RetryAfter is the delay after which rejected callers are told to retry, in the RetryInfo detail of the GRPC
+	// errors. Defaults to 1 second.
+	RetryAfter time.Duration
+}
+
+func (c AdaptiveConfig) withDefaults() AdaptiveConfig {
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = 20
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = 4
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = 200
+	}
+	if c.Tolerance < 1 {
+		c.Tolerance = 1.5
+	}
+	if c.Smoothing <= 0 || c.Smoothing > 1 {
+		c.Smoothing = 0.2
+	}
+	if c.Window <= 0 {
+		c.Window = 20
+	}
+	if c.RetryAfter <= 0 {
+		c.RetryAfter = time.Second
+	}
+
+	return c
+}
+
+// Adaptive is a concurrency limiter whose limit follows the latency of the protected operations, with a
+// gradient algorithm: the limit grows while the recent latency stays close to the baseline latency, and shrinks
+// as soon as latency rises, when the operations start queuing on a saturated resource such as a database pool.
+// Operations over the limit are rejected right away, instead of queuing behind the slow ones.
+type Adaptive struct {
+	config AdaptiveConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	// baseline is the lowest recent latency, recent the average of the last samples, in seconds.
+	baseline float64
+	recent   float64
+	samples  int
+}
+
+func NewAdaptive(config AdaptiveConfig) *Adaptive {
+	config = config.withDefaults()
+	return &Adaptive{config: config, limit: float64(config.InitialLimit)}
+}
+
+// Limit returns the current concurrency limit.
+func (l *Adaptive) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of operations running.
+func (l *Adaptive) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// TryAcquire starts an operation if the limit is not reached. The release function must be called once the
+// operation completes: operations that failed without reaching the protected resource, such as invalid requests,
+// must be released with ignore set, so their latency does not count.
+func (l *Adaptive) TryAcquire() (func(ignore bool), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+
+	l.inFlight++
+	start := time.Now()
+
+	var once sync.Once
+	return func(ignore bool) {
+		once.Do(func() { l.release(time.Since(start), ignore) })
+	}, true
+}
+
+func (l *Adaptive) release(latency time.Duration, ignore bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if ignore {
+		return
+	}
+
+	sample := latency.Seconds()
+	l.samples++
+
+	if l.samples == 1 {
+		l.baseline, l.recent = sample, sample
+		return
+	}
+
+	l.recent += (sample - l.recent) / float64(min(l.samples, l.config.Window))
+
+	// The baseline is the lowest latency observed, the latency of the unloaded system. It slowly drifts up, so it
+	// follows durable changes of the latency, such as a slower query after a deploy.
+	l.baseline = math.Min(sample, l.baseline*(1+1/float64(l.config.Window*50)))
+
+	gradient := math.Max(0.5, math.Min(1, l.config.Tolerance*l.baseline/l.recent))
+	// The queue allowance lets the limit grow while the latency stays under the tolerance.
+	next := l.limit*gradient + math.Sqrt(l.limit)
+
+	// Only grow when the limit is actually used, so an idle limiter does not drift up to MaxLimit.
+	if next > l.limit && float64(l.inFlight+1) < l.limit/2 {
+		return
+	}
+
+	next = l.limit*(1-l.config.Smoothing) + next*l.config.Smoothing
+	l.limit = math.Max(float64(l.config.MinLimit), math.Min(float64(l.config.MaxLimit), next))
+}
+
+// Do runs fn if the limit is not reached, and returns ErrLimitExceeded otherwise.
+func (l *Adaptive) Do(fn func() error) error {
+	release, ok := l.TryAcquire()
+	if !ok {
+		return ErrLimitExceeded
+	}
+
+	err := fn()
+	release(false)
+
+	return err
+}
+
+// ignoredCode reports whether an error code means the request failed before doing any work.
+func ignoredCode(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.NotFound, codes.Canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnaryServerInterceptor rejects the RPCs over the limit with ResourceExhausted, and a RetryInfo detail of
+// RetryAfter, so callers back off and retry.
+// Select the methods hitting the protected resource with the filter, or nil to protect every method.
+func (l *Adaptive) UnaryServerInterceptor(filter func(fullMethod string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if filter != nil && !filter(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		release, ok := l.TryAcquire()
+		if !ok {
+			return nil, Exhausted(l.config.RetryAfter, "%s", ErrLimitExceeded)
+		}
+
+		res, err := handler(ctx, req)
+		release(ignoredCode(status.Code(err)))
+
+		return res, err
+	}
+}
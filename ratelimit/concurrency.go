@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+)
+
+// Concurrency limits the number of operations running at the same time for each key.
+type Concurrency struct {
+	limit int
+
+	mu      sync.Mutex
+	running map[string]int
+	waiters map[string][]chan struct{}
+}
+
+func NewConcurrency(limit int) *Concurrency {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return &Concurrency{
+		limit:   limit,
+		running: make(map[string]int),
+		waiters: make(map[string][]chan struct{}),
+	}
+}
+
+// TryAcquire starts an operation if the limit is not reached. The release function must be called once the
+// operation completes.
+func (l *Concurrency) TryAcquire(key string) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.running[key] >= l.limit {
+		return nil, false
+	}
+
+	l.running[key]++
+	return l.releaser(key), true
+}
+
+// Acquire waits until an operation can start, or the context is done. The release function must be called once
+// the operation completes.
+func (l *Concurrency) Acquire(ctx context.Context, key string) (func(), error) {
+	for {
+		l.mu.Lock()
+		if l.running[key] < l.limit {
+			l.running[key]++
+			l.mu.Unlock()
+			return l.releaser(key), nil
+		}
+
+		wake := make(chan struct{})
+		l.waiters[key] = append(l.waiters[key], wake)
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Running returns the number of operations running for the key.
+func (l *Concurrency) Running(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.running[key]
+}
+
+func (l *Concurrency) releaser(key string) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.running[key]--
+			if l.running[key] <= 0 {
+				delete(l.running, key)
+			}
+
+			// Wake up every waiter: they compete again for the free slot.
+			for _, wake := range l.waiters[key] {
+				close(wake)
+			}
+			delete(l.waiters, key)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter throttles operations per key, such as an account ID.
+type Limiter interface {
+	// Take consumes a permit for the key if one is available right away. Otherwise, it returns false and the
+	// delay after which a permit may become available.
+	Take(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// Wait blocks until a permit is taken for the key, or the context is done.
+func Wait(ctx context.Context, limiter Limiter, key string) error {
+	for {
+		ok, retryAfter, err := limiter.Take(ctx, key)
+		if err != nil || ok {
+			return err
+		}
+
+		if retryAfter <= 0 {
+			retryAfter = 10 * time.Millisecond
+		}
+
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+type allLimiter []Limiter
+
+func (limiters allLimiter) Take(ctx context.Context, key string) (bool, time.Duration, error) {
+	for _, limiter := range limiters {
+		ok, retryAfter, err := limiter.Take(ctx, key)
+		if err != nil || !ok {
+			return ok, retryAfter, err
+		}
+	}
+
+	return true, 0, nil
+}
+
+// All combines limiters: a permit is granted only if every limiter grants one, in order. Permits taken from the
+// first limiters are not returned when a later limiter denies the request, so put the most restrictive limiter
+// first.
+func All(limiters ...Limiter) Limiter {
+	return allLimiter(limiters)
+}
+
+// Event is emitted by observed limiters, for every permit request.
+type Event struct {
+	Limiter    string
+	Key        string
+	Allowed    bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+type Observer func(event Event)
+
+type observedLimiter struct {
+	Limiter
+	name     string
+	observer Observer
+}
+
+func (l *observedLimiter) Take(ctx context.Context, key string) (bool, time.Duration, error) {
+	ok, retryAfter, err := l.Limiter.Take(ctx, key)
+	l.observer(Event{Limiter: l.name, Key: key, Allowed: ok, RetryAfter: retryAfter, Err: err})
+
+	return ok, retryAfter, err
+}
+
+// Observe reports every permit request of the limiter to the observer, to feed metrics.
+func Observe(limiter Limiter, name string, observer Observer) Limiter {
+	return &observedLimiter{Limiter: limiter, name: name, observer: observer}
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Keys: bucket key. Args: rate (tokens/s), burst, now (ms). Returns {allowed, retry after (ms)}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - last) / 1000 * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, retry}
+`)
+
+// Keys: window key. Args: limit, window (ms), now (ms), member. Returns {allowed, retry after (ms)}.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+if redis.call("ZCARD", KEYS[1]) >= limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	return {0, tonumber(oldest[2]) + window - now}
+end
+
+redis.call("ZADD", KEYS[1], now, ARGV[4])
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {1, 0}
+`)
+
+type redisTokenBucket struct {
+	client redis.Scripter
+	prefix string
+	rate   float64
+	burst  int
+}
+
+func (l *redisTokenBucket) Take(ctx context.Context, key string) (bool, time.Duration, error) {
+	res, err := tokenBucketScript.Run(
+		ctx, l.client, []string{l.prefix + key}, l.rate, l.burst, time.Now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// NewRedisTokenBucket creates a token bucket shared by every instance connected to the same Redis server. Keys are
+// prefixed with the given prefix, such as "ratelimit:linkedin:". It panics if the rate is not positive.
+func NewRedisTokenBucket(client redis.Scripter, prefix string, rate float64, burst int) Limiter {
+	if rate <= 0 {
+		panic("ratelimit: the rate of a token bucket must be positive")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &redisTokenBucket{client: client, prefix: prefix, rate: rate, burst: burst}
+}
+
+type redisSlidingWindow struct {
+	client redis.Scripter
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+func (l *redisSlidingWindow) Take(ctx context.Context, key string) (bool, time.Duration, error) {
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return false, 0, err
+	}
+
+	res, err := slidingWindowScript.Run(
+		ctx, l.client, []string{l.prefix + key},
+		l.limit, l.window.Milliseconds(), time.Now().UnixMilli(), hex.EncodeToString(member),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// NewRedisSlidingWindow creates a sliding window limiter shared by every instance connected to the same Redis
+// server. Keys are prefixed with the given prefix. It panics if the limit or the window is not positive.
+func NewRedisSlidingWindow(client redis.Scripter, prefix string, limit int, window time.Duration) Limiter {
+	checkWindow(limit, window)
+
+	return &redisSlidingWindow{client: client, prefix: prefix, limit: limit, window: window}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time"
+)
+
+// Exhausted returns a ResourceExhausted error telling the caller when to retry, with a RetryInfo detail. Callers
+// going through the bridge package receive it as a Retry-After header. A zero delay omits the detail.
+//
+//	allowed, retryAfter, err := limiter.Take(ctx, accountID)
+//	if !allowed {
+//		return nil, ratelimit.Exhausted(retryAfter, "too many exports")
+//	}
+func Exhausted(retryAfter time.Duration, format string, args ...any) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf(format, args...))
+	if retryAfter <= 0 {
+		return st.Err()
+	}
+
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// RetryAfter returns the delay carried by the RetryInfo detail of a GRPC error, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return 0, false
+	}
+
+	for _, detail := range grpcErr.GRPCStatus().Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
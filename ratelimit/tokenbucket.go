@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket is an in-memory token bucket limiter. Each key has a bucket of Burst tokens, refilled at Rate tokens
+// per second.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewTokenBucket creates a token bucket. For instance, NewTokenBucket(1, 1) allows 1 operation per second per key,
+// with no burst.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// refill must be called with the lock held.
+func (l *TokenBucket) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	return b
+}
+
+func (l *TokenBucket) delay(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - tokens) / l.rate * float64(time.Second))
+}
+
+func (l *TokenBucket) Take(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, time.Now())
+	if b.tokens < 1 {
+		return false, l.delay(b.tokens), nil
+	}
+
+	b.tokens--
+	l.evict()
+	return true, 0, nil
+}
+
+// Reservation is a permit that can be used after a delay.
+type Reservation struct {
+	// Delay is the time to wait before using the permit.
+	Delay time.Duration
+
+	limiter *TokenBucket
+	key     string
+}
+
+// Cancel returns the permit to the bucket, when it is not going to be used.
+func (r *Reservation) Cancel() {
+	r.limiter.mu.Lock()
+	defer r.limiter.mu.Unlock()
+
+	b := r.limiter.refill(r.key, time.Now())
+	b.tokens++
+	if b.tokens > r.limiter.burst {
+		b.tokens = r.limiter.burst
+	}
+}
+
+// Reserve takes a permit from the future: it always succeeds, and tells how long the caller must wait before
+// using the permit. Cancel the reservation if the permit is not used.
+func (l *TokenBucket) Reserve(_ context.Context, key string) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, time.Now())
+	delay := l.delay(b.tokens)
+	b.tokens--
+
+	return &Reservation{Delay: delay, limiter: l, key: key}
+}
+
+// evict removes full buckets, which are equivalent to missing ones. It must be called with the lock held.
+func (l *TokenBucket) evict() {
+	if len(l.buckets) < 10000 {
+		return
+	}
+
+	now := time.Now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlidingWindow is an in-memory limiter allowing at most Limit operations per key within any Window-long period.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+
+	mu   sync.Mutex
+	logs map[string][]time.Time
+	// swept is the time of the last removal of the keys whose window emptied.
+	swept time.Time
+}
+
+// NewSlidingWindow creates a sliding window limiter. It panics if the limit or the window is not positive.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	checkWindow(limit, window)
+
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+		logs:   make(map[string][]time.Time),
+	}
+}
+
+func (l *SlidingWindow) Take(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	log := l.logs[key]
+
+	// Drop the operations that left the window.
+	start := 0
+	for start < len(log) && now.Sub(log[start]) >= l.window {
+		start++
+	}
+	log = log[start:]
+
+	if len(log) >= l.limit {
+		l.logs[key] = log
+		return false, log[0].Add(l.window).Sub(now), nil
+	}
+
+	l.logs[key] = append(log, now)
+	return true, 0, nil
+}
+
+// sweep forgets the keys without operation in the window, at most once per window, so the map does not grow with
+// every key ever seen. It must be called with the lock held.
+func (l *SlidingWindow) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+
+	for key, log := range l.logs {
+		if len(log) == 0 || now.Sub(log[len(log)-1]) >= l.window {
+			delete(l.logs, key)
+		}
+	}
+
+	l.swept = now
+}
+
+func checkWindow(limit int, window time.Duration) {
+	if limit <= 0 {
+		panic("ratelimit: the limit of a sliding window must be positive")
+	}
+	if window <= 0 {
+		panic("ratelimit: the window of a sliding window must be positive")
+	}
+}
@@ -0,0 +1,148 @@
+package realtime
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"sync"
+)
+
+// Message is a message received on a channel.
+type Message struct {
+	Channel string
+	Data    []byte
+}
+
+// Subscription receives the messages of the subscribed channels, until it is closed.
+type Subscription interface {
+	Messages() <-chan Message
+	Close() error
+}
+
+// Backend transports messages between instances.
+type Backend interface {
+	Publish(ctx context.Context, channel string, data []byte) error
+	Subscribe(ctx context.Context, channels ...string) (Subscription, error)
+}
+
+type redisSubscription struct {
+	pubsub   *redis.PubSub
+	messages chan Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (s *redisSubscription) Messages() <-chan Message {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+func (b *redisBackend) Publish(ctx context.Context, channel string, data []byte) error {
+	return b.client.Publish(ctx, channel, data).Err()
+}
+
+func (b *redisBackend) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	pubsub := b.client.Subscribe(ctx, channels...)
+
+	// Wait for the confirmation, so messages published after Subscribe returns are received.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	subscription := &redisSubscription{pubsub: pubsub, messages: make(chan Message, 100), done: make(chan struct{})}
+
+	go func() {
+		defer close(subscription.messages)
+
+		// The channel of go-redis reconnects and subscribes again automatically when the connection is lost.
+		// Messages published while disconnected are lost, as Redis Pub/Sub does not store them.
+		for msg := range pubsub.Channel() {
+			select {
+			case subscription.messages <- Message{Channel: msg.Channel, Data: []byte(msg.Payload)}:
+			case <-subscription.done:
+				return
+			}
+		}
+	}()
+
+	return subscription, nil
+}
+
+// NewRedisBackend creates a backend over Redis Pub/Sub.
+func NewRedisBackend(client redis.UniversalClient) Backend {
+	return &redisBackend{client: client}
+}
+
+type memorySubscription struct {
+	backend  *memoryBackend
+	channels []string
+	messages chan Message
+	once     sync.Once
+}
+
+func (s *memorySubscription) Messages() <-chan Message {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.once.Do(func() {
+		s.backend.mu.Lock()
+		defer s.backend.mu.Unlock()
+
+		for _, channel := range s.channels {
+			delete(s.backend.subscriptions[channel], s)
+		}
+		close(s.messages)
+	})
+
+	return nil
+}
+
+type memoryBackend struct {
+	mu            sync.RWMutex
+	subscriptions map[string]map[*memorySubscription]struct{}
+}
+
+func (b *memoryBackend) Publish(_ context.Context, channel string, data []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for subscription := range b.subscriptions[channel] {
+		// Slow subscribers drop messages, like Redis clients with a full buffer.
+		select {
+		case subscription.messages <- Message{Channel: channel, Data: data}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (b *memoryBackend) Subscribe(_ context.Context, channels ...string) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscription := &memorySubscription{backend: b, channels: channels, messages: make(chan Message, 100)}
+	for _, channel := range channels {
+		if b.subscriptions[channel] == nil {
+			b.subscriptions[channel] = make(map[*memorySubscription]struct{})
+		}
+
+		b.subscriptions[channel][subscription] = struct{}{}
+	}
+
+	return subscription, nil
+}
+
+// NewMemoryBackend creates a backend delivering messages within the current process, for local environments.
+func NewMemoryBackend() Backend {
+	return &memoryBackend{subscriptions: make(map[string]map[*memorySubscription]struct{})}
+}
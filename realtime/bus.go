@@ -0,0 +1,81 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/monitor"
+)
+
+// Bus publishes and receives JSON messages on namespaced channels.
+//
+//	bus := realtime.NewBus(realtime.NewRedisBackend(redisClient), "", logger)
+//
+//	err := realtime.Publish(ctx, bus, "notes.invalidated", NoteInvalidated{NoteID: id})
+//
+//	go realtime.Subscribe(ctx, bus, "notes.invalidated", func(ctx context.Context, msg NoteInvalidated) error {
+//		return cache.Delete(ctx, msg.NoteID)
+//	})
+type Bus struct {
+	backend   Backend
+	namespace string
+	logger    monitor.Logger
+}
+
+// NewBus creates a bus. Channels are prefixed with the namespace, which defaults to the current environment, so
+// environments sharing a Redis instance do not receive each other's messages.
+func NewBus(backend Backend, namespace string, logger monitor.Logger) *Bus {
+	if namespace == "" {
+		namespace = deploy.ENV
+	}
+
+	return &Bus{backend: backend, namespace: namespace, logger: logger}
+}
+
+func (b *Bus) channel(name string) string {
+	return b.namespace + ":" + name
+}
+
+// Publish sends a message to every subscriber of the channel. Delivery is best-effort: subscribers that are
+// disconnected when the message is published never receive it.
+func Publish[T any](ctx context.Context, bus *Bus, channel string, payload T) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("serialize message: %w", err)
+	}
+
+	return bus.backend.Publish(ctx, bus.channel(channel), data)
+}
+
+// Subscribe calls the handler for every message received on the channel, until the context is canceled. Messages
+// that cannot be decoded, and handler errors, are logged.
+func Subscribe[T any](ctx context.Context, bus *Bus, channel string, handler func(ctx context.Context, payload T) error) error {
+	subscription, err := bus.backend.Subscribe(ctx, bus.channel(channel))
+	if err != nil {
+		return fmt.Errorf("subscribe to %s: %w", channel, err)
+	}
+	defer func() { _ = subscription.Close() }()
+
+	messages := subscription.Messages()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return ctx.Err()
+			}
+
+			var payload T
+			if err := json.Unmarshal(msg.Data, &payload); err != nil {
+				bus.logger.Error(err, fmt.Sprintf("[realtime] invalid message on %s", channel))
+				continue
+			}
+
+			if err := handler(ctx, payload); err != nil {
+				bus.logger.Error(err, fmt.Sprintf("[realtime] failed to handle message on %s", channel))
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package realtime
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type presenceBeat struct {
+	Room   string `json:"room"`
+	Member string `json:"member"`
+	// Leave is set when the member explicitly leaves the room.
+	Leave bool `json:"leave,omitempty"`
+}
+
+// Presence tracks the members of rooms, such as the users viewing a dashboard, across instances. Members announce
+// themselves with heartbeats, and are considered gone once they stop sending them.
+//
+//	presence := realtime.NewPresence(bus, "dashboard", 30*time.Second)
+//	go presence.Run(ctx)
+//
+//	presence.Join(ctx, "team:"+teamID, userID) // On every client heartbeat.
+//	online := presence.Members("team:" + teamID)
+type Presence struct {
+	bus     *Bus
+	channel string
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	rooms map[string]map[string]time.Time
+}
+
+// NewPresence creates a presence tracker on the given channel. Members expire after ttl without heartbeat.
+func NewPresence(bus *Bus, channel string, ttl time.Duration) *Presence {
+	return &Presence{
+		bus:     bus,
+		channel: "presence." + channel,
+		ttl:     ttl,
+		rooms:   make(map[string]map[string]time.Time),
+	}
+}
+
+// Run receives the heartbeats of the other instances, until the context is canceled.
+func (p *Presence) Run(ctx context.Context) error {
+	return Subscribe(ctx, p.bus, p.channel, func(_ context.Context, beat presenceBeat) error {
+		p.apply(beat)
+		return nil
+	})
+}
+
+func (p *Presence) apply(beat presenceBeat) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	members, ok := p.rooms[beat.Room]
+	if !ok {
+		if beat.Leave {
+			return
+		}
+
+		members = make(map[string]time.Time)
+		p.rooms[beat.Room] = members
+	}
+
+	if beat.Leave {
+		delete(members, beat.Member)
+	} else {
+		members[beat.Member] = time.Now().Add(p.ttl)
+	}
+
+	if len(members) == 0 {
+		delete(p.rooms, beat.Room)
+	}
+}
+
+// Join announces a member in a room, or refreshes its presence. Call it at least once per ttl.
+func (p *Presence) Join(ctx context.Context, room, member string) error {
+	beat := presenceBeat{Room: room, Member: member}
+	p.apply(beat)
+	return Publish(ctx, p.bus, p.channel, beat)
+}
+
+// Leave removes a member from a room immediately.
+func (p *Presence) Leave(ctx context.Context, room, member string) error {
+	beat := presenceBeat{Room: room, Member: member, Leave: true}
+	p.apply(beat)
+	return Publish(ctx, p.bus, p.channel, beat)
+}
+
+// Members returns the members currently present in a room, sorted.
+func (p *Presence) Members(room string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	members := make([]string, 0, len(p.rooms[room]))
+
+	for member, expiresAt := range p.rooms[room] {
+		if now.After(expiresAt) {
+			delete(p.rooms[room], member)
+			continue
+		}
+
+		members = append(members, member)
+	}
+
+	if len(p.rooms[room]) == 0 {
+		delete(p.rooms, room)
+	}
+
+	sort.Strings(members)
+	return members
+}
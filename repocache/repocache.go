@@ -0,0 +1,145 @@
+package repocache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/httpcache"
+	"github.com/in-rich/lib-go/monitor"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"time"
+)
+
+// Method configures the caching of a repository method.
+type Method[A any, R any] struct {
+	// Name prefixes the cache keys of the method, such as "notes.get".
+	Name string
+	// TTL is the lifetime of cached results. Defaults to 60 seconds.
+	TTL time.Duration
+	// Key derives the cache key from the arguments. Defaults to fmt.Sprint.
+	Key func(args A) string
+	// Tags derives the invalidation tags of a result. Defaults to the cache key of the result (see Tag), so the
+	// result can be invalidated with the same arguments.
+	Tags func(args A, result R) []string
+}
+
+// Tag returns the default invalidation tag of the result of a method for the given key.
+func Tag(method, key string) string {
+	return method + ":" + key
+}
+
+// Cache wraps a repository method with a read-through cache. Errors are never cached. When the cache is
+// unavailable, calls go straight to the wrapped method.
+//
+// Concurrent misses for the same key are merged, so an expired entry only triggers a single call.
+//
+//	type cachedNotes struct {
+//		repositories.NotesRepository
+//		store httpcache.Store
+//		get   func(ctx context.Context, id string) (*entities.Note, error)
+//	}
+//
+//	func NewCachedNotes(repository repositories.NotesRepository, store httpcache.Store, logger monitor.Logger) repositories.NotesRepository {
+//		return &cachedNotes{
+//			NotesRepository: repository,
+//			store:           store,
+//			get:             repocache.Cache(store, logger, repocache.Method[string, *entities.Note]{Name: "notes.get"}, repository.GetNote),
+//		}
+//	}
+//
+//	func (r *cachedNotes) GetNote(ctx context.Context, id string) (*entities.Note, error) {
+//		return r.get(ctx, id)
+//	}
+//
+//	func (r *cachedNotes) UpdateNote(ctx context.Context, id string, data *models.UpdateNote) (*entities.Note, error) {
+//		note, err := r.NotesRepository.UpdateNote(ctx, id, data)
+//		if err == nil {
+//			_ = r.store.InvalidateTags(ctx, repocache.Tag("notes.get", id))
+//		}
+//		return note, err
+//	}
+func Cache[A any, R any](
+	store httpcache.Store, logger monitor.Logger, method Method[A, R], fn func(ctx context.Context, args A) (R, error),
+) func(ctx context.Context, args A) (R, error) {
+	if method.TTL <= 0 {
+		method.TTL = 60 * time.Second
+	}
+	if method.Key == nil {
+		method.Key = func(args A) string { return fmt.Sprint(args) }
+	}
+	if method.Tags == nil {
+		method.Tags = func(args A, _ R) []string { return []string{Tag(method.Name, method.Key(args))} }
+	}
+
+	group := new(singleflight.Group)
+
+	return func(ctx context.Context, args A) (R, error) {
+		key := "repocache:" + Tag(method.Name, method.Key(args))
+
+		entry, err := store.Get(ctx, key)
+		if err == nil {
+			var result R
+			if err := json.Unmarshal(entry.Body, &result); err == nil {
+				return result, nil
+			}
+
+			logger.Warn(fmt.Sprintf("[repocache] invalid cache entry for %s, ignoring", key))
+		} else if !errors.Is(err, httpcache.ErrNotFound) {
+			logger.Error(err, fmt.Sprintf("[repocache] failed to read %s", key))
+		}
+
+		value, err, _ := group.Do(key, func() (any, error) {
+			result, err := fn(ctx, args)
+			if err != nil {
+				return result, err
+			}
+
+			body, err := json.Marshal(result)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("[repocache] failed to serialize %s", key))
+				return result, nil
+			}
+
+			entry := &httpcache.Entry{
+				Status:   http.StatusOK,
+				Body:     body,
+				Tags:     method.Tags(args, result),
+				StoredAt: time.Now(),
+			}
+			if err := store.Set(ctx, key, entry, method.TTL); err != nil {
+				logger.Error(err, fmt.Sprintf("[repocache] failed to write %s", key))
+			}
+
+			return result, nil
+		})
+
+		result, _ := value.(R)
+		return result, err
+	}
+}
+
+// Args2 holds the arguments of a method with two parameters.
+type Args2[A any, B any] struct {
+	A A
+	B B
+}
+
+// Cache2 wraps a repository method with two parameters, such as GetNote(ctx, author, id), with a read-through
+// cache. Key and Tags receive both arguments in an Args2.
+func Cache2[A any, B any, R any](
+	store httpcache.Store, logger monitor.Logger, method Method[Args2[A, B], R], fn func(ctx context.Context, a A, b B) (R, error),
+) func(ctx context.Context, a A, b B) (R, error) {
+	if method.Key == nil {
+		method.Key = func(args Args2[A, B]) string { return fmt.Sprint(args.A) + ":" + fmt.Sprint(args.B) }
+	}
+
+	cached := Cache(store, logger, method, func(ctx context.Context, args Args2[A, B]) (R, error) {
+		return fn(ctx, args.A, args.B)
+	})
+
+	return func(ctx context.Context, a A, b B) (R, error) {
+		return cached(ctx, Args2[A, B]{A: a, B: b})
+	}
+}
@@ -0,0 +1,201 @@
+package reqsign
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/jwtsign"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/tokens"
+	"google.golang.org/grpc"
+	"time"
+)
+
+var (
+	// ErrMissingTLS is returned by NewAuth in HMAC mode without TLS configuration.
+	ErrMissingTLS = errors.New("hmac auth mode requires a TLS configuration")
+	// ErrMissingReplayGuard is returned by NewAuth in HMAC mode without replay guard.
+	ErrMissingReplayGuard = errors.New("hmac auth mode requires a replay guard")
+	// ErrKeySource is returned by NewAuth in HMAC mode unless exactly one source of keys is configured.
+	ErrKeySource = errors.New("hmac auth mode requires exactly one of keys, keysFile and keysEnv")
+)
+
+type AuthMode string
+
+const (
+	// IDTokenMode authenticates calls with Google ID tokens. It is the default. The tokens are only checked by the
+	// Cloud Run ingress of services requiring authentication: services reached otherwise must verify them, for
+	// example with authz.ServiceAccount.
+	IDTokenMode AuthMode = "idtoken"
+	// HMACMode signs calls with keys shared by the services, for installations without Google ID tokens.
+	HMACMode AuthMode = "hmac"
+)
+
+// AuthConfig selects how internal calls are authenticated.
+//
+//	auth:
+//	  mode: hmac
+//	  keys: projects/inrich/secrets/internal-request-keys/versions/latest
+//	  maxSkew: 2m
+//	  tls:
+//	    certFile: /etc/certs/tls.crt
+//	    keyFile: /etc/certs/tls.key
+//	    caFile: /etc/certs/ca.crt
+type AuthConfig struct {
+	Mode AuthMode `yaml:"mode" json:"mode"`
+	// Keys is the Secret Manager version holding the key set in HMAC mode, in the format of jwtsign.ParseKeySet.
+	Keys string `yaml:"keys" json:"keys"`
+	// KeysFile and KeysEnv replace Keys for installations without Secret Manager: they name a file, or an
+	// environment variable, holding the key set. Exactly one of Keys, KeysFile and KeysEnv is required in HMAC mode.
+	KeysFile string          `yaml:"keysFile" json:"keysFile"`
+	KeysEnv  string          `yaml:"keysEnv" json:"keysEnv"`
+	MaxSkew  deploy.Duration `yaml:"maxSkew" json:"maxSkew"`
+	// TLS encrypts the calls in HMAC mode, where signatures authenticate the calls but do not hide them. Required in
+	// HMAC mode.
+	TLS deploy.TLSConfig `yaml:"tls" json:"tls"`
+}
+
+// keySource returns the source of the key set of the HMAC mode.
+func (c AuthConfig) keySource(ctx context.Context) (jwtsign.KeySource, error) {
+	switch {
+	case c.Keys != "" && c.KeysFile == "" && c.KeysEnv == "":
+		return jwtsign.SecretManagerSource(ctx, c.Keys)
+	case c.Keys == "" && c.KeysFile != "" && c.KeysEnv == "":
+		return jwtsign.FileSource(c.KeysFile), nil
+	case c.Keys == "" && c.KeysFile == "" && c.KeysEnv != "":
+		return jwtsign.EnvSource(c.KeysEnv), nil
+	default:
+		return nil, ErrKeySource
+	}
+}
+
+// Auth holds the authentication of the internal calls of a service, in the mode selected by the configuration.
+//
+//	auth, err := reqsign.NewAuth(ctx, logger, cfg.Auth, nil)
+//	go auth.Refresh(ctx, 5*time.Minute)
+//
+//	listener, server, health := deploy.StartGRPCServer(logger, 50051, depsCheck, auth.ServerOptions()...)
+//	conn := deploy.OpenGRPCConn(logger, host, auth.ConnOptions()...)
+type Auth struct {
+	config AuthConfig
+	guard  tokens.ReplayGuard
+	signer *jwtsign.Signer
+	// serverTLS and clientTLS are nil for static auth.
+	serverTLS *tls.Config
+	clientTLS *tls.Config
+}
+
+// NewAuth loads the keys and the TLS configuration of the HMAC mode. The replay guard is required in HMAC mode, and
+// must be shared by the instances of the service, such as tokens.NewRedisReplayGuard.
+func NewAuth(ctx context.Context, logger monitor.Logger, config AuthConfig, guard tokens.ReplayGuard) (*Auth, error) {
+	auth := &Auth{config: config, guard: guard}
+
+	switch config.Mode {
+	case "", IDTokenMode:
+		return auth, nil
+	case HMACMode:
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", config.Mode)
+	}
+
+	if guard == nil {
+		return nil, ErrMissingReplayGuard
+	}
+	if config.TLS.CertFile == "" {
+		return nil, ErrMissingTLS
+	}
+
+	var err error
+	if auth.serverTLS, err = config.TLS.Server(); err != nil {
+		return nil, fmt.Errorf("load server TLS: %w", err)
+	}
+	if auth.clientTLS, err = config.TLS.Client(); err != nil {
+		return nil, fmt.Errorf("load client TLS: %w", err)
+	}
+
+	source, err := config.keySource(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.signer, err = jwtsign.NewSigner(ctx, source, logger, jwtsign.SignerConfig{}); err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// NewStaticAuth signs calls with a fixed key set, for local environments and tools. Calls are sent in plaintext, so
+// its connections refuse to open in release environments. Replays are rejected by a guard local to the process
+// when the configuration has none.
+func NewStaticAuth(logger monitor.Logger, set *jwtsign.KeySet, config VerifierConfig) (*Auth, error) {
+	if config.ReplayGuard == nil {
+		config.ReplayGuard = tokens.NewMemoryReplayGuard()
+	}
+
+	signer, err := jwtsign.NewSigner(context.Background(), jwtsign.StaticSource(set), logger, jwtsign.SignerConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Auth{
+		config: AuthConfig{Mode: HMACMode, MaxSkew: deploy.Duration(config.MaxSkew)},
+		guard:  config.ReplayGuard,
+		signer: signer,
+	}, nil
+}
+
+// Refresh reloads the keys periodically, until the context is canceled. It returns immediately in ID token mode.
+func (a *Auth) Refresh(ctx context.Context, interval time.Duration) {
+	if a.signer != nil {
+		a.signer.Refresh(ctx, interval)
+	}
+}
+
+// ServerOptions returns the options verifying the signatures of incoming calls. Nothing is verified in ID token
+// mode: the tokens are left to the Cloud Run ingress, or to authz.ServiceAccount for services reached without it.
+func (a *Auth) ServerOptions() []deploy.ServerOption {
+	if a.signer == nil {
+		return nil
+	}
+
+	config := VerifierConfig{MaxSkew: a.config.MaxSkew.Duration(), ReplayGuard: a.guard}
+
+	options := []deploy.ServerOption{
+		deploy.WithServerOptions(grpc.ForceServerCodec(ServerCodec())),
+		deploy.WithUnaryInterceptors(UnaryServerInterceptor(a.signer, config)),
+		deploy.WithStreamInterceptors(StreamServerInterceptor(a.signer, config)),
+	}
+	if a.serverTLS != nil {
+		options = append(options, deploy.WithServerTLS(a.serverTLS))
+	}
+
+	return options
+}
+
+// ConnOptions returns the options signing outgoing calls over TLS, instead of attaching ID tokens. It panics in
+// release environments when the auth has no TLS configuration.
+func (a *Auth) ConnOptions() []deploy.ConnOption {
+	if a.signer == nil {
+		return nil
+	}
+
+	options := []deploy.ConnOption{
+		deploy.WithoutIDToken(),
+		deploy.WithDialOptions(
+			grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(a.signer)),
+			grpc.WithChainStreamInterceptor(StreamClientInterceptor(a.signer)),
+		),
+	}
+
+	switch {
+	case a.clientTLS != nil:
+		options = append(options, deploy.WithClientTLS(a.clientTLS))
+	case deploy.IsReleaseEnv():
+		panic("reqsign: signed calls require TLS in release environments")
+	}
+
+	return options
+}
@@ -0,0 +1,123 @@
+package reqsign
+
+import (
+	"fmt"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Requests are signed over their exact wire bytes: re-marshaling a decoded message does not give the same bytes
+// across versions of the protobuf runtime, nor when the peer sends fields unknown to the server. The client
+// interceptor marshals the request itself, signs the bytes, and sends them as-is with clientCodec. On the server,
+// ServerCodec hashes the bytes it decodes, and hands the hash to the interceptors in an unknown field of the request,
+// removed before the request reaches the handler.
+
+// hashField is the unknown field carrying the hash of a request from ServerCodec to the interceptors.
+const hashField = protowire.MaxValidNumber
+
+// encodedMessage is a request already marshaled by the client interceptor. It still exposes the request to the
+// interceptors that follow.
+type encodedMessage struct {
+	proto.Message
+
+	data []byte
+}
+
+type clientCodec struct{}
+
+var _ encoding.Codec = clientCodec{}
+
+func (clientCodec) Name() string {
+	return "proto"
+}
+
+func (clientCodec) Marshal(v any) ([]byte, error) {
+	if encoded, ok := v.(*encodedMessage); ok {
+		return encoded.data, nil
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("reqsign: cannot marshal %T, not a proto message", v)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (clientCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("reqsign: cannot unmarshal into %T, not a proto message", v)
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+type serverCodec struct {
+	clientCodec
+}
+
+// ServerCodec returns the GRPC codec hashing the requests received by the server, for the server interceptors. It
+// replaces the default proto codec, and is installed by Auth.ServerOptions:
+//
+//	server := grpc.NewServer(
+//		grpc.ForceServerCodec(reqsign.ServerCodec()),
+//		grpc.ChainUnaryInterceptor(reqsign.UnaryServerInterceptor(signer, config)),
+//	)
+func ServerCodec() encoding.Codec {
+	return serverCodec{}
+}
+
+func (c serverCodec) Unmarshal(data []byte, v any) error {
+	if err := c.clientCodec.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	msg := v.(proto.Message).ProtoReflect()
+	unknown := protowire.AppendTag(msg.GetUnknown(), hashField, protowire.BytesType)
+	msg.SetUnknown(protowire.AppendString(unknown, bodyHash(data)))
+
+	return nil
+}
+
+// takeHash removes the hash added by ServerCodec from a request, and returns it.
+func takeHash(req any) (string, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+
+	reflected := msg.ProtoReflect()
+	unknown := reflected.GetUnknown()
+
+	var (
+		hash  string
+		found bool
+		kept  []byte
+	)
+	for len(unknown) > 0 {
+		number, wireType, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return "", false
+		}
+		size := protowire.ConsumeFieldValue(number, wireType, unknown[n:])
+		if size < 0 {
+			return "", false
+		}
+
+		if number == hashField && wireType == protowire.BytesType {
+			value, _ := protowire.ConsumeString(unknown[n : n+size])
+			hash, found = value, true
+		} else {
+			kept = append(kept, unknown[:n+size]...)
+		}
+		unknown = unknown[n+size:]
+	}
+
+	if found {
+		reflected.SetUnknown(kept)
+	}
+
+	return hash, found
+}
@@ -0,0 +1,150 @@
+package reqsign
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"github.com/in-rich/lib-go/jwtsign"
+	"github.com/in-rich/lib-go/pattern"
+	"github.com/in-rich/lib-go/tokens"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing request signature")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrStaleSignature   = errors.New("request signature is too old or in the future")
+	// ErrMissingHash is returned when requests are not decoded with ServerCodec.
+	ErrMissingHash = errors.New("request was not decoded by the reqsign codec")
+)
+
+type VerifierConfig struct {
+	// MaxSkew is the maximum difference between the time of the signature and the time of the server. Defaults to
+	// 5 minutes.
+	MaxSkew time.Duration
+	// ReplayGuard rejects signatures used twice within MaxSkew, so captured calls cannot be sent again. Required:
+	// share it between instances, such as tokens.NewRedisReplayGuard.
+	ReplayGuard tokens.ReplayGuard
+	// Skip lists the methods that don't require a signature, such as the health checks. Keys ending with "*" match
+	// by prefix. Defaults to the GRPC health service.
+	Skip []string
+}
+
+func (c VerifierConfig) withDefaults() VerifierConfig {
+	if c.MaxSkew <= 0 {
+		c.MaxSkew = 5 * time.Minute
+	}
+	if c.Skip == nil {
+		c.Skip = []string{"/grpc.health.v1.Health/*"}
+	}
+
+	return c
+}
+
+func (c VerifierConfig) validate() {
+	if c.ReplayGuard == nil {
+		panic("reqsign: a replay guard is required to verify signatures")
+	}
+}
+
+// verify checks the signature of the incoming call, with any non-expired key of the signer. The hash of the request
+// is computed by ServerCodec.
+func verify(ctx context.Context, signer *jwtsign.Signer, config VerifierConfig, method, hash string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(SignatureMetadataKey)
+	if len(values) == 0 {
+		return ErrMissingSignature
+	}
+
+	parts := strings.Split(values[0], ":")
+	if len(parts) != 4 {
+		return ErrInvalidSignature
+	}
+	keyID, timestamp, nonce, sig := parts[0], parts[1], parts[2], parts[3]
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	now := time.Now()
+	signedAt := time.Unix(unix, 0)
+	if signedAt.Before(now.Add(-config.MaxSkew)) || signedAt.After(now.Add(config.MaxSkew)) {
+		return ErrStaleSignature
+	}
+
+	key, ok := signer.Keys().Key(keyID)
+	if !ok || key.Expired(now) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(key.Secret, method, timestamp, nonce, hash))) {
+		return ErrInvalidSignature
+	}
+
+	return config.ReplayGuard.Consume(ctx, keyID+":"+nonce, signedAt.Add(config.MaxSkew))
+}
+
+func unauthenticated(err error) error {
+	return status.Error(codes.Unauthenticated, err.Error())
+}
+
+// UnaryServerInterceptor rejects the calls without a valid signature from UnaryClientInterceptor, with
+// Unauthenticated. The server must decode requests with ServerCodec. It panics without replay guard.
+func UnaryServerInterceptor(signer *jwtsign.Signer, config VerifierConfig) grpc.UnaryServerInterceptor {
+	config.validate()
+	config = config.withDefaults()
+	skip := pattern.NewSet(config.Skip...)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		hash, ok := takeHash(req)
+		if !skip.Contains(info.FullMethod) {
+			if !ok {
+				return nil, unauthenticated(ErrMissingHash)
+			}
+			if err := verify(ctx, signer, config, info.FullMethod, hash); err != nil {
+				return nil, unauthenticated(err)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects the streams without a valid signature from StreamClientInterceptor, with
+// Unauthenticated. It panics without replay guard.
+func StreamServerInterceptor(signer *jwtsign.Signer, config VerifierConfig) grpc.StreamServerInterceptor {
+	config.validate()
+	config = config.withDefaults()
+	skip := pattern.NewSet(config.Skip...)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !skip.Contains(info.FullMethod) {
+			if err := verify(ss.Context(), signer, config, info.FullMethod, bodyHash(nil)); err != nil {
+				return unauthenticated(err)
+			}
+		}
+
+		return handler(srv, &hashedStream{ServerStream: ss})
+	}
+}
+
+// hashedStream removes the hashes added by ServerCodec from the messages of a stream, which are not signed.
+type hashedStream struct {
+	grpc.ServerStream
+}
+
+func (s *hashedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	takeHash(m)
+	return nil
+}
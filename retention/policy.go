@@ -0,0 +1,115 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	DefaultSoftDeleteColumn = "deleted_at"
+	DefaultKeyColumn        = "id"
+	DefaultBatchSize        = 500
+)
+
+var ErrInvalidPolicy = errors.New("invalid retention policy")
+
+// Policy declares how long soft-deleted rows of a table are kept before being purged for good.
+type Policy struct {
+	// Table is the name of the table holding the soft-deleted rows.
+	Table string
+	// SoftDeleteColumn is the nullable timestamp column set when a row is soft-deleted. Defaults to "deleted_at".
+	SoftDeleteColumn string
+	// KeyColumn uniquely identifies a row, and is used to delete rows in batches. Defaults to "id".
+	KeyColumn string
+	// Period is the amount of time a soft-deleted row is kept before being purged.
+	Period time.Duration
+	// BatchSize is the maximum number of rows deleted by a single statement. Defaults to 500.
+	BatchSize int
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.SoftDeleteColumn == "" {
+		p.SoftDeleteColumn = DefaultSoftDeleteColumn
+	}
+	if p.KeyColumn == "" {
+		p.KeyColumn = DefaultKeyColumn
+	}
+	if p.BatchSize <= 0 {
+		p.BatchSize = DefaultBatchSize
+	}
+
+	return p
+}
+
+func (p Policy) validate() error {
+	if p.Table == "" {
+		return fmt.Errorf("%w: table is required", ErrInvalidPolicy)
+	}
+	if p.Period <= 0 {
+		return fmt.Errorf("%w: period must be positive for table %s", ErrInvalidPolicy, p.Table)
+	}
+
+	return nil
+}
+
+// Cutoff returns the date before which soft-deleted rows are considered expired.
+func (p Policy) Cutoff(now time.Time) time.Time {
+	return now.Add(-p.Period)
+}
+
+func (p Policy) expired(db bun.IDB, now time.Time) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("?", bun.Ident(p.Table)).
+		Where("? IS NOT NULL", bun.Ident(p.SoftDeleteColumn)).
+		Where("? < ?", bun.Ident(p.SoftDeleteColumn), p.Cutoff(now))
+}
+
+// SoftDelete marks the rows matching the given keys as deleted, so they are later purged by the policy.
+func SoftDelete(ctx context.Context, db bun.IDB, policy Policy, keys ...any) (int64, error) {
+	policy = policy.withDefaults()
+	if err := policy.validate(); err != nil {
+		return 0, err
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	res, err := db.NewUpdate().
+		TableExpr("?", bun.Ident(policy.Table)).
+		Set("? = ?", bun.Ident(policy.SoftDeleteColumn), time.Now()).
+		Where("? IN (?)", bun.Ident(policy.KeyColumn), bun.In(keys)).
+		Where("? IS NULL", bun.Ident(policy.SoftDeleteColumn)).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// Restore reverts a soft-delete, as long as the rows have not been purged yet.
+func Restore(ctx context.Context, db bun.IDB, policy Policy, keys ...any) (int64, error) {
+	policy = policy.withDefaults()
+	if err := policy.validate(); err != nil {
+		return 0, err
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	res, err := db.NewUpdate().
+		TableExpr("?", bun.Ident(policy.Table)).
+		Set("? = NULL", bun.Ident(policy.SoftDeleteColumn)).
+		Where("? IN (?)", bun.Ident(policy.KeyColumn), bun.In(keys)).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,162 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Report describes the outcome of a purge for a single policy.
+type Report struct {
+	Table  string
+	Cutoff time.Time
+	// Expired is the number of rows past their retention period when the purge started.
+	Expired int
+	// Purged is the number of rows actually deleted. Always 0 in dry-run mode.
+	Purged int64
+	DryRun bool
+}
+
+type PurgerConfig struct {
+	// BatchDelay is the pause between two consecutive delete batches, to limit the pressure on the database.
+	BatchDelay time.Duration
+	// DryRun only reports the number of expired rows, without deleting anything.
+	DryRun bool
+}
+
+// Purger permanently deletes soft-deleted rows that have exceeded their retention period.
+type Purger struct {
+	db       bun.IDB
+	logger   monitor.Logger
+	policies []Policy
+	config   PurgerConfig
+}
+
+func NewPurger(db bun.IDB, logger monitor.Logger, config PurgerConfig, policies ...Policy) (*Purger, error) {
+	purger := &Purger{
+		db:     db,
+		logger: logger,
+		config: config,
+	}
+
+	for _, policy := range policies {
+		policy = policy.withDefaults()
+		if err := policy.validate(); err != nil {
+			return nil, err
+		}
+
+		purger.policies = append(purger.policies, policy)
+	}
+
+	return purger, nil
+}
+
+// Report computes the number of expired rows for every policy, without deleting anything.
+func (p *Purger) Report(ctx context.Context) ([]Report, error) {
+	now := time.Now()
+	reports := make([]Report, 0, len(p.policies))
+
+	for _, policy := range p.policies {
+		expired, err := policy.expired(p.db, now).Count(ctx)
+		if err != nil {
+			return reports, fmt.Errorf("count expired rows in %s: %w", policy.Table, err)
+		}
+
+		reports = append(reports, Report{
+			Table:   policy.Table,
+			Cutoff:  policy.Cutoff(now),
+			Expired: expired,
+			DryRun:  true,
+		})
+	}
+
+	return reports, nil
+}
+
+// Purge deletes expired rows for every policy, in batches. Under dry-run mode, this method is equivalent to Report.
+//
+// Each purge is written to the logger, to keep an audit trail of deleted data.
+func (p *Purger) Purge(ctx context.Context) ([]Report, error) {
+	reports, err := p.Report(ctx)
+	if err != nil {
+		return reports, err
+	}
+
+	for i, policy := range p.policies {
+		report := &reports[i]
+
+		if p.config.DryRun {
+			p.logger.Info(fmt.Sprintf(
+				"[retention] dry-run: %d rows of %s soft-deleted before %s would be purged",
+				report.Expired, report.Table, report.Cutoff.Format(time.RFC3339),
+			))
+			continue
+		}
+
+		report.DryRun = false
+		report.Purged, err = p.purgePolicy(ctx, policy, report.Cutoff)
+
+		p.logger.Info(fmt.Sprintf(
+			"[retention] purged %d rows of %s soft-deleted before %s",
+			report.Purged, report.Table, report.Cutoff.Format(time.RFC3339),
+		))
+
+		if err != nil {
+			return reports, fmt.Errorf("purge %s: %w", policy.Table, err)
+		}
+	}
+
+	return reports, nil
+}
+
+func (p *Purger) purgePolicy(ctx context.Context, policy Policy, cutoff time.Time) (int64, error) {
+	var total int64
+
+	for {
+		batch := p.db.NewSelect().
+			TableExpr("?", bun.Ident(policy.Table)).
+			ColumnExpr("?", bun.Ident(policy.KeyColumn)).
+			Where("? IS NOT NULL", bun.Ident(policy.SoftDeleteColumn)).
+			Where("? < ?", bun.Ident(policy.SoftDeleteColumn), cutoff).
+			Limit(policy.BatchSize)
+
+		res, err := p.db.NewDelete().
+			TableExpr("?", bun.Ident(policy.Table)).
+			Where("? IN (?)", bun.Ident(policy.KeyColumn), batch).
+			Exec(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+
+		total += affected
+		if affected < int64(policy.BatchSize) {
+			return total, nil
+		}
+
+		if err := ctxutil.Sleep(ctx, p.config.BatchDelay); err != nil {
+			return total, err
+		}
+	}
+}
+
+// Run purges expired rows periodically, until the context is canceled. Purge errors are logged, and do not stop
+// the worker.
+func (p *Purger) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if _, err := p.Purge(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error(err, "[retention] purge failed")
+		}
+
+		if err := ctxutil.Sleep(ctx, interval); err != nil {
+			return
+		}
+	}
+}
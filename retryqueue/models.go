@@ -0,0 +1,55 @@
+package retryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Item is a unit of work waiting for its next attempt.
+type Item struct {
+	bun.BaseModel `bun:"table:retry_items,alias:item"`
+
+	ID            int64           `bun:"id,pk,autoincrement"`
+	Queue         string          `bun:"queue,notnull"`
+	Payload       json.RawMessage `bun:"payload,type:jsonb,notnull"`
+	Attempts      int             `bun:"attempts,notnull"`
+	MaxAttempts   int             `bun:"max_attempts,notnull"`
+	NextAttemptAt time.Time       `bun:"next_attempt_at,notnull"`
+	LastError     string          `bun:"last_error"`
+	CreatedAt     time.Time       `bun:"created_at,notnull"`
+	UpdatedAt     time.Time       `bun:"updated_at,notnull"`
+}
+
+// DeadLetter is a unit of work that exhausted its attempts.
+type DeadLetter struct {
+	bun.BaseModel `bun:"table:retry_dead_letters,alias:dead_letter"`
+
+	ID        int64           `bun:"id,pk,autoincrement"`
+	Queue     string          `bun:"queue,notnull"`
+	Payload   json.RawMessage `bun:"payload,type:jsonb,notnull"`
+	Attempts  int             `bun:"attempts,notnull"`
+	LastError string          `bun:"last_error"`
+	CreatedAt time.Time       `bun:"created_at,notnull"`
+	DiedAt    time.Time       `bun:"died_at,notnull"`
+}
+
+// CreateTables creates the retry and dead-letter tables, if they do not exist yet.
+func CreateTables(ctx context.Context, db bun.IDB) error {
+	if _, err := db.NewCreateTable().Model((*Item)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	if _, err := db.NewCreateIndex().
+		Model((*Item)(nil)).
+		Index("retry_items_due_idx").
+		Column("queue", "next_attempt_at").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	_, err := db.NewCreateTable().Model((*DeadLetter)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
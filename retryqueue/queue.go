@@ -0,0 +1,265 @@
+package retryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/uptrace/bun"
+	"math/rand/v2"
+	"time"
+)
+
+type QueueConfig struct {
+	// Name of the queue. Several queues can share the same tables.
+	Name string
+	// MaxAttempts is the default number of attempts before an item is moved to the dead-letter table.
+	// Defaults to 10.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles after each failed attempt. Defaults to 30 seconds.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts. Defaults to 6 hours.
+	MaxDelay time.Duration
+}
+
+// Queue stores failed payloads of type T, until they are successfully processed.
+type Queue[T any] struct {
+	db     bun.IDB
+	config QueueConfig
+}
+
+func NewQueue[T any](db bun.IDB, config QueueConfig) *Queue[T] {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = 30 * time.Second
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = 6 * time.Hour
+	}
+
+	return &Queue[T]{db: db, config: config}
+}
+
+// Backoff returns the delay before the next attempt, after the given number of failed attempts. A jitter of up
+// to 20% is added, to spread retries of items that failed together.
+func (q *Queue[T]) Backoff(attempts int) time.Duration {
+	delay := q.config.BaseDelay
+	for i := 1; i < attempts && delay < q.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > q.config.MaxDelay {
+		delay = q.config.MaxDelay
+	}
+
+	return delay + time.Duration(rand.Float64()*0.2*float64(delay))
+}
+
+// Enqueue stores a payload whose processing failed, with the error of the first attempt. Its next attempt is
+// scheduled with backoff.
+//
+// Pass the current transaction as db to enqueue atomically with other changes. If db is nil, the queue
+// database is used.
+func (q *Queue[T]) Enqueue(ctx context.Context, db bun.IDB, payload T, cause error) (*Item, error) {
+	if db == nil {
+		db = q.db
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("serialize payload: %w", err)
+	}
+
+	now := time.Now()
+	item := &Item{
+		Queue:         q.config.Name,
+		Payload:       raw,
+		Attempts:      1,
+		MaxAttempts:   q.config.MaxAttempts,
+		NextAttemptAt: now.Add(q.Backoff(1)),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if cause != nil {
+		item.LastError = cause.Error()
+	}
+
+	if _, err := db.NewInsert().Model(item).Returning("id").Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// claim leases due items, so concurrent workers do not process them at the same time. Leased items are not
+// claimed again before the lease expires. The next attempt of the claimed items is set to the end of their lease.
+func (q *Queue[T]) claim(ctx context.Context, limit int, lease time.Duration) ([]*Item, error) {
+	items := make([]*Item, 0, limit)
+	// Postgres stores microseconds: truncate, so the lease can be compared by extend.
+	leasedUntil := time.Now().Add(lease).Truncate(time.Microsecond)
+
+	err := q.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewSelect().
+			Model(&items).
+			Where("queue = ?", q.config.Name).
+			Where("next_attempt_at <= ?", time.Now()).
+			OrderExpr("next_attempt_at ASC").
+			Limit(limit).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx)
+		if err != nil || len(items) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*Item)(nil)).
+			Set("next_attempt_at = ?", leasedUntil).
+			Where("id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.NextAttemptAt = leasedUntil
+	}
+
+	return items, nil
+}
+
+// extend renews the lease of a claimed item, before it is processed. It returns false if the lease expired and
+// another worker claimed the item meanwhile.
+func (q *Queue[T]) extend(ctx context.Context, item *Item, lease time.Duration) (bool, error) {
+	leasedUntil := time.Now().Add(lease).Truncate(time.Microsecond)
+
+	res, err := q.db.NewUpdate().
+		Model((*Item)(nil)).
+		Set("next_attempt_at = ?", leasedUntil).
+		Where("id = ?", item.ID).
+		Where("next_attempt_at = ?", item.NextAttemptAt).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if count, err := res.RowsAffected(); err != nil || count == 0 {
+		return false, err
+	}
+
+	item.NextAttemptAt = leasedUntil
+	return true, nil
+}
+
+func (q *Queue[T]) succeed(ctx context.Context, item *Item) error {
+	_, err := q.db.NewDelete().Model((*Item)(nil)).Where("id = ?", item.ID).Exec(ctx)
+	return err
+}
+
+// fail schedules the next attempt of an item, or moves it to the dead-letter table. It returns true if the item
+// died.
+func (q *Queue[T]) fail(ctx context.Context, item *Item, cause error) (bool, error) {
+	item.Attempts++
+	item.LastError = cause.Error()
+	item.UpdatedAt = time.Now()
+
+	if item.Attempts < item.MaxAttempts {
+		item.NextAttemptAt = time.Now().Add(q.Backoff(item.Attempts))
+
+		_, err := q.db.NewUpdate().
+			Model(item).
+			Column("attempts", "last_error", "next_attempt_at", "updated_at").
+			WherePK().
+			Exec(ctx)
+		return false, err
+	}
+
+	err := q.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		deadLetter := &DeadLetter{
+			Queue:     item.Queue,
+			Payload:   item.Payload,
+			Attempts:  item.Attempts,
+			LastError: item.LastError,
+			CreatedAt: item.CreatedAt,
+			DiedAt:    time.Now(),
+		}
+
+		if _, err := tx.NewInsert().Model(deadLetter).Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewDelete().Model((*Item)(nil)).Where("id = ?", item.ID).Exec(ctx)
+		return err
+	})
+
+	return true, err
+}
+
+// DeadLetters returns the dead items of the queue, most recent first.
+func (q *Queue[T]) DeadLetters(ctx context.Context, limit, offset int) ([]*DeadLetter, error) {
+	deadLetters := make([]*DeadLetter, 0, limit)
+
+	err := q.db.NewSelect().
+		Model(&deadLetters).
+		Where("queue = ?", q.config.Name).
+		OrderExpr("died_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+
+	return deadLetters, err
+}
+
+// Requeue moves dead items back to the queue, with a fresh set of attempts. Their next attempt is immediate.
+func (q *Queue[T]) Requeue(ctx context.Context, ids ...int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	count := 0
+	err := q.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		deadLetters := make([]*DeadLetter, 0, len(ids))
+		err := tx.NewSelect().
+			Model(&deadLetters).
+			Where("queue = ?", q.config.Name).
+			Where("id IN (?)", bun.In(ids)).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil || len(deadLetters) == 0 {
+			return err
+		}
+
+		now := time.Now()
+		items := make([]*Item, len(deadLetters))
+		for i, deadLetter := range deadLetters {
+			items[i] = &Item{
+				Queue:         deadLetter.Queue,
+				Payload:       deadLetter.Payload,
+				MaxAttempts:   q.config.MaxAttempts,
+				NextAttemptAt: now,
+				LastError:     deadLetter.LastError,
+				CreatedAt:     deadLetter.CreatedAt,
+				UpdatedAt:     now,
+			}
+		}
+
+		if _, err := tx.NewInsert().Model(&items).Exec(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewDelete().Model(&deadLetters).WherePK().Exec(ctx); err != nil {
+			return err
+		}
+
+		count = len(deadLetters)
+		return nil
+	})
+
+	return count, err
+}
@@ -0,0 +1,118 @@
+package retryqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"time"
+)
+
+type Handler[T any] func(ctx context.Context, payload T) error
+
+type WorkerConfig struct {
+	// BatchSize is the number of items claimed at once. Defaults to 20.
+	BatchSize int
+	// PollInterval is the delay between two polls when no item is due. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Lease is the time a worker has to process an item, before another worker can claim it. Items of a batch are
+	// processed one after the other, and the lease of each item is renewed when its processing starts. Defaults to
+	// 5 minutes.
+	Lease time.Duration
+}
+
+// Worker retries the due items of a queue.
+type Worker[T any] struct {
+	queue   *Queue[T]
+	handler Handler[T]
+	logger  monitor.Logger
+	config  WorkerConfig
+}
+
+func NewWorker[T any](queue *Queue[T], handler Handler[T], logger monitor.Logger, config WorkerConfig) *Worker[T] {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 20
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = 5 * time.Minute
+	}
+
+	return &Worker[T]{queue: queue, handler: handler, logger: logger, config: config}
+}
+
+// Run processes due items until the context is canceled.
+func (w *Worker[T]) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := w.ProcessOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error(err, fmt.Sprintf("[retryqueue] %s: failed to process items", w.queue.config.Name))
+		}
+
+		if processed < w.config.BatchSize || err != nil {
+			if ctxutil.Sleep(ctx, w.config.PollInterval) != nil {
+				return
+			}
+		}
+	}
+}
+
+// ProcessOnce claims and processes a batch of due items, and returns the number of claimed items.
+func (w *Worker[T]) ProcessOnce(ctx context.Context) (int, error) {
+	items, err := w.queue.claim(ctx, w.config.BatchSize, w.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		// The lease of the batch may have expired while the previous items were processed.
+		leased, err := w.queue.extend(ctx, item, w.config.Lease)
+		if err != nil {
+			return len(items), err
+		}
+		if !leased {
+			w.logger.Warn(fmt.Sprintf("[retryqueue] %s: lease of item %d expired, skipping it", item.Queue, item.ID))
+			continue
+		}
+
+		if err := w.process(ctx, item); err != nil {
+			return len(items), err
+		}
+	}
+
+	return len(items), nil
+}
+
+func (w *Worker[T]) process(ctx context.Context, item *Item) error {
+	var payload T
+	handlerErr := json.Unmarshal(item.Payload, &payload)
+	if handlerErr == nil {
+		handlerErr = w.handler(ctx, payload)
+	}
+
+	if handlerErr == nil {
+		return w.queue.succeed(ctx, item)
+	}
+
+	died, err := w.queue.fail(ctx, item, handlerErr)
+	if err != nil {
+		return err
+	}
+
+	if died {
+		w.logger.Error(handlerErr, fmt.Sprintf(
+			"[retryqueue] %s: item %d moved to dead letters after %d attempts",
+			item.Queue, item.ID, item.Attempts,
+		))
+	} else {
+		w.logger.Warn(fmt.Sprintf(
+			"[retryqueue] %s: attempt %d of item %d failed, next attempt at %s: %s",
+			item.Queue, item.Attempts, item.ID, item.NextAttemptAt.Format(time.RFC3339), handlerErr,
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"runtime/debug"
+	"time"
+)
+
+var ErrPanic = errors.New("panic recovered")
+
+// PanicError is returned when the protected function panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic recovered: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() []error {
+	errs := []error{ErrPanic}
+	if err, ok := e.Value.(error); ok {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// GRPCStatus converts the panic to an Internal GRPC error, without leaking the panic details to clients.
+func (e *PanicError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "internal error")
+}
+
+// Call runs fn with panic recovery. If the context is canceled or expires before fn returns, Call returns the
+// context error right away, and fn keeps running in the background until it returns on its own.
+//
+//	err := safe.Call(ctx, func(ctx context.Context) error {
+//		return scraper.FetchProfile(ctx, profileURL)
+//	})
+func Call(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := CallValue(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+
+	return err
+}
+
+// CallTimeout runs fn like Call, with a timeout.
+func CallTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return Call(ctx, fn)
+}
+
+// CallValue runs fn like Call, and returns its result.
+func CallValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+
+	// Buffered, so the goroutine never leaks when the caller stopped waiting.
+	done := make(chan result, 1)
+
+	go func() {
+		var res result
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				res.err = &PanicError{Value: recovered, Stack: debug.Stack()}
+			}
+
+			done <- res
+		}()
+
+		res.value, res.err = fn(ctx)
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Go runs fn in a new goroutine with panic recovery. Errors and panics are reported to the logger.
+func Go(ctx context.Context, logger monitor.Logger, fn func(ctx context.Context) error) {
+	go func() {
+		err := func() (err error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					err = &PanicError{Value: recovered, Stack: debug.Stack()}
+				}
+			}()
+
+			return fn(ctx)
+		}()
+
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			logger.Error(err, fmt.Sprintf("goroutine panicked\n%s", panicErr.Stack))
+		} else if err != nil {
+			logger.Error(err, "goroutine failed")
+		}
+	}()
+}
+
+// ToStatus converts context errors to their GRPC equivalent, before returning them from a handler. Other errors
+// are returned unchanged.
+func ToStatus(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,94 @@
+package sanitize
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// TrackingParams lists the query parameters removed by StripTracking. Parameters ending with "*" match by prefix.
+var TrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_hsenc",
+	"_hsmi", "mkt_tok", "oly_anon_id", "oly_enc_id", "vero_id", "wickedid",
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, param := range TrackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StripTracking removes the tracking parameters of a URL. Invalid URLs are returned unchanged.
+func StripTracking(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	changed := false
+
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return rawURL
+	}
+
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// RewriteLinks applies rewrite to the href attribute of every link in an HTML fragment. The rest of the fragment is
+// left untouched.
+func RewriteLinks(fragment string, rewrite func(href string) string) string {
+	var buf bytes.Buffer
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				return buf.String()
+			}
+
+			// Malformed fragments are returned as is, they have been sanitized already.
+			return fragment
+		}
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			buf.Write(tokenizer.Raw())
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.Data != "a" {
+			buf.WriteString(token.String())
+			continue
+		}
+
+		for i, attr := range token.Attr {
+			if attr.Key == "href" {
+				token.Attr[i].Val = rewrite(attr.Val)
+			}
+		}
+
+		buf.WriteString(token.String())
+	}
+}
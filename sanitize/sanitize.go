@@ -0,0 +1,90 @@
+package sanitize
+
+import (
+	"bytes"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"regexp"
+)
+
+// NotePolicy returns the sanitization policy of note content: the formatting elements of user-generated content,
+// links restricted to http, https and mailto, and no script, style, form or embedded content.
+func NotePolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.RequireParseableURLs(true)
+	policy.RequireNoFollowOnLinks(true)
+	policy.RequireNoReferrerOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	// Language hints of fenced code blocks, used for syntax highlighting.
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	// Task lists rendered from markdown.
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+
+	return policy
+}
+
+// Sanitizer cleans user-provided content before it is stored or rendered.
+type Sanitizer struct {
+	policy   *bluemonday.Policy
+	markdown goldmark.Markdown
+	// stripTracking removes tracking parameters from links.
+	stripTracking bool
+}
+
+type Config struct {
+	// Policy overrides the sanitization policy. Defaults to NotePolicy.
+	Policy *bluemonday.Policy
+	// KeepTrackingParams disables the removal of tracking parameters from links.
+	KeepTrackingParams bool
+}
+
+func NewSanitizer(config Config) *Sanitizer {
+	if config.Policy == nil {
+		config.Policy = NotePolicy()
+	}
+
+	return &Sanitizer{
+		policy: config.Policy,
+		// Raw HTML is escaped by default, and the output is sanitized anyway.
+		markdown:      goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		stripTracking: !config.KeepTrackingParams,
+	}
+}
+
+var defaultSanitizer = NewSanitizer(Config{})
+
+// HTML sanitizes user-provided HTML with the default sanitizer.
+func HTML(input string) string {
+	return defaultSanitizer.HTML(input)
+}
+
+// Markdown renders user-provided markdown to safe HTML with the default sanitizer.
+func Markdown(input string) (string, error) {
+	return defaultSanitizer.Markdown(input)
+}
+
+// HTML sanitizes user-provided HTML, and rewrites its links.
+func (s *Sanitizer) HTML(input string) string {
+	output := s.policy.Sanitize(input)
+
+	if s.stripTracking {
+		output = RewriteLinks(output, StripTracking)
+	}
+
+	return output
+}
+
+// Markdown renders user-provided markdown to HTML, then sanitizes the result like HTML does.
+func (s *Sanitizer) Markdown(input string) (string, error) {
+	var buf bytes.Buffer
+	if err := s.markdown.Convert([]byte(input), &buf); err != nil {
+		return "", err
+	}
+
+	return s.HTML(buf.String()), nil
+}
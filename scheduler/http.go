@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/in-rich/lib-go/introspect"
+	"net/http"
+	"strings"
+)
+
+// Mount registers the ops endpoints of the scheduler on the mux:
+//
+//	GET  prefix + "/jobs"              lists the jobs with their last and next runs.
+//	POST prefix + "/jobs/run?name=..." runs a job now.
+//
+// Manual runs are detached from the request, so they keep running after the response is sent.
+func (s *Scheduler) Mount(mux *http.ServeMux, prefix string, allowlist *introspect.IPAllowlist) {
+	if allowlist == nil {
+		panic("scheduler: an IP allowlist is required to mount the jobs endpoints")
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	mux.Handle(prefix+"/jobs", allowlist.Middleware(http.HandlerFunc(s.list)))
+	mux.Handle(prefix+"/jobs/run", allowlist.Middleware(http.HandlerFunc(s.runNow)))
+}
+
+func (s *Scheduler) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Statuses())
+}
+
+func (s *Scheduler) runNow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := s.RunNow(context.WithoutCancel(r.Context()), r.URL.Query().Get("name"))
+	switch {
+	case errors.Is(err, ErrUnknownJob):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAlreadyRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
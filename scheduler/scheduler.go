@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/robfig/cron/v3"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownJob     = errors.New("unknown job")
+	ErrDuplicateJob   = errors.New("job already registered")
+	ErrAlreadyRunning = errors.New("job already running")
+)
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Job is a task run periodically by the scheduler.
+type Job struct {
+	// Name identifies the job in the catalog.
+	Name string
+	// Schedule is a standard 5 fields cron expression ("0 3 * * *"), or a descriptor ("@hourly", "@every 10m").
+	// Times are UTC.
+	Schedule string
+	// Timeout bounds a single run. Defaults to 1 hour.
+	Timeout time.Duration
+	// MaxAge is the maximum time since the last successful run, before the job is reported as unhealthy. Optional:
+	// when empty, the job is only unhealthy when its last run failed.
+	MaxAge time.Duration
+	Run    func(ctx context.Context) error
+}
+
+// RunReport describes a single run of a job.
+type RunReport struct {
+	Job       string
+	StartedAt time.Time
+	Duration  time.Duration
+	// Manual is true when the run was triggered with RunNow.
+	Manual bool
+	Error  error
+}
+
+type Config struct {
+	// OnRun is called after each run, to export metrics. Optional.
+	OnRun func(report RunReport)
+}
+
+type entry struct {
+	job      Job
+	schedule cron.Schedule
+	status   Status
+}
+
+// Scheduler runs a catalog of jobs, and keeps track of their last runs.
+//
+//	jobs := scheduler.NewScheduler(logger, scheduler.Config{})
+//	jobs.MustRegister(scheduler.Job{Name: "nightly-sync", Schedule: "0 3 * * *", MaxAge: 26 * time.Hour, Run: sync})
+//	go jobs.Run(ctx)
+//
+//	jobs.Mount(opsMux, "/ops", allowlist)
+type Scheduler struct {
+	logger monitor.Logger
+	config Config
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(logger monitor.Logger, config Config) *Scheduler {
+	return &Scheduler{
+		logger:  logger,
+		config:  config,
+		entries: make(map[string]*entry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Register adds a job to the catalog. Jobs can be registered while the scheduler is running.
+func (s *Scheduler) Register(job Job) error {
+	schedule, err := parser.Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", job.Name, err)
+	}
+
+	if job.Timeout <= 0 {
+		job.Timeout = time.Hour
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+	}
+
+	s.entries[job.Name] = &entry{
+		job:      job,
+		schedule: schedule,
+		status: Status{
+			Name:      job.Name,
+			Schedule:  job.Schedule,
+			NextRunAt: schedule.Next(time.Now().UTC()),
+
+			registeredAt: time.Now(),
+		},
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// MustRegister adds a job to the catalog, and panics if the job is invalid.
+func (s *Scheduler) MustRegister(job Job) {
+	if err := s.Register(job); err != nil {
+		panic(err)
+	}
+}
+
+// Run starts the due jobs until the context is canceled, then waits for the running jobs to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.wg.Wait()
+
+	for {
+		next := s.startDue(ctx)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// startDue starts the jobs whose next run is due, and returns the time of the next run.
+func (s *Scheduler) startDue(ctx context.Context) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	next := now.Add(time.Hour)
+
+	for _, e := range s.entries {
+		if !e.status.NextRunAt.After(now) {
+			if e.status.Running {
+				s.logger.Warn(fmt.Sprintf("[scheduler] skipping run of %s: previous run still in progress", e.job.Name))
+			} else {
+				s.start(ctx, e, false)
+			}
+
+			e.status.NextRunAt = e.schedule.Next(now)
+		}
+
+		if e.status.NextRunAt.Before(next) {
+			next = e.status.NextRunAt
+		}
+	}
+
+	return next
+}
+
+// RunNow starts a job immediately, outside its schedule. It does not wait for the run to complete.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+
+	if e.status.Running {
+		return fmt.Errorf("%w: %s", ErrAlreadyRunning, name)
+	}
+
+	s.start(ctx, e, true)
+	return nil
+}
+
+// start runs the job in a new goroutine. Must be called with the lock held.
+func (s *Scheduler) start(ctx context.Context, e *entry, manual bool) {
+	e.status.Running = true
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		report := RunReport{Job: e.job.Name, StartedAt: time.Now(), Manual: manual}
+		report.Error = s.execute(ctx, e.job)
+		report.Duration = time.Since(report.StartedAt)
+
+		s.mu.Lock()
+		e.status.record(report)
+		s.mu.Unlock()
+
+		if report.Error != nil {
+			s.logger.Error(report.Error, fmt.Sprintf("[scheduler] job %s failed after %s", e.job.Name, report.Duration))
+		} else {
+			s.logger.Info(fmt.Sprintf("[scheduler] job %s succeeded in %s", e.job.Name, report.Duration))
+		}
+
+		if s.config.OnRun != nil {
+			s.config.OnRun(report)
+		}
+	}()
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v\n%s", recovered, debug.Stack())
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+// Statuses returns the status of every registered job, sorted by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Health reports the jobs whose last run failed, or whose last success is older than their MaxAge. It can be used
+// as a deploy.DepCheckCallback, or as the Health of an introspect.Bundle.
+func (s *Scheduler) Health() map[string]error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	out := make(map[string]error, len(s.entries))
+
+	for name, e := range s.entries {
+		out["job:"+name] = e.status.check(now, e.job.MaxAge)
+	}
+
+	return out
+}
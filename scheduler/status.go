@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrJobFailed = errors.New("last run failed")
+	ErrJobStale  = errors.New("no successful run")
+)
+
+// Status is the state of a job in the catalog.
+type Status struct {
+	Name          string
+	Schedule      string
+	Running       bool
+	LastRunAt     time.Time
+	LastDuration  time.Duration
+	LastError     error
+	LastSuccessAt time.Time
+	NextRunAt     time.Time
+	Runs          int
+	Failures      int
+
+	registeredAt time.Time
+}
+
+func (s *Status) record(report RunReport) {
+	s.Running = false
+	s.LastRunAt = report.StartedAt
+	s.LastDuration = report.Duration
+	s.LastError = report.Error
+	s.Runs++
+
+	if report.Error != nil {
+		s.Failures++
+	} else {
+		s.LastSuccessAt = report.StartedAt
+	}
+}
+
+func (s *Status) check(now time.Time, maxAge time.Duration) error {
+	if s.LastError != nil {
+		return fmt.Errorf("%w: %w", ErrJobFailed, s.LastError)
+	}
+
+	// Jobs that never succeeded are not stale until their MaxAge has elapsed since their registration.
+	since := s.LastSuccessAt
+	if since.IsZero() {
+		since = s.registeredAt
+	}
+
+	if maxAge > 0 && now.Sub(since) > maxAge {
+		return fmt.Errorf("%w since %s", ErrJobStale, since.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	out := map[string]any{
+		"name":      s.Name,
+		"schedule":  s.Schedule,
+		"running":   s.Running,
+		"nextRunAt": s.NextRunAt,
+		"runs":      s.Runs,
+		"failures":  s.Failures,
+	}
+
+	if !s.LastRunAt.IsZero() {
+		out["lastRunAt"] = s.LastRunAt
+		out["lastDurationMs"] = s.LastDuration.Milliseconds()
+		out["success"] = s.LastError == nil
+	}
+	if !s.LastSuccessAt.IsZero() {
+		out["lastSuccessAt"] = s.LastSuccessAt
+	}
+	if s.LastError != nil {
+		out["error"] = s.LastError.Error()
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,39 @@
+package searchsync
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Document is a single entry of a search index.
+type Document struct {
+	ID   string
+	Body any
+}
+
+// Engine is implemented by search backends.
+//
+// Indexes are accessed through aliases, so a full reindex can be built in a fresh index, then atomically swapped
+// in place of the live one.
+type Engine interface {
+	Upsert(ctx context.Context, index string, documents []Document) error
+	Delete(ctx context.Context, index string, ids []string) error
+
+	CreateIndex(ctx context.Context, index string) error
+	DropIndex(ctx context.Context, index string) error
+	// SwapAlias points the alias to the given index, and returns the index the alias previously pointed to, if any.
+	SwapAlias(ctx context.Context, alias string, index string) (string, error)
+}
+
+// DocumentFunc builds the search document of an entity, from its latest snapshot. Returning a nil body removes
+// the entity from the index.
+type DocumentFunc func(ctx context.Context, entityID string, snapshot json.RawMessage) (any, error)
+
+// Binding maps a history entity to a search index.
+type Binding struct {
+	// Entity is the name of the entity, as recorded by the history package.
+	Entity string
+	// Alias is the name of the search index alias.
+	Alias    string
+	Document DocumentFunc
+}
@@ -0,0 +1,84 @@
+package searchsync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SourceFunc emits every document that must be present in a fresh index.
+type SourceFunc func(ctx context.Context, emit func(document Document) error) error
+
+// Reindex rebuilds the index behind an alias from scratch. Documents are loaded in a new index, which replaces the
+// live one once complete. The previous index is then dropped.
+//
+// The live index keeps serving requests, and keeps receiving changes from the workers, during the whole operation.
+// Changes applied to the live index between the start of the source scan and the alias swap may be lost: run
+// the worker with a cursor positioned before the reindex started to replay them.
+func (w *Worker) Reindex(ctx context.Context, alias string, source SourceFunc) error {
+	index := fmt.Sprintf("%s_%d", alias, time.Now().UnixMilli())
+
+	if err := w.engine.CreateIndex(ctx, index); err != nil {
+		return fmt.Errorf("create index %s: %w", index, err)
+	}
+
+	w.logger.Info(fmt.Sprintf("[searchsync] reindexing %s into %s", alias, index))
+
+	batch := make([]Document, 0, w.config.BatchSize)
+	total := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := w.retry(ctx, func() error { return w.engine.Upsert(ctx, index, batch) }); err != nil {
+			return err
+		}
+
+		total += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := source(ctx, func(document Document) error {
+		batch = append(batch, document)
+		if len(batch) >= w.config.BatchSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// Do not leave half-built indexes behind.
+		if dropErr := w.engine.DropIndex(context.WithoutCancel(ctx), index); dropErr != nil {
+			w.logger.Error(dropErr, fmt.Sprintf("[searchsync] failed to drop incomplete index %s", index))
+		}
+
+		return fmt.Errorf("load index %s: %w", index, err)
+	}
+
+	previous, err := w.engine.SwapAlias(ctx, alias, index)
+	if err != nil {
+		return fmt.Errorf("swap alias %s to %s: %w", alias, index, err)
+	}
+
+	w.logger.Info(fmt.Sprintf("[searchsync] alias %s now points to %s (%d documents)", alias, index, total))
+
+	if previous != "" && previous != index {
+		if err := w.engine.DropIndex(ctx, previous); err != nil {
+			return fmt.Errorf("drop previous index %s: %w", previous, err)
+		}
+	}
+
+	return nil
+}
+
+func isNoRows(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
@@ -0,0 +1,255 @@
+package searchsync
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/database"
+	"github.com/in-rich/lib-go/history"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Cursor stores the position of a worker in the changes stream: the transaction and ID of the last change applied.
+type Cursor struct {
+	bun.BaseModel `bun:"table:search_sync_cursors,alias:cursor"`
+
+	Name      string    `bun:"name,pk"`
+	TxID      int64     `bun:"tx_id,notnull"`
+	Position  int64     `bun:"position,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+type WorkerConfig struct {
+	// Name identifies the cursor of the worker. Workers sharing a name share their progress.
+	Name string
+	// BatchSize is the maximum number of changes applied at once. Defaults to 200.
+	BatchSize int
+	// PollInterval is the delay between two polls when the stream is exhausted. Defaults to 2 seconds.
+	PollInterval time.Duration
+	// MaxRetries is the number of times a failing batch is retried before the worker reports an error.
+	// Defaults to 5.
+	MaxRetries int
+	// RetryDelay is the initial delay between two retries. It doubles after each attempt. Defaults to 1 second.
+	RetryDelay time.Duration
+}
+
+func (c WorkerConfig) withDefaults() WorkerConfig {
+	if c.Name == "" {
+		c.Name = "default"
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 200
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 2 * time.Second
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 5
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = time.Second
+	}
+
+	return c
+}
+
+// Worker tails the changes table, and applies the changes of bound entities to the search engine.
+type Worker struct {
+	db       bun.IDB
+	engine   Engine
+	logger   monitor.Logger
+	config   WorkerConfig
+	bindings map[string]Binding
+}
+
+func NewWorker(db bun.IDB, engine Engine, logger monitor.Logger, config WorkerConfig, bindings ...Binding) *Worker {
+	worker := &Worker{
+		db:       db,
+		engine:   engine,
+		logger:   logger,
+		config:   config.withDefaults(),
+		bindings: make(map[string]Binding, len(bindings)),
+	}
+
+	for _, binding := range bindings {
+		worker.bindings[binding.Entity] = binding
+	}
+
+	return worker
+}
+
+// CreateTable creates the cursor table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*Cursor)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+// Run synchronizes changes until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		applied, err := w.SyncOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error(err, "[searchsync] failed to apply changes")
+		}
+
+		if applied < w.config.BatchSize || err != nil {
+			if ctxutil.Sleep(ctx, w.config.PollInterval) != nil {
+				return
+			}
+		}
+	}
+}
+
+// SyncOnce applies the next batch of changes, and returns the number of changes consumed from the stream. Changes
+// are read in transaction order, and only once every older transaction has ended, so changes recorded by a long
+// transaction are never skipped: they delay the changes that follow instead.
+func (w *Worker) SyncOnce(ctx context.Context) (int, error) {
+	position, err := w.position(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	entities := make([]string, 0, len(w.bindings))
+	for entity := range w.bindings {
+		entities = append(entities, entity)
+	}
+
+	changes := make([]*history.Change, 0)
+	query := w.db.NewSelect().Model(&changes).Where("entity IN (?)", bun.In(entities))
+	err = database.AfterPosition(query, position).Limit(w.config.BatchSize).Scan(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	if err := w.retry(ctx, func() error { return w.apply(ctx, changes) }); err != nil {
+		return 0, err
+	}
+
+	for _, change := range changes {
+		if position.Advance(change.TxID, change.ID) {
+			w.logger.Warn(fmt.Sprintf("[searchsync] change %d was committed out of order", change.ID))
+		}
+	}
+
+	if err := w.setPosition(ctx, position); err != nil {
+		return 0, err
+	}
+
+	return len(changes), nil
+}
+
+type operations struct {
+	upserts map[string]Document
+	deletes map[string]struct{}
+}
+
+func (w *Worker) apply(ctx context.Context, changes []*history.Change) error {
+	// Only the latest change of each entity matters.
+	byAlias := make(map[string]*operations)
+
+	for _, change := range changes {
+		binding := w.bindings[change.Entity]
+
+		ops, ok := byAlias[binding.Alias]
+		if !ok {
+			ops = &operations{upserts: make(map[string]Document), deletes: make(map[string]struct{})}
+			byAlias[binding.Alias] = ops
+		}
+
+		var body any
+		if change.Action != history.ActionDelete {
+			var err error
+			if body, err = binding.Document(ctx, change.EntityID, change.After); err != nil {
+				return fmt.Errorf("build document for %s %s: %w", change.Entity, change.EntityID, err)
+			}
+		}
+
+		if body == nil {
+			delete(ops.upserts, change.EntityID)
+			ops.deletes[change.EntityID] = struct{}{}
+		} else {
+			delete(ops.deletes, change.EntityID)
+			ops.upserts[change.EntityID] = Document{ID: change.EntityID, Body: body}
+		}
+	}
+
+	for alias, ops := range byAlias {
+		if len(ops.upserts) > 0 {
+			documents := make([]Document, 0, len(ops.upserts))
+			for _, document := range ops.upserts {
+				documents = append(documents, document)
+			}
+
+			if err := w.engine.Upsert(ctx, alias, documents); err != nil {
+				return fmt.Errorf("upsert documents in %s: %w", alias, err)
+			}
+		}
+
+		if len(ops.deletes) > 0 {
+			ids := make([]string, 0, len(ops.deletes))
+			for id := range ops.deletes {
+				ids = append(ids, id)
+			}
+
+			if err := w.engine.Delete(ctx, alias, ids); err != nil {
+				return fmt.Errorf("delete documents from %s: %w", alias, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) retry(ctx context.Context, fn func() error) error {
+	delay := w.config.RetryDelay
+
+	var err error
+	for attempt := 0; attempt <= w.config.MaxRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if attempt < w.config.MaxRetries {
+			w.logger.Warn(fmt.Sprintf("[searchsync] attempt %d failed, retrying in %s: %s", attempt+1, delay, err))
+			if sleepErr := ctxutil.Sleep(ctx, delay); sleepErr != nil {
+				return sleepErr
+			}
+			delay *= 2
+		}
+	}
+
+	return err
+}
+
+func (w *Worker) position(ctx context.Context) (database.Position, error) {
+	cursor := &Cursor{Name: w.config.Name}
+	err := w.db.NewSelect().Model(cursor).WherePK().Scan(ctx)
+	if err != nil {
+		if isNoRows(err) {
+			return database.Position{}, nil
+		}
+
+		return database.Position{}, err
+	}
+
+	return database.Position{TxID: cursor.TxID, ID: cursor.Position}, nil
+}
+
+func (w *Worker) setPosition(ctx context.Context, position database.Position) error {
+	cursor := &Cursor{Name: w.config.Name, TxID: position.TxID, Position: position.ID, UpdatedAt: time.Now()}
+	_, err := w.db.NewInsert().
+		Model(cursor).
+		On("CONFLICT (name) DO UPDATE").
+		Set("tx_id = EXCLUDED.tx_id").
+		Set("position = EXCLUDED.position").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+
+	return err
+}
@@ -0,0 +1,19 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Backend stores serialized sessions.
+type Backend interface {
+	// Load returns the session data, or ErrNotFound if the session does not exist or expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	// Touch extends the expiration of a session. It returns ErrNotFound if the session does not exist or expired.
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func (b *memoryBackend) Load(_ context.Context, id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(b.entries, id)
+		return nil, ErrNotFound
+	}
+
+	return entry.data, nil
+}
+
+func (b *memoryBackend) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evict()
+	b.entries[id] = memoryEntry{data: data, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *memoryBackend) Touch(_ context.Context, id string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(b.entries, id)
+		return ErrNotFound
+	}
+
+	entry.expiresAt = time.Now().Add(ttl)
+	b.entries[id] = entry
+	return nil
+}
+
+func (b *memoryBackend) Delete(_ context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, id)
+	return nil
+}
+
+func (b *memoryBackend) evict() {
+	now := time.Now()
+	for id, entry := range b.entries {
+		if now.After(entry.expiresAt) {
+			delete(b.entries, id)
+		}
+	}
+}
+
+// NewMemoryBackend creates a backend that keeps sessions in the memory of the current process. Sessions are lost on
+// restart, and are not shared between instances: only use it for local development and tests.
+func NewMemoryBackend() Backend {
+	return &memoryBackend{entries: make(map[string]memoryEntry)}
+}
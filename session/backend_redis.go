@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type redisBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (b *redisBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := b.client.Get(ctx, b.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (b *redisBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, b.prefix+id, data, ttl).Err()
+}
+
+func (b *redisBackend) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	ok, err := b.client.Expire(ctx, b.prefix+id, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (b *redisBackend) Delete(ctx context.Context, id string) error {
+	return b.client.Del(ctx, b.prefix+id).Err()
+}
+
+// NewRedisBackend creates a backend that stores sessions in Redis. Keys are prefixed with the given prefix, such as
+// "session:dashboard:".
+func NewRedisBackend(client redis.UniversalClient, prefix string) Backend {
+	return &redisBackend{client: client, prefix: prefix}
+}
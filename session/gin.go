@@ -0,0 +1,131 @@
+package session
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const ginContextKey = "github.com/in-rich/lib-go/session"
+
+type CookieConfig struct {
+	// Name of the cookie holding the session ID. Defaults to "session_id".
+	Name     string
+	Domain   string
+	Path     string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+type ginSession[T any] struct {
+	store  *Store[T]
+	cookie CookieConfig
+	id     string
+	value  *T
+}
+
+// Middleware loads the session referenced by the session cookie, if any. Handlers access it with Get, Save
+// and Destroy. An invalid or expired session cookie is ignored.
+func Middleware[T any](store *Store[T], cookie CookieConfig) gin.HandlerFunc {
+	if cookie.Name == "" {
+		cookie.Name = "session_id"
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c *gin.Context) {
+		sess := &ginSession[T]{store: store, cookie: cookie}
+
+		if id, err := c.Cookie(cookie.Name); err == nil && id != "" {
+			value, err := store.Get(c.Request.Context(), id)
+			switch {
+			case err == nil:
+				sess.id = id
+				sess.value = value
+
+				if store.config.Rolling {
+					sess.setCookie(c, int(store.TTL().Seconds()))
+				}
+			case !errors.Is(err, ErrNotFound):
+				_ = c.Error(err)
+			}
+		}
+
+		c.Set(ginContextKey, sess)
+		c.Next()
+	}
+}
+
+func (s *ginSession[T]) setCookie(c *gin.Context, maxAge int) {
+	c.SetSameSite(s.cookie.SameSite)
+	c.SetCookie(s.cookie.Name, s.id, maxAge, s.cookie.Path, s.cookie.Domain, s.cookie.Secure, true)
+}
+
+func fromGin[T any](c *gin.Context) *ginSession[T] {
+	sess, ok := c.Get(ginContextKey)
+	if !ok {
+		panic("session: Middleware is not installed on this route")
+	}
+
+	typed, ok := sess.(*ginSession[T])
+	if !ok {
+		panic("session: Middleware is installed with a different session type")
+	}
+
+	return typed
+}
+
+// Get returns the value of the current session, or false if the request has no valid session.
+func Get[T any](c *gin.Context) (*T, bool) {
+	sess := fromGin[T](c)
+	return sess.value, sess.value != nil
+}
+
+// ID returns the ID of the current session, or an empty string if the request has no valid session.
+func ID[T any](c *gin.Context) string {
+	return fromGin[T](c).id
+}
+
+// Save stores the value of the current session, creating the session if needed. It must be called before the
+// response body is written, so the session cookie can be set.
+func Save[T any](c *gin.Context, value *T) error {
+	sess := fromGin[T](c)
+
+	if sess.id == "" {
+		id, err := NewID()
+		if err != nil {
+			return err
+		}
+
+		sess.id = id
+	}
+
+	if err := sess.store.Set(c.Request.Context(), sess.id, value); err != nil {
+		return err
+	}
+
+	sess.value = value
+	sess.setCookie(c, int(sess.store.TTL().Seconds()))
+	return nil
+}
+
+// Destroy deletes the current session, and clears the session cookie.
+func Destroy[T any](c *gin.Context) error {
+	sess := fromGin[T](c)
+	if sess.id == "" {
+		return nil
+	}
+
+	if err := sess.store.Delete(c.Request.Context(), sess.id); err != nil {
+		return err
+	}
+
+	sess.setCookie(c, -1)
+	sess.id = ""
+	sess.value = nil
+	return nil
+}
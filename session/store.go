@@ -0,0 +1,81 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+type StoreConfig struct {
+	// TTL is the lifetime of a session. Defaults to 24 hours.
+	TTL time.Duration
+	// Rolling extends the lifetime of a session every time it is read.
+	Rolling bool
+}
+
+// Store gives typed access to the sessions of a backend.
+type Store[T any] struct {
+	backend Backend
+	config  StoreConfig
+}
+
+func NewStore[T any](backend Backend, config StoreConfig) *Store[T] {
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+
+	return &Store[T]{backend: backend, config: config}
+}
+
+// Get returns the value of a session, or ErrNotFound. Under rolling expiration, reading a session extends its
+// lifetime.
+func (s *Store[T]) Get(ctx context.Context, id string) (*T, error) {
+	data, err := s.backend.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	value := new(T)
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+
+	if s.config.Rolling {
+		if err := s.backend.Touch(ctx, id, s.config.TTL); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// Set creates or replaces the value of a session, and resets its lifetime.
+func (s *Store[T]) Set(ctx context.Context, id string, value *T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Save(ctx, id, data, s.config.TTL)
+}
+
+func (s *Store[T]) Delete(ctx context.Context, id string) error {
+	return s.backend.Delete(ctx, id)
+}
+
+// TTL returns the lifetime of the sessions of the store.
+func (s *Store[T]) TTL() time.Duration {
+	return s.config.TTL
+}
+
+// NewID generates a random, unguessable session ID.
+func NewID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,44 @@
+package shard
+
+// Move describes a key that changes shard between two assignments.
+type Move struct {
+	Key  string
+	From string
+	To   string
+}
+
+// Moves lists the keys whose shard changes between two assignments, such as before and after a worker joins. Each
+// worker can use it to release the keys it loses, and acquire the keys it gains.
+func Moves(keys []string, before, after Assigner) []Move {
+	moves := make([]Move, 0)
+	for _, key := range keys {
+		from, to := before.Assign(key), after.Assign(key)
+		if from != to {
+			moves = append(moves, Move{Key: key, From: from, To: to})
+		}
+	}
+
+	return moves
+}
+
+// Owned filters the keys assigned to the given shard.
+func Owned(keys []string, assigner Assigner, shard string) []string {
+	owned := make([]string, 0)
+	for _, key := range keys {
+		if assigner.Assign(key) == shard {
+			owned = append(owned, key)
+		}
+	}
+
+	return owned
+}
+
+// Distribution counts the number of keys assigned to each shard, to check the balance of an assignment.
+func Distribution(keys []string, assigner Assigner) map[string]int {
+	out := make(map[string]int)
+	for _, key := range keys {
+		out[assigner.Assign(key)]++
+	}
+
+	return out
+}
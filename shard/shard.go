@@ -0,0 +1,160 @@
+package shard
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// Hash returns a deterministic 64-bit hash of a key, stable across processes and releases.
+func Hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	// FNV has a poor avalanche effect on short keys: mix the bits before use.
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Jump assigns a key to a bucket in [0, buckets), using the jump consistent hash algorithm. When the number of
+// buckets grows from n to n+1, only 1/(n+1) of the keys move, all of them to the new bucket.
+//
+// Jump only supports adding or removing buckets at the end of the range. Use Rendezvous when arbitrary nodes can
+// join or leave.
+//
+// https://arxiv.org/abs/1406.2294
+func Jump(key string, buckets int) int {
+	if buckets <= 0 {
+		return -1
+	}
+
+	h := Hash(key)
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		h = h*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((h>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// Assigner assigns keys to shards.
+type Assigner interface {
+	Assign(key string) string
+}
+
+// JumpRing assigns keys to an ordered list of shards with Jump. New shards must be appended at the end of the
+// list, and only the last shards may be removed.
+type JumpRing []string
+
+func (r JumpRing) Assign(key string) string {
+	if len(r) == 0 {
+		return ""
+	}
+
+	return r[Jump(key, len(r))]
+}
+
+// Node is a member of a rendezvous set.
+type Node struct {
+	Name string
+	// Weight is the relative capacity of the node. Defaults to 1.
+	Weight float64
+}
+
+// Rendezvous assigns keys to nodes with weighted rendezvous (highest random weight) hashing. When a node joins or
+// leaves, only the keys owned by this node move.
+//
+// https://en.wikipedia.org/wiki/Rendezvous_hashing
+type Rendezvous struct {
+	nodes []Node
+}
+
+func NewRendezvous(nodes ...Node) *Rendezvous {
+	normalized := make([]Node, len(nodes))
+	for i, node := range nodes {
+		if node.Weight <= 0 {
+			node.Weight = 1
+		}
+		normalized[i] = node
+	}
+
+	return &Rendezvous{nodes: normalized}
+}
+
+// NewRendezvousNames creates a rendezvous set of nodes with identical weights.
+func NewRendezvousNames(names ...string) *Rendezvous {
+	nodes := make([]Node, len(names))
+	for i, name := range names {
+		nodes[i] = Node{Name: name}
+	}
+
+	return NewRendezvous(nodes...)
+}
+
+func (r *Rendezvous) score(key string, node Node) float64 {
+	h := mix(Hash(key) ^ Hash(node.Name))
+	// Map the hash to (0, 1), then apply the logarithmic method for weighted rendezvous.
+	u := (float64(h>>11) + 0.5) / float64(uint64(1)<<53)
+	return -node.Weight / math.Log(u)
+}
+
+func (r *Rendezvous) Assign(key string) string {
+	owners := r.Owners(key, 1)
+	if len(owners) == 0 {
+		return ""
+	}
+
+	return owners[0]
+}
+
+// Owners returns the n nodes with the highest score for the key, best first. It is useful to pick replicas, or a
+// fallback node when the owner is unavailable. n is clamped to the number of nodes, and no node is returned when it is
+// not positive.
+func (r *Rendezvous) Owners(key string, n int) []string {
+	type scored struct {
+		name  string
+		score float64
+	}
+
+	scores := make([]scored, len(r.nodes))
+	for i, node := range r.nodes {
+		scores[i] = scored{name: node.Name, score: r.score(key, node)}
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score == scores[j].score {
+			return scores[i].name < scores[j].name
+		}
+		return scores[i].score > scores[j].score
+	})
+
+	n = min(max(n, 0), len(scores))
+
+	out := make([]string, n)
+	for i := 0; i < n; i++ {
+		out[i] = scores[i].name
+	}
+
+	return out
+}
+
+// Nodes returns the names of the nodes of the set.
+func (r *Rendezvous) Nodes() []string {
+	out := make([]string, len(r.nodes))
+	for i, node := range r.nodes {
+		out[i] = node.Name
+	}
+
+	return out
+}
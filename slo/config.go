@@ -0,0 +1,55 @@
+package slo
+
+import (
+	"github.com/in-rich/lib-go/deploy"
+	"time"
+)
+
+// Objective is the service level objective of an endpoint.
+type Objective struct {
+	// Availability is the target percentage of requests that do not fail, such as 99.9. Ignored when 0.
+	Availability deploy.Percent `yaml:"availability" json:"availability"`
+	// Latency is the duration under which requests are considered fast. Ignored when 0.
+	Latency deploy.Duration `yaml:"latency" json:"latency"`
+	// LatencyTarget is the target percentage of requests faster than Latency. Defaults to 99.
+	LatencyTarget deploy.Percent `yaml:"latencyTarget" json:"latencyTarget"`
+}
+
+// Config declares the objectives of a service, keyed by GRPC full method ("/notes.v1.Notes/GetNote") or HTTP route
+// ("GET /notes/:id"). Keys ending with "*" match by prefix. Endpoints without objective are not tracked.
+//
+//	window: 24h
+//	objectives:
+//	  "/notes.v1.Notes/*":
+//	    availability: 99.9
+//	    latency: 300ms
+//	  "GET /exports/*":
+//	    availability: 99
+type Config struct {
+	Objectives map[string]Objective `yaml:"objectives" json:"objectives"`
+	// Window is the rolling window of the error budget. Defaults to 24 hours.
+	Window deploy.Duration `yaml:"window" json:"window"`
+	// BurnRateThreshold is the burn rate over which a warning is emitted, when it is exceeded over both the last 5
+	// minutes and the last hour. A burn rate of 1 consumes the budget exactly over the window. Defaults to 14.4.
+	BurnRateThreshold float64 `yaml:"burnRateThreshold" json:"burnRateThreshold"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = deploy.Duration(24 * time.Hour)
+	}
+	if c.BurnRateThreshold <= 0 {
+		c.BurnRateThreshold = 14.4
+	}
+
+	objectives := make(map[string]Objective, len(c.Objectives))
+	for key, objective := range c.Objectives {
+		if objective.LatencyTarget <= 0 {
+			objective.LatencyTarget = 99
+		}
+		objectives[key] = objective
+	}
+	c.Objectives = objectives
+
+	return c
+}
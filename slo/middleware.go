@@ -0,0 +1,62 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/in-rich/lib-go/introspect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// serverFault reports whether a GRPC code is the fault of the server. Errors caused by the caller, such as
+// invalid arguments, do not consume the error budget.
+func serverFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnaryServerInterceptor records the outcome and the latency of every RPC.
+func (t *Tracker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		t.Record(info.FullMethod, time.Since(start), serverFault(status.Code(err)))
+
+		return res, err
+	}
+}
+
+// GinMiddleware records the outcome and the latency of every HTTP request, keyed by method and route
+// ("GET /notes/:id"). Responses with a 5xx status are failures.
+func (t *Tracker) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if route := c.FullPath(); route != "" {
+			t.Record(c.Request.Method+" "+route, time.Since(start), c.Writer.Status() >= http.StatusInternalServerError)
+		}
+	}
+}
+
+// Mount registers the ops endpoint of the tracker on the mux, at prefix + "/slo". It lists the error budgets of
+// the tracked endpoints.
+func (t *Tracker) Mount(mux *http.ServeMux, prefix string, allowlist *introspect.IPAllowlist) {
+	if allowlist == nil {
+		panic("slo: an IP allowlist is required to mount the SLO endpoint")
+	}
+
+	mux.Handle(strings.TrimSuffix(prefix, "/")+"/slo", allowlist.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(t.Budgets())
+	})))
+}
@@ -0,0 +1,244 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"github.com/in-rich/lib-go/monitor"
+	"github.com/in-rich/lib-go/pattern"
+	"sort"
+	"sync"
+	"time"
+)
+
+type counts struct {
+	minute int64
+	total  int64
+	failed int64
+	slow   int64
+}
+
+func (c *counts) add(other counts) {
+	c.total += other.total
+	c.failed += other.failed
+	c.slow += other.slow
+}
+
+// series holds per-minute counts over the budget window, in a ring.
+type series struct {
+	buckets []counts
+}
+
+func (s *series) record(minute int64, failed, slow bool) {
+	bucket := &s.buckets[minute%int64(len(s.buckets))]
+	if bucket.minute != minute {
+		*bucket = counts{minute: minute}
+	}
+
+	bucket.total++
+	if failed {
+		bucket.failed++
+	}
+	if slow {
+		bucket.slow++
+	}
+}
+
+// sum returns the counts of the last minutes, including the current one.
+func (s *series) sum(now int64, minutes int) counts {
+	var out counts
+	for _, bucket := range s.buckets {
+		if bucket.minute > now-int64(minutes) && bucket.minute <= now {
+			out.add(bucket)
+		}
+	}
+
+	return out
+}
+
+// Budget is the state of the objective of an endpoint.
+type Budget struct {
+	Endpoint  string    `json:"endpoint"`
+	Objective Objective `json:"objective"`
+	Requests  int64     `json:"requests"`
+
+	Availability *Indicator `json:"availability,omitempty"`
+	Latency      *Indicator `json:"latency,omitempty"`
+}
+
+// Indicator is the state of a single objective.
+type Indicator struct {
+	// Ratio is the percentage of good requests over the window.
+	Ratio float64 `json:"ratio"`
+	// Remaining is the fraction of the error budget left over the window. It is negative once the budget is
+	// exhausted.
+	Remaining float64 `json:"remaining"`
+	// BurnRate5m and BurnRate1h are the rates at which the budget is consumed, 1 meaning exactly over the window.
+	BurnRate5m float64 `json:"burnRate5m"`
+	BurnRate1h float64 `json:"burnRate1h"`
+}
+
+func indicator(window, short, long counts, bad func(counts) int64, target float64) *Indicator {
+	allowed := 1 - target/100
+
+	rate := func(c counts) float64 {
+		if c.total == 0 || allowed <= 0 {
+			return 0
+		}
+		return float64(bad(c)) / float64(c.total) / allowed
+	}
+
+	out := &Indicator{Ratio: 100, Remaining: 1, BurnRate5m: rate(short), BurnRate1h: rate(long)}
+	if window.total > 0 {
+		out.Ratio = 100 * (1 - float64(bad(window))/float64(window.total))
+		if allowed > 0 {
+			out.Remaining = 1 - float64(bad(window))/(allowed*float64(window.total))
+		}
+	}
+
+	return out
+}
+
+// Alert is emitted when an objective burns its budget faster than the threshold.
+type Alert struct {
+	Endpoint string
+	// Indicator is "availability" or "latency".
+	Indicator string
+	BurnRate  float64
+}
+
+// Tracker records the requests of the endpoints with an objective, and computes their error budget over a rolling
+// window. Counts are kept in memory, per instance.
+//
+//	tracker := slo.NewTracker(logger, cfg.SLO, nil)
+//	go tracker.Run(ctx)
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(tracker.UnaryServerInterceptor()))
+//	tracker.Mount(opsMux, "/ops", allowlist)
+type Tracker struct {
+	logger  monitor.Logger
+	config  Config
+	onAlert func(alert Alert)
+	// objectives matches endpoints to the keys of config.Objectives.
+	objectives *pattern.Matcher[Objective]
+
+	mu      sync.Mutex
+	series  map[string]*series
+	burning map[string]bool
+}
+
+// NewTracker creates a tracker. Alerts are logged as warnings, and passed to onAlert when given.
+func NewTracker(logger monitor.Logger, config Config, onAlert func(alert Alert)) *Tracker {
+	config = config.withDefaults()
+
+	return &Tracker{
+		logger:     logger,
+		config:     config,
+		onAlert:    onAlert,
+		objectives: pattern.NewMatcher(config.Objectives),
+		series:     make(map[string]*series),
+		burning:    make(map[string]bool),
+	}
+}
+
+func (t *Tracker) windowMinutes() int {
+	return max(int(t.config.Window.Duration()/time.Minute), 60)
+}
+
+// Record counts a request of an endpoint. Requests of endpoints without objective are ignored.
+func (t *Tracker) Record(endpoint string, duration time.Duration, failed bool) {
+	objective, key, ok := t.objectives.Match(endpoint)
+	if !ok {
+		return
+	}
+
+	slow := objective.Latency > 0 && duration > objective.Latency.Duration()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[key]
+	if !ok {
+		s = &series{buckets: make([]counts, t.windowMinutes())}
+		t.series[key] = s
+	}
+
+	s.record(time.Now().Unix()/60, failed, slow)
+}
+
+// Budgets returns the error budgets of the tracked endpoints, sorted by endpoint.
+func (t *Tracker) Budgets() []Budget {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now().Unix() / 60
+	out := make([]Budget, 0, len(t.series))
+
+	for key, s := range t.series {
+		objective := t.config.Objectives[key]
+		window, short, long := s.sum(now, len(s.buckets)), s.sum(now, 5), s.sum(now, 60)
+
+		budget := Budget{Endpoint: key, Objective: objective, Requests: window.total}
+		if objective.Availability > 0 {
+			budget.Availability = indicator(window, short, long, func(c counts) int64 { return c.failed }, float64(objective.Availability))
+		}
+		if objective.Latency > 0 {
+			budget.Latency = indicator(window, short, long, func(c counts) int64 { return c.slow }, float64(objective.LatencyTarget))
+		}
+
+		out = append(out, budget)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+// Evaluate emits an alert for every objective whose burn rate exceeds the threshold over both the last 5 minutes
+// and the last hour. An objective is only reported again after it recovered.
+func (t *Tracker) Evaluate() {
+	for _, budget := range t.Budgets() {
+		t.evaluate(budget.Endpoint, "availability", budget.Availability)
+		t.evaluate(budget.Endpoint, "latency", budget.Latency)
+	}
+}
+
+func (t *Tracker) evaluate(endpoint, name string, value *Indicator) {
+	if value == nil {
+		return
+	}
+
+	burning := value.BurnRate5m >= t.config.BurnRateThreshold && value.BurnRate1h >= t.config.BurnRateThreshold
+	key := endpoint + " " + name
+
+	t.mu.Lock()
+	wasBurning := t.burning[key]
+	t.burning[key] = burning
+	t.mu.Unlock()
+
+	if !burning || wasBurning {
+		return
+	}
+
+	t.logger.Warn(fmt.Sprintf(
+		"[slo] %s objective of %s is burning its error budget %.1fx too fast (%.1f%% left)",
+		name, endpoint, value.BurnRate1h, value.Remaining*100,
+	))
+
+	if t.onAlert != nil {
+		t.onAlert(Alert{Endpoint: endpoint, Indicator: name, BurnRate: value.BurnRate1h})
+	}
+}
+
+// Run evaluates the objectives every minute, until the context is canceled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.Evaluate()
+		}
+	}
+}
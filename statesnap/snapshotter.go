@@ -0,0 +1,162 @@
+package statesnap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/in-rich/lib-go/ctxutil"
+	"github.com/in-rich/lib-go/monitor"
+	"sync"
+	"time"
+)
+
+// ErrVersion is returned when restoring a snapshot saved with another version of the state.
+var ErrVersion = errors.New("snapshot version mismatch")
+
+// ErrExpired is returned when restoring a snapshot older than the configured maximum age.
+var ErrExpired = errors.New("snapshot expired")
+
+type Config struct {
+	// Interval between two snapshots. Defaults to 30 seconds.
+	Interval time.Duration
+	// Version of the state layout. Snapshots saved with another version are not restored, so bump it when the
+	// state type changes in a way JSON decoding cannot absorb.
+	Version int
+	// MaxAge discards snapshots older than this on restore, for state that is meaningless once too old, such as a
+	// dedup window. Zero keeps snapshots forever.
+	MaxAge time.Duration
+	// SaveTimeout bounds the final snapshot, saved once the context of Run is canceled. Defaults to 10 seconds.
+	SaveTimeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.SaveTimeout <= 0 {
+		c.SaveTimeout = 10 * time.Second
+	}
+
+	return c
+}
+
+type envelope[T any] struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"savedAt"`
+	State   T         `json:"state"`
+}
+
+// Snapshotter periodically saves the state of a worker under a key, and restores it on startup. Use one key per
+// worker: instances sharing a key overwrite each other's state.
+type Snapshotter[T any] struct {
+	store  Store
+	key    string
+	logger monitor.Logger
+	config Config
+
+	mu sync.Mutex
+	// last is the encoded state of the last snapshot saved or restored.
+	last []byte
+}
+
+func NewSnapshotter[T any](store Store, key string, logger monitor.Logger, config Config) *Snapshotter[T] {
+	return &Snapshotter[T]{
+		store:  store,
+		key:    key,
+		logger: logger,
+		config: config.withDefaults(),
+	}
+}
+
+// Restore loads the last saved state. It returns ErrNotFound on the first run, and ErrVersion or ErrExpired when
+// the snapshot cannot be used: in every case, the worker starts from an empty state.
+//
+//	cursor, err := snapshotter.Restore(ctx)
+//	if err != nil && !errors.Is(err, statesnap.ErrNotFound) {
+//		logger.Warn(fmt.Sprintf("[sync] starting from scratch: %s", err))
+//	}
+func (s *Snapshotter[T]) Restore(ctx context.Context) (T, error) {
+	var zero T
+
+	data, err := s.store.Load(ctx, s.key)
+	if err != nil {
+		return zero, err
+	}
+
+	var snapshot envelope[T]
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return zero, fmt.Errorf("decode snapshot %s: %w", s.key, err)
+	}
+
+	if snapshot.Version != s.config.Version {
+		return zero, fmt.Errorf("%w: got %d, expected %d", ErrVersion, snapshot.Version, s.config.Version)
+	}
+	if s.config.MaxAge > 0 && time.Since(snapshot.SavedAt) > s.config.MaxAge {
+		return zero, fmt.Errorf("%w: saved at %s", ErrExpired, snapshot.SavedAt.Format(time.RFC3339))
+	}
+
+	if encoded, err := json.Marshal(snapshot.State); err == nil {
+		s.mu.Lock()
+		s.last = encoded
+		s.mu.Unlock()
+	}
+
+	return snapshot.State, nil
+}
+
+// Save stores the state, unless it did not change since the last snapshot.
+func (s *Snapshotter[T]) Save(ctx context.Context, state T) error {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if bytes.Equal(s.last, encoded) {
+		return nil
+	}
+
+	data, err := json.Marshal(envelope[json.RawMessage]{
+		Version: s.config.Version,
+		SavedAt: time.Now(),
+		State:   encoded,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Save(ctx, s.key, data); err != nil {
+		return err
+	}
+
+	s.last = encoded
+	return nil
+}
+
+// Run saves the state returned by the callback at every interval, until the context is canceled. A last snapshot
+// is then saved, so the progress made since the previous one survives a graceful shutdown. The callback must be
+// safe to call concurrently with the worker, and should return a copy of the state.
+func (s *Snapshotter[T]) Run(ctx context.Context, state func() T) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			saveCtx, cancel := ctxutil.DetachWithTimeout(ctx, s.config.SaveTimeout)
+			if err := s.Save(saveCtx, state()); err != nil {
+				s.logger.Error(err, fmt.Sprintf("[statesnap] failed to save final snapshot %s", s.key))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := s.Save(ctx, state()); err != nil && ctx.Err() == nil {
+				s.logger.Error(err, fmt.Sprintf("[statesnap] failed to save snapshot %s", s.key))
+			}
+		}
+	}
+}
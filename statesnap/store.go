@@ -0,0 +1,149 @@
+package statesnap
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/storage/v1"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("snapshot not found")
+
+// Store persists snapshots, outside the instance that produced them.
+type Store interface {
+	// Load returns ErrNotFound if no snapshot was saved under the key.
+	Load(ctx context.Context, key string) ([]byte, error)
+	Save(ctx context.Context, key string, data []byte) error
+}
+
+type gcsStore struct {
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func (s *gcsStore) Load(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.service.Objects.Get(s.bucket, s.prefix+key).Context(ctx).Download()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("download snapshot %s: %w", key, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	return io.ReadAll(res.Body)
+}
+
+func (s *gcsStore) Save(ctx context.Context, key string, data []byte) error {
+	_, err := s.service.Objects.
+		Insert(s.bucket, &storage.Object{Name: s.prefix + key, ContentType: "application/json"}).
+		Media(bytes.NewReader(data)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("upload snapshot %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// NewGCSStore creates a store that keeps one JSON object per key in a GCS bucket, under the given prefix. Every
+// save overwrites the previous object: enable versioning on the bucket to keep a history.
+func NewGCSStore(ctx context.Context, bucket, prefix string, opts ...option.ClientOption) (Store, error) {
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create storage client: %w", err)
+	}
+
+	return &gcsStore{service: service, bucket: bucket, prefix: prefix}, nil
+}
+
+// Snapshot is a snapshot stored in Postgres.
+type Snapshot struct {
+	bun.BaseModel `bun:"table:statesnap_snapshots,alias:snapshot"`
+
+	Key     string    `bun:"key,pk"`
+	Data    []byte    `bun:"data,notnull"`
+	SavedAt time.Time `bun:"saved_at,notnull"`
+}
+
+// CreateTable creates the snapshots table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*Snapshot)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+type postgresStore struct {
+	db bun.IDB
+}
+
+func (s *postgresStore) Load(ctx context.Context, key string) ([]byte, error) {
+	snapshot := &Snapshot{Key: key}
+	if err := s.db.NewSelect().Model(snapshot).WherePK().Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return snapshot.Data, nil
+}
+
+func (s *postgresStore) Save(ctx context.Context, key string, data []byte) error {
+	_, err := s.db.NewInsert().
+		Model(&Snapshot{Key: key, Data: data, SavedAt: time.Now()}).
+		On("CONFLICT (key) DO UPDATE").
+		Set("data = EXCLUDED.data").
+		Set("saved_at = EXCLUDED.saved_at").
+		Exec(ctx)
+	return err
+}
+
+// NewPostgresStore creates a store that keeps snapshots in the table created by CreateTable.
+func NewPostgresStore(db bun.IDB) Store {
+	return &postgresStore{db: db}
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	snapshots map[string][]byte
+}
+
+func (s *memoryStore) Load(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.snapshots[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[key] = bytes.Clone(data)
+	return nil
+}
+
+// NewMemoryStore creates a store that keeps snapshots in the memory of the current instance. It does not survive
+// a restart, and is meant for local development.
+func NewMemoryStore() Store {
+	return &memoryStore{snapshots: make(map[string][]byte)}
+}
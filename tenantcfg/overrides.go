@@ -0,0 +1,132 @@
+package tenantcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/in-rich/lib-go/collections"
+	"github.com/in-rich/lib-go/deploy"
+	"github.com/in-rich/lib-go/metering"
+	"github.com/in-rich/lib-go/monitor"
+	"time"
+)
+
+type Config struct {
+	// TTL is the time an override is cached before being read again from the store. Defaults to 1 minute.
+	TTL time.Duration
+	// MaxTenants is the number of tenant configurations kept in cache. Defaults to 10000.
+	MaxTenants int
+}
+
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = time.Minute
+	}
+	if c.MaxTenants <= 0 {
+		c.MaxTenants = 10000
+	}
+
+	return c
+}
+
+// Overrides resolves the configuration of the tenant of a request: the configuration files, with the override of
+// the tenant applied on top, like an additional file.
+//
+//	cfg := tenantcfg.NewOverrides[Config](store, logger, tenantcfg.Config{},
+//		deploy.GlobalConfig(globalFile), deploy.ProdConfig(prodFile),
+//	)
+//
+//	limit := cfg.For(ctx).Exports.MaxRows
+//
+// An override only holds the fields it changes:
+//
+//	exports:
+//	  maxRows: 500000
+//
+// Resolved configurations are cached and shared: never modify them.
+type Overrides[Cfg any] struct {
+	store  Store
+	logger monitor.Logger
+	config Config
+	files  []deploy.ConfigFile
+
+	base  *Cfg
+	cache *collections.LRU[string, *Cfg]
+}
+
+func NewOverrides[Cfg any](store Store, logger monitor.Logger, config Config, files ...deploy.ConfigFile) *Overrides[Cfg] {
+	config = config.withDefaults()
+
+	return &Overrides[Cfg]{
+		store:  store,
+		logger: logger,
+		config: config,
+		files:  files,
+		base:   deploy.LoadConfig[Cfg](files...),
+		cache: collections.NewLRU(collections.LRUConfig[string, *Cfg]{
+			MaxEntries: config.MaxTenants,
+			TTL:        config.TTL,
+		}),
+	}
+}
+
+// Base returns the configuration without override.
+func (o *Overrides[Cfg]) Base() *Cfg {
+	return o.base
+}
+
+// For returns the configuration of the tenant of the context (see metering.WithTenant), or the base configuration
+// for requests without tenant.
+func (o *Overrides[Cfg]) For(ctx context.Context) *Cfg {
+	tenant := metering.TenantFromContext(ctx)
+	if tenant == "" {
+		return o.base
+	}
+
+	return o.ForTenant(ctx, tenant)
+}
+
+// ForTenant returns the configuration of a tenant. The base configuration is returned, and not cached, when the
+// override cannot be loaded or is invalid, so a broken override never fails requests.
+func (o *Overrides[Cfg]) ForTenant(ctx context.Context, tenant string) *Cfg {
+	if cfg, ok := o.cache.Get(tenant); ok {
+		return cfg
+	}
+
+	cfg, err := o.resolve(ctx, tenant)
+	if err != nil {
+		o.logger.Error(err, fmt.Sprintf("[tenantcfg] failed to resolve configuration of tenant %s", tenant))
+		return o.base
+	}
+
+	o.cache.Set(tenant, cfg)
+	return cfg
+}
+
+func (o *Overrides[Cfg]) resolve(ctx context.Context, tenant string) (*Cfg, error) {
+	override, err := o.store.Load(ctx, tenant)
+	if errors.Is(err, ErrNotFound) {
+		return o.base, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Load a fresh copy of the files, so the override does not leak into the base configuration.
+	cfg, err := deploy.ReadConfig[Cfg](o.files...)
+	if err != nil {
+		return nil, fmt.Errorf("load configuration: %w", err)
+	}
+
+	if err := yaml.Unmarshal(override, cfg); err != nil {
+		return nil, fmt.Errorf("decode override: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Invalidate drops the cached configuration of a tenant, after its override was updated.
+func (o *Overrides[Cfg]) Invalidate(tenant string) {
+	o.cache.Delete(tenant)
+}
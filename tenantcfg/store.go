@@ -0,0 +1,106 @@
+package tenantcfg
+
+import (
+	gcpfirestore "cloud.google.com/go/firestore"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/in-rich/lib-go/firestore"
+	"github.com/uptrace/bun"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("no override for tenant")
+
+// Store holds the overrides of each tenant, as YAML documents using the layout of the configuration files.
+type Store interface {
+	// Load returns ErrNotFound if the tenant has no override.
+	Load(ctx context.Context, tenant string) ([]byte, error)
+}
+
+// Override is an override stored in Postgres.
+type Override struct {
+	bun.BaseModel `bun:"table:tenant_config_overrides,alias:override"`
+
+	Tenant    string    `bun:"tenant,pk"`
+	Document  string    `bun:"document,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+// CreateTable creates the overrides table, if it does not exist yet.
+func CreateTable(ctx context.Context, db bun.IDB) error {
+	_, err := db.NewCreateTable().Model((*Override)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+type postgresStore struct {
+	db bun.IDB
+}
+
+func (s *postgresStore) Load(ctx context.Context, tenant string) ([]byte, error) {
+	override := &Override{Tenant: tenant}
+	if err := s.db.NewSelect().Model(override).WherePK().Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return []byte(override.Document), nil
+}
+
+// NewPostgresStore creates a store reading the table created by CreateTable.
+func NewPostgresStore(db bun.IDB) Store {
+	return &postgresStore{db: db}
+}
+
+// FirestoreOverride is an override stored in Firestore, with the tenant as document ID.
+type FirestoreOverride struct {
+	Document string `firestore:"document"`
+}
+
+type firestoreStore struct {
+	collection *firestore.Collection[FirestoreOverride]
+}
+
+func (s *firestoreStore) Load(ctx context.Context, tenant string) ([]byte, error) {
+	document, err := s.collection.Get(ctx, tenant)
+	if err != nil {
+		if errors.Is(err, firestore.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return []byte(document.Data.Document), nil
+}
+
+// NewFirestoreStore creates a store reading the documents of the given collection.
+func NewFirestoreStore(client *gcpfirestore.Client, path string) Store {
+	return &firestoreStore{collection: firestore.NewCollection[FirestoreOverride](client, path)}
+}
+
+type memoryStore struct {
+	mu        sync.RWMutex
+	overrides map[string][]byte
+}
+
+func (s *memoryStore) Load(_ context.Context, tenant string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	override, ok := s.overrides[tenant]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return override, nil
+}
+
+// NewMemoryStore creates a store serving fixed overrides, for local development.
+func NewMemoryStore(overrides map[string][]byte) Store {
+	return &memoryStore{overrides: overrides}
+}
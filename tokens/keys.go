@@ -0,0 +1,126 @@
+package tokens
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidKey = errors.New("invalid token key")
+
+// Key is a secret used to encrypt and authenticate tokens.
+type Key struct {
+	// ID identifies the key inside tokens, so tokens minted with a retired key can still be verified.
+	ID string
+	// Secret must be 32 bytes long.
+	Secret []byte
+}
+
+// ParseKey decodes a key from its base64 representation, as stored in configuration.
+func ParseKey(id, secret string) (Key, error) {
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w %s: %w", ErrInvalidKey, id, err)
+	}
+
+	return Key{ID: id, Secret: decoded}, nil
+}
+
+// Keyring holds the key used to mint new tokens, and older keys still accepted for verification.
+//
+// To rotate keys, deploy the new key as a retired one first, so every instance can verify it. Then promote it to
+// active, and keep the previous active key as retired until the tokens it minted expired.
+type Keyring struct {
+	active  string
+	ciphers map[string]cipher.AEAD
+}
+
+func NewKeyring(active Key, retired ...Key) (*Keyring, error) {
+	keyring := &Keyring{active: active.ID, ciphers: make(map[string]cipher.AEAD)}
+
+	for _, key := range append([]Key{active}, retired...) {
+		if key.ID == "" || len(key.ID) > 255 {
+			return nil, fmt.Errorf("%w: key ID must be between 1 and 255 bytes", ErrInvalidKey)
+		}
+
+		if len(key.Secret) != 32 {
+			return nil, fmt.Errorf("%w %s: secret must be 32 bytes long", ErrInvalidKey, key.ID)
+		}
+
+		if _, ok := keyring.ciphers[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate key ID %s", ErrInvalidKey, key.ID)
+		}
+
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", ErrInvalidKey, key.ID, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", ErrInvalidKey, key.ID, err)
+		}
+
+		keyring.ciphers[key.ID] = aead
+	}
+
+	return keyring, nil
+}
+
+func additionalData(keyID, purpose string) []byte {
+	return []byte(keyID + "\x00" + purpose)
+}
+
+// Seal encrypts and authenticates data with the active key, bound to the given purpose. The output holds the ID
+// of the key, so it can still be opened after the key is retired.
+func (k *Keyring) Seal(plaintext []byte, purpose string) ([]byte, error) {
+	keyID := k.active
+	aead := k.ciphers[keyID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// Layout: key ID length (1 byte) | key ID | nonce | ciphertext.
+	out := make([]byte, 0, 1+len(keyID)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, byte(len(keyID)))
+	out = append(out, keyID...)
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, plaintext, additionalData(keyID, purpose)), nil
+}
+
+// Open decrypts data sealed for the given purpose. It returns ErrUnknownKey if the data was sealed with a key
+// missing from the keyring, and ErrInvalidToken if it is malformed, tampered with, or sealed for another purpose.
+func (k *Keyring) Open(sealed []byte, purpose string) ([]byte, error) {
+	if len(sealed) < 1 {
+		return nil, ErrInvalidToken
+	}
+
+	keyLength := int(sealed[0])
+	if len(sealed) < 1+keyLength {
+		return nil, ErrInvalidToken
+	}
+
+	keyID := string(sealed[1 : 1+keyLength])
+	aead, ok := k.ciphers[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	sealed = sealed[1+keyLength:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidToken
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData(keyID, purpose))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,75 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+var ErrReplayed = errors.New("token already used")
+
+// ReplayGuard prevents single-use tokens from being used twice.
+type ReplayGuard interface {
+	// Consume marks a token as used. It returns ErrReplayed if the token was already consumed. The guard only needs
+	// to remember the token until it expires.
+	Consume(ctx context.Context, tokenID string, expiresAt time.Time) error
+}
+
+type memoryReplayGuard struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func (g *memoryReplayGuard) Consume(_ context.Context, tokenID string, expiresAt time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for id, expiration := range g.used {
+		if now.After(expiration) {
+			delete(g.used, id)
+		}
+	}
+
+	if _, ok := g.used[tokenID]; ok {
+		return ErrReplayed
+	}
+
+	g.used[tokenID] = expiresAt
+	return nil
+}
+
+// NewMemoryReplayGuard creates a replay guard local to the current process. Only use it for tests, or for
+// services running a single instance.
+func NewMemoryReplayGuard() ReplayGuard {
+	return &memoryReplayGuard{used: make(map[string]time.Time)}
+}
+
+type redisReplayGuard struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (g *redisReplayGuard) Consume(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+
+	ok, err := g.client.SetNX(ctx, g.prefix+tokenID, 1, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReplayed
+	}
+
+	return nil
+}
+
+// NewRedisReplayGuard creates a replay guard shared by every instance connected to the same Redis server.
+func NewRedisReplayGuard(client redis.UniversalClient, prefix string) ReplayGuard {
+	return &redisReplayGuard{client: client, prefix: prefix}
+}
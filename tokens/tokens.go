@@ -0,0 +1,128 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpired      = errors.New("token expired")
+	ErrUnknownKey   = errors.New("token minted with an unknown key")
+)
+
+// Purposes of the tokens shared across services.
+const (
+	PurposeTeamInvitation    = "team_invitation"
+	PurposeEmailVerification = "email_verification"
+	PurposeUnsubscribe       = "unsubscribe"
+)
+
+type claims struct {
+	ID        string          `json:"jti"`
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Token is a verified token.
+type Token[T any] struct {
+	ID        string
+	Purpose   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Payload   T
+}
+
+// Issuer mints and verifies tokens. The content of tokens is encrypted, and bound to their purpose: a token
+// minted for a purpose is rejected for any other purpose.
+type Issuer struct {
+	keys *Keyring
+	// replay is optional. When set, verified tokens can only be used once.
+	replay ReplayGuard
+}
+
+// NewIssuer creates an issuer. The replay guard is optional: when nil, tokens can be verified any number of
+// times before they expire.
+func NewIssuer(keys *Keyring, replay ReplayGuard) *Issuer {
+	return &Issuer{keys: keys, replay: replay}
+}
+
+// Mint creates a token for the given purpose, that expires after ttl.
+func Mint[T any](issuer *Issuer, purpose string, payload T, ttl time.Duration) (string, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("serialize payload: %w", err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	plaintext, err := json.Marshal(claims{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Payload:   rawPayload,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	out, err := issuer.keys.Seal(plaintext, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Verify decrypts a token, and checks its purpose and expiration. Under a replay guard, the token is consumed, and
+// any later verification fails with ErrReplayed.
+func Verify[T any](ctx context.Context, issuer *Issuer, purpose string, token string) (*Token[T], error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Also fails when the token was minted for another purpose.
+	plaintext, err := issuer.keys.Open(raw, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded claims
+	if err := json.Unmarshal(plaintext, &decoded); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	out := &Token[T]{
+		ID:        decoded.ID,
+		Purpose:   purpose,
+		IssuedAt:  time.Unix(decoded.IssuedAt, 0),
+		ExpiresAt: time.Unix(decoded.ExpiresAt, 0),
+	}
+
+	if time.Now().After(out.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	if err := json.Unmarshal(decoded.Payload, &out.Payload); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if issuer.replay != nil {
+		if err := issuer.replay.Consume(ctx, purpose+":"+out.ID, out.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}